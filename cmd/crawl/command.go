@@ -0,0 +1,103 @@
+package crawl
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ettle/strcase"
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/plugin-service/cmd/internal"
+	"github.com/traefik/plugin-service/pkg/crawler"
+	"github.com/traefik/plugin-service/pkg/db/mongodb"
+	"github.com/traefik/plugin-service/pkg/events"
+	"github.com/traefik/plugin-service/pkg/jobs"
+	"github.com/traefik/plugin-service/pkg/webhooks"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	flagTopic = "topic"
+)
+
+// Config holds the crawl configuration.
+type Config struct {
+	Topic string
+
+	MongoDB mongodb.Config
+	GitHub  internal.GitHub
+}
+
+// Command creates the command for discovering the plugins published on GitHub.
+func Command() *cli.Command {
+	cmd := &cli.Command{
+		Name:        "crawl",
+		Usage:       "Crawl the plugin repositories",
+		Description: "Search the GitHub repositories by topic, and create or update the plugins from their manifest and tags",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    flagTopic,
+				Usage:   "GitHub topic of the plugin repositories",
+				EnvVars: []string{strcase.ToSNAKE(flagTopic)},
+				Value:   crawler.DefaultTopic,
+			},
+		},
+		Action: func(cliCtx *cli.Context) error {
+			return run(cliCtx.Context, buildConfig(cliCtx))
+		},
+	}
+
+	cmd.Flags = append(cmd.Flags, internal.GitHubFlags()...)
+	cmd.Flags = append(cmd.Flags, internal.MongoFlags()...)
+
+	return cmd
+}
+
+func buildConfig(cliCtx *cli.Context) Config {
+	return Config{
+		Topic:   cliCtx.String(flagTopic),
+		MongoDB: internal.BuildMongoConfig(cliCtx),
+		GitHub:  internal.BuildGitHubConfig(cliCtx),
+	}
+}
+
+func run(ctx context.Context, cfg Config) error {
+	store, tearDown, err := internal.CreateMongoClient(ctx, cfg.MongoDB)
+	if err != nil {
+		return fmt.Errorf("unable to create MongoDB client: %w", err)
+	}
+	defer tearDown()
+
+	ghClient, _, err := internal.NewGitHubClient(cfg.GitHub)
+	if err != nil {
+		return fmt.Errorf("unable to create GitHub client: %w", err)
+	}
+
+	queue, err := jobs.NewQueue(store, jobs.DefaultConfig())
+	if err != nil {
+		return fmt.Errorf("unable to create job queue: %w", err)
+	}
+
+	// The events are streamed to the clients by the change streams of the serve replicas,
+	// and the webhook deliveries are run by their job workers.
+	emitter := events.NewEmitter(store, webhooks.NewDispatcher(store, queue, nil))
+
+	report, err := crawler.New(ghClient, store, emitter, cfg.Topic).Crawl(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to crawl the plugin repositories: %w", err)
+	}
+
+	log.Info().
+		Int("repositories", report.Repositories).
+		Int("created", report.Created).
+		Int("updated", report.Updated).
+		Int("unchanged", report.Unchanged).
+		Int("rejected", len(report.Rejected)).
+		Int("failed", report.Failed).
+		Msg("Crawl done")
+
+	if report.Failed > 0 {
+		return fmt.Errorf("%d plugin repositories couldn't be crawled", report.Failed)
+	}
+
+	return nil
+}
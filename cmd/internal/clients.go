@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/ettle/strcase"
+	"github.com/google/go-github/v74/github"
+	"github.com/ldez/grignotin/goproxy"
+	"github.com/traefik/plugin-service/pkg/archive"
+	"github.com/traefik/plugin-service/pkg/upstream"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	flagGoProxyURL      = "go-proxy-url"
+	flagGoProxyUsername = "go-proxy-username"
+	flagGoProxyPassword = "go-proxy-password"
+	flagGoProxyPrefix   = "go-proxy"
+)
+
+// GoProxy holds the go-proxy configuration.
+type GoProxy struct {
+	URL      string
+	Username string
+	Password string
+	Upstream upstream.Config
+}
+
+// GoProxyFlags setup CLI flags for the Go proxy.
+func GoProxyFlags() []cli.Flag {
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:     flagGoProxyURL,
+			Usage:    "Go Proxy URLs, with the GOPROXY syntax: comma or pipe separated URLs (credentials in the URL user info), direct or off",
+			EnvVars:  []string{strcase.ToSNAKE(flagGoProxyURL)},
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:    flagGoProxyUsername,
+			Usage:   "Go Proxy Username, for the first Go proxy URL without user info",
+			EnvVars: []string{strcase.ToSNAKE(flagGoProxyUsername)},
+		},
+		&cli.StringFlag{
+			Name:    flagGoProxyPassword,
+			Usage:   "Go Proxy Password, for the first Go proxy URL without user info",
+			EnvVars: []string{strcase.ToSNAKE(flagGoProxyPassword)},
+		},
+	}
+
+	return append(flags, upstreamFlags(flagGoProxyPrefix, "Go proxy")...)
+}
+
+// BuildGoProxyConfig creates the go-proxy configuration.
+func BuildGoProxyConfig(cliCtx *cli.Context) GoProxy {
+	return GoProxy{
+		URL:      cliCtx.String(flagGoProxyURL),
+		Username: cliCtx.String(flagGoProxyUsername),
+		Password: cliCtx.String(flagGoProxyPassword),
+		Upstream: buildUpstreamConfig(cliCtx, flagGoProxyPrefix),
+	}
+}
+
+// NewGoProxyClient creates a Go proxy client fetching the modules from the configured GOPROXY list,
+// and returns the circuit breakers of the proxies.
+// The direct mode builds the modules from their GitHub repository with the GitHub client.
+func NewGoProxyClient(cfg GoProxy, gh *github.Client) (*archive.GoProxy, []*upstream.Breaker, error) {
+	entries, err := archive.ParseGoProxy(cfg.URL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		upstreams []archive.Upstream
+		breakers  []*upstream.Breaker
+		// credentials true once the credentials of the flags are used.
+		credentials bool
+	)
+
+	for _, entry := range entries {
+		switch entry.URL {
+		case archive.GoProxyOff:
+			upstreams = append(upstreams, archive.Upstream{Name: archive.GoProxyOff})
+
+		case archive.GoProxyDirect:
+			if gh == nil {
+				return nil, nil, errors.New("the direct Go proxy mode requires a GitHub client")
+			}
+
+			upstreams = append(upstreams, archive.Upstream{Name: archive.GoProxyDirect, Source: archive.NewDirectSource(gh), Direct: true})
+
+		default:
+			proxyURL, err := url.Parse(entry.URL)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid Go proxy URL: %w", err)
+			}
+
+			var username, password string
+			if proxyURL.User != nil {
+				username = proxyURL.User.Username()
+				password, _ = proxyURL.User.Password()
+			} else if !credentials {
+				username, password = cfg.Username, cfg.Password
+				credentials = true
+			}
+
+			proxyURL.User = nil
+
+			var base http.RoundTripper
+			if username != "" && password != "" {
+				base, err = goproxy.NewBasicAuthTransport(username, password)
+				if err != nil {
+					return nil, nil, err
+				}
+			}
+
+			// The timeout is applied by the transport, to each attempt.
+			transport := upstream.NewTransport(proxyURL.Host, base, cfg.Upstream)
+			breakers = append(breakers, transport.Breaker())
+
+			gpClient := goproxy.NewClient(proxyURL.String())
+			gpClient.HTTPClient = &http.Client{Transport: transport}
+
+			upstreams = append(upstreams, archive.Upstream{
+				Name:            proxyURL.Host,
+				Source:          archive.NewProxySource(gpClient),
+				FallbackOnError: entry.FallbackOnError,
+			})
+		}
+	}
+
+	return archive.NewGoProxy(upstreams), breakers, nil
+}
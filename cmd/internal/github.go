@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ettle/strcase"
+	"github.com/google/go-github/v74/github"
+	"github.com/traefik/plugin-service/pkg/githubapi"
+	"github.com/traefik/plugin-service/pkg/upstream"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	flagGHToken             = "github-token"
+	flagGHAppIDFile         = "github-app-id-file"
+	flagGHAppPrivateKeyFile = "github-app-private-key-file"
+	flagGHAppInstallationID = "github-app-installation-id"
+	flagGHRateLimitReserve  = "github-rate-limit-reserve"
+	flagGHPrefix            = "github"
+)
+
+// GitHub holds the GitHub API configuration.
+type GitHub struct {
+	Tokens           []string
+	App              GitHubApp
+	RateLimitReserve int
+	Upstream         upstream.Config
+}
+
+// GitHubApp holds the configuration of the GitHub App authentication.
+type GitHubApp struct {
+	IDFile         string
+	PrivateKeyFile string
+	// InstallationID the installation of the app, the only installation of the app is used when 0.
+	InstallationID int64
+}
+
+// Enabled returns true if the GitHub App authentication is configured.
+func (a GitHubApp) Enabled() bool {
+	return a.IDFile != "" || a.PrivateKeyFile != ""
+}
+
+// Enabled returns true if the GitHub API is authenticated.
+func (g GitHub) Enabled() bool {
+	return len(g.Tokens) > 0 || g.App.Enabled()
+}
+
+// GitHubFlags setup CLI flags for the GitHub API.
+func GitHubFlags() []cli.Flag {
+	flags := []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:    flagGHToken,
+			Usage:   "GitHub Token, several comma separated tokens spread the requests over their rate limits",
+			EnvVars: []string{strcase.ToSNAKE(flagGHToken)},
+		},
+		&cli.StringFlag{
+			Name:    flagGHAppIDFile,
+			Usage:   "File containing the ID of the GitHub App authenticating the requests, instead of or in addition to the tokens",
+			EnvVars: []string{strcase.ToSNAKE(flagGHAppIDFile)},
+		},
+		&cli.StringFlag{
+			Name:    flagGHAppPrivateKeyFile,
+			Usage:   "File containing the PEM private key of the GitHub App",
+			EnvVars: []string{strcase.ToSNAKE(flagGHAppPrivateKeyFile)},
+		},
+		&cli.Int64Flag{
+			Name:    flagGHAppInstallationID,
+			Usage:   "ID of the installation of the GitHub App, defaults to the only installation of the app",
+			EnvVars: []string{strcase.ToSNAKE(flagGHAppInstallationID)},
+		},
+		&cli.IntFlag{
+			Name:    flagGHRateLimitReserve,
+			Usage:   "Number of remaining requests under which a GitHub token is not used until the reset of its rate limit",
+			EnvVars: []string{strcase.ToSNAKE(flagGHRateLimitReserve)},
+			Value:   githubapi.DefaultReserve,
+		},
+	}
+
+	return append(flags, upstreamFlags(flagGHPrefix, "GitHub API")...)
+}
+
+// BuildGitHubConfig creates the GitHub API configuration.
+func BuildGitHubConfig(cliCtx *cli.Context) GitHub {
+	return GitHub{
+		Tokens: cliCtx.StringSlice(flagGHToken),
+		App: GitHubApp{
+			IDFile:         cliCtx.String(flagGHAppIDFile),
+			PrivateKeyFile: cliCtx.String(flagGHAppPrivateKeyFile),
+			InstallationID: cliCtx.Int64(flagGHAppInstallationID),
+		},
+		RateLimitReserve: cliCtx.Int(flagGHRateLimitReserve),
+		Upstream:         buildUpstreamConfig(cliCtx, flagGHPrefix),
+	}
+}
+
+// NewGitHubClient creates a GitHub client spreading the requests over the configured tokens and GitHub App installation,
+// and returns the circuit breaker of the GitHub API.
+func NewGitHubClient(cfg GitHub) (*github.Client, *upstream.Breaker, error) {
+	var credentials []githubapi.Credential
+
+	// The rate limited responses don't count as failures of the upstream, the retries with another credential are done by the pool.
+	transport := upstream.NewTransport("github", nil, cfg.Upstream)
+
+	// The installation tokens of a GitHub App have higher rate limits than the personal tokens, the app comes first.
+	if cfg.App.Enabled() {
+		app, err := githubapi.LoadApp(cfg.App.IDFile, cfg.App.PrivateKeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("load GitHub App: %w", err)
+		}
+
+		credentials = append(credentials, githubapi.Credential{
+			Name:   "app",
+			Source: githubapi.NewInstallationTokenSource(app, cfg.App.InstallationID, transport, nil),
+		})
+	}
+
+	for i, token := range cfg.Tokens {
+		credentials = append(credentials, githubapi.Credential{
+			Name:   fmt.Sprintf("token-%d", i+1),
+			Source: githubapi.StaticToken(token),
+		})
+	}
+
+	return github.NewClient(&http.Client{Transport: githubapi.NewTransport(transport, credentials, cfg.RateLimitReserve)}), transport.Breaker(), nil
+}
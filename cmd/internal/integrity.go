@@ -0,0 +1,28 @@
+package internal
+
+import (
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/plugin-service/pkg/handlers"
+)
+
+// LogIntegrityReport logs the result of an integrity check.
+func LogIntegrityReport(report handlers.IntegrityReport) {
+	for _, drift := range report.Drifts {
+		log.Warn().
+			Str("module_name", drift.Module).
+			Str("module_version", drift.Version).
+			Str("source", drift.Source).
+			Str("expected_hash", drift.Expected).
+			Str("received_hash", drift.Received).
+			Msg("Plugin archive drift")
+	}
+
+	log.Info().
+		Int("plugins", report.Plugins).
+		Int("versions", report.Versions).
+		Int("recorded", report.Recorded).
+		Int("verified", report.Verified).
+		Int("failed", report.Failed).
+		Int("drifts", len(report.Drifts)).
+		Msg("Integrity check done")
+}
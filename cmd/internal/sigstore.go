@@ -0,0 +1,63 @@
+package internal
+
+import (
+	"os"
+
+	"github.com/ettle/strcase"
+	"github.com/traefik/plugin-service/pkg/archive"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	flagSigstoreFulcioRoots = "sigstore-fulcio-roots"
+	flagSigstoreRekorKeys   = "sigstore-rekor-keys"
+)
+
+// Sigstore holds the trust roots used to verify keyless signatures.
+type Sigstore struct {
+	FulcioRoots string
+	RekorKeys   string
+}
+
+// SigstoreFlags setup CLI flags for the Sigstore trust roots.
+func SigstoreFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    flagSigstoreFulcioRoots,
+			Usage:   "Path to the PEM Fulcio certificates trusted for keyless signatures",
+			EnvVars: []string{strcase.ToSNAKE(flagSigstoreFulcioRoots)},
+		},
+		&cli.StringFlag{
+			Name:    flagSigstoreRekorKeys,
+			Usage:   "Path to the PEM Rekor public keys trusted for keyless signatures",
+			EnvVars: []string{strcase.ToSNAKE(flagSigstoreRekorKeys)},
+		},
+	}
+}
+
+// BuildSigstoreConfig creates the Sigstore configuration.
+func BuildSigstoreConfig(cliCtx *cli.Context) Sigstore {
+	return Sigstore{
+		FulcioRoots: cliCtx.String(flagSigstoreFulcioRoots),
+		RekorKeys:   cliCtx.String(flagSigstoreRekorKeys),
+	}
+}
+
+// LoadTrustRoots loads the Sigstore trust roots, keyless verification is disabled without them.
+func LoadTrustRoots(cfg Sigstore) (archive.TrustRoots, error) {
+	if cfg.FulcioRoots == "" && cfg.RekorKeys == "" {
+		return archive.TrustRoots{}, nil
+	}
+
+	fulcioPEM, err := os.ReadFile(cfg.FulcioRoots)
+	if err != nil {
+		return archive.TrustRoots{}, err
+	}
+
+	rekorPEM, err := os.ReadFile(cfg.RekorKeys)
+	if err != nil {
+		return archive.TrustRoots{}, err
+	}
+
+	return archive.ParseTrustRoots(fulcioPEM, rekorPEM)
+}
@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"github.com/ettle/strcase"
+	"github.com/traefik/plugin-service/pkg/upstream"
+	"github.com/urfave/cli/v2"
+)
+
+// upstreamFlags setup the CLI flags of the timeout, the retries and the circuit breaker of an upstream, prefixed by the upstream flag prefix.
+func upstreamFlags(prefix, name string) []cli.Flag {
+	return []cli.Flag{
+		&cli.DurationFlag{
+			Name:    prefix + "-timeout",
+			Usage:   "Timeout of a request to the " + name + ", including the download of the response (0 to disable)",
+			EnvVars: []string{strcase.ToSNAKE(prefix + "-timeout")},
+			Value:   upstream.DefaultTimeout,
+		},
+		&cli.IntFlag{
+			Name:    prefix + "-retries",
+			Usage:   "Number of retries, with a jittered backoff, of the " + name + " fetches failing with a network error or a 5xx",
+			EnvVars: []string{strcase.ToSNAKE(prefix + "-retries")},
+			Value:   upstream.DefaultRetries,
+		},
+		&cli.IntFlag{
+			Name:    prefix + "-breaker-threshold",
+			Usage:   "Number of consecutive " + name + " failures opening its circuit breaker (0 to disable)",
+			EnvVars: []string{strcase.ToSNAKE(prefix + "-breaker-threshold")},
+			Value:   upstream.DefaultFailureThreshold,
+		},
+		&cli.DurationFlag{
+			Name:    prefix + "-breaker-cooldown",
+			Usage:   "Duration during which the open circuit breaker of the " + name + " rejects the requests",
+			EnvVars: []string{strcase.ToSNAKE(prefix + "-breaker-cooldown")},
+			Value:   upstream.DefaultCooldown,
+		},
+	}
+}
+
+// buildUpstreamConfig creates the configuration of an upstream from the flags with the given prefix.
+func buildUpstreamConfig(cliCtx *cli.Context, prefix string) upstream.Config {
+	return upstream.Config{
+		Timeout:          cliCtx.Duration(prefix + "-timeout"),
+		Retries:          cliCtx.Int(prefix + "-retries"),
+		FailureThreshold: cliCtx.Int(prefix + "-breaker-threshold"),
+		Cooldown:         cliCtx.Duration(prefix + "-breaker-cooldown"),
+	}
+}
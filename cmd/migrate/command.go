@@ -0,0 +1,41 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/plugin-service/cmd/internal"
+	"github.com/traefik/plugin-service/pkg/db/mongodb"
+	"github.com/urfave/cli/v2"
+)
+
+// Command creates the command for migrating the database.
+func Command() *cli.Command {
+	return &cli.Command{
+		Name:        "migrate",
+		Usage:       "Migrate the database",
+		Description: "Migrate the plugin documents recorded with a previous version of the service",
+		Flags:       internal.MongoFlags(),
+		Action: func(cliCtx *cli.Context) error {
+			return run(cliCtx.Context, internal.BuildMongoConfig(cliCtx))
+		},
+	}
+}
+
+func run(ctx context.Context, cfg mongodb.Config) error {
+	store, tearDown, err := internal.CreateMongoClient(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create MongoDB client: %w", err)
+	}
+	defer tearDown()
+
+	count, err := store.MigrateHashDigests(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to migrate hash digests: %w", err)
+	}
+
+	log.Info().Int64("plugins", count).Msg("Hash digests migrated")
+
+	return nil
+}
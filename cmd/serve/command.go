@@ -1,19 +1,35 @@
 package serve
 
 import (
+	"time"
+
 	"github.com/ettle/strcase"
 	"github.com/traefik/plugin-service/cmd/internal"
+	"github.com/traefik/plugin-service/pkg/crawler"
 	"github.com/traefik/plugin-service/pkg/tracer"
 	"github.com/urfave/cli/v2"
 )
 
 const (
-	flagAddr    = "addr"
-	flagGHToken = "github-token"
+	flagAddr      = "addr"
+	flagPublicURL = "public-url"
+
+	flagGHWebhookSecret = "github-webhook-secret"
+
+	flagIncidentThreshold = "incident-threshold"
+	flagSigningKey        = "signing-key"
+	flagTransparencyLog   = "transparency-log-name"
+
+	flagArchiveCacheSize = "archive-cache-size"
 
-	flagGoProxyURL      = "go-proxy-url"
-	flagGoProxyUsername = "go-proxy-username"
-	flagGoProxyPassword = "go-proxy-password"
+	flagJobWorkers     = "job-workers"
+	flagJobMaxAttempts = "job-max-attempts"
+
+	flagIntegrityCheckInterval    = "integrity-check-interval"
+	flagIntegrityCheckConcurrency = "integrity-check-concurrency"
+
+	flagMetadataRefreshInterval = "metadata-refresh-interval"
+	flagMetadataRefreshReserve  = "metadata-refresh-reserve"
 
 	flagTracingAddress     = "tracing-address"
 	flagTracingInsecure    = "tracing-insecure"
@@ -35,10 +51,50 @@ func Command() *cli.Command {
 				EnvVars: []string{strcase.ToSNAKE(flagAddr)},
 			},
 			&cli.StringFlag{
-				Name:     flagGHToken,
-				Usage:    "GitHub Token",
-				EnvVars:  []string{strcase.ToSNAKE(flagGHToken)},
-				Required: true,
+				Name:    flagPublicURL,
+				Usage:   "URL through which the public API is served, used in the links and identifiers of the Atom feeds",
+				EnvVars: []string{strcase.ToSNAKE(flagPublicURL)},
+				Value:   "https://plugins.traefik.io",
+			},
+			&cli.StringFlag{
+				Name:    flagGHWebhookSecret,
+				Usage:   "Secret of the GitHub webhooks, enables the /hooks/github endpoint",
+				EnvVars: []string{strcase.ToSNAKE(flagGHWebhookSecret)},
+			},
+			&cli.IntFlag{
+				Name:    flagIncidentThreshold,
+				Usage:   "Number of upstream hash mismatches after which a plugin version is quarantined (0 to disable)",
+				EnvVars: []string{strcase.ToSNAKE(flagIncidentThreshold)},
+				Value:   0,
+			},
+			&cli.StringFlag{
+				Name:    flagSigningKey,
+				Usage:   "Path to the PEM Ed25519 private key signing the served archives",
+				EnvVars: []string{strcase.ToSNAKE(flagSigningKey)},
+			},
+			&cli.StringFlag{
+				Name:    flagTransparencyLog,
+				Usage:   "Name of the transparency log, used in the signed tree heads",
+				EnvVars: []string{strcase.ToSNAKE(flagTransparencyLog)},
+				Value:   "plugins.traefik.io",
+			},
+			&cli.IntFlag{
+				Name:    flagArchiveCacheSize,
+				Usage:   "Size in MiB of the in-memory cache of the served archives, served while the circuit breaker of their upstream is open (0 to disable)",
+				EnvVars: []string{strcase.ToSNAKE(flagArchiveCacheSize)},
+				Value:   64,
+			},
+			&cli.IntFlag{
+				Name:    flagJobWorkers,
+				Usage:   "Number of workers running the asynchronous jobs (pre-warming the hashes of the new plugin versions, delivering the webhooks)",
+				EnvVars: []string{strcase.ToSNAKE(flagJobWorkers)},
+				Value:   2,
+			},
+			&cli.IntFlag{
+				Name:    flagJobMaxAttempts,
+				Usage:   "Number of attempts of an asynchronous job before it is moved to the dead letters",
+				EnvVars: []string{strcase.ToSNAKE(flagJobMaxAttempts)},
+				Value:   5,
 			},
 		},
 		Action: func(cliCtx *cli.Context) error {
@@ -46,7 +102,11 @@ func Command() *cli.Command {
 		},
 	}
 
-	cmd.Flags = append(cmd.Flags, goProxyFlags()...)
+	cmd.Flags = append(cmd.Flags, internal.GitHubFlags()...)
+	cmd.Flags = append(cmd.Flags, internal.GoProxyFlags()...)
+	cmd.Flags = append(cmd.Flags, internal.SigstoreFlags()...)
+	cmd.Flags = append(cmd.Flags, integrityCheckFlags()...)
+	cmd.Flags = append(cmd.Flags, metadataRefreshFlags()...)
 	cmd.Flags = append(cmd.Flags, tracingFlags()...)
 	cmd.Flags = append(cmd.Flags, internal.MongoFlags()...)
 
@@ -64,35 +124,61 @@ func buildConfig(cliCtx *cli.Context) Config {
 			Probability: cliCtx.Float64(flagTracingProbability),
 			ServiceName: "plugin-service",
 		},
-		Addr:        cliCtx.String(flagAddr),
-		GitHubToken: cliCtx.String(flagGHToken),
-		GoProxy: GoProxy{
-			URL:      cliCtx.String(flagGoProxyURL),
-			Username: cliCtx.String(flagGoProxyUsername),
-			Password: cliCtx.String(flagGoProxyPassword),
+		Addr:      cliCtx.String(flagAddr),
+		PublicURL: cliCtx.String(flagPublicURL),
+		GitHub:    internal.BuildGitHubConfig(cliCtx),
+
+		GitHubWebhookSecret: cliCtx.String(flagGHWebhookSecret),
+
+		IncidentThreshold: cliCtx.Int(flagIncidentThreshold),
+		SigningKey:        cliCtx.String(flagSigningKey),
+		TransparencyLog:   cliCtx.String(flagTransparencyLog),
+		ArchiveCacheSize:  cliCtx.Int(flagArchiveCacheSize),
+		JobWorkers:        cliCtx.Int(flagJobWorkers),
+		JobMaxAttempts:    cliCtx.Int(flagJobMaxAttempts),
+		GoProxy:           internal.BuildGoProxyConfig(cliCtx),
+		Sigstore:          internal.BuildSigstoreConfig(cliCtx),
+		IntegrityCheck: IntegrityCheck{
+			Interval:    cliCtx.Duration(flagIntegrityCheckInterval),
+			Concurrency: cliCtx.Int(flagIntegrityCheckConcurrency),
+		},
+		MetadataRefresh: MetadataRefresh{
+			Interval: cliCtx.Duration(flagMetadataRefreshInterval),
+			Reserve:  cliCtx.Int(flagMetadataRefreshReserve),
 		},
 	}
 }
 
-func goProxyFlags() []cli.Flag {
+func integrityCheckFlags() []cli.Flag {
 	return []cli.Flag{
-		&cli.StringFlag{
-			Name:     flagGoProxyURL,
-			Usage:    "Go Proxy URL",
-			EnvVars:  []string{strcase.ToSNAKE(flagGoProxyURL)},
-			Required: true,
+		&cli.DurationFlag{
+			Name:    flagIntegrityCheckInterval,
+			Usage:   "Interval between two integrity checks of all the known plugin versions (0 to disable)",
+			EnvVars: []string{strcase.ToSNAKE(flagIntegrityCheckInterval)},
+			Value:   0,
 		},
-		&cli.StringFlag{
-			Name:     flagGoProxyUsername,
-			Usage:    "Go Proxy Username",
-			EnvVars:  []string{strcase.ToSNAKE(flagGoProxyUsername)},
-			Required: true,
+		&cli.IntFlag{
+			Name:    flagIntegrityCheckConcurrency,
+			Usage:   "Number of plugin archives fetched concurrently by the integrity check",
+			EnvVars: []string{strcase.ToSNAKE(flagIntegrityCheckConcurrency)},
+			Value:   4,
 		},
-		&cli.StringFlag{
-			Name:     flagGoProxyPassword,
-			Usage:    "Go Proxy Password",
-			EnvVars:  []string{strcase.ToSNAKE(flagGoProxyPassword)},
-			Required: true,
+	}
+}
+
+func metadataRefreshFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.DurationFlag{
+			Name:    flagMetadataRefreshInterval,
+			Usage:   "Interval between two refreshes of the GitHub repository metadata of the plugins: stars, topics, archived state and license (0 to disable)",
+			EnvVars: []string{strcase.ToSNAKE(flagMetadataRefreshInterval)},
+			Value:   6 * time.Hour,
+		},
+		&cli.IntFlag{
+			Name:    flagMetadataRefreshReserve,
+			Usage:   "Number of remaining GitHub requests under which the metadata refresh pauses until the reset of the rate limit",
+			EnvVars: []string{strcase.ToSNAKE(flagMetadataRefreshReserve)},
+			Value:   crawler.DefaultReserve,
 		},
 	}
 }
@@ -1,23 +1,45 @@
 package serve
 
 import (
+	"time"
+
+	"github.com/traefik/plugin-service/cmd/internal"
 	"github.com/traefik/plugin-service/pkg/db/mongodb"
 	"github.com/traefik/plugin-service/pkg/tracer"
 )
 
 // Config holds the serve configuration.
 type Config struct {
-	Addr        string
-	GitHubToken string
+	Addr      string
+	PublicURL string
+	GitHub    internal.GitHub
+
+	GitHubWebhookSecret string
+
+	IncidentThreshold int
+	SigningKey        string
+	TransparencyLog   string
+	ArchiveCacheSize  int
+	JobWorkers        int
+	JobMaxAttempts    int
+
+	MongoDB  mongodb.Config
+	Tracing  tracer.Config
+	GoProxy  internal.GoProxy
+	Sigstore internal.Sigstore
+
+	IntegrityCheck  IntegrityCheck
+	MetadataRefresh MetadataRefresh
+}
 
-	MongoDB mongodb.Config
-	Tracing tracer.Config
-	GoProxy GoProxy
+// IntegrityCheck holds the configuration of the periodic integrity check of the known plugin versions.
+type IntegrityCheck struct {
+	Interval    time.Duration
+	Concurrency int
 }
 
-// GoProxy holds the go-proxy configuration.
-type GoProxy struct {
-	URL      string
-	Username string
-	Password string
+// MetadataRefresh holds the configuration of the periodic refresh of the GitHub repository metadata of the plugins.
+type MetadataRefresh struct {
+	Interval time.Duration
+	Reserve  int
 }
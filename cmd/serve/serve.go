@@ -2,21 +2,30 @@ package serve
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
-	"github.com/google/go-github/v74/github"
 	"github.com/gorilla/mux"
 	"github.com/julienschmidt/httprouter"
-	"github.com/ldez/grignotin/goproxy"
+	"github.com/rs/zerolog/log"
 	"github.com/traefik/plugin-service/cmd/internal"
+	"github.com/traefik/plugin-service/pkg/archive"
+	"github.com/traefik/plugin-service/pkg/crawler"
+	"github.com/traefik/plugin-service/pkg/db"
+	"github.com/traefik/plugin-service/pkg/events"
 	"github.com/traefik/plugin-service/pkg/handlers"
 	"github.com/traefik/plugin-service/pkg/healthcheck"
+	"github.com/traefik/plugin-service/pkg/jobs"
+	"github.com/traefik/plugin-service/pkg/signing"
 	"github.com/traefik/plugin-service/pkg/tracer"
+	"github.com/traefik/plugin-service/pkg/transparency"
+	"github.com/traefik/plugin-service/pkg/webhooks"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/propagation"
-	"golang.org/x/oauth2"
+	"golang.org/x/mod/sumdb/note"
 )
 
 func run(ctx context.Context, cfg Config) error {
@@ -36,37 +45,181 @@ func run(ctx context.Context, cfg Config) error {
 		return fmt.Errorf("unable to bootstrap database: %w", err)
 	}
 
-	gpClient, err := newGoProxyClient(cfg.GoProxy)
+	if !cfg.GitHub.Enabled() {
+		return errors.New("a GitHub token or a GitHub App is required")
+	}
+
+	ghClient, gitHubBreaker, err := internal.NewGitHubClient(cfg.GitHub)
+	if err != nil {
+		return fmt.Errorf("unable to create GitHub client: %w", err)
+	}
+
+	gpClient, goProxyBreakers, err := internal.NewGoProxyClient(cfg.GoProxy, ghClient)
 	if err != nil {
 		return fmt.Errorf("unable to create go proxy client: %w", err)
 	}
 
-	var ghClient *github.Client
-	if cfg.GitHubToken != "" {
-		ghClient = newGitHubClient(context.Background(), cfg.GitHubToken)
+	trustRoots, err := internal.LoadTrustRoots(cfg.Sigstore)
+	if err != nil {
+		return fmt.Errorf("unable to load Sigstore trust roots: %w", err)
+	}
+
+	opts := []handlers.Option{
+		handlers.WithIncidentThreshold(cfg.IncidentThreshold),
+		handlers.WithPublicURL(cfg.PublicURL),
+		handlers.WithArchiveCache(int64(cfg.ArchiveCacheSize) << 20),
+		handlers.WithSignatureVerifier(archive.NewVerifier(trustRoots)),
+		handlers.WithGitHubWebhook(cfg.GitHubWebhookSecret, crawler.New(ghClient, store, nil, "")),
+	}
+
+	// Without signing key, the hashes are still appended to the transparency log, but the tree heads are not served.
+	var logSigner note.Signer
+
+	if cfg.SigningKey != "" {
+		signer, errSigner := signing.LoadSigner(cfg.SigningKey)
+		if errSigner != nil {
+			return fmt.Errorf("unable to load signing key: %w", errSigner)
+		}
+
+		logSigner, err = signer.NoteSigner(cfg.TransparencyLog)
+		if err != nil {
+			return fmt.Errorf("unable to create transparency log signer: %w", err)
+		}
+
+		opts = append(opts, handlers.WithStatementSigner(signer))
+	}
+
+	opts = append(opts, handlers.WithTransparencyLog(transparency.New(store, logSigner)))
+
+	jobsCfg := jobs.DefaultConfig()
+	jobsCfg.MaxAttempts = cfg.JobMaxAttempts
+
+	queue, err := jobs.NewQueue(store, jobsCfg)
+	if err != nil {
+		return fmt.Errorf("unable to create job queue: %w", err)
+	}
+
+	dispatcher := webhooks.NewDispatcher(store, queue, nil)
+
+	opts = append(opts, handlers.WithJobQueue(queue), handlers.WithWebhooks(dispatcher))
+
+	// The change streams publish the events to the clients of all the replicas,
+	// each replica relays a single change stream to its clients.
+	var stream handlers.EventStream
+	if store.ChangeStreamsSupported(ctx) {
+		relay := events.NewRelay(store, events.DefaultHistory, events.DefaultMaxStreams)
+		go relay.Run(ctx)
+
+		stream = relay
+	} else {
+		log.Warn().Msg("MongoDB change streams are not available, the events are only streamed to the clients of the replica which has published them")
+
+		stream = events.NewBus(events.DefaultHistory)
+	}
+
+	opts = append(opts, handlers.WithEventStream(stream))
+
+	handler := handlers.New(store, archive.NewFetcher(gpClient, ghClient), opts...)
+
+	queue.Handle(db.JobPrewarm, handler.Prewarm)
+	queue.Handle(db.JobDeliver, dispatcher.Deliver)
+
+	go queue.Run(ctx, cfg.JobWorkers)
+
+	if cfg.IntegrityCheck.Interval > 0 {
+		go runIntegrityChecks(ctx, handler, cfg.IntegrityCheck)
 	}
 
-	handler := handlers.New(store, gpClient, ghClient)
+	if cfg.MetadataRefresh.Interval > 0 {
+		refresher := crawler.NewRefresher(ghClient, store, events.NewEmitter(stream, dispatcher), cfg.MetadataRefresh.Reserve)
+
+		go runMetadataRefreshes(ctx, refresher, cfg.MetadataRefresh.Interval)
+	}
 
-	healthChecker := healthcheck.Client{DB: store}
+	healthChecker := healthcheck.Client{DB: store, Breakers: append(goProxyBreakers, gitHubBreaker)}
 
 	r := http.NewServeMux()
 
 	r.Handle("/public/", buildPublicRouter(handler))
 	r.Handle("/internal/", buildInternalRouter(handler))
+	// Registered outside the internal router because httprouter doesn't allow static segments next to /:uuid.
+	r.Handle("/internal/incidents", otelhttp.NewHandler(http.HandlerFunc(handler.Incidents), "internal_incidents"))
+	r.Handle("/internal/jobs", otelhttp.NewHandler(http.HandlerFunc(handler.Jobs), "internal_jobs"))
+	r.Handle("/internal/jobs/", buildJobsRouter(handler))
+	r.Handle("GET /internal/subscriptions", otelhttp.NewHandler(http.HandlerFunc(handler.Subscriptions), "internal_subscriptions"))
+	r.Handle("POST /internal/subscriptions", otelhttp.NewHandler(http.HandlerFunc(handler.Subscribe), "internal_subscribe"))
+	r.Handle("/internal/subscriptions/", buildSubscriptionsRouter(handler))
 	r.Handle("/external/", buildExternalRouter(handler))
+	r.Handle("POST /hooks/github", otelhttp.NewHandler(http.HandlerFunc(handler.GitHubWebhook), "hooks_github"))
+	r.Handle("/.well-known/plugin-signing-keys", otelhttp.NewHandler(http.HandlerFunc(handler.SigningKeys), "well_known_signing_keys"))
 	r.HandleFunc("/live", healthChecker.Live)
 	r.HandleFunc("/ready", healthChecker.Ready)
 
 	return http.ListenAndServe(cfg.Addr, r)
 }
 
+// runIntegrityChecks periodically checks the integrity of all the known plugin versions.
+func runIntegrityChecks(ctx context.Context, handler handlers.Handlers, cfg IntegrityCheck) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := handler.CheckIntegrity(ctx, cfg.Concurrency)
+			if err != nil {
+				log.Error().Err(err).Msg("Integrity check failed")
+				continue
+			}
+
+			internal.LogIntegrityReport(report)
+		}
+	}
+}
+
+// runMetadataRefreshes periodically refreshes the GitHub repository metadata of the plugins.
+func runMetadataRefreshes(ctx context.Context, refresher *crawler.Refresher, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := refresher.Refresh(ctx)
+			if err != nil {
+				log.Error().Err(err).Msg("Metadata refresh failed")
+				continue
+			}
+
+			log.Info().
+				Int("plugins", report.Plugins).
+				Int("updated", report.Updated).
+				Int("unchanged", report.Unchanged).
+				Int("not_modified", report.NotModified).
+				Int("skipped", report.Skipped).
+				Int("failed", report.Failed).
+				Msg("Metadata refresh done")
+		}
+	}
+}
+
 func buildPublicRouter(handler handlers.Handlers) http.Handler {
 	r := mux.NewRouter()
 
 	r.Handle("/", otelhttp.NewHandler(http.HandlerFunc(handler.List), "public_list"))
 	r.Handle("/download/{all:.+}", otelhttp.NewHandler(http.HandlerFunc(handler.Download), "public_download"))
 	r.Handle("/validate/{all:.+}", otelhttp.NewHandler(http.HandlerFunc(handler.Validate), "public_validate"))
+	r.Handle("/lookup/{all:.+}", otelhttp.NewHandler(http.HandlerFunc(handler.Lookup), "public_lookup"))
+	r.Handle("/feeds/plugins.atom", otelhttp.NewHandler(http.HandlerFunc(handler.PluginsFeed), "public_plugins_feed"))
+	r.Handle("/feeds/{uuid}.atom", otelhttp.NewHandler(http.HandlerFunc(handler.PluginFeed), "public_plugin_feed"))
+	r.Handle("/events", otelhttp.NewHandler(http.HandlerFunc(handler.Events), "public_events"))
+	r.Handle("/tlog/latest", otelhttp.NewHandler(http.HandlerFunc(handler.TreeHead), "public_tlog_latest"))
+	r.Handle("/tlog/proof/record", otelhttp.NewHandler(http.HandlerFunc(handler.RecordProof), "public_tlog_record_proof"))
+	r.Handle("/tlog/proof/tree", otelhttp.NewHandler(http.HandlerFunc(handler.TreeProof), "public_tlog_tree_proof"))
 	r.Handle("/{uuid}", otelhttp.NewHandler(http.HandlerFunc(handler.Get), "public_get"))
 
 	r.NotFoundHandler = http.HandlerFunc(handlers.NotFound)
@@ -81,6 +234,10 @@ func buildInternalRouter(handler handlers.Handlers) http.Handler {
 	r.Handler(http.MethodPost, "/", otelhttp.NewHandler(http.HandlerFunc(handler.Create), "internal_create"))
 	r.Handler(http.MethodPut, "/:uuid", otelhttp.NewHandler(http.HandlerFunc(handler.Update), "internal_update"))
 	r.Handler(http.MethodDelete, "/:uuid", otelhttp.NewHandler(http.HandlerFunc(handler.Delete), "internal_delete"))
+	r.Handler(http.MethodGet, "/:uuid/diagnostics", otelhttp.NewHandler(http.HandlerFunc(handler.Diagnostics), "internal_diagnostics"))
+	r.Handler(http.MethodGet, "/:uuid/hashes", otelhttp.NewHandler(http.HandlerFunc(handler.Hashes), "internal_hashes"))
+	r.Handler(http.MethodPost, "/:uuid/hashes/:version/verify", otelhttp.NewHandler(http.HandlerFunc(handler.VerifyHash), "internal_verify_hash"))
+	r.Handler(http.MethodPost, "/:uuid/hashes/:version/reset", otelhttp.NewHandler(http.HandlerFunc(handler.ResetHash), "internal_reset_hash"))
 
 	r.NotFound = http.HandlerFunc(handlers.NotFound)
 	r.PanicHandler = handlers.PanicHandler
@@ -88,43 +245,44 @@ func buildInternalRouter(handler handlers.Handlers) http.Handler {
 	return http.StripPrefix("/internal", r)
 }
 
-func buildExternalRouter(handler handlers.Handlers) http.Handler {
+func buildJobsRouter(handler handlers.Handlers) http.Handler {
 	r := httprouter.New()
 
-	r.Handler(http.MethodGet, "/", otelhttp.NewHandler(http.HandlerFunc(handler.List), "external_list"))
-	r.Handler(http.MethodGet, "/:uuid", otelhttp.NewHandler(http.HandlerFunc(handler.Get), "external_get"))
+	r.Handler(http.MethodGet, "/", otelhttp.NewHandler(http.HandlerFunc(handler.Jobs), "internal_jobs"))
+	r.Handler(http.MethodGet, "/:id", otelhttp.NewHandler(http.HandlerFunc(handler.Job), "internal_job"))
+	r.Handler(http.MethodPost, "/:id/retry", otelhttp.NewHandler(http.HandlerFunc(handler.RetryJob), "internal_retry_job"))
 
 	r.NotFound = http.HandlerFunc(handlers.NotFound)
 	r.PanicHandler = handlers.PanicHandler
 
-	return http.StripPrefix("/external", r)
+	return http.StripPrefix("/internal/jobs", r)
 }
 
-func newGoProxyClient(cfg GoProxy) (*goproxy.Client, error) {
-	gpClient := goproxy.NewClient(cfg.URL)
+func buildSubscriptionsRouter(handler handlers.Handlers) http.Handler {
+	r := httprouter.New()
 
-	if cfg.URL != "" && cfg.Username != "" && cfg.Password != "" {
-		tr, err := goproxy.NewBasicAuthTransport(cfg.Username, cfg.Password)
-		if err != nil {
-			return nil, err
-		}
+	r.Handler(http.MethodGet, "/", otelhttp.NewHandler(http.HandlerFunc(handler.Subscriptions), "internal_subscriptions"))
+	r.Handler(http.MethodPost, "/", otelhttp.NewHandler(http.HandlerFunc(handler.Subscribe), "internal_subscribe"))
+	r.Handler(http.MethodGet, "/:id", otelhttp.NewHandler(http.HandlerFunc(handler.Subscription), "internal_subscription"))
+	r.Handler(http.MethodDelete, "/:id", otelhttp.NewHandler(http.HandlerFunc(handler.Unsubscribe), "internal_unsubscribe"))
+	r.Handler(http.MethodGet, "/:id/deliveries", otelhttp.NewHandler(http.HandlerFunc(handler.Deliveries), "internal_deliveries"))
 
-		gpClient.HTTPClient = tr.Client()
-	}
+	r.NotFound = http.HandlerFunc(handlers.NotFound)
+	r.PanicHandler = handlers.PanicHandler
 
-	return gpClient, nil
+	return http.StripPrefix("/internal/subscriptions", r)
 }
 
-func newGitHubClient(ctx context.Context, tk string) *github.Client {
-	if tk == "" {
-		return github.NewClient(nil)
-	}
+func buildExternalRouter(handler handlers.Handlers) http.Handler {
+	r := httprouter.New()
 
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: tk},
-	)
+	r.Handler(http.MethodGet, "/", otelhttp.NewHandler(http.HandlerFunc(handler.List), "external_list"))
+	r.Handler(http.MethodGet, "/:uuid", otelhttp.NewHandler(http.HandlerFunc(handler.Get), "external_get"))
 
-	return github.NewClient(oauth2.NewClient(ctx, ts))
+	r.NotFound = http.HandlerFunc(handlers.NotFound)
+	r.PanicHandler = handlers.PanicHandler
+
+	return http.StripPrefix("/external", r)
 }
 
 func setupTracing(ctx context.Context, cfg tracer.Config) (func(), error) {
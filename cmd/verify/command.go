@@ -0,0 +1,121 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ettle/strcase"
+	"github.com/google/go-github/v74/github"
+	"github.com/traefik/plugin-service/cmd/internal"
+	"github.com/traefik/plugin-service/pkg/archive"
+	"github.com/traefik/plugin-service/pkg/db/mongodb"
+	"github.com/traefik/plugin-service/pkg/handlers"
+	"github.com/traefik/plugin-service/pkg/transparency"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	flagConcurrency       = "concurrency"
+	flagIncidentThreshold = "incident-threshold"
+)
+
+// Config holds the verify configuration.
+type Config struct {
+	Concurrency       int
+	IncidentThreshold int
+
+	MongoDB  mongodb.Config
+	GitHub   internal.GitHub
+	GoProxy  internal.GoProxy
+	Sigstore internal.Sigstore
+}
+
+// Command creates the command for verifying the integrity of the known plugin versions.
+func Command() *cli.Command {
+	cmd := &cli.Command{
+		Name:        "verify",
+		Usage:       "Verify the plugin archives",
+		Description: "Fetch the archive of every known plugin version, record the missing hashes and report the archives modified since their first download",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:    flagConcurrency,
+				Usage:   "Number of plugin archives fetched concurrently",
+				EnvVars: []string{strcase.ToSNAKE(flagConcurrency)},
+				Value:   4,
+			},
+			&cli.IntFlag{
+				Name:    flagIncidentThreshold,
+				Usage:   "Number of hash mismatches after which a plugin version is quarantined (0 to disable)",
+				EnvVars: []string{strcase.ToSNAKE(flagIncidentThreshold)},
+				Value:   0,
+			},
+		},
+		Action: func(cliCtx *cli.Context) error {
+			return run(cliCtx.Context, buildConfig(cliCtx))
+		},
+	}
+
+	cmd.Flags = append(cmd.Flags, internal.GitHubFlags()...)
+	cmd.Flags = append(cmd.Flags, internal.GoProxyFlags()...)
+	cmd.Flags = append(cmd.Flags, internal.SigstoreFlags()...)
+	cmd.Flags = append(cmd.Flags, internal.MongoFlags()...)
+
+	return cmd
+}
+
+func buildConfig(cliCtx *cli.Context) Config {
+	return Config{
+		Concurrency:       cliCtx.Int(flagConcurrency),
+		IncidentThreshold: cliCtx.Int(flagIncidentThreshold),
+		MongoDB:           internal.BuildMongoConfig(cliCtx),
+		GitHub:            internal.BuildGitHubConfig(cliCtx),
+		GoProxy:           internal.BuildGoProxyConfig(cliCtx),
+		Sigstore:          internal.BuildSigstoreConfig(cliCtx),
+	}
+}
+
+func run(ctx context.Context, cfg Config) error {
+	store, tearDown, err := internal.CreateMongoClient(ctx, cfg.MongoDB)
+	if err != nil {
+		return fmt.Errorf("unable to create MongoDB client: %w", err)
+	}
+	defer tearDown()
+
+	var ghClient *github.Client
+	if cfg.GitHub.Enabled() {
+		ghClient, _, err = internal.NewGitHubClient(cfg.GitHub)
+		if err != nil {
+			return fmt.Errorf("unable to create GitHub client: %w", err)
+		}
+	}
+
+	gpClient, _, err := internal.NewGoProxyClient(cfg.GoProxy, ghClient)
+	if err != nil {
+		return fmt.Errorf("unable to create go proxy client: %w", err)
+	}
+
+	trustRoots, err := internal.LoadTrustRoots(cfg.Sigstore)
+	if err != nil {
+		return fmt.Errorf("unable to load Sigstore trust roots: %w", err)
+	}
+
+	// The recorded hashes are appended to the transparency log, the tree heads are signed by the service.
+	handler := handlers.New(store, archive.NewFetcher(gpClient, ghClient),
+		handlers.WithIncidentThreshold(cfg.IncidentThreshold),
+		handlers.WithSignatureVerifier(archive.NewVerifier(trustRoots)),
+		handlers.WithTransparencyLog(transparency.New(store, nil)),
+	)
+
+	report, err := handler.CheckIntegrity(ctx, cfg.Concurrency)
+	if err != nil {
+		return fmt.Errorf("unable to check the plugin archives: %w", err)
+	}
+
+	internal.LogIntegrityReport(report)
+
+	if len(report.Drifts) > 0 {
+		return fmt.Errorf("%d plugin archives have been modified", len(report.Drifts))
+	}
+
+	return nil
+}
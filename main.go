@@ -4,7 +4,10 @@ import (
 	"os"
 
 	"github.com/rs/zerolog/log"
+	"github.com/traefik/plugin-service/cmd/crawl"
+	"github.com/traefik/plugin-service/cmd/migrate"
 	"github.com/traefik/plugin-service/cmd/serve"
+	"github.com/traefik/plugin-service/cmd/verify"
 	"github.com/traefik/plugin-service/pkg/logger"
 	"github.com/urfave/cli/v2"
 )
@@ -17,6 +20,9 @@ func main() {
 		Usage: "Run plugin service",
 		Commands: []*cli.Command{
 			serve.Command(),
+			migrate.Command(),
+			verify.Command(),
+			crawl.Command(),
 		},
 	}
 
@@ -0,0 +1,225 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/google/go-github/v74/github"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/mod/modfile"
+)
+
+// ErrGitHubDisabled is returned when a GitHub source is requested without a GitHub client.
+var ErrGitHubDisabled = errors.New("missing GitHub client")
+
+// Asset represents a plugin archive attached to a GitHub release.
+type Asset struct {
+	Name   string
+	URL    string
+	Digest string
+
+	// Signatures the signature assets of the archive (<name>.minisig, <name>.sig, <name>.bundle), indexed by extension.
+	Signatures map[string]Asset
+}
+
+// Fetcher fetches plugin archives from the Go proxy and from GitHub.
+type Fetcher struct {
+	goProxy *GoProxy
+	gh      *github.Client
+	tracer  trace.Tracer
+}
+
+// NewFetcher creates a Fetcher.
+// The GitHub client is optional.
+func NewFetcher(goProxy *GoProxy, gh *github.Client) *Fetcher {
+	return &Fetcher{
+		goProxy: goProxy,
+		gh:      gh,
+		tracer:  otel.Tracer("archive"),
+	}
+}
+
+// GitHubEnabled returns true if the GitHub sources are available.
+func (f *Fetcher) GitHubEnabled() bool {
+	return f.gh != nil
+}
+
+// GetModFile gets the go.mod file of a module from the Go proxy.
+func (f *Fetcher) GetModFile(ctx context.Context, moduleName, version string) (*modfile.File, error) {
+	ctx, span := f.tracer.Start(ctx, "archive_getModFile")
+	defer span.End()
+
+	modFile, err := f.goProxy.GetModFile(ctx, moduleName, version)
+	if err != nil {
+		span.RecordError(err)
+
+		return nil, fmt.Errorf("failed to get module file: %w", err)
+	}
+
+	return modFile, nil
+}
+
+// DownloadSourcesMatching returns the module zip from the first upstream of the Go proxy serving one accepted by match.
+func (f *Fetcher) DownloadSourcesMatching(ctx context.Context, moduleName, version string, match func(raw []byte) bool) ([]byte, error) {
+	ctx, span := f.tracer.Start(ctx, "archive_downloadSources")
+	defer span.End()
+
+	sources, err := f.goProxy.DownloadSourcesMatching(ctx, moduleName, version, match)
+	if err != nil {
+		span.RecordError(err)
+
+		return nil, fmt.Errorf("failed to download sources: %w", err)
+	}
+
+	return sources, nil
+}
+
+// DownloadZipball returns the GitHub zipball of a repository at the given version.
+// It is the caller's responsibility to close the ReadCloser.
+func (f *Fetcher) DownloadZipball(ctx context.Context, moduleName, version string) (io.ReadCloser, error) {
+	ctx, span := f.tracer.Start(ctx, "archive_downloadZipball")
+	defer span.End()
+
+	if f.gh == nil {
+		return nil, ErrGitHubDisabled
+	}
+
+	owner, repoName := splitModuleName(moduleName)
+
+	sources, err := downloadZipball(ctx, f.gh, owner, repoName, version)
+	if err != nil {
+		span.RecordError(err)
+
+		return nil, err
+	}
+
+	return sources, nil
+}
+
+// downloadZipball returns the GitHub zipball of a repository at the given ref.
+func downloadZipball(ctx context.Context, gh *github.Client, owner, repoName, ref string) (io.ReadCloser, error) {
+	opts := &github.RepositoryContentGetOptions{Ref: ref}
+
+	link, resp, err := gh.Repositories.GetArchiveLink(ctx, owner, repoName, github.Zipball, opts, 3)
+	if err != nil {
+		err = fmt.Errorf("failed to get archive link: %w", err)
+
+		// The unexpected statuses of the archive links are not reported as an ErrorResponse.
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, notExistError{err: err}
+		}
+
+		return nil, wrapGitHubNotExist(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link.String(), http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	return bareDo(ctx, gh, req)
+}
+
+// GetReleaseAsset gets the zip archive attached to the GitHub release of the given version.
+func (f *Fetcher) GetReleaseAsset(ctx context.Context, moduleName, version string) (Asset, error) {
+	ctx, span := f.tracer.Start(ctx, "archive_getReleaseAsset")
+	defer span.End()
+
+	if f.gh == nil {
+		return Asset{}, ErrGitHubDisabled
+	}
+
+	owner, repoName := splitModuleName(moduleName)
+
+	release, _, err := f.gh.Repositories.GetReleaseByTag(ctx, owner, repoName, version)
+	if err != nil {
+		span.RecordError(err)
+
+		return Asset{}, fmt.Errorf("failed to get release: %w", err)
+	}
+
+	var assets []*github.ReleaseAsset
+	// Find the zip archive in the release assets.
+	for _, asset := range release.Assets {
+		if filepath.Ext(asset.GetName()) == ".zip" {
+			assets = append(assets, asset)
+		}
+	}
+
+	switch len(assets) {
+	case 0:
+		return Asset{}, errors.New("zip archive not found")
+	case 1:
+		return Asset{
+			Name:       assets[0].GetName(),
+			URL:        assets[0].GetURL(),
+			Digest:     strings.TrimPrefix(assets[0].GetDigest(), "sha256:"),
+			Signatures: findSignatures(release.Assets, assets[0].GetName()),
+		}, nil
+	default:
+		return Asset{}, fmt.Errorf("too many zip archive (%d)", len(assets))
+	}
+}
+
+// findSignatures finds the signature assets attached next to an archive.
+func findSignatures(releaseAssets []*github.ReleaseAsset, archiveName string) map[string]Asset {
+	signatures := make(map[string]Asset)
+
+	for _, asset := range releaseAssets {
+		ext, ok := strings.CutPrefix(asset.GetName(), archiveName)
+		if !ok || !slices.Contains(signatureExtensions, ext) {
+			continue
+		}
+
+		signatures[ext] = Asset{
+			Name:   asset.GetName(),
+			URL:    asset.GetURL(),
+			Digest: strings.TrimPrefix(asset.GetDigest(), "sha256:"),
+		}
+	}
+
+	return signatures
+}
+
+// DownloadAsset returns the content of a release asset.
+// It is the caller's responsibility to close the ReadCloser.
+func (f *Fetcher) DownloadAsset(ctx context.Context, asset Asset) (io.ReadCloser, error) {
+	ctx, span := f.tracer.Start(ctx, "archive_downloadAsset")
+	defer span.End()
+
+	if f.gh == nil {
+		return nil, ErrGitHubDisabled
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, asset.URL, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/octet-stream")
+
+	return bareDo(ctx, f.gh, req)
+}
+
+func bareDo(ctx context.Context, gh *github.Client, req *http.Request) (io.ReadCloser, error) {
+	resp, err := gh.BareDo(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get archive content: %w", err)
+	}
+
+	return resp.Body, nil
+}
+
+func splitModuleName(moduleName string) (string, string) {
+	owner, repoName := path.Split(strings.TrimPrefix(moduleName, "github.com/"))
+
+	return strings.TrimSuffix(owner, "/"), repoName
+}
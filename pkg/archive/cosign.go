@@ -0,0 +1,235 @@
+package archive
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+// OIDs of the Fulcio certificate extensions holding the OIDC issuer.
+var (
+	oidIssuerV1 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// cosignBundle is the bundle written by `cosign sign-blob --bundle`.
+type cosignBundle struct {
+	Base64Signature string       `json:"base64Signature"`
+	Cert            string       `json:"cert"`
+	RekorBundle     *rekorBundle `json:"rekorBundle"`
+}
+
+type rekorBundle struct {
+	SignedEntryTimestamp []byte       `json:"SignedEntryTimestamp"`
+	Payload              rekorPayload `json:"Payload"`
+}
+
+// rekorPayload is the transparency log entry signed by Rekor.
+// The fields are sorted to produce the canonical JSON signed by the log.
+type rekorPayload struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+}
+
+// hashedRekord is the body of a transparency log entry.
+type hashedRekord struct {
+	Kind string `json:"kind"`
+	Spec struct {
+		Data struct {
+			Hash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"hash"`
+		} `json:"data"`
+		Signature struct {
+			Content string `json:"content"`
+		} `json:"signature"`
+	} `json:"spec"`
+}
+
+// verifyBundle verifies a keyless signature, and returns the identity of the signer.
+// The signing certificate must chain to the Fulcio roots at the time the signature has been recorded in Rekor.
+func (v *Verifier) verifyBundle(content, raw []byte, policy db.SignaturePolicy) (string, error) {
+	if v.roots.Fulcio == nil || len(v.roots.Rekor) == 0 {
+		return "", errors.New("keyless verification is not configured")
+	}
+
+	if err := ValidatePolicy(policy); err != nil {
+		return "", err
+	}
+
+	var bundle cosignBundle
+	if err := json.Unmarshal(raw, &bundle); err != nil {
+		return "", fmt.Errorf("invalid bundle: %w", err)
+	}
+
+	if bundle.RekorBundle == nil {
+		return "", errors.New("missing transparency log entry")
+	}
+
+	if err := v.verifyEntryTimestamp(*bundle.RekorBundle); err != nil {
+		return "", err
+	}
+
+	cert, err := parseCertificate(bundle.Cert)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots:         v.roots.Fulcio,
+		Intermediates: v.roots.Fulcio,
+		CurrentTime:   time.Unix(bundle.RekorBundle.Payload.IntegratedTime, 0),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	})
+	if err != nil {
+		return "", fmt.Errorf("untrusted certificate: %w", err)
+	}
+
+	identities := certificateIdentities(cert)
+	if !slices.Contains(identities, policy.Identity) {
+		return "", fmt.Errorf("certificate identity %q doesn't match %q", strings.Join(identities, ", "), policy.Identity)
+	}
+
+	issuer, err := certificateIssuer(cert)
+	if err != nil {
+		return "", err
+	}
+
+	if issuer != policy.Issuer {
+		return "", fmt.Errorf("certificate issuer %q doesn't match %q", issuer, policy.Issuer)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(bundle.Base64Signature)
+	if err != nil {
+		return "", fmt.Errorf("invalid signature: %w", err)
+	}
+
+	digest := sha256.Sum256(content)
+
+	if err = verifyDigest(cert.PublicKey, digest[:], signature); err != nil {
+		return "", err
+	}
+
+	if err = verifyEntryBody(bundle.RekorBundle.Payload.Body, digest[:], bundle.Base64Signature); err != nil {
+		return "", err
+	}
+
+	return "sigstore:" + policy.Identity, nil
+}
+
+// verifyEntryTimestamp verifies the signed entry timestamp (SET) of the transparency log.
+func (v *Verifier) verifyEntryTimestamp(bundle rekorBundle) error {
+	payload, err := json.Marshal(bundle.Payload)
+	if err != nil {
+		return fmt.Errorf("invalid transparency log entry: %w", err)
+	}
+
+	digest := sha256.Sum256(payload)
+
+	for _, key := range v.roots.Rekor {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			continue
+		}
+
+		logID := sha256.Sum256(der)
+		if hex.EncodeToString(logID[:]) != bundle.Payload.LogID {
+			continue
+		}
+
+		if err = verifyDigest(key, digest[:], bundle.SignedEntryTimestamp); err != nil {
+			return fmt.Errorf("invalid transparency log entry: %w", err)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("unknown transparency log %s", bundle.Payload.LogID)
+}
+
+// verifyEntryBody checks that the transparency log entry records the signature of the archive.
+func verifyEntryBody(body string, digest []byte, signature string) error {
+	raw, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return fmt.Errorf("invalid transparency log entry body: %w", err)
+	}
+
+	var entry hashedRekord
+	if err = json.Unmarshal(raw, &entry); err != nil {
+		return fmt.Errorf("invalid transparency log entry body: %w", err)
+	}
+
+	if entry.Kind != "hashedrekord" || entry.Spec.Data.Hash.Algorithm != "sha256" {
+		return fmt.Errorf("unsupported transparency log entry %s", entry.Kind)
+	}
+
+	if entry.Spec.Data.Hash.Value != hex.EncodeToString(digest) || entry.Spec.Signature.Content != signature {
+		return errors.New("transparency log entry doesn't match the archive")
+	}
+
+	return nil
+}
+
+// parseCertificate parses a PEM certificate, base64 encoded or not.
+func parseCertificate(cert string) (*x509.Certificate, error) {
+	raw := []byte(cert)
+
+	if !strings.HasPrefix(cert, "-----BEGIN") {
+		var err error
+
+		raw, err = base64.StdEncoding.DecodeString(cert)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate: %w", err)
+		}
+	}
+
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("invalid certificate: no PEM block")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func certificateIdentities(cert *x509.Certificate) []string {
+	identities := slices.Clone(cert.EmailAddresses)
+
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+
+	return identities
+}
+
+func certificateIssuer(cert *x509.Certificate) (string, error) {
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(oidIssuerV2):
+			var issuer string
+			if _, err := asn1.Unmarshal(ext.Value, &issuer); err != nil {
+				return "", fmt.Errorf("invalid certificate issuer: %w", err)
+			}
+
+			return issuer, nil
+
+		case ext.Id.Equal(oidIssuerV1):
+			return string(ext.Value), nil
+		}
+	}
+
+	return "", errors.New("missing certificate issuer")
+}
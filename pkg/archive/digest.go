@@ -0,0 +1,80 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/traefik/plugin-service/pkg/db"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// Digests computes the digests of an archive.
+// The Go h1: dirhash is only computed for Go module zips, as served by a Go proxy:
+// the digests are always returned, even when the dirhash can't be computed.
+func Digests(raw []byte, moduleZip bool) (map[string]string, error) {
+	digests := map[string]string{
+		db.DigestSHA256: fmt.Sprintf("%x", sha256.Sum256(raw)),
+		db.DigestSHA512: fmt.Sprintf("%x", sha512.Sum512(raw)),
+	}
+
+	if !moduleZip {
+		return digests, nil
+	}
+
+	h1, err := ModuleHash(raw)
+	if err != nil {
+		return digests, fmt.Errorf("unable to compute h1 digest: %w", err)
+	}
+
+	digests[db.DigestH1] = h1
+
+	return digests, nil
+}
+
+// ParseDigest parses a typed digest (<algorithm>:<digest>), like the h1: hashes of go.sum.
+func ParseDigest(typed string) (string, string, error) {
+	algorithm, digest, ok := strings.Cut(strings.TrimSpace(typed), ":")
+	if !ok || digest == "" {
+		return "", "", fmt.Errorf("invalid digest %q, expected <algorithm>:<digest>", typed)
+	}
+
+	switch algorithm {
+	case db.DigestSHA256, db.DigestSHA512:
+		return algorithm, strings.ToLower(digest), nil
+	case db.DigestH1:
+		return algorithm, digest, nil
+	default:
+		return "", "", fmt.Errorf("unsupported digest algorithm %q", algorithm)
+	}
+}
+
+// ModuleHash computes the Go dirhash of a module zip (as dirhash.HashZip), without the h1: prefix.
+// Unlike the SHA-256 of the zip, it only depends on the contents of the module.
+func ModuleHash(raw []byte) (string, error) {
+	reader, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		return "", err
+	}
+
+	files := make(map[string]*zip.File, len(reader.File))
+	names := make([]string, 0, len(reader.File))
+
+	for _, file := range reader.File {
+		files[file.Name] = file
+		names = append(names, file.Name)
+	}
+
+	h1, err := dirhash.Hash1(names, func(name string) (io.ReadCloser, error) {
+		return files[name].Open()
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimPrefix(h1, db.DigestH1+":"), nil
+}
@@ -0,0 +1,113 @@
+package archive
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/plugin-service/pkg/db"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+func TestDigests(t *testing.T) {
+	raw := buildZip(t, []entry{
+		{name: "github.com/traefik/plugindemo@v0.2.1/.traefik.yml", content: "displayName: Demo Plugin\n"},
+		{name: "github.com/traefik/plugindemo@v0.2.1/demo.go", content: "package plugindemo"},
+	})
+
+	path := filepath.Join(t.TempDir(), "module.zip")
+	require.NoError(t, os.WriteFile(path, raw, 0o600))
+
+	h1, err := dirhash.HashZip(path, dirhash.Hash1)
+	require.NoError(t, err)
+
+	digests, err := Digests(raw, true)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		db.DigestSHA256: fmt.Sprintf("%x", sha256.Sum256(raw)),
+		db.DigestSHA512: fmt.Sprintf("%x", sha512.Sum512(raw)),
+		db.DigestH1:     h1[len("h1:"):],
+	}, digests)
+
+	digests, err = Digests(raw, false)
+	require.NoError(t, err)
+
+	assert.NotContains(t, digests, db.DigestH1)
+	assert.Len(t, digests, 2)
+}
+
+func TestDigests_invalidModuleZip(t *testing.T) {
+	digests, err := Digests([]byte("not a zip"), true)
+	require.Error(t, err)
+
+	assert.Contains(t, digests, db.DigestSHA256)
+	assert.Contains(t, digests, db.DigestSHA512)
+	assert.NotContains(t, digests, db.DigestH1)
+}
+
+func TestParseDigest(t *testing.T) {
+	testCases := []struct {
+		desc              string
+		typed             string
+		expectedAlgorithm string
+		expectedDigest    string
+		expectedErr       string
+	}{
+		{
+			desc:              "sha256",
+			typed:             "sha256:ABC123",
+			expectedAlgorithm: db.DigestSHA256,
+			expectedDigest:    "abc123",
+		},
+		{
+			desc:              "h1",
+			typed:             " h1:AbC/12+=",
+			expectedAlgorithm: db.DigestH1,
+			expectedDigest:    "AbC/12+=",
+		},
+		{
+			desc:              "sha512",
+			typed:             "sha512:abc",
+			expectedAlgorithm: db.DigestSHA512,
+			expectedDigest:    "abc",
+		},
+		{
+			desc:        "untyped",
+			typed:       "abc",
+			expectedErr: `invalid digest "abc", expected <algorithm>:<digest>`,
+		},
+		{
+			desc:        "empty digest",
+			typed:       "sha256:",
+			expectedErr: `invalid digest "sha256:", expected <algorithm>:<digest>`,
+		},
+		{
+			desc:        "unsupported algorithm",
+			typed:       "md5:abc",
+			expectedErr: `unsupported digest algorithm "md5"`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			algorithm, digest, err := ParseDigest(test.typed)
+			if test.expectedErr != "" {
+				require.EqualError(t, err, test.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expectedAlgorithm, algorithm)
+			assert.Equal(t, test.expectedDigest, digest)
+		})
+	}
+}
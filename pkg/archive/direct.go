@@ -0,0 +1,159 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v74/github"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	modzip "golang.org/x/mod/zip"
+)
+
+// directSource builds the modules from their GitHub repository, as the "direct" entry of a GOPROXY list.
+type directSource struct {
+	gh *github.Client
+}
+
+// NewDirectSource creates a ModuleSource building the modules from the zipball of their GitHub repository.
+// The module must be at the root of the repository.
+func NewDirectSource(gh *github.Client) ModuleSource {
+	return directSource{gh: gh}
+}
+
+func (s directSource) GetModFile(ctx context.Context, moduleName, version string) (*modfile.File, error) {
+	owner, repoName, ref, err := directRef(moduleName, version)
+	if err != nil {
+		return nil, err
+	}
+
+	content, _, _, err := s.gh.Repositories.GetContents(ctx, owner, repoName, "go.mod", &github.RepositoryContentGetOptions{Ref: ref})
+	if err != nil {
+		return nil, wrapGitHubNotExist(fmt.Errorf("failed to get go.mod: %w", err))
+	}
+
+	raw, err := content.GetContent()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode go.mod: %w", err)
+	}
+
+	return modfile.Parse("go.mod", []byte(raw), nil)
+}
+
+func (s directSource) DownloadSources(ctx context.Context, moduleName, version string) (io.ReadCloser, error) {
+	owner, repoName, ref, err := directRef(moduleName, version)
+	if err != nil {
+		return nil, err
+	}
+
+	zipball, err := downloadZipball(ctx, s.gh, owner, repoName, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = zipball.Close() }()
+
+	raw, err := io.ReadAll(io.LimitReader(zipball, modzip.MaxZipFile+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zipball: %w", err)
+	}
+
+	if len(raw) > modzip.MaxZipFile {
+		return nil, fmt.Errorf("zipball too large (max size is %d bytes)", modzip.MaxZipFile)
+	}
+
+	moduleZip, err := buildModuleZip(module.Version{Path: moduleName, Version: version}, raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build module zip: %w", err)
+	}
+
+	return io.NopCloser(bytes.NewReader(moduleZip)), nil
+}
+
+// buildModuleZip builds the module zip from a GitHub zipball, the files of which are in a top-level directory.
+// The files are kept in the order of the zipball, the order of git archive, as the go command does.
+func buildModuleZip(version module.Version, zipball []byte) ([]byte, error) {
+	reader, err := zip.NewReader(bytes.NewReader(zipball), int64(len(zipball)))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []modzip.File
+
+	for _, file := range reader.File {
+		if strings.HasSuffix(file.Name, "/") {
+			continue
+		}
+
+		_, name, ok := strings.Cut(file.Name, "/")
+		if !ok {
+			continue
+		}
+
+		files = append(files, zipballFile{name: name, file: file})
+	}
+
+	var buf bytes.Buffer
+	if err = modzip.Create(&buf, version, files); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// zipballFile a file of a GitHub zipball, without the top-level directory.
+type zipballFile struct {
+	name string
+	file *zip.File
+}
+
+func (f zipballFile) Path() string {
+	return f.name
+}
+
+func (f zipballFile) Lstat() (fs.FileInfo, error) {
+	return f.file.FileInfo(), nil
+}
+
+func (f zipballFile) Open() (io.ReadCloser, error) {
+	return f.file.Open()
+}
+
+// directRef returns the GitHub repository of a module, and the git ref of a version:
+// the tag of a release version, the revision of a pseudo-version.
+func directRef(moduleName, version string) (string, string, string, error) {
+	parts := strings.Split(moduleName, "/")
+	if len(parts) != 3 || parts[0] != "github.com" {
+		return "", "", "", fmt.Errorf("direct mode only supports the modules at the root of a GitHub repository: %s", moduleName)
+	}
+
+	ref := strings.TrimSuffix(version, "+incompatible")
+
+	if module.IsPseudoVersion(ref) {
+		rev, err := module.PseudoVersionRev(ref)
+		if err != nil {
+			return "", "", "", err
+		}
+
+		ref = rev
+	}
+
+	return parts[1], parts[2], ref, nil
+}
+
+// wrapGitHubNotExist marks the 404 answers of GitHub as fs.ErrNotExist.
+func wrapGitHubNotExist(err error) error {
+	var errResp *github.ErrorResponse
+	if errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusNotFound {
+		return notExistError{err: err}
+	}
+
+	return err
+}
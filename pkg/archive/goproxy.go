@@ -0,0 +1,265 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/ldez/grignotin/goproxy"
+	"golang.org/x/mod/modfile"
+)
+
+// GOPROXY list keywords.
+const (
+	GoProxyDirect = "direct"
+	GoProxyOff    = "off"
+)
+
+// ErrGoProxyOff is returned by the "off" entry of a GOPROXY list.
+var ErrGoProxyOff = errors.New("module lookup disabled by GOPROXY=off")
+
+// errSourcesRejected is returned for the upstreams whose module zip is rejected by DownloadSourcesMatching.
+var errSourcesRejected = errors.New("module zip rejected")
+
+// ModuleSource is capable of fetching the go.mod file and the module zip of a module version.
+type ModuleSource interface {
+	GetModFile(ctx context.Context, moduleName, version string) (*modfile.File, error)
+	// DownloadSources returns the module zip, it is the caller's responsibility to close the ReadCloser.
+	DownloadSources(ctx context.Context, moduleName, version string) (io.ReadCloser, error)
+}
+
+// GoProxyEntry an entry of a GOPROXY list.
+type GoProxyEntry struct {
+	// URL the URL of the proxy, or GoProxyDirect, or GoProxyOff.
+	URL string
+	// FallbackOnError true if the next entry is tried after any error (entry followed by "|"),
+	// false if it is only tried after a 404 or a 410 (entry followed by ",").
+	FallbackOnError bool
+}
+
+// ParseGoProxy parses a GOPROXY list: proxy URLs, "direct" and "off", separated by commas or pipes.
+// As for the go command, the URLs without scheme use HTTPS, and the entries after "direct" or "off" are ignored.
+func ParseGoProxy(value string) ([]GoProxyEntry, error) {
+	var entries []GoProxyEntry
+
+	for value != "" {
+		var entry GoProxyEntry
+
+		if i := strings.IndexAny(value, ",|"); i >= 0 {
+			entry.URL = value[:i]
+			entry.FallbackOnError = value[i] == '|'
+			value = value[i+1:]
+		} else {
+			entry.URL = value
+			value = ""
+		}
+
+		entry.URL = strings.TrimSpace(entry.URL)
+
+		switch {
+		case entry.URL == "":
+			continue
+
+		case entry.URL == GoProxyDirect || entry.URL == GoProxyOff:
+			return append(entries, entry), nil
+
+		case !strings.Contains(entry.URL, "://"):
+			entry.URL = "https://" + entry.URL
+		}
+
+		entry.URL = strings.TrimSuffix(entry.URL, "/")
+
+		entries = append(entries, entry)
+	}
+
+	if len(entries) == 0 {
+		return nil, errors.New("empty GOPROXY list")
+	}
+
+	return entries, nil
+}
+
+// Upstream a source of a GoProxy.
+type Upstream struct {
+	Name string
+	// Source the source of the modules, a nil source disables the lookups (GOPROXY off).
+	Source ModuleSource
+	// Direct true if the source builds the modules from their repository.
+	Direct bool
+	// FallbackOnError true if the next upstream is tried after any error, false if it is only tried after a 404 or a 410.
+	FallbackOnError bool
+}
+
+// GoProxy fetches the modules from a list of upstreams, with the fallback semantics of the GOPROXY list of the go command.
+type GoProxy struct {
+	upstreams []Upstream
+}
+
+// NewGoProxy creates a GoProxy trying the upstreams in order.
+func NewGoProxy(upstreams []Upstream) *GoProxy {
+	return &GoProxy{upstreams: upstreams}
+}
+
+// GetModFile gets the go.mod file of a module version from the first upstream having it.
+func (p *GoProxy) GetModFile(ctx context.Context, moduleName, version string) (*modfile.File, error) {
+	var modFile *modfile.File
+
+	err := p.try(func(source ModuleSource) error {
+		var err error
+		modFile, err = source.GetModFile(ctx, moduleName, version)
+
+		return err
+	})
+
+	return modFile, err
+}
+
+// DownloadSources returns the module zip of a module version from the first upstream having it.
+// It is the caller's responsibility to close the ReadCloser.
+func (p *GoProxy) DownloadSources(ctx context.Context, moduleName, version string) (io.ReadCloser, error) {
+	var sources io.ReadCloser
+
+	err := p.try(func(source ModuleSource) error {
+		var err error
+		sources, err = source.DownloadSources(ctx, moduleName, version)
+
+		return err
+	})
+
+	return sources, err
+}
+
+// DownloadSourcesMatching returns the module zip of a module version from the first upstream serving one accepted by match,
+// an upstream serving a rejected module zip is skipped as if it didn't have the module version.
+// The module zips of the same contents aren't byte-identical from one upstream to another (e.g. rebuilt from the repository by the direct mode).
+func (p *GoProxy) DownloadSourcesMatching(ctx context.Context, moduleName, version string, match func(raw []byte) bool) ([]byte, error) {
+	var sources []byte
+
+	err := p.try(func(source ModuleSource) error {
+		rc, err := source.DownloadSources(ctx, moduleName, version)
+		if err != nil {
+			return err
+		}
+
+		defer func() { _ = rc.Close() }()
+
+		raw, err := io.ReadAll(rc)
+		if err != nil {
+			return err
+		}
+
+		if !match(raw) {
+			return notExistError{err: errSourcesRejected}
+		}
+
+		sources = raw
+
+		return nil
+	})
+
+	return sources, err
+}
+
+// try calls fn with the upstreams until it succeeds, and returns the most helpful error, as the go command does:
+// the error of a direct upstream, then the last error other than a 404 or a 410, then the last 404 or 410.
+func (p *GoProxy) try(fn func(source ModuleSource) error) error {
+	const (
+		notExistRank = iota
+		proxyRank
+		directRank
+	)
+
+	var (
+		bestErr     error
+		bestErrRank = notExistRank
+	)
+
+	for _, up := range p.upstreams {
+		err := ErrGoProxyOff
+		if up.Source != nil {
+			err = fn(up.Source)
+		}
+
+		if err == nil {
+			return nil
+		}
+
+		err = fmt.Errorf("%s: %w", up.Name, err)
+
+		notExist := errors.Is(err, fs.ErrNotExist)
+
+		switch {
+		case up.Direct:
+			bestErr, bestErrRank = err, directRank
+		case bestErrRank <= proxyRank && !notExist:
+			bestErr, bestErrRank = err, proxyRank
+		case bestErrRank == notExistRank:
+			bestErr = err
+		}
+
+		if !up.FallbackOnError && !notExist {
+			break
+		}
+	}
+
+	if bestErr == nil {
+		return errors.New("no Go proxy upstream")
+	}
+
+	return bestErr
+}
+
+// proxySource fetches the modules from a Go module proxy.
+type proxySource struct {
+	client *goproxy.Client
+}
+
+// NewProxySource creates a ModuleSource fetching the modules from a Go module proxy.
+func NewProxySource(client *goproxy.Client) ModuleSource {
+	return proxySource{client: client}
+}
+
+func (s proxySource) GetModFile(_ context.Context, moduleName, version string) (*modfile.File, error) {
+	modFile, err := s.client.GetModFile(moduleName, version)
+	if err != nil {
+		return nil, wrapNotExist(err)
+	}
+
+	return modFile, nil
+}
+
+func (s proxySource) DownloadSources(_ context.Context, moduleName, version string) (io.ReadCloser, error) {
+	sources, err := s.client.DownloadSources(moduleName, version)
+	if err != nil {
+		return nil, wrapNotExist(err)
+	}
+
+	return sources, nil
+}
+
+// notExistError an upstream error meaning that the module version doesn't exist.
+type notExistError struct {
+	err error
+}
+
+func (e notExistError) Error() string {
+	return e.err.Error()
+}
+
+func (e notExistError) Unwrap() []error {
+	return []error{e.err, fs.ErrNotExist}
+}
+
+// wrapNotExist marks the 404 and 410 answers of a proxy as fs.ErrNotExist.
+func wrapNotExist(err error) error {
+	var apiErr *goproxy.APIError
+	if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusNotFound || apiErr.StatusCode == http.StatusGone) {
+		return notExistError{err: err}
+	}
+
+	return err
+}
@@ -0,0 +1,419 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-github/v74/github"
+	"github.com/ldez/grignotin/goproxy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	modzip "golang.org/x/mod/zip"
+)
+
+func TestParseGoProxy(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected []GoProxyEntry
+		expErr   bool
+	}{
+		{
+			value:    "https://proxy.golang.org",
+			expected: []GoProxyEntry{{URL: "https://proxy.golang.org"}},
+		},
+		{
+			value: "https://athens.example.com/,proxy.golang.org|direct",
+			expected: []GoProxyEntry{
+				{URL: "https://athens.example.com"},
+				{URL: "https://proxy.golang.org", FallbackOnError: true},
+				{URL: "direct"},
+			},
+		},
+		{
+			value:    " , https://proxy.golang.org,off,direct",
+			expected: []GoProxyEntry{{URL: "https://proxy.golang.org"}, {URL: "off"}},
+		},
+		{
+			value:  " , ",
+			expErr: true,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.value, func(t *testing.T) {
+			t.Parallel()
+
+			entries, err := ParseGoProxy(test.value)
+			if test.expErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, entries)
+		})
+	}
+}
+
+// fakeSource is a ModuleSource returning the given error, or a module zip containing its name.
+type fakeSource struct {
+	name  string
+	err   error
+	calls *[]string
+}
+
+func (f fakeSource) GetModFile(_ context.Context, moduleName, _ string) (*modfile.File, error) {
+	*f.calls = append(*f.calls, f.name)
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return modfile.Parse("go.mod", []byte("module "+moduleName), nil)
+}
+
+func (f fakeSource) DownloadSources(_ context.Context, _, _ string) (io.ReadCloser, error) {
+	*f.calls = append(*f.calls, f.name)
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return io.NopCloser(bytes.NewReader([]byte(f.name))), nil
+}
+
+func TestGoProxy_DownloadSources(t *testing.T) {
+	notFound := notExistError{err: &goproxy.APIError{StatusCode: http.StatusNotFound, Message: "404 Not Found"}}
+	gone := notExistError{err: &goproxy.APIError{StatusCode: http.StatusGone, Message: "410 Gone"}}
+	unavailable := &goproxy.APIError{StatusCode: http.StatusServiceUnavailable, Message: "503 Service Unavailable"}
+
+	testCases := []struct {
+		desc     string
+		errs     []error
+		fallback []bool
+		expCalls []string
+		expected string
+		expErr   string
+	}{
+		{
+			desc:     "first upstream",
+			errs:     []error{nil, nil},
+			fallback: []bool{false, false},
+			expCalls: []string{"athens"},
+			expected: "athens",
+		},
+		{
+			desc:     "fallback after 404 and 410",
+			errs:     []error{notFound, gone, nil},
+			fallback: []bool{false, false, false},
+			expCalls: []string{"athens", "proxy.golang.org", "direct"},
+			expected: "direct",
+		},
+		{
+			desc:     "no fallback after an error with a comma",
+			errs:     []error{unavailable, nil},
+			fallback: []bool{false, false},
+			expCalls: []string{"athens"},
+			expErr:   "athens: error: 503: 503 Service Unavailable",
+		},
+		{
+			desc:     "fallback after an error with a pipe",
+			errs:     []error{unavailable, nil},
+			fallback: []bool{true, false},
+			expCalls: []string{"athens", "proxy.golang.org"},
+			expected: "proxy.golang.org",
+		},
+		{
+			desc:     "the error is preferred to a 404",
+			errs:     []error{unavailable, notFound},
+			fallback: []bool{true, false},
+			expCalls: []string{"athens", "proxy.golang.org"},
+			expErr:   "athens: error: 503: 503 Service Unavailable",
+		},
+		{
+			desc:     "the direct error is preferred",
+			errs:     []error{unavailable, notFound, errors.New("boom")},
+			fallback: []bool{true, false, false},
+			expCalls: []string{"athens", "proxy.golang.org", "direct"},
+			expErr:   "direct: boom",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var calls []string
+
+			names := []string{"athens", "proxy.golang.org", "direct"}
+
+			var upstreams []Upstream
+			for i, err := range test.errs {
+				upstreams = append(upstreams, Upstream{
+					Name:            names[i],
+					Source:          fakeSource{name: names[i], err: err, calls: &calls},
+					Direct:          names[i] == "direct",
+					FallbackOnError: test.fallback[i],
+				})
+			}
+
+			sources, err := NewGoProxy(upstreams).DownloadSources(context.Background(), "github.com/traefik/plugindemo", "v0.2.1")
+
+			assert.Equal(t, test.expCalls, calls)
+
+			if test.expErr != "" {
+				require.EqualError(t, err, test.expErr)
+				return
+			}
+
+			require.NoError(t, err)
+
+			content, err := io.ReadAll(sources)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, string(content))
+		})
+	}
+}
+
+func TestGoProxy_DownloadSourcesMatching(t *testing.T) {
+	var calls []string
+
+	proxy := NewGoProxy([]Upstream{
+		{Name: "athens", Source: fakeSource{name: "athens", calls: &calls}},
+		{Name: "proxy.golang.org", Source: fakeSource{name: "proxy.golang.org", calls: &calls}},
+	})
+
+	// The module zips rejected by an upstream are skipped, even without a pipe.
+	sources, err := proxy.DownloadSourcesMatching(context.Background(), "github.com/traefik/plugindemo", "v0.2.1", func(raw []byte) bool {
+		return string(raw) == "proxy.golang.org"
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "proxy.golang.org", string(sources))
+	assert.Equal(t, []string{"athens", "proxy.golang.org"}, calls)
+
+	_, err = proxy.DownloadSourcesMatching(context.Background(), "github.com/traefik/plugindemo", "v0.2.1", func([]byte) bool {
+		return false
+	})
+	require.ErrorIs(t, err, errSourcesRejected)
+}
+
+func TestGoProxy_GetModFile_off(t *testing.T) {
+	var calls []string
+
+	notFound := notExistError{err: &goproxy.APIError{StatusCode: http.StatusNotFound}}
+
+	proxy := NewGoProxy([]Upstream{
+		{Name: "athens", Source: fakeSource{name: "athens", err: notFound, calls: &calls}},
+		{Name: "off"},
+	})
+
+	_, err := proxy.GetModFile(context.Background(), "github.com/traefik/plugindemo", "v0.2.1")
+	require.ErrorIs(t, err, ErrGoProxyOff)
+
+	assert.Equal(t, []string{"athens"}, calls)
+}
+
+func TestWrapNotExist(t *testing.T) {
+	assert.ErrorIs(t, wrapNotExist(&goproxy.APIError{StatusCode: http.StatusNotFound}), fs.ErrNotExist)
+	assert.ErrorIs(t, wrapNotExist(&goproxy.APIError{StatusCode: http.StatusGone}), fs.ErrNotExist)
+	assert.NotErrorIs(t, wrapNotExist(&goproxy.APIError{StatusCode: http.StatusBadGateway}), fs.ErrNotExist)
+	assert.NotErrorIs(t, wrapNotExist(errors.New("boom")), fs.ErrNotExist)
+}
+
+// setupDirectAPI serves the go.mod and the zipball of traefik/plugindemo at v0.2.1.
+func setupDirectAPI(t *testing.T, goMod string, files map[string]string) *github.Client {
+	t.Helper()
+
+	var zipball bytes.Buffer
+
+	zw := zip.NewWriter(&zipball)
+
+	_, err := zw.Create("traefik-plugindemo-abc123/")
+	require.NoError(t, err)
+
+	for name, content := range files {
+		w, err := zw.Create("traefik-plugindemo-abc123/" + name)
+		require.NoError(t, err)
+
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, zw.Close())
+
+	mux := http.NewServeMux()
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("GET /repos/traefik/plugindemo/contents/go.mod", func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("ref") != "v0.2.1" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(rw).Encode(map[string]string{
+			"type":     "file",
+			"encoding": "base64",
+			"content":  base64.StdEncoding.EncodeToString([]byte(goMod)),
+		}))
+	})
+
+	mux.HandleFunc("GET /repos/traefik/plugindemo/zipball/v0.2.1", func(rw http.ResponseWriter, req *http.Request) {
+		http.Redirect(rw, req, server.URL+"/codeload/traefik/plugindemo/zip/v0.2.1", http.StatusFound)
+	})
+
+	mux.HandleFunc("GET /codeload/traefik/plugindemo/zip/v0.2.1", func(rw http.ResponseWriter, _ *http.Request) {
+		_, _ = rw.Write(zipball.Bytes())
+	})
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	return client
+}
+
+func TestDirectSource(t *testing.T) {
+	goMod := "module github.com/traefik/plugindemo\n\ngo 1.22\n"
+
+	gh := setupDirectAPI(t, goMod, map[string]string{
+		"go.mod":              goMod,
+		"demo.go":             "package plugindemo\n",
+		"sub/go.mod":          "module github.com/traefik/plugindemo/sub\n",
+		"sub/sub.go":          "package sub\n",
+		".traefik.yml":        "displayName: Demo\n",
+		"vendor/modules.txt":  "",
+		"vendor/foo/bar.go":   "package bar\n",
+		"testdata/fixture.go": "package testdata\n",
+	})
+
+	source := NewDirectSource(gh)
+
+	modFile, err := source.GetModFile(context.Background(), "github.com/traefik/plugindemo", "v0.2.1")
+	require.NoError(t, err)
+
+	assert.Equal(t, "github.com/traefik/plugindemo", modFile.Module.Mod.Path)
+
+	_, err = source.GetModFile(context.Background(), "github.com/traefik/plugindemo", "v0.3.0")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	sources, err := source.DownloadSources(context.Background(), "github.com/traefik/plugindemo", "v0.2.1")
+	require.NoError(t, err)
+
+	raw, err := io.ReadAll(sources)
+	require.NoError(t, err)
+
+	reader, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	require.NoError(t, err)
+
+	var names []string
+	for _, file := range reader.File {
+		names = append(names, file.Name)
+	}
+
+	// The submodule and the vendored packages are excluded, as the go command does.
+	assert.ElementsMatch(t, []string{
+		"github.com/traefik/plugindemo@v0.2.1/go.mod",
+		"github.com/traefik/plugindemo@v0.2.1/demo.go",
+		"github.com/traefik/plugindemo@v0.2.1/.traefik.yml",
+		"github.com/traefik/plugindemo@v0.2.1/vendor/modules.txt",
+		"github.com/traefik/plugindemo@v0.2.1/testdata/fixture.go",
+	}, names)
+
+	// The module zip is valid.
+	zipFile := filepath.Join(t.TempDir(), "v0.2.1.zip")
+	require.NoError(t, os.WriteFile(zipFile, raw, 0o600))
+
+	checked, err := modzip.CheckZip(module.Version{Path: "github.com/traefik/plugindemo", Version: "v0.2.1"}, zipFile)
+	require.NoError(t, err)
+	require.NoError(t, checked.Err())
+
+	// A missing tag falls back as a missing module version.
+	_, err = source.DownloadSources(context.Background(), "github.com/traefik/plugindemo", "v0.3.0")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = source.DownloadSources(context.Background(), "gitlab.com/traefik/plugindemo", "v0.2.1")
+	require.ErrorContains(t, err, "direct mode only supports the modules at the root of a GitHub repository")
+}
+
+func TestDirectSource_moduleHash(t *testing.T) {
+	goMod := "module github.com/traefik/plugindemo\n\ngo 1.22\n"
+
+	files := map[string]string{
+		"go.mod":       goMod,
+		"demo.go":      "package plugindemo\n",
+		"pkg/pkg.go":   "package pkg\n",
+		".traefik.yml": "displayName: Demo\n",
+	}
+
+	version := module.Version{Path: "github.com/traefik/plugindemo", Version: "v0.2.1"}
+
+	// The module zip of a Go proxy, built by the go command from the module directory.
+	dir := t.TempDir()
+
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+
+	var proxyZip bytes.Buffer
+	require.NoError(t, modzip.CreateFromDir(&proxyZip, version, dir))
+
+	// The module zip rebuilt from the zipball by the direct mode.
+	sources, err := NewDirectSource(setupDirectAPI(t, goMod, files)).DownloadSources(context.Background(), version.Path, version.Version)
+	require.NoError(t, err)
+
+	directZip, err := io.ReadAll(sources)
+	require.NoError(t, err)
+
+	// The zips are not necessarily byte-identical, but they have the same contents.
+	expected, err := ModuleHash(proxyZip.Bytes())
+	require.NoError(t, err)
+
+	h1, err := ModuleHash(directZip)
+	require.NoError(t, err)
+
+	assert.Equal(t, expected, h1)
+}
+
+func TestDirectRef(t *testing.T) {
+	testCases := []struct {
+		version  string
+		expected string
+	}{
+		{version: "v0.2.1", expected: "v0.2.1"},
+		{version: "v2.0.0+incompatible", expected: "v2.0.0"},
+		{version: "v0.0.0-20240102150405-0123456789ab", expected: "0123456789ab"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.version, func(t *testing.T) {
+			t.Parallel()
+
+			owner, repoName, ref, err := directRef("github.com/traefik/plugindemo", test.version)
+			require.NoError(t, err)
+
+			assert.Equal(t, "traefik", owner)
+			assert.Equal(t, "plugindemo", repoName)
+			assert.Equal(t, test.expected, ref)
+		})
+	}
+}
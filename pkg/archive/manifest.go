@@ -0,0 +1,93 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/traefik/plugin-service/pkg/db"
+	"gopkg.in/yaml.v3"
+)
+
+const maxManifestSize = 1 << 20 // 1MB
+
+// ReadManifest reads the plugin manifest from an archive.
+// The manifest is searched at the root of the archive,
+// or inside the directory prefixing all entries (Go proxy archives and GitHub zipballs).
+func ReadManifest(content []byte) (db.Manifest, error) {
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return db.Manifest{}, fmt.Errorf("invalid zip archive: %w", err)
+	}
+
+	file := findManifest(reader)
+	if file == nil {
+		return db.Manifest{}, errors.New("manifest not found")
+	}
+
+	rc, err := file.Open()
+	if err != nil {
+		return db.Manifest{}, fmt.Errorf("failed to open manifest: %w", err)
+	}
+
+	defer func() { _ = rc.Close() }()
+
+	raw, err := io.ReadAll(io.LimitReader(rc, maxManifestSize))
+	if err != nil {
+		return db.Manifest{}, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	return ParseManifest(raw)
+}
+
+// ParseManifest parses the content of a plugin manifest.
+func ParseManifest(raw []byte) (db.Manifest, error) {
+	var manifest db.Manifest
+	if err := yaml.Unmarshal(raw, &manifest); err != nil {
+		return db.Manifest{}, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	return manifest, nil
+}
+
+func findManifest(reader *zip.Reader) *zip.File {
+	name := path.Join(rootDir(reader), ManifestFile)
+
+	for _, file := range reader.File {
+		if path.Clean(file.Name) == name {
+			return file
+		}
+	}
+
+	return nil
+}
+
+// rootDir returns the directory containing all the entries of an archive.
+func rootDir(reader *zip.Reader) string {
+	if len(reader.File) == 0 {
+		return ""
+	}
+
+	root := path.Dir(path.Clean(reader.File[0].Name))
+	if reader.File[0].FileInfo().IsDir() {
+		root = path.Clean(reader.File[0].Name)
+	}
+
+	for _, file := range reader.File[1:] {
+		name := path.Clean(file.Name)
+
+		for root != "." && name != root && !strings.HasPrefix(name, root+"/") {
+			root = path.Dir(root)
+		}
+	}
+
+	if root == "." {
+		return ""
+	}
+
+	return root
+}
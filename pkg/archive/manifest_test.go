@@ -0,0 +1,108 @@
+package archive
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+func TestReadManifest(t *testing.T) {
+	manifest := `displayName: Demo Plugin
+type: middleware
+import: github.com/traefik/plugindemo
+summary: '[Demo] Add Request Header'
+testData:
+  Headers:
+    X-Demo: test
+`
+
+	expected := db.Manifest{
+		DisplayName: "Demo Plugin",
+		Type:        "middleware",
+		Import:      "github.com/traefik/plugindemo",
+		Summary:     "[Demo] Add Request Header",
+		TestData: map[string]interface{}{
+			"Headers": map[string]interface{}{"X-Demo": "test"},
+		},
+	}
+
+	testCases := []struct {
+		desc     string
+		entries  []entry
+		expected db.Manifest
+	}{
+		{
+			desc: "root",
+			entries: []entry{
+				{name: ".traefik.yml", content: manifest},
+				{name: "plugin.wasm"},
+			},
+			expected: expected,
+		},
+		{
+			desc: "Go proxy archive",
+			entries: []entry{
+				{name: "github.com/traefik/plugindemo@v0.2.1/.traefik.yml", content: manifest},
+				{name: "github.com/traefik/plugindemo@v0.2.1/demo.go"},
+				{name: "github.com/traefik/plugindemo@v0.2.1/vendor/github.com/foo/bar/.traefik.yml", content: "displayName: Bar"},
+			},
+			expected: expected,
+		},
+		{
+			desc: "GitHub zipball",
+			entries: []entry{
+				{name: "traefik-plugindemo-1a2b3c/"},
+				{name: "traefik-plugindemo-1a2b3c/demo.go"},
+				{name: "traefik-plugindemo-1a2b3c/.traefik.yml", content: manifest},
+			},
+			expected: expected,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ReadManifest(buildZip(t, test.entries))
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, got)
+		})
+	}
+}
+
+func TestReadManifest_errors(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		content []byte
+	}{
+		{
+			desc:    "invalid zip",
+			content: []byte("not a zip"),
+		},
+		{
+			desc: "missing manifest",
+			content: buildZip(t, []entry{
+				{name: "github.com/traefik/plugindemo@v0.2.1/demo.go"},
+				{name: "github.com/traefik/plugindemo@v0.2.1/sub/.traefik.yml"},
+			}),
+		},
+		{
+			desc: "invalid manifest",
+			content: buildZip(t, []entry{
+				{name: ".traefik.yml", content: "displayName: [invalid"},
+			}),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ReadManifest(test.content)
+			require.Error(t, err)
+		})
+	}
+}
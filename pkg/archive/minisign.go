@@ -0,0 +1,125 @@
+package archive
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Signature algorithms of minisign.
+const (
+	minisignAlgLegacy = "Ed"
+	minisignAlgHashed = "ED"
+)
+
+const (
+	untrustedCommentPrefix = "untrusted comment: "
+	trustedCommentPrefix   = "trusted comment: "
+)
+
+type minisignSignature struct {
+	algorithm       string
+	keyID           []byte
+	signature       []byte
+	trustedComment  string
+	globalSignature []byte
+}
+
+// verifyMinisign verifies a minisign signature, and returns the key ID of the signer.
+func verifyMinisign(content, signature []byte, publicKey string) (string, error) {
+	keyID, key, err := parseMinisignPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	sig, err := parseMinisignSignature(signature)
+	if err != nil {
+		return "", err
+	}
+
+	if !bytes.Equal(sig.keyID, keyID) {
+		return "", fmt.Errorf("signed with key %s, expected key %s", minisignKeyID(sig.keyID), minisignKeyID(keyID))
+	}
+
+	message := content
+	if sig.algorithm == minisignAlgHashed {
+		sum := blake2b.Sum512(content)
+		message = sum[:]
+	}
+
+	if !ed25519.Verify(key, message, sig.signature) {
+		return "", errors.New("invalid signature")
+	}
+
+	if !ed25519.Verify(key, append(sig.signature, sig.trustedComment...), sig.globalSignature) {
+		return "", errors.New("invalid trusted comment signature")
+	}
+
+	return "minisign:" + minisignKeyID(keyID), nil
+}
+
+// parseMinisignPublicKey parses a public key, with or without its untrusted comment.
+func parseMinisignPublicKey(publicKey string) ([]byte, ed25519.PublicKey, error) {
+	lines := strings.Split(strings.TrimSpace(publicKey), "\n")
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[len(lines)-1]))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid minisign public key: %w", err)
+	}
+
+	if len(raw) != 2+8+ed25519.PublicKeySize || string(raw[:2]) != minisignAlgLegacy {
+		return nil, nil, errors.New("invalid minisign public key")
+	}
+
+	return raw[2:10], raw[10:], nil
+}
+
+func parseMinisignSignature(signature []byte) (minisignSignature, error) {
+	// The .sig assets are also used by cosign (base64 signature), which can't be verified with a minisign public key.
+	if !strings.HasPrefix(strings.TrimSpace(string(signature)), untrustedCommentPrefix) {
+		return minisignSignature{}, errors.New("not a minisign signature: a public key policy only accepts minisign signatures, cosign key-based signatures are not supported")
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(signature)), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], untrustedCommentPrefix) || !strings.HasPrefix(lines[2], trustedCommentPrefix) {
+		return minisignSignature{}, errors.New("invalid minisign signature file")
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil {
+		return minisignSignature{}, fmt.Errorf("invalid minisign signature: %w", err)
+	}
+
+	if len(raw) != 2+8+ed25519.SignatureSize {
+		return minisignSignature{}, errors.New("invalid minisign signature")
+	}
+
+	algorithm := string(raw[:2])
+	if algorithm != minisignAlgLegacy && algorithm != minisignAlgHashed {
+		return minisignSignature{}, fmt.Errorf("unsupported minisign algorithm %q", algorithm)
+	}
+
+	globalSignature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || len(globalSignature) != ed25519.SignatureSize {
+		return minisignSignature{}, errors.New("invalid minisign trusted comment signature")
+	}
+
+	return minisignSignature{
+		algorithm:       algorithm,
+		keyID:           raw[2:10],
+		signature:       raw[10:],
+		trustedComment:  strings.TrimSuffix(strings.TrimPrefix(lines[2], trustedCommentPrefix), "\r"),
+		globalSignature: globalSignature,
+	}, nil
+}
+
+// minisignKeyID formats a key ID as displayed by minisign.
+func minisignKeyID(keyID []byte) string {
+	return fmt.Sprintf("%016X", binary.LittleEndian.Uint64(keyID))
+}
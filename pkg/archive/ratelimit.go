@@ -0,0 +1,36 @@
+package archive
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/go-github/v74/github"
+	"github.com/traefik/plugin-service/pkg/githubapi"
+)
+
+// secondaryRateLimitDelay is the delay to wait when GitHub doesn't tell how long to wait after a secondary rate limit.
+const secondaryRateLimitDelay = time.Minute
+
+// RateLimitReset returns the time after which GitHub accepts requests again, if the error is caused by a GitHub rate limit.
+func RateLimitReset(err error, now time.Time) (time.Time, bool) {
+	var rateLimitErr *github.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return rateLimitErr.Rate.Reset.Time, true
+	}
+
+	var reserveErr *githubapi.RateLimitedError
+	if errors.As(err, &reserveErr) {
+		return reserveErr.Reset, true
+	}
+
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) {
+		if abuseErr.RetryAfter != nil {
+			return now.Add(*abuseErr.RetryAfter), true
+		}
+
+		return now.Add(secondaryRateLimitDelay), true
+	}
+
+	return time.Time{}, false
+}
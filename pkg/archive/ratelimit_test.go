@@ -0,0 +1,65 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v74/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/traefik/plugin-service/pkg/githubapi"
+)
+
+func TestRateLimitReset(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	retryAfter := 30 * time.Second
+
+	testCases := []struct {
+		desc          string
+		err           error
+		expected      time.Time
+		expectedLimit bool
+	}{
+		{
+			desc:          "primary rate limit",
+			err:           fmt.Errorf("failed to get release: %w", &github.RateLimitError{Rate: github.Rate{Reset: github.Timestamp{Time: now.Add(time.Hour)}}}),
+			expected:      now.Add(time.Hour),
+			expectedLimit: true,
+		},
+		{
+			desc:          "credentials reserve reached",
+			err:           &url.Error{Op: "Get", URL: "https://api.github.com/repos/traefik/plugindemo", Err: &githubapi.RateLimitedError{Resource: "core", Reset: now.Add(time.Minute)}},
+			expected:      now.Add(time.Minute),
+			expectedLimit: true,
+		},
+		{
+			desc:          "secondary rate limit with retry after",
+			err:           fmt.Errorf("failed to get release: %w", &github.AbuseRateLimitError{RetryAfter: &retryAfter}),
+			expected:      now.Add(retryAfter),
+			expectedLimit: true,
+		},
+		{
+			desc:          "secondary rate limit",
+			err:           &github.AbuseRateLimitError{},
+			expected:      now.Add(secondaryRateLimitDelay),
+			expectedLimit: true,
+		},
+		{
+			desc: "other error",
+			err:  errors.New("boom"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			reset, ok := RateLimitReset(test.err, now)
+
+			assert.Equal(t, test.expectedLimit, ok)
+			assert.Equal(t, test.expected, reset)
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+const maxSourceSize = 5 << 20 // 5MB
+
+// importCapabilities maps the packages to the capability their import grants.
+var importCapabilities = map[string]string{
+	"unsafe":                   db.CapabilityUnsafe,
+	"syscall":                  db.CapabilitySyscall,
+	"golang.org/x/sys/unix":    db.CapabilitySyscall,
+	"golang.org/x/sys/windows": db.CapabilitySyscall,
+	"os/exec":                  db.CapabilityExec,
+}
+
+// callCapabilities maps package members to the capability their use grants.
+var callCapabilities = map[string]map[string]string{
+	"reflect": {
+		"NewAt":        db.CapabilityReflect,
+		"SliceHeader":  db.CapabilityReflect,
+		"StringHeader": db.CapabilityReflect,
+	},
+	"net": {
+		"Listen":       db.CapabilityNetwork,
+		"ListenIP":     db.CapabilityNetwork,
+		"ListenPacket": db.CapabilityNetwork,
+		"ListenTCP":    db.CapabilityNetwork,
+		"ListenUDP":    db.CapabilityNetwork,
+		"ListenUnix":   db.CapabilityNetwork,
+	},
+	"net/http": {
+		"ListenAndServe":    db.CapabilityNetwork,
+		"ListenAndServeTLS": db.CapabilityNetwork,
+		"Serve":             db.CapabilityNetwork,
+		"ServeTLS":          db.CapabilityNetwork,
+	},
+}
+
+// reflectMethods are the reflect.Value methods exposing memory addresses.
+var reflectMethods = map[string]struct{}{
+	"UnsafeAddr":    {},
+	"UnsafePointer": {},
+	"InterfaceData": {},
+}
+
+// ScanSources analyzes the Go sources of a Yaegi plugin archive and reports the sensitive capabilities they use.
+// Test files are ignored because they are not interpreted by Yaegi.
+func ScanSources(content []byte) (db.CapabilityReport, error) {
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return db.CapabilityReport{}, fmt.Errorf("invalid zip archive: %w", err)
+	}
+
+	root := rootDir(reader)
+
+	var report db.CapabilityReport
+
+	fset := token.NewFileSet()
+
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() || path.Ext(file.Name) != ".go" || strings.HasSuffix(file.Name, "_test.go") {
+			continue
+		}
+
+		name := strings.TrimPrefix(strings.TrimPrefix(path.Clean(file.Name), root), "/")
+
+		src, err := readFile(file, maxSourceSize)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+
+		astFile, err := parser.ParseFile(fset, name, src, parser.SkipObjectResolution)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+
+		report.Findings = append(report.Findings, scanFile(fset, astFile)...)
+	}
+
+	for _, finding := range report.Findings {
+		if !slices.Contains(report.Capabilities, finding.Capability) {
+			report.Capabilities = append(report.Capabilities, finding.Capability)
+		}
+	}
+
+	slices.Sort(report.Capabilities)
+
+	return report, nil
+}
+
+func scanFile(fset *token.FileSet, file *ast.File) []db.Finding {
+	var findings []db.Finding
+
+	add := func(capability string, pos token.Pos, detail string) {
+		position := fset.Position(pos)
+
+		findings = append(findings, db.Finding{
+			Capability: capability,
+			File:       position.Filename,
+			Line:       position.Line,
+			Detail:     detail,
+		})
+	}
+
+	// Local names of the imported packages, and the packages imported in the file block.
+	imports := make(map[string]string)
+	var dotImports []string
+
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+
+		if capability, ok := importCapabilities[importPath]; ok {
+			add(capability, spec.Pos(), "import "+importPath)
+		}
+
+		name := path.Base(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+
+		if name == "." {
+			dotImports = append(dotImports, importPath)
+			continue
+		}
+
+		imports[name] = importPath
+	}
+
+	usesReflect := slices.Contains(dotImports, "reflect")
+	for _, importPath := range imports {
+		usesReflect = usesReflect || importPath == "reflect"
+	}
+
+	// Selected names are not the unqualified members of the dot imports.
+	selected := make(map[*ast.Ident]struct{})
+
+	ast.Inspect(file, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Ident); ok {
+			if _, ok := selected[ident]; ok {
+				return true
+			}
+
+			for _, importPath := range dotImports {
+				if capability, ok := callCapabilities[importPath][ident.Name]; ok {
+					add(capability, ident.Pos(), importPath+"."+ident.Name)
+				}
+			}
+
+			return true
+		}
+
+		sel, ok := node.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+
+		selected[sel.Sel] = struct{}{}
+
+		if ident, ok := sel.X.(*ast.Ident); ok {
+			if importPath, ok := imports[ident.Name]; ok {
+				if capability, ok := callCapabilities[importPath][sel.Sel.Name]; ok {
+					add(capability, sel.Pos(), importPath+"."+sel.Sel.Name)
+				}
+
+				return true
+			}
+		}
+
+		if _, ok := reflectMethods[sel.Sel.Name]; ok && usesReflect {
+			add(db.CapabilityReflect, sel.Pos(), "reflect.Value."+sel.Sel.Name)
+		}
+
+		return true
+	})
+
+	return findings
+}
+
+func readFile(file *zip.File, limit int64) ([]byte, error) {
+	if file.UncompressedSize64 > uint64(limit) {
+		return nil, fmt.Errorf("file too large (%d bytes)", file.UncompressedSize64)
+	}
+
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rc.Close() }()
+
+	return io.ReadAll(io.LimitReader(rc, limit))
+}
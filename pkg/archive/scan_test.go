@@ -0,0 +1,176 @@
+package archive
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+func TestScanSources(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		entries  []entry
+		expected db.CapabilityReport
+	}{
+		{
+			desc: "safe plugin",
+			entries: []entry{
+				{name: "github.com/traefik/plugindemo@v0.2.1/.traefik.yml"},
+				{name: "github.com/traefik/plugindemo@v0.2.1/demo.go", content: `package plugindemo
+
+import (
+	"net/http"
+	"reflect"
+)
+
+func New() http.Handler {
+	_ = reflect.ValueOf(1).Int()
+	return http.NotFoundHandler()
+}
+`},
+			},
+		},
+		{
+			desc: "unsafe and syscall imports",
+			entries: []entry{
+				{name: "github.com/traefik/plugindemo@v0.2.1/demo.go", content: `package plugindemo
+
+import (
+	"syscall"
+	u "unsafe"
+)
+
+var _ = u.Sizeof(syscall.Getpid())
+`},
+			},
+			expected: db.CapabilityReport{
+				Capabilities: []string{db.CapabilitySyscall, db.CapabilityUnsafe},
+				Findings: []db.Finding{
+					{Capability: db.CapabilitySyscall, File: "demo.go", Line: 4, Detail: "import syscall"},
+					{Capability: db.CapabilityUnsafe, File: "demo.go", Line: 5, Detail: "import unsafe"},
+				},
+			},
+		},
+		{
+			desc: "exec in vendored dependency",
+			entries: []entry{
+				{name: "traefik-plugindemo-1a2b3c/"},
+				{name: "traefik-plugindemo-1a2b3c/demo.go", content: "package plugindemo\n"},
+				{name: "traefik-plugindemo-1a2b3c/vendor/github.com/foo/bar/bar.go", content: `package bar
+
+import "os/exec"
+
+func Run() error { return exec.Command("ls").Run() }
+`},
+			},
+			expected: db.CapabilityReport{
+				Capabilities: []string{db.CapabilityExec},
+				Findings: []db.Finding{
+					{Capability: db.CapabilityExec, File: "vendor/github.com/foo/bar/bar.go", Line: 3, Detail: "import os/exec"},
+				},
+			},
+		},
+		{
+			desc: "reflect tricks",
+			entries: []entry{
+				{name: "demo.go", content: `package plugindemo
+
+import refl "reflect"
+
+func addr(v refl.Value) uintptr {
+	_ = refl.NewAt(v.Type(), nil)
+	_ = refl.SliceHeader{}
+	return v.UnsafeAddr()
+}
+`},
+			},
+			expected: db.CapabilityReport{
+				Capabilities: []string{db.CapabilityReflect},
+				Findings: []db.Finding{
+					{Capability: db.CapabilityReflect, File: "demo.go", Line: 6, Detail: "reflect.NewAt"},
+					{Capability: db.CapabilityReflect, File: "demo.go", Line: 7, Detail: "reflect.SliceHeader"},
+					{Capability: db.CapabilityReflect, File: "demo.go", Line: 8, Detail: "reflect.Value.UnsafeAddr"},
+				},
+			},
+		},
+		{
+			desc: "network listeners",
+			entries: []entry{
+				{name: "demo.go", content: `package plugindemo
+
+import (
+	"net"
+	"net/http"
+)
+
+func init() {
+	_, _ = net.Listen("tcp", ":8080")
+	go http.ListenAndServe(":8081", nil)
+}
+`},
+			},
+			expected: db.CapabilityReport{
+				Capabilities: []string{db.CapabilityNetwork},
+				Findings: []db.Finding{
+					{Capability: db.CapabilityNetwork, File: "demo.go", Line: 9, Detail: "net.Listen"},
+					{Capability: db.CapabilityNetwork, File: "demo.go", Line: 10, Detail: "net/http.ListenAndServe"},
+				},
+			},
+		},
+		{
+			desc: "dot imports",
+			entries: []entry{
+				{name: "demo.go", content: `package plugindemo
+
+import (
+	. "net"
+	. "os/exec"
+	. "unsafe"
+)
+
+func init() {
+	_ = Sizeof(0)
+	_ = Command("sh")
+	_, _ = Listen("tcp", ":8080")
+}
+`},
+			},
+			expected: db.CapabilityReport{
+				Capabilities: []string{db.CapabilityExec, db.CapabilityNetwork, db.CapabilityUnsafe},
+				Findings: []db.Finding{
+					{Capability: db.CapabilityExec, File: "demo.go", Line: 5, Detail: "import os/exec"},
+					{Capability: db.CapabilityUnsafe, File: "demo.go", Line: 6, Detail: "import unsafe"},
+					{Capability: db.CapabilityNetwork, File: "demo.go", Line: 12, Detail: "net.Listen"},
+				},
+			},
+		},
+		{
+			desc: "test files and invalid files",
+			entries: []entry{
+				{name: "demo_test.go", content: "package plugindemo\n\nimport \"unsafe\"\n"},
+				{name: "broken.go", content: "package plugindemo\n\nfunc {\n"},
+			},
+			expected: db.CapabilityReport{
+				Errors: []string{"broken.go: broken.go:3:6: expected 'IDENT', found '{'"},
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			report, err := ScanSources(buildZip(t, test.entries))
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, report)
+		})
+	}
+}
+
+func TestScanSources_invalidZip(t *testing.T) {
+	_, err := ScanSources([]byte("not a zip"))
+	require.Error(t, err)
+}
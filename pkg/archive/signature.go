@@ -0,0 +1,132 @@
+package archive
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+// Extensions of the signature assets.
+const (
+	ExtMinisign = ".minisig"
+	ExtSig      = ".sig"
+	ExtBundle   = ".bundle"
+)
+
+var signatureExtensions = []string{ExtMinisign, ExtSig, ExtBundle}
+
+// TrustRoots holds the Sigstore trust roots used to verify keyless signatures offline.
+type TrustRoots struct {
+	// Fulcio the certificate authorities issuing the signing certificates (roots and intermediates).
+	Fulcio *x509.CertPool
+	// Rekor the public keys of the transparency logs.
+	Rekor []crypto.PublicKey
+}
+
+// ParseTrustRoots parses the PEM encoded Fulcio certificates and Rekor public keys.
+func ParseTrustRoots(fulcioPEM, rekorPEM []byte) (TrustRoots, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(fulcioPEM) {
+		return TrustRoots{}, errors.New("no Fulcio certificate found")
+	}
+
+	var keys []crypto.PublicKey
+
+	for rest := rekorPEM; ; {
+		var block *pem.Block
+
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return TrustRoots{}, fmt.Errorf("invalid Rekor public key: %w", err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return TrustRoots{}, errors.New("no Rekor public key found")
+	}
+
+	return TrustRoots{Fulcio: pool, Rekor: keys}, nil
+}
+
+// Verifier verifies the signatures of release assets.
+type Verifier struct {
+	roots TrustRoots
+}
+
+// NewVerifier creates a Verifier.
+// Without trust roots, only the minisign signatures can be verified.
+func NewVerifier(roots TrustRoots) *Verifier {
+	return &Verifier{roots: roots}
+}
+
+// ValidatePolicy checks a signature policy, a keyless identity is only trusted with the issuer of its certificate.
+func ValidatePolicy(policy db.SignaturePolicy) error {
+	switch {
+	case policy.Identity != "" && policy.Issuer == "":
+		return errors.New("a keyless signature policy requires an issuer")
+	case policy.Issuer != "" && policy.Identity == "":
+		return errors.New("a keyless signature policy requires an identity")
+	default:
+		return nil
+	}
+}
+
+// SignatureExtensions returns the extensions of the signature assets accepted for a policy, by order of preference.
+// With a public key policy, the .sig assets must be minisign signatures.
+func SignatureExtensions(policy db.SignaturePolicy) []string {
+	switch {
+	case policy.PublicKey != "":
+		return []string{ExtMinisign, ExtSig}
+	case policy.Identity != "":
+		return []string{ExtBundle}
+	default:
+		return nil
+	}
+}
+
+// Verify verifies the signature of an archive against the policy of a plugin, and returns the signer.
+func (v *Verifier) Verify(content, signature []byte, policy db.SignaturePolicy) (string, error) {
+	switch {
+	case policy.PublicKey != "":
+		return verifyMinisign(content, signature, policy.PublicKey)
+	case policy.Identity != "":
+		return v.verifyBundle(content, signature, policy)
+	default:
+		return "", errors.New("empty signature policy")
+	}
+}
+
+// verifyDigest verifies a signature of a SHA-256 digest.
+func verifyDigest(key crypto.PublicKey, digest, signature []byte) error {
+	switch pub := key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(pub, digest, signature) {
+			return errors.New("invalid signature")
+		}
+
+		return nil
+
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest, signature)
+
+	case ed25519.PublicKey:
+		return errors.New("ed25519 keys can't verify a digest")
+
+	default:
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+}
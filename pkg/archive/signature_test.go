@@ -0,0 +1,435 @@
+package archive
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/plugin-service/pkg/db"
+	"golang.org/x/crypto/blake2b"
+)
+
+func TestVerifier_Verify_minisign(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	keyID := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	publicKey := base64.StdEncoding.EncodeToString(append(append([]byte(minisignAlgLegacy), keyID...), pub...))
+
+	content := []byte("archive content")
+
+	testCases := []struct {
+		desc           string
+		publicKey      string
+		signature      []byte
+		content        []byte
+		expectedSigner string
+		expectedErr    string
+	}{
+		{
+			desc:           "legacy signature",
+			publicKey:      publicKey,
+			signature:      minisign(t, priv, keyID, minisignAlgLegacy, content, "timestamp:1760000000"),
+			expectedSigner: "minisign:0807060504030201",
+		},
+		{
+			desc:           "prehashed signature",
+			publicKey:      publicKey,
+			signature:      minisign(t, priv, keyID, minisignAlgHashed, content, "timestamp:1760000000"),
+			expectedSigner: "minisign:0807060504030201",
+		},
+		{
+			desc:           "public key file",
+			publicKey:      "untrusted comment: minisign public key 0807060504030201\n" + publicKey + "\n",
+			signature:      minisign(t, priv, keyID, minisignAlgHashed, content, "timestamp:1760000000"),
+			expectedSigner: "minisign:0807060504030201",
+		},
+		{
+			desc:        "other key",
+			publicKey:   publicKey,
+			signature:   minisign(t, otherPriv, []byte{8, 7, 6, 5, 4, 3, 2, 1}, minisignAlgHashed, content, "timestamp:1760000000"),
+			expectedErr: "signed with key 0102030405060708, expected key 0807060504030201",
+		},
+		{
+			desc:        "forged signature",
+			publicKey:   publicKey,
+			signature:   minisign(t, otherPriv, keyID, minisignAlgHashed, content, "timestamp:1760000000"),
+			expectedErr: "invalid signature",
+		},
+		{
+			desc:        "modified content",
+			publicKey:   publicKey,
+			signature:   minisign(t, priv, keyID, minisignAlgHashed, content, "timestamp:1760000000"),
+			content:     []byte("modified content"),
+			expectedErr: "invalid signature",
+		},
+		{
+			desc:        "modified trusted comment",
+			publicKey:   publicKey,
+			signature:   withTrustedComment(t, minisign(t, priv, keyID, minisignAlgHashed, content, "timestamp:1760000000"), "timestamp:0"),
+			expectedErr: "invalid trusted comment signature",
+		},
+		{
+			desc:        "invalid signature file",
+			publicKey:   publicKey,
+			signature:   []byte("untrusted comment: not a signature"),
+			expectedErr: "invalid minisign signature file",
+		},
+		{
+			desc:        "cosign signature",
+			publicKey:   publicKey,
+			signature:   []byte("MEUCIQDx2N1g1Rz9Yv2kNqTZ0rRMr3tQ6y8FJ4Hk6mH1x8c9MwIgV3w5c1zXl0oJ8h9R4sYb2mC7kzqF1dUuWnP6yXjLk5A="),
+			expectedErr: "not a minisign signature: a public key policy only accepts minisign signatures, cosign key-based signatures are not supported",
+		},
+		{
+			desc:        "invalid public key",
+			publicKey:   "not a key",
+			signature:   minisign(t, priv, keyID, minisignAlgHashed, content, "timestamp:1760000000"),
+			expectedErr: "invalid minisign public key: illegal base64 data at input byte 3",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			signed := content
+			if test.content != nil {
+				signed = test.content
+			}
+
+			signer, err := NewVerifier(TrustRoots{}).Verify(signed, test.signature, db.SignaturePolicy{PublicKey: test.publicKey})
+			if test.expectedErr != "" {
+				require.EqualError(t, err, test.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expectedSigner, signer)
+		})
+	}
+}
+
+func TestVerifier_Verify_keyless(t *testing.T) {
+	const (
+		identity = "https://github.com/traefik/plugindemo/.github/workflows/release.yml@refs/tags/v0.2.1"
+		issuer   = "https://token.actions.githubusercontent.com"
+	)
+
+	content := []byte("archive content")
+
+	// The signing certificates are short-lived, they must be valid when the signature has been recorded, not now.
+	signedAt := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+
+	sigstore := newSigstore(t, "sigstore")
+	otherSigstore := newSigstore(t, "other")
+
+	testCases := []struct {
+		desc           string
+		roots          TrustRoots
+		policy         db.SignaturePolicy
+		bundle         func(b *cosignBundle)
+		content        []byte
+		expectedSigner string
+		expectedErr    string
+	}{
+		{
+			desc:           "valid bundle",
+			roots:          sigstore.roots,
+			policy:         db.SignaturePolicy{Identity: identity, Issuer: issuer},
+			expectedSigner: "sigstore:" + identity,
+		},
+		{
+			desc:        "without issuer",
+			roots:       sigstore.roots,
+			policy:      db.SignaturePolicy{Identity: identity},
+			expectedErr: "a keyless signature policy requires an issuer",
+		},
+		{
+			desc:        "not configured",
+			policy:      db.SignaturePolicy{Identity: identity, Issuer: issuer},
+			expectedErr: "keyless verification is not configured",
+		},
+		{
+			desc:        "other identity",
+			roots:       sigstore.roots,
+			policy:      db.SignaturePolicy{Identity: "https://github.com/traefik/other/.github/workflows/release.yml@refs/tags/v0.2.1", Issuer: issuer},
+			expectedErr: fmt.Sprintf("certificate identity %q doesn't match %q", identity, "https://github.com/traefik/other/.github/workflows/release.yml@refs/tags/v0.2.1"),
+		},
+		{
+			desc:        "other issuer",
+			roots:       sigstore.roots,
+			policy:      db.SignaturePolicy{Identity: identity, Issuer: "https://accounts.google.com"},
+			expectedErr: fmt.Sprintf("certificate issuer %q doesn't match %q", issuer, "https://accounts.google.com"),
+		},
+		{
+			desc:        "modified content",
+			roots:       sigstore.roots,
+			policy:      db.SignaturePolicy{Identity: identity, Issuer: issuer},
+			content:     []byte("modified content"),
+			expectedErr: "invalid signature",
+		},
+		{
+			desc:        "untrusted certificate authority",
+			roots:       TrustRoots{Fulcio: otherSigstore.roots.Fulcio, Rekor: sigstore.roots.Rekor},
+			policy:      db.SignaturePolicy{Identity: identity, Issuer: issuer},
+			expectedErr: "untrusted certificate: x509: certificate signed by unknown authority",
+		},
+		{
+			desc:        "unknown transparency log",
+			roots:       TrustRoots{Fulcio: sigstore.roots.Fulcio, Rekor: otherSigstore.roots.Rekor},
+			policy:      db.SignaturePolicy{Identity: identity, Issuer: issuer},
+			expectedErr: "unknown transparency log " + sigstore.logID,
+		},
+		{
+			desc:   "modified integrated time",
+			roots:  sigstore.roots,
+			policy: db.SignaturePolicy{Identity: identity, Issuer: issuer},
+			bundle: func(b *cosignBundle) {
+				b.RekorBundle.Payload.IntegratedTime = time.Now().Unix()
+			},
+			expectedErr: "invalid transparency log entry: invalid signature",
+		},
+		{
+			desc:   "missing transparency log entry",
+			roots:  sigstore.roots,
+			policy: db.SignaturePolicy{Identity: identity, Issuer: issuer},
+			bundle: func(b *cosignBundle) {
+				b.RekorBundle = nil
+			},
+			expectedErr: "missing transparency log entry",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			bundle := sigstore.sign(t, content, identity, issuer, signedAt)
+			if test.bundle != nil {
+				test.bundle(&bundle)
+			}
+
+			raw, err := json.Marshal(bundle)
+			require.NoError(t, err)
+
+			signed := content
+			if test.content != nil {
+				signed = test.content
+			}
+
+			signer, err := NewVerifier(test.roots).Verify(signed, raw, test.policy)
+			if test.expectedErr != "" {
+				require.EqualError(t, err, test.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expectedSigner, signer)
+		})
+	}
+}
+
+func TestParseTrustRoots(t *testing.T) {
+	sigstore := newSigstore(t, "sigstore")
+
+	roots, err := ParseTrustRoots(sigstore.fulcioPEM, sigstore.rekorPEM)
+	require.NoError(t, err)
+
+	assert.True(t, roots.Fulcio.Equal(sigstore.roots.Fulcio))
+	assert.Len(t, roots.Rekor, 1)
+
+	_, err = ParseTrustRoots(nil, sigstore.rekorPEM)
+	require.EqualError(t, err, "no Fulcio certificate found")
+
+	_, err = ParseTrustRoots(sigstore.fulcioPEM, nil)
+	require.EqualError(t, err, "no Rekor public key found")
+}
+
+func TestValidatePolicy(t *testing.T) {
+	require.NoError(t, ValidatePolicy(db.SignaturePolicy{PublicKey: "key"}))
+	require.NoError(t, ValidatePolicy(db.SignaturePolicy{Identity: "release@example.com", Issuer: "https://accounts.google.com"}))
+
+	err := ValidatePolicy(db.SignaturePolicy{Identity: "release@example.com"})
+	require.EqualError(t, err, "a keyless signature policy requires an issuer")
+
+	err = ValidatePolicy(db.SignaturePolicy{Issuer: "https://accounts.google.com"})
+	require.EqualError(t, err, "a keyless signature policy requires an identity")
+}
+
+func minisign(t *testing.T, key ed25519.PrivateKey, keyID []byte, algorithm string, content []byte, trustedComment string) []byte {
+	t.Helper()
+
+	message := content
+	if algorithm == minisignAlgHashed {
+		sum := blake2b.Sum512(content)
+		message = sum[:]
+	}
+
+	signature := ed25519.Sign(key, message)
+	globalSignature := ed25519.Sign(key, append(signature, trustedComment...))
+
+	raw := append(append([]byte(algorithm), keyID...), signature...)
+
+	return []byte(fmt.Sprintf("%s%s\n%s\n%s%s\n%s\n",
+		untrustedCommentPrefix, "signature from minisign secret key",
+		base64.StdEncoding.EncodeToString(raw),
+		trustedCommentPrefix, trustedComment,
+		base64.StdEncoding.EncodeToString(globalSignature),
+	))
+}
+
+func withTrustedComment(t *testing.T, signature []byte, trustedComment string) []byte {
+	t.Helper()
+
+	sig, err := parseMinisignSignature(signature)
+	require.NoError(t, err)
+
+	raw := append(append([]byte(sig.algorithm), sig.keyID...), sig.signature...)
+
+	return []byte(fmt.Sprintf("%s\n%s\n%s%s\n%s\n",
+		untrustedCommentPrefix,
+		base64.StdEncoding.EncodeToString(raw),
+		trustedCommentPrefix, trustedComment,
+		base64.StdEncoding.EncodeToString(sig.globalSignature),
+	))
+}
+
+// fakeSigstore is an in-memory certificate authority and transparency log.
+type fakeSigstore struct {
+	caKey     *ecdsa.PrivateKey
+	ca        *x509.Certificate
+	rekorKey  *ecdsa.PrivateKey
+	logID     string
+	roots     TrustRoots
+	fulcioPEM []byte
+	rekorPEM  []byte
+}
+
+func newSigstore(t *testing.T, name string) fakeSigstore {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-365 * 24 * time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, caKey.Public(), caKey)
+	require.NoError(t, err)
+
+	ca, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	rekorKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	rekorDER, err := x509.MarshalPKIXPublicKey(rekorKey.Public())
+	require.NoError(t, err)
+
+	logID := sha256.Sum256(rekorDER)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	return fakeSigstore{
+		caKey:     caKey,
+		ca:        ca,
+		rekorKey:  rekorKey,
+		logID:     hex.EncodeToString(logID[:]),
+		roots:     TrustRoots{Fulcio: pool, Rekor: []crypto.PublicKey{rekorKey.Public()}},
+		fulcioPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		rekorPEM:  pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rekorDER}),
+	}
+}
+
+// sign signs content with a short-lived certificate, and records the signature in the transparency log.
+func (s fakeSigstore) sign(t *testing.T, content []byte, identity, issuer string, signedAt time.Time) cosignBundle {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	identityURI, err := url.Parse(identity)
+	require.NoError(t, err)
+
+	issuerValue, err := asn1.MarshalWithParams(issuer, "utf8")
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		NotBefore:       signedAt.Add(-time.Minute),
+		NotAfter:        signedAt.Add(10 * time.Minute),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		URIs:            []*url.URL{identityURI},
+		ExtraExtensions: []pkix.Extension{{Id: oidIssuerV2, Value: issuerValue}},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, s.ca, key.Public(), s.caKey)
+	require.NoError(t, err)
+
+	digest := sha256.Sum256(content)
+
+	signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+
+	var entry hashedRekord
+	entry.Kind = "hashedrekord"
+	entry.Spec.Data.Hash.Algorithm = "sha256"
+	entry.Spec.Data.Hash.Value = hex.EncodeToString(digest[:])
+	entry.Spec.Signature.Content = base64.StdEncoding.EncodeToString(signature)
+
+	body, err := json.Marshal(entry)
+	require.NoError(t, err)
+
+	payload := rekorPayload{
+		Body:           base64.StdEncoding.EncodeToString(body),
+		IntegratedTime: signedAt.Unix(),
+		LogID:          s.logID,
+		LogIndex:       42,
+	}
+
+	rawPayload, err := json.Marshal(payload)
+	require.NoError(t, err)
+
+	payloadDigest := sha256.Sum256(rawPayload)
+
+	set, err := ecdsa.SignASN1(rand.Reader, s.rekorKey, payloadDigest[:])
+	require.NoError(t, err)
+
+	return cosignBundle{
+		Base64Signature: base64.StdEncoding.EncodeToString(signature),
+		Cert:            base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		RekorBundle:     &rekorBundle{SignedEntryTimestamp: set, Payload: payload},
+	}
+}
@@ -0,0 +1,175 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+const (
+	// ManifestFile is the name of the plugin manifest.
+	ManifestFile = ".traefik.yml"
+	// DefaultWasmPath is the path of the WASM file when the plugin doesn't define one.
+	DefaultWasmPath = "plugin.wasm"
+
+	maxUncompressedSize = 100 << 20 // 100MB
+	maxCompressionRatio = 100
+)
+
+var wasmMagic = []byte("\x00asm")
+
+// check is a step of the validation pipeline, it returns the reasons of the failures.
+type check func(reader *zip.Reader) []string
+
+// ValidateWasm validates a WASM plugin archive and returns the reasons why it is rejected.
+func ValidateWasm(content []byte, wasmPath string) []string {
+	return validate(content, checkEntries, checkSize, checkManifest, checkWasm(wasmPath))
+}
+
+func validate(content []byte, checks ...check) []string {
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return []string{fmt.Sprintf("invalid zip archive: %v", err)}
+	}
+
+	var reasons []string
+	for _, c := range checks {
+		reasons = append(reasons, c(reader)...)
+	}
+
+	return reasons
+}
+
+// checkEntries rejects path traversals, absolute paths, symlinks and duplicate entries.
+func checkEntries(reader *zip.Reader) []string {
+	var reasons []string
+
+	names := make(map[string]struct{})
+
+	for _, file := range reader.File {
+		name := strings.ReplaceAll(file.Name, `\`, "/")
+
+		if strings.Contains(name, "..") {
+			reasons = append(reasons, fmt.Sprintf("path traversal: %s", file.Name))
+		}
+
+		if path.IsAbs(name) || (len(name) > 1 && name[1] == ':') {
+			reasons = append(reasons, fmt.Sprintf("absolute path: %s", file.Name))
+		}
+
+		if file.Mode()&fs.ModeSymlink != 0 {
+			reasons = append(reasons, fmt.Sprintf("symlink: %s", file.Name))
+		}
+
+		clean := path.Clean(name)
+		if _, ok := names[clean]; ok {
+			reasons = append(reasons, fmt.Sprintf("duplicate entry: %s", file.Name))
+		}
+
+		names[clean] = struct{}{}
+	}
+
+	return reasons
+}
+
+// checkSize rejects zip bombs: the declared and the real sizes are both checked.
+func checkSize(reader *zip.Reader) []string {
+	var total uint64
+
+	for _, file := range reader.File {
+		if file.CompressedSize64 > 0 && file.UncompressedSize64/file.CompressedSize64 > maxCompressionRatio {
+			return []string{fmt.Sprintf("compression ratio too high: %s", file.Name)}
+		}
+
+		total += file.UncompressedSize64
+		if total > maxUncompressedSize {
+			return []string{fmt.Sprintf("uncompressed size exceeds %d bytes", maxUncompressedSize)}
+		}
+	}
+
+	var read int64
+
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		n, err := readSize(file, maxUncompressedSize-read)
+		if err != nil {
+			return []string{fmt.Sprintf("unreadable entry %s: %v", file.Name, err)}
+		}
+
+		if n > int64(file.UncompressedSize64) {
+			return []string{fmt.Sprintf("entry larger than declared: %s", file.Name)}
+		}
+
+		read += n
+		if read > maxUncompressedSize {
+			return []string{fmt.Sprintf("uncompressed size exceeds %d bytes", maxUncompressedSize)}
+		}
+	}
+
+	return nil
+}
+
+func readSize(file *zip.File, limit int64) (int64, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() { _ = rc.Close() }()
+
+	return io.Copy(io.Discard, io.LimitReader(rc, limit+1))
+}
+
+func checkManifest(reader *zip.Reader) []string {
+	if findFile(reader, ManifestFile) == nil {
+		return []string{fmt.Sprintf("missing manifest: %s", ManifestFile)}
+	}
+
+	return nil
+}
+
+func checkWasm(wasmPath string) check {
+	if wasmPath == "" {
+		wasmPath = DefaultWasmPath
+	}
+
+	return func(reader *zip.Reader) []string {
+		file := findFile(reader, wasmPath)
+		if file == nil {
+			return []string{fmt.Sprintf("missing WASM file: %s", wasmPath)}
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return []string{fmt.Sprintf("unreadable WASM file %s: %v", wasmPath, err)}
+		}
+
+		defer func() { _ = rc.Close() }()
+
+		header := make([]byte, len(wasmMagic))
+		if _, err = io.ReadFull(rc, header); err != nil || !bytes.Equal(header, wasmMagic) {
+			return []string{fmt.Sprintf("invalid WASM header: %s", wasmPath)}
+		}
+
+		return nil
+	}
+}
+
+func findFile(reader *zip.Reader, name string) *zip.File {
+	name = path.Clean(strings.TrimPrefix(name, "./"))
+
+	for _, file := range reader.File {
+		if path.Clean(file.Name) == name {
+			return file
+		}
+	}
+
+	return nil
+}
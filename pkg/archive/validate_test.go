@@ -0,0 +1,162 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateWasm(t *testing.T) {
+	wasm := "\x00asm\x01\x00\x00\x00"
+
+	testCases := []struct {
+		desc     string
+		entries  []entry
+		wasmPath string
+		expected []string
+	}{
+		{
+			desc: "valid archive",
+			entries: []entry{
+				{name: ".traefik.yml", content: "displayName: demo"},
+				{name: "plugin.wasm", content: wasm},
+			},
+		},
+		{
+			desc: "valid archive with custom WASM path",
+			entries: []entry{
+				{name: ".traefik.yml", content: "displayName: demo"},
+				{name: "build/demo.wasm", content: wasm},
+			},
+			wasmPath: "./build/demo.wasm",
+		},
+		{
+			desc: "path traversal",
+			entries: []entry{
+				{name: ".traefik.yml"},
+				{name: "plugin.wasm", content: wasm},
+				{name: "../evil.wasm", content: wasm},
+			},
+			expected: []string{"path traversal: ../evil.wasm"},
+		},
+		{
+			desc: "absolute paths",
+			entries: []entry{
+				{name: ".traefik.yml"},
+				{name: "plugin.wasm", content: wasm},
+				{name: "/etc/passwd"},
+				{name: `C:\Windows\evil.dll`},
+			},
+			expected: []string{"absolute path: /etc/passwd", `absolute path: C:\Windows\evil.dll`},
+		},
+		{
+			desc: "symlink",
+			entries: []entry{
+				{name: ".traefik.yml"},
+				{name: "plugin.wasm", content: wasm},
+				{name: "link", content: "/etc/passwd", mode: fs.ModeSymlink | 0o777},
+			},
+			expected: []string{"symlink: link"},
+		},
+		{
+			desc: "duplicate entries",
+			entries: []entry{
+				{name: ".traefik.yml"},
+				{name: "plugin.wasm", content: wasm},
+				{name: "./plugin.wasm", content: "\x00asm"},
+			},
+			expected: []string{"duplicate entry: ./plugin.wasm"},
+		},
+		{
+			desc: "compression ratio too high",
+			entries: []entry{
+				{name: ".traefik.yml"},
+				{name: "plugin.wasm", content: wasm + strings.Repeat("\x00", 1<<20)},
+			},
+			expected: []string{"compression ratio too high: plugin.wasm"},
+		},
+		{
+			desc: "missing manifest",
+			entries: []entry{
+				{name: "plugin.wasm", content: wasm},
+			},
+			expected: []string{"missing manifest: .traefik.yml"},
+		},
+		{
+			desc: "missing WASM file",
+			entries: []entry{
+				{name: ".traefik.yml"},
+				{name: "plugin.wasm", content: wasm},
+			},
+			wasmPath: "demo.wasm",
+			expected: []string{"missing WASM file: demo.wasm"},
+		},
+		{
+			desc: "invalid WASM header",
+			entries: []entry{
+				{name: ".traefik.yml"},
+				{name: "plugin.wasm", content: "MZ\x90\x00"},
+			},
+			expected: []string{"invalid WASM header: plugin.wasm"},
+		},
+		{
+			desc: "truncated WASM file",
+			entries: []entry{
+				{name: ".traefik.yml"},
+				{name: "plugin.wasm"},
+			},
+			expected: []string{"invalid WASM header: plugin.wasm"},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			reasons := ValidateWasm(buildZip(t, test.entries), test.wasmPath)
+
+			assert.Equal(t, test.expected, reasons)
+		})
+	}
+}
+
+func TestValidateWasm_invalidZip(t *testing.T) {
+	reasons := ValidateWasm([]byte("not a zip"), "")
+
+	assert.Equal(t, []string{"invalid zip archive: zip: not a valid zip file"}, reasons)
+}
+
+type entry struct {
+	name    string
+	content string
+	mode    fs.FileMode
+}
+
+func buildZip(t *testing.T, entries []entry) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	w := zip.NewWriter(buf)
+
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		if e.mode != 0 {
+			header.SetMode(e.mode)
+		}
+
+		f, err := w.CreateHeader(header)
+		require.NoError(t, err)
+
+		_, err = f.Write([]byte(e.content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
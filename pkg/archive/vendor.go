@@ -0,0 +1,214 @@
+package archive
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+const (
+	goModFile      = "go.mod"
+	vendorDir      = "vendor"
+	vendorManifest = "vendor/modules.txt"
+)
+
+// vendoredModule is a module listed in vendor/modules.txt.
+type vendoredModule struct {
+	version     string
+	replacement string
+	explicit    bool
+	packages    []string
+}
+
+// ValidateVendor checks that the vendor directory of a Yaegi plugin archive matches its go.mod,
+// and returns the reasons why it is rejected.
+// The rules are the ones applied by the go command when building with -mod=vendor.
+func ValidateVendor(content []byte) []string {
+	return validate(content, checkVendor)
+}
+
+func checkVendor(reader *zip.Reader) []string {
+	root := rootDir(reader)
+
+	files := make(map[string]*zip.File)
+	dirs := make(map[string]struct{})
+
+	for _, file := range reader.File {
+		name := strings.TrimPrefix(strings.TrimPrefix(path.Clean(file.Name), root), "/")
+		files[name] = file
+
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			dirs[dir] = struct{}{}
+		}
+	}
+
+	goMod, err := readModFile(files[goModFile])
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	if len(goMod.Require) == 0 {
+		return nil
+	}
+
+	vendored, err := readVendorManifest(files[vendorManifest])
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	var reasons []string
+
+	required := make(map[string]struct{})
+
+	for _, req := range goMod.Require {
+		required[req.Mod.Path] = struct{}{}
+
+		mod, ok := vendored[req.Mod.Path]
+		if !ok {
+			reasons = append(reasons, fmt.Sprintf("module not vendored: %s@%s", req.Mod.Path, req.Mod.Version))
+			continue
+		}
+
+		if mod.version != req.Mod.Version {
+			reasons = append(reasons, fmt.Sprintf("vendored version mismatch: %s@%s, go.mod requires %s", req.Mod.Path, mod.version, req.Mod.Version))
+		}
+
+		if !mod.explicit {
+			reasons = append(reasons, fmt.Sprintf("module not marked as explicit in %s: %s", vendorManifest, req.Mod.Path))
+		}
+
+		if mod.replacement != replacement(goMod, req.Mod.Path, req.Mod.Version) {
+			reasons = append(reasons, fmt.Sprintf("vendored replacement mismatch: %s", req.Mod.Path))
+		}
+	}
+
+	for _, modPath := range slices.Sorted(maps.Keys(vendored)) {
+		mod := vendored[modPath]
+
+		if _, ok := required[modPath]; mod.explicit && !ok {
+			reasons = append(reasons, fmt.Sprintf("module marked as explicit in %s but not required: %s", vendorManifest, modPath))
+		}
+
+		for _, pkg := range mod.packages {
+			if _, ok := dirs[path.Join(vendorDir, pkg)]; !ok {
+				reasons = append(reasons, fmt.Sprintf("missing vendored package: %s", pkg))
+			}
+		}
+	}
+
+	return reasons
+}
+
+func readModFile(file *zip.File) (*modfile.File, error) {
+	if file == nil {
+		return nil, fmt.Errorf("missing %s", goModFile)
+	}
+
+	raw, err := readFile(file, maxManifestSize)
+	if err != nil {
+		return nil, fmt.Errorf("unreadable %s: %w", goModFile, err)
+	}
+
+	goMod, err := modfile.Parse(goModFile, raw, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", goModFile, err)
+	}
+
+	return goMod, nil
+}
+
+// readVendorManifest parses vendor/modules.txt.
+func readVendorManifest(file *zip.File) (map[string]*vendoredModule, error) {
+	if file == nil {
+		return nil, fmt.Errorf("missing %s", vendorManifest)
+	}
+
+	raw, err := readFile(file, maxManifestSize)
+	if err != nil {
+		return nil, fmt.Errorf("unreadable %s: %w", vendorManifest, err)
+	}
+
+	modules := make(map[string]*vendoredModule)
+
+	var current *vendoredModule
+
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, "## "):
+			if current == nil {
+				continue
+			}
+
+			for _, annotation := range strings.Split(strings.TrimPrefix(line, "## "), ";") {
+				if strings.TrimSpace(annotation) == "explicit" {
+					current.explicit = true
+				}
+			}
+
+		case strings.HasPrefix(line, "# "):
+			current = nil
+
+			// # path version [=> replacement [version]] or # path => replacement [version] for wildcard replacements.
+			fields := strings.Fields(strings.TrimPrefix(line, "# "))
+			if len(fields) < 2 || (fields[1] == "=>" && len(fields) < 3) {
+				return nil, fmt.Errorf("invalid %s line: %q", vendorManifest, line)
+			}
+
+			mod := &vendoredModule{}
+			rest := fields[1:]
+
+			if fields[1] != "=>" {
+				mod.version = fields[1]
+				rest = fields[2:]
+			}
+
+			if len(rest) > 1 && rest[0] == "=>" {
+				mod.replacement = strings.Join(rest[1:], " ")
+			}
+
+			// Wildcard replacements are not modules.
+			if mod.version == "" {
+				continue
+			}
+
+			modules[fields[0]] = mod
+			current = mod
+
+		case line != "" && current != nil:
+			current.packages = append(current.packages, line)
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unreadable %s: %w", vendorManifest, err)
+	}
+
+	return modules, nil
+}
+
+// replacement returns the replacement of a module as written in vendor/modules.txt.
+func replacement(goMod *modfile.File, modPath, version string) string {
+	for _, r := range goMod.Replace {
+		if r.Old.Path != modPath || (r.Old.Version != "" && r.Old.Version != version) {
+			continue
+		}
+
+		if r.New.Version == "" {
+			return r.New.Path
+		}
+
+		return r.New.Path + " " + r.New.Version
+	}
+
+	return ""
+}
@@ -0,0 +1,119 @@
+package archive
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateVendor(t *testing.T) {
+	goMod := "module github.com/traefik/plugindemo\n\ngo 1.22\n\nrequire github.com/foo/bar v1.0.0\n"
+	modulesTxt := "# github.com/foo/bar v1.0.0\n## explicit\ngithub.com/foo/bar\n"
+
+	testCases := []struct {
+		desc     string
+		entries  []entry
+		expected []string
+	}{
+		{
+			desc: "consistent vendor directory",
+			entries: []entry{
+				{name: "demo-123/"},
+				{name: "demo-123/go.mod", content: goMod},
+				{name: "demo-123/vendor/modules.txt", content: modulesTxt},
+				{name: "demo-123/vendor/github.com/foo/bar/bar.go", content: "package bar"},
+			},
+		},
+		{
+			desc: "no dependencies",
+			entries: []entry{
+				{name: "go.mod", content: "module github.com/traefik/plugindemo\n"},
+			},
+		},
+		{
+			desc: "missing go.mod",
+			entries: []entry{
+				{name: "demo.go", content: "package demo"},
+			},
+			expected: []string{"missing go.mod"},
+		},
+		{
+			desc: "missing modules.txt",
+			entries: []entry{
+				{name: "go.mod", content: goMod},
+			},
+			expected: []string{"missing vendor/modules.txt"},
+		},
+		{
+			desc: "module not vendored",
+			entries: []entry{
+				{name: "go.mod", content: goMod},
+				{name: "vendor/modules.txt", content: ""},
+			},
+			expected: []string{"module not vendored: github.com/foo/bar@v1.0.0"},
+		},
+		{
+			desc: "version mismatch",
+			entries: []entry{
+				{name: "go.mod", content: goMod},
+				{name: "vendor/modules.txt", content: "# github.com/foo/bar v0.9.0\n## explicit\ngithub.com/foo/bar\n"},
+				{name: "vendor/github.com/foo/bar/bar.go", content: "package bar"},
+			},
+			expected: []string{"vendored version mismatch: github.com/foo/bar@v0.9.0, go.mod requires v1.0.0"},
+		},
+		{
+			desc: "module not explicit",
+			entries: []entry{
+				{name: "go.mod", content: goMod},
+				{name: "vendor/modules.txt", content: "# github.com/foo/bar v1.0.0\ngithub.com/foo/bar\n"},
+				{name: "vendor/github.com/foo/bar/bar.go", content: "package bar"},
+			},
+			expected: []string{"module not marked as explicit in vendor/modules.txt: github.com/foo/bar"},
+		},
+		{
+			desc: "explicit module not required",
+			entries: []entry{
+				{name: "go.mod", content: goMod},
+				{name: "vendor/modules.txt", content: modulesTxt + "# github.com/baz/qux v1.2.0\n## explicit; go 1.21\n"},
+				{name: "vendor/github.com/foo/bar/bar.go", content: "package bar"},
+			},
+			expected: []string{"module marked as explicit in vendor/modules.txt but not required: github.com/baz/qux"},
+		},
+		{
+			desc: "replacement mismatch",
+			entries: []entry{
+				{name: "go.mod", content: goMod + "\nreplace github.com/foo/bar => github.com/fork/bar v1.0.1\n"},
+				{name: "vendor/modules.txt", content: modulesTxt},
+				{name: "vendor/github.com/foo/bar/bar.go", content: "package bar"},
+			},
+			expected: []string{"vendored replacement mismatch: github.com/foo/bar"},
+		},
+		{
+			desc: "consistent replacement",
+			entries: []entry{
+				{name: "go.mod", content: goMod + "\nreplace github.com/foo/bar => github.com/fork/bar v1.0.1\n"},
+				{name: "vendor/modules.txt", content: "# github.com/foo/bar v1.0.0 => github.com/fork/bar v1.0.1\n## explicit\ngithub.com/foo/bar\n"},
+				{name: "vendor/github.com/foo/bar/bar.go", content: "package bar"},
+			},
+		},
+		{
+			desc: "missing vendored package",
+			entries: []entry{
+				{name: "go.mod", content: goMod},
+				{name: "vendor/modules.txt", content: modulesTxt + "github.com/foo/bar/baz\n"},
+				{name: "vendor/github.com/foo/bar/bar.go", content: "package bar"},
+			},
+			expected: []string{"missing vendored package: github.com/foo/bar/baz"},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			reasons := ValidateVendor(buildZip(t, test.entries))
+
+			assert.Equal(t, test.expected, reasons)
+		})
+	}
+}
@@ -0,0 +1,566 @@
+// Package crawler discovers the plugins published on GitHub, and records them in the catalog.
+//
+// The repositories are searched by topic, their manifest (.traefik.yml) is read at the latest valid tag,
+// and the plugin documents are created or updated from the manifest, the tags and the repository metadata.
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v74/github"
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/plugin-service/pkg/archive"
+	"github.com/traefik/plugin-service/pkg/db"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/mod/semver"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultTopic the GitHub topic of the plugin repositories.
+	DefaultTopic = "traefik-plugin"
+
+	perPage             = 100
+	maxRateLimitRetries = 3
+	rawContentURL       = "https://raw.githubusercontent.com"
+)
+
+// Store is capable of storing plugins.
+type Store interface {
+	GetByName(ctx context.Context, name string, filterDisabled, filterHidden bool) (db.Plugin, error)
+	Create(ctx context.Context, plugin db.Plugin) (db.Plugin, error)
+	Update(ctx context.Context, id string, plugin db.Plugin) (db.Plugin, error)
+}
+
+// Emitter is capable of emitting the catalog change events, e.g. to the event stream and to the webhook subscribers.
+type Emitter interface {
+	EmitCreated(ctx context.Context, plugin db.Plugin)
+	EmitChanges(ctx context.Context, previous, plugin db.Plugin)
+}
+
+// Report the result of a crawl.
+type Report struct {
+	Repositories int `json:"repositories"`
+	Created      int `json:"created"`
+	Updated      int `json:"updated"`
+	Unchanged    int `json:"unchanged"`
+	// Failed the number of repositories which couldn't be read.
+	Failed   int         `json:"failed"`
+	Rejected []Rejection `json:"rejected,omitempty"`
+}
+
+// Rejection a repository which is not a valid plugin.
+type Rejection struct {
+	Repository string   `json:"repository"`
+	Reasons    []string `json:"reasons"`
+}
+
+// Crawler discovers the plugin repositories on GitHub.
+type Crawler struct {
+	gh      *github.Client
+	store   Store
+	emitter Emitter
+	topic   string
+	tracer  trace.Tracer
+}
+
+// New creates a Crawler searching the repositories with the given topic.
+// The changes of the catalog are emitted by the emitter, if any.
+func New(gh *github.Client, store Store, emitter Emitter, topic string) *Crawler {
+	if topic == "" {
+		topic = DefaultTopic
+	}
+
+	return &Crawler{
+		gh:      gh,
+		store:   store,
+		emitter: emitter,
+		topic:   topic,
+		tracer:  otel.Tracer("crawler"),
+	}
+}
+
+// Crawl searches the plugin repositories, and creates or updates their plugins.
+// The hidden and disabled states of the known plugins are preserved.
+func (c *Crawler) Crawl(ctx context.Context) (Report, error) {
+	ctx, span := c.tracer.Start(ctx, "crawler_crawl")
+	defer span.End()
+
+	repositories, err := c.search(ctx)
+	if err != nil {
+		span.RecordError(err)
+		return Report{}, fmt.Errorf("search repositories: %w", err)
+	}
+
+	report := Report{Repositories: len(repositories)}
+
+	for _, repository := range repositories {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+
+		logger := log.With().Str("repository", repository.GetFullName()).Logger()
+
+		plugin, reasons, err := c.readPlugin(ctx, repository)
+		if err != nil {
+			report.Failed++
+			logger.Error().Err(err).Msg("Unable to read plugin repository")
+
+			continue
+		}
+
+		if len(reasons) > 0 {
+			report.Rejected = append(report.Rejected, Rejection{Repository: repository.GetFullName(), Reasons: reasons})
+			logger.Warn().Strs("reasons", reasons).Msg("Plugin repository rejected")
+
+			continue
+		}
+
+		result, err := c.save(ctx, plugin)
+		if err != nil {
+			report.Failed++
+			logger.Error().Err(err).Msg("Unable to save plugin")
+
+			continue
+		}
+
+		switch result {
+		case resultCreated:
+			report.Created++
+		case resultUpdated:
+			report.Updated++
+		default:
+			report.Unchanged++
+		}
+	}
+
+	return report, nil
+}
+
+type saveResult int
+
+const (
+	resultUnchanged saveResult = iota
+	resultCreated
+	resultUpdated
+)
+
+// save creates the plugin, or updates the known plugin while preserving the fields managed by the service.
+func (c *Crawler) save(ctx context.Context, plugin db.Plugin) (saveResult, error) {
+	existing, err := c.store.GetByName(ctx, plugin.Name, false, false)
+	if err != nil {
+		if !errors.As(err, &db.NotFoundError{}) {
+			return resultUnchanged, fmt.Errorf("get plugin: %w", err)
+		}
+
+		created, err := c.store.Create(ctx, plugin)
+		if err != nil {
+			return resultUnchanged, fmt.Errorf("create plugin: %w", err)
+		}
+
+		if c.emitter != nil {
+			c.emitter.EmitCreated(ctx, created)
+		}
+
+		return resultCreated, nil
+	}
+
+	plugin.ID = existing.ID
+	plugin.CreatedAt = existing.CreatedAt
+	plugin.Disabled = existing.Disabled
+	plugin.Hidden = existing.Hidden
+	plugin.UseUnsafe = existing.UseUnsafe
+	plugin.Signature = existing.Signature
+	plugin.Aliases = existing.Aliases
+	plugin.RepositoryETag = existing.RepositoryETag
+
+	if reflect.DeepEqual(existing, plugin) {
+		return resultUnchanged, nil
+	}
+
+	updated, err := c.store.Update(ctx, existing.ID, plugin)
+	if err != nil {
+		return resultUnchanged, fmt.Errorf("update plugin: %w", err)
+	}
+
+	if c.emitter != nil {
+		c.emitter.EmitChanges(ctx, existing, updated)
+	}
+
+	return resultUpdated, nil
+}
+
+// search returns the repositories with the plugin topic, forks excluded.
+func (c *Crawler) search(ctx context.Context) ([]*github.Repository, error) {
+	query := fmt.Sprintf("topic:%s fork:false", c.topic)
+	opts := &github.SearchOptions{Sort: "stars", ListOptions: github.ListOptions{PerPage: perPage}}
+
+	var repositories []*github.Repository
+
+	for {
+		var (
+			result *github.RepositoriesSearchResult
+			resp   *github.Response
+		)
+
+		err := withRateLimit(ctx, func() (err error) {
+			result, resp, err = c.gh.Search.Repositories(ctx, query, opts)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		repositories = append(repositories, result.Repositories...)
+
+		if resp.NextPage == 0 {
+			return repositories, nil
+		}
+
+		opts.Page = resp.NextPage
+	}
+}
+
+// readPlugin builds the plugin of a repository, and returns the reasons why the repository is not a valid plugin.
+func (c *Crawler) readPlugin(ctx context.Context, repository *github.Repository) (db.Plugin, []string, error) {
+	ctx, span := c.tracer.Start(ctx, "crawler_readPlugin")
+	defer span.End()
+
+	owner, repoName := repository.GetOwner().GetLogin(), repository.GetName()
+	moduleName := "github.com/" + repository.GetFullName()
+
+	tags, err := c.listTags(ctx, owner, repoName)
+	if err != nil {
+		span.RecordError(err)
+		return db.Plugin{}, nil, fmt.Errorf("list tags: %w", err)
+	}
+
+	versions := ValidVersions(tags)
+	if len(versions) == 0 {
+		return db.Plugin{}, []string{"no valid semver tag"}, nil
+	}
+
+	latest := LatestVersion(versions)
+
+	manifest, reasons, err := c.readManifest(ctx, owner, repoName, moduleName, latest)
+	if err != nil || len(reasons) > 0 {
+		return db.Plugin{}, reasons, err
+	}
+
+	// The major versions above v1 require a module path suffix, which the catalog doesn't support for the Go proxy sources.
+	if runtimeOrDefault(manifest.Runtime) != "wasm" {
+		versions = slices.DeleteFunc(versions, func(version string) bool {
+			return semver.Major(version) != "v0" && semver.Major(version) != "v1"
+		})
+
+		if len(versions) == 0 {
+			return db.Plugin{}, []string{"no v0 or v1 semver tag"}, nil
+		}
+
+		if LatestVersion(versions) != latest {
+			latest = LatestVersion(versions)
+
+			manifest, reasons, err = c.readManifest(ctx, owner, repoName, moduleName, latest)
+			if err != nil || len(reasons) > 0 {
+				return db.Plugin{}, reasons, err
+			}
+		}
+	}
+
+	readme, err := c.getReadme(ctx, owner, repoName, latest)
+	if err != nil {
+		span.RecordError(err)
+		return db.Plugin{}, nil, fmt.Errorf("get readme: %w", err)
+	}
+
+	snippet, err := buildSnippet(repoName, manifest.Type, manifest.TestData)
+	if err != nil {
+		return db.Plugin{}, []string{fmt.Sprintf("invalid testData: %v", err)}, nil
+	}
+
+	return db.Plugin{
+		Name:          moduleName,
+		DisplayName:   manifest.DisplayName,
+		Runtime:       manifest.Runtime,
+		WasmPath:      manifest.WasmPath,
+		Author:        owner,
+		Type:          manifest.Type,
+		Import:        manifest.Import,
+		Compatibility: manifest.Compatibility,
+		Summary:       manifest.Summary,
+		IconURL:       contentURL(repository.GetFullName(), latest, manifest.IconPath),
+		BannerURL:     contentURL(repository.GetFullName(), latest, manifest.BannerPath),
+		Readme:        readme,
+		LatestVersion: latest,
+		Versions:      versions,
+		Stars:         repository.GetStargazersCount(),
+		Archived:      repository.GetArchived(),
+		Snippet:       snippet,
+		Topics:        repository.Topics,
+		License:       repository.GetLicense().GetSPDXID(),
+	}, nil, nil
+}
+
+// ReadManifest reads the manifest of a module at the given ref, and returns the reasons why it can't be published.
+func (c *Crawler) ReadManifest(ctx context.Context, moduleName, ref string) (db.Manifest, []string, error) {
+	owner, repoName, ok := repositoryOf(moduleName)
+	if !ok {
+		return db.Manifest{}, nil, fmt.Errorf("not a GitHub module: %s", moduleName)
+	}
+
+	return c.readManifest(ctx, owner, repoName, moduleName, ref)
+}
+
+// readManifest reads the manifest at the given ref, and returns the reasons why it can't be published.
+func (c *Crawler) readManifest(ctx context.Context, owner, repoName, moduleName, ref string) (db.Manifest, []string, error) {
+	raw, found, err := c.getFile(ctx, owner, repoName, archive.ManifestFile, ref)
+	if err != nil {
+		return db.Manifest{}, nil, fmt.Errorf("get manifest: %w", err)
+	}
+
+	if !found {
+		return db.Manifest{}, []string{fmt.Sprintf("missing manifest %s at %s", archive.ManifestFile, ref)}, nil
+	}
+
+	manifest, err := archive.ParseManifest(raw)
+	if err != nil {
+		return db.Manifest{}, []string{err.Error()}, nil
+	}
+
+	return manifest, ValidateManifest(moduleName, manifest), nil
+}
+
+func (c *Crawler) listTags(ctx context.Context, owner, repoName string) ([]string, error) {
+	opts := &github.ListOptions{PerPage: perPage}
+
+	var tags []string
+
+	for {
+		var (
+			page []*github.RepositoryTag
+			resp *github.Response
+		)
+
+		err := withRateLimit(ctx, func() (err error) {
+			page, resp, err = c.gh.Repositories.ListTags(ctx, owner, repoName, opts)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tag := range page {
+			tags = append(tags, tag.GetName())
+		}
+
+		if resp.NextPage == 0 {
+			return tags, nil
+		}
+
+		opts.Page = resp.NextPage
+	}
+}
+
+// getFile returns the content of a file at the given ref, and false if the file doesn't exist.
+func (c *Crawler) getFile(ctx context.Context, owner, repoName, filePath, ref string) ([]byte, bool, error) {
+	var file *github.RepositoryContent
+
+	err := withRateLimit(ctx, func() (err error) {
+		file, _, _, err = c.gh.Repositories.GetContents(ctx, owner, repoName, filePath, &github.RepositoryContentGetOptions{Ref: ref})
+		return err
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, false, nil
+		}
+
+		return nil, false, err
+	}
+
+	if file == nil {
+		return nil, false, nil
+	}
+
+	content, err := file.GetContent()
+	if err != nil {
+		return nil, false, err
+	}
+
+	return []byte(content), true, nil
+}
+
+// getReadme returns the readme at the given ref, or an empty string if the repository doesn't have one.
+func (c *Crawler) getReadme(ctx context.Context, owner, repoName, ref string) (string, error) {
+	var readme *github.RepositoryContent
+
+	err := withRateLimit(ctx, func() (err error) {
+		readme, _, err = c.gh.Repositories.GetReadme(ctx, owner, repoName, &github.RepositoryContentGetOptions{Ref: ref})
+		return err
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	return readme.GetContent()
+}
+
+// withRateLimit calls fn, waiting for the reset of the GitHub rate limits when they are hit.
+func withRateLimit(ctx context.Context, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		reset, limited := archive.RateLimitReset(err, time.Now())
+		if !limited || attempt >= maxRateLimitRetries {
+			return err
+		}
+
+		log.Warn().Err(err).Time("reset", reset).Msg("GitHub rate limit hit, pausing until its reset")
+
+		timer := time.NewTimer(time.Until(reset))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// ValidVersions returns the tags which are canonical semantic versions, the most recent first.
+func ValidVersions(tags []string) []string {
+	var versions []string
+
+	for _, tag := range tags {
+		if semver.IsValid(tag) && semver.Canonical(tag) == tag && !slices.Contains(versions, tag) {
+			versions = append(versions, tag)
+		}
+	}
+
+	slices.SortFunc(versions, func(a, b string) int {
+		return semver.Compare(b, a)
+	})
+
+	return versions
+}
+
+// LatestVersion returns the most recent release of the versions sorted by ValidVersions,
+// or the most recent pre-release if there is no release.
+func LatestVersion(versions []string) string {
+	for _, version := range versions {
+		if semver.Prerelease(version) == "" {
+			return version
+		}
+	}
+
+	if len(versions) == 0 {
+		return ""
+	}
+
+	return versions[0]
+}
+
+// ValidateManifest returns the reasons why a manifest can't be published in the catalog.
+func ValidateManifest(moduleName string, manifest db.Manifest) []string {
+	var reasons []string
+
+	if manifest.DisplayName == "" {
+		reasons = append(reasons, "missing displayName")
+	}
+
+	if manifest.Summary == "" {
+		reasons = append(reasons, "missing summary")
+	}
+
+	if manifest.Type != "middleware" && manifest.Type != "provider" {
+		reasons = append(reasons, fmt.Sprintf("unsupported type: %q", manifest.Type))
+	}
+
+	switch runtimeOrDefault(manifest.Runtime) {
+	case "yaegi":
+		if manifest.Import != moduleName && !strings.HasPrefix(manifest.Import, moduleName+"/") {
+			reasons = append(reasons, fmt.Sprintf("import %q differs from the module path %q", manifest.Import, moduleName))
+		}
+	case "wasm":
+	default:
+		reasons = append(reasons, fmt.Sprintf("unsupported runtime: %q", manifest.Runtime))
+	}
+
+	if len(manifest.TestData) == 0 {
+		reasons = append(reasons, "missing testData")
+	}
+
+	return reasons
+}
+
+// buildSnippet builds the configuration of the plugin from the manifest test data:
+// the static configuration of a provider, the dynamic configuration of a middleware.
+func buildSnippet(pluginKey, pluginType string, testData map[string]interface{}) (map[string]interface{}, error) {
+	config := map[string]interface{}{
+		"http": map[string]interface{}{
+			"middlewares": map[string]interface{}{
+				"my-" + pluginKey: map[string]interface{}{
+					"plugin": map[string]interface{}{
+						pluginKey: testData,
+					},
+				},
+			},
+		},
+	}
+
+	if pluginType == "provider" {
+		config = map[string]interface{}{
+			"providers": map[string]interface{}{
+				"plugin": map[string]interface{}{
+					pluginKey: testData,
+				},
+			},
+		}
+	}
+
+	raw, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{"yaml": string(raw)}, nil
+}
+
+// contentURL returns the URL of a repository file at the given ref, or the file path if it is already a URL.
+func contentURL(fullName, ref, filePath string) string {
+	if filePath == "" || strings.HasPrefix(filePath, "http://") || strings.HasPrefix(filePath, "https://") {
+		return filePath
+	}
+
+	return rawContentURL + "/" + path.Join(fullName, ref, strings.TrimPrefix(filePath, "./"))
+}
+
+func runtimeOrDefault(runtime string) string {
+	if runtime == "" {
+		return "yaegi"
+	}
+
+	return strings.ToLower(runtime)
+}
+
+func isNotFound(err error) bool {
+	var errResp *github.ErrorResponse
+
+	return errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,437 @@
+package crawler
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v74/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+const demoManifest = `displayName: Demo Plugin
+type: middleware
+import: github.com/traefik/plugindemo
+summary: '[Demo] Add Request Header'
+iconPath: .assets/icon.png
+testData:
+  Headers:
+    X-Demo: test
+`
+
+// memoryStore is a Store keeping the plugins in memory.
+type memoryStore struct {
+	plugins map[string]db.Plugin
+	updates int
+}
+
+func (s *memoryStore) GetByName(_ context.Context, name string, _, _ bool) (db.Plugin, error) {
+	plugin, ok := s.plugins[name]
+	if !ok {
+		return db.Plugin{}, db.NotFoundError{Err: errors.New("not found")}
+	}
+
+	return plugin, nil
+}
+
+func (s *memoryStore) Create(_ context.Context, plugin db.Plugin) (db.Plugin, error) {
+	plugin.ID = fmt.Sprint(len(s.plugins) + 1)
+	plugin.CreatedAt = time.Now()
+
+	s.plugins[plugin.Name] = plugin
+
+	return plugin, nil
+}
+
+// recordingEmitter is an Emitter recording the emitted changes.
+type recordingEmitter struct {
+	created []db.Plugin
+	changes [][2]db.Plugin
+}
+
+func (e *recordingEmitter) EmitCreated(_ context.Context, plugin db.Plugin) {
+	e.created = append(e.created, plugin)
+}
+
+func (e *recordingEmitter) EmitChanges(_ context.Context, previous, plugin db.Plugin) {
+	e.changes = append(e.changes, [2]db.Plugin{previous, plugin})
+}
+
+func (s *memoryStore) Update(_ context.Context, id string, plugin db.Plugin) (db.Plugin, error) {
+	for name, existing := range s.plugins {
+		if existing.ID == id {
+			s.updates++
+			s.plugins[name] = plugin
+
+			return plugin, nil
+		}
+	}
+
+	return db.Plugin{}, db.NotFoundError{Err: errors.New("not found")}
+}
+
+// repository is a repository served by the fake GitHub API.
+type repository struct {
+	owner, name string
+	stars       int
+	tags        []string
+	// files the files of the repository, by ref and path.
+	files map[string]map[string]string
+}
+
+func setupGitHub(t *testing.T, repositories ...repository) *github.Client {
+	t.Helper()
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /search/repositories", func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "topic:traefik-plugin fork:false", req.URL.Query().Get("q"))
+
+		result := github.RepositoriesSearchResult{Total: github.Ptr(len(repositories))}
+
+		for _, repo := range repositories {
+			result.Repositories = append(result.Repositories, &github.Repository{
+				Name:            github.Ptr(repo.name),
+				FullName:        github.Ptr(repo.owner + "/" + repo.name),
+				Owner:           &github.User{Login: github.Ptr(repo.owner)},
+				StargazersCount: github.Ptr(repo.stars),
+			})
+		}
+
+		writeJSON(t, rw, result)
+	})
+
+	for _, repo := range repositories {
+		prefix := "/repos/" + repo.owner + "/" + repo.name
+
+		mux.HandleFunc("GET "+prefix+"/tags", func(rw http.ResponseWriter, _ *http.Request) {
+			var tags []*github.RepositoryTag
+			for _, tag := range repo.tags {
+				tags = append(tags, &github.RepositoryTag{Name: github.Ptr(tag)})
+			}
+
+			writeJSON(t, rw, tags)
+		})
+
+		mux.HandleFunc("GET "+prefix+"/contents/{path...}", func(rw http.ResponseWriter, req *http.Request) {
+			serveFile(t, rw, repo.files[req.URL.Query().Get("ref")], req.PathValue("path"))
+		})
+
+		mux.HandleFunc("GET "+prefix+"/readme", func(rw http.ResponseWriter, req *http.Request) {
+			serveFile(t, rw, repo.files[req.URL.Query().Get("ref")], "README.md")
+		})
+	}
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	return client
+}
+
+func serveFile(t *testing.T, rw http.ResponseWriter, files map[string]string, filePath string) {
+	t.Helper()
+
+	content, ok := files[filePath]
+	if !ok {
+		rw.WriteHeader(http.StatusNotFound)
+		writeJSON(t, rw, map[string]string{"message": "Not Found"})
+
+		return
+	}
+
+	writeJSON(t, rw, github.RepositoryContent{
+		Type:     github.Ptr("file"),
+		Path:     github.Ptr(filePath),
+		Encoding: github.Ptr("base64"),
+		Content:  github.Ptr(base64.StdEncoding.EncodeToString([]byte(content))),
+	})
+}
+
+func writeJSON(t *testing.T, rw http.ResponseWriter, value any) {
+	t.Helper()
+
+	rw.Header().Set("Content-Type", "application/json")
+
+	require.NoError(t, json.NewEncoder(rw).Encode(value))
+}
+
+func TestCrawler_Crawl(t *testing.T) {
+	gh := setupGitHub(t,
+		repository{
+			owner: "traefik",
+			name:  "plugindemo",
+			stars: 22,
+			tags:  []string{"v0.1.0", "v0.2.0", "v0.2.1", "v0.3.0-rc1", "v1.0", "latest", "v2.0.0"},
+			files: map[string]map[string]string{
+				"v0.2.1": {".traefik.yml": demoManifest, "README.md": "README"},
+				"v2.0.0": {".traefik.yml": demoManifest},
+			},
+		},
+		repository{
+			owner: "traefik",
+			name:  "notags",
+			tags:  []string{"main"},
+		},
+		repository{
+			owner: "traefik",
+			name:  "nomanifest",
+			tags:  []string{"v1.0.0"},
+			files: map[string]map[string]string{"v1.0.0": {"README.md": "README"}},
+		},
+		repository{
+			owner: "traefik",
+			name:  "invalid",
+			tags:  []string{"v1.0.0"},
+			files: map[string]map[string]string{
+				"v1.0.0": {".traefik.yml": "displayName: Invalid\ntype: middleware\nimport: github.com/other/invalid\n"},
+			},
+		},
+	)
+
+	store := &memoryStore{plugins: map[string]db.Plugin{}}
+	emitter := &recordingEmitter{}
+
+	crawler := New(gh, store, emitter, "")
+
+	report, err := crawler.Crawl(context.Background())
+	require.NoError(t, err)
+
+	expected := Report{
+		Repositories: 4,
+		Created:      1,
+		Rejected: []Rejection{
+			{Repository: "traefik/notags", Reasons: []string{"no valid semver tag"}},
+			{Repository: "traefik/nomanifest", Reasons: []string{"missing manifest .traefik.yml at v1.0.0"}},
+			{Repository: "traefik/invalid", Reasons: []string{
+				"missing summary",
+				`import "github.com/other/invalid" differs from the module path "github.com/traefik/invalid"`,
+				"missing testData",
+			}},
+		},
+	}
+	assert.Equal(t, expected, report)
+
+	plugin := store.plugins["github.com/traefik/plugindemo"]
+
+	assert.Equal(t, "Demo Plugin", plugin.DisplayName)
+	assert.Equal(t, "traefik", plugin.Author)
+	assert.Equal(t, "middleware", plugin.Type)
+	assert.Equal(t, "github.com/traefik/plugindemo", plugin.Import)
+	assert.Equal(t, "[Demo] Add Request Header", plugin.Summary)
+	assert.Equal(t, "https://raw.githubusercontent.com/traefik/plugindemo/v0.2.1/.assets/icon.png", plugin.IconURL)
+	assert.Equal(t, "README", plugin.Readme)
+	assert.Equal(t, "v0.2.1", plugin.LatestVersion)
+	assert.Equal(t, []string{"v0.3.0-rc1", "v0.2.1", "v0.2.0", "v0.1.0"}, plugin.Versions)
+	assert.Equal(t, 22, plugin.Stars)
+
+	snippet := "http:\n    middlewares:\n        my-plugindemo:\n            plugin:\n                plugindemo:\n                    Headers:\n                        X-Demo: test\n"
+	assert.Equal(t, map[string]interface{}{"yaml": snippet}, plugin.Snippet)
+
+	assert.Equal(t, []db.Plugin{plugin}, emitter.created)
+
+	// A second crawl doesn't update the unchanged plugin.
+	report, err = crawler.Crawl(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, report.Unchanged)
+	assert.Equal(t, 0, store.updates)
+	assert.Len(t, emitter.created, 1)
+	assert.Empty(t, emitter.changes)
+}
+
+func TestCrawler_Crawl_update(t *testing.T) {
+	gh := setupGitHub(t, repository{
+		owner: "traefik",
+		name:  "plugindemo",
+		stars: 30,
+		tags:  []string{"v0.1.0", "v0.2.0"},
+		files: map[string]map[string]string{
+			"v0.2.0": {".traefik.yml": demoManifest},
+		},
+	})
+
+	createdAt := time.Date(2020, time.January, 1, 1, 0, 0, 0, time.UTC)
+
+	previous := db.Plugin{
+		ID:            "123",
+		Name:          "github.com/traefik/plugindemo",
+		DisplayName:   "Old name",
+		LatestVersion: "v0.1.0",
+		Versions:      []string{"v0.1.0"},
+		CreatedAt:     createdAt,
+		Hidden:        true,
+		Signature:     &db.SignaturePolicy{PublicKey: "key"},
+	}
+
+	store := &memoryStore{plugins: map[string]db.Plugin{previous.Name: previous}}
+	emitter := &recordingEmitter{}
+
+	report, err := New(gh, store, emitter, "").Crawl(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, Report{Repositories: 1, Updated: 1}, report)
+
+	plugin := store.plugins["github.com/traefik/plugindemo"]
+
+	assert.Equal(t, "123", plugin.ID)
+	assert.Equal(t, "Demo Plugin", plugin.DisplayName)
+	assert.Equal(t, "v0.2.0", plugin.LatestVersion)
+	assert.Equal(t, []string{"v0.2.0", "v0.1.0"}, plugin.Versions)
+	assert.Equal(t, 30, plugin.Stars)
+	assert.Empty(t, plugin.Readme)
+	assert.Equal(t, createdAt, plugin.CreatedAt)
+	assert.True(t, plugin.Hidden)
+	assert.Equal(t, &db.SignaturePolicy{PublicKey: "key"}, plugin.Signature)
+
+	assert.Empty(t, emitter.created)
+	assert.Equal(t, [][2]db.Plugin{{previous, plugin}}, emitter.changes)
+}
+
+func TestValidVersions(t *testing.T) {
+	t.Parallel()
+
+	versions := ValidVersions([]string{"v1.0.0", "1.1.0", "v1.2", "v1.1.0", "v1.1.0-beta.1", "v1.0.0", "v1.0.1+build"})
+
+	assert.Equal(t, []string{"v1.1.0", "v1.1.0-beta.1", "v1.0.0"}, versions)
+}
+
+func TestLatestVersion(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc     string
+		versions []string
+		expected string
+	}{
+		{
+			desc:     "release",
+			versions: []string{"v1.1.0-rc1", "v1.0.0"},
+			expected: "v1.0.0",
+		},
+		{
+			desc:     "only pre-releases",
+			versions: []string{"v1.1.0-rc2", "v1.1.0-rc1"},
+			expected: "v1.1.0-rc2",
+		},
+		{
+			desc:     "empty",
+			expected: "",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, LatestVersion(test.versions))
+		})
+	}
+}
+
+func TestValidateManifest(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc     string
+		manifest db.Manifest
+		expected []string
+	}{
+		{
+			desc: "valid yaegi",
+			manifest: db.Manifest{
+				DisplayName: "Demo",
+				Type:        "middleware",
+				Import:      "github.com/traefik/plugindemo/pkg",
+				Summary:     "Demo",
+				TestData:    map[string]interface{}{"a": "b"},
+			},
+		},
+		{
+			desc: "valid wasm",
+			manifest: db.Manifest{
+				DisplayName: "Demo",
+				Type:        "middleware",
+				Runtime:     "wasm",
+				Summary:     "Demo",
+				TestData:    map[string]interface{}{"a": "b"},
+			},
+		},
+		{
+			desc: "invalid",
+			manifest: db.Manifest{
+				Type:    "other",
+				Runtime: "lua",
+			},
+			expected: []string{
+				"missing displayName",
+				"missing summary",
+				`unsupported type: "other"`,
+				`unsupported runtime: "lua"`,
+				"missing testData",
+			},
+		},
+		{
+			desc: "import outside of the module",
+			manifest: db.Manifest{
+				DisplayName: "Demo",
+				Type:        "middleware",
+				Import:      "github.com/traefik/plugindemo2",
+				Summary:     "Demo",
+				TestData:    map[string]interface{}{"a": "b"},
+			},
+			expected: []string{`import "github.com/traefik/plugindemo2" differs from the module path "github.com/traefik/plugindemo"`},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, ValidateManifest("github.com/traefik/plugindemo", test.manifest))
+		})
+	}
+}
+
+func TestBuildSnippet(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc       string
+		pluginType string
+		expected   string
+	}{
+		{
+			desc:       "middleware",
+			pluginType: "middleware",
+			expected:   "http:\n    middlewares:\n        my-plugindemo:\n            plugin:\n                plugindemo:\n                    a: b\n",
+		},
+		{
+			desc:       "provider",
+			pluginType: "provider",
+			expected:   "providers:\n    plugin:\n        plugindemo:\n            a: b\n",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			snippet, err := buildSnippet("plugindemo", test.pluginType, map[string]interface{}{"a": "b"})
+			require.NoError(t, err)
+
+			assert.Equal(t, map[string]interface{}{"yaml": test.expected}, snippet)
+		})
+	}
+}
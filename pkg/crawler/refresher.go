@@ -0,0 +1,244 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v74/github"
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/plugin-service/pkg/db"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultReserve the number of GitHub requests the refresh leaves to the other users of the token (archive downloads, crawl).
+const DefaultReserve = 500
+
+// MetadataStore is capable of storing the repository metadata of the plugins.
+type MetadataStore interface {
+	ListAll(ctx context.Context) ([]db.Plugin, error)
+	UpdateRepositoryMetadata(ctx context.Context, id string, metadata db.RepositoryMetadata) (db.Plugin, error)
+}
+
+// RefreshReport the result of a refresh of the repository metadata.
+type RefreshReport struct {
+	Plugins   int `json:"plugins"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	// NotModified the number of repositories answered by a 304, which doesn't count against the rate limit.
+	NotModified int `json:"notModified"`
+	// Skipped the number of plugins which are not hosted on GitHub.
+	Skipped int `json:"skipped"`
+	Failed  int `json:"failed"`
+}
+
+// Refresher refreshes the metadata of the GitHub repositories of the plugins: stars, topics, archived state and license.
+// The repositories are requested with the entity tag of their previous response,
+// and the refresh pauses until the reset of the rate limit when the remaining requests drop to the reserve.
+// The changes of the archived state, the topics and the license are emitted as plugin updates,
+// the changes of the stars alone are too frequent to be notified.
+type Refresher struct {
+	gh      *github.Client
+	store   MetadataStore
+	emitter Emitter
+	reserve int
+	tracer  trace.Tracer
+}
+
+// NewRefresher creates a Refresher leaving reserve GitHub requests to the other users of the token.
+// The changes of the catalog are emitted by the emitter, if any.
+func NewRefresher(gh *github.Client, store MetadataStore, emitter Emitter, reserve int) *Refresher {
+	return &Refresher{
+		gh:      gh,
+		store:   store,
+		emitter: emitter,
+		reserve: reserve,
+		tracer:  otel.Tracer("crawler"),
+	}
+}
+
+// Refresh refreshes the repository metadata of all the enabled plugins.
+func (r *Refresher) Refresh(ctx context.Context) (RefreshReport, error) {
+	ctx, span := r.tracer.Start(ctx, "crawler_refresh")
+	defer span.End()
+
+	plugins, err := r.store.ListAll(ctx)
+	if err != nil {
+		span.RecordError(err)
+		return RefreshReport{}, fmt.Errorf("list plugins: %w", err)
+	}
+
+	report := RefreshReport{Plugins: len(plugins)}
+
+	for _, plugin := range plugins {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+
+		owner, repoName, ok := repositoryOf(plugin.Name)
+		if !ok {
+			report.Skipped++
+			continue
+		}
+
+		result, err := r.refresh(ctx, plugin, owner, repoName)
+		if err != nil {
+			if ctx.Err() != nil {
+				return report, ctx.Err()
+			}
+
+			report.Failed++
+			log.Error().Err(err).Str("module_name", plugin.Name).Msg("Unable to refresh the repository metadata")
+
+			continue
+		}
+
+		switch result {
+		case refreshUpdated:
+			report.Updated++
+		case refreshNotModified:
+			report.NotModified++
+		default:
+			report.Unchanged++
+		}
+	}
+
+	return report, nil
+}
+
+type refreshResult int
+
+const (
+	refreshUnchanged refreshResult = iota
+	refreshNotModified
+	refreshUpdated
+)
+
+func (r *Refresher) refresh(ctx context.Context, plugin db.Plugin, owner, repoName string) (refreshResult, error) {
+	ctx, span := r.tracer.Start(ctx, "crawler_refreshPlugin")
+	defer span.End()
+
+	repository, etag, err := r.getRepository(ctx, owner, repoName, plugin.RepositoryETag)
+	if err != nil {
+		span.RecordError(err)
+		return refreshUnchanged, fmt.Errorf("get repository: %w", err)
+	}
+
+	if repository == nil {
+		return refreshNotModified, nil
+	}
+
+	metadata := db.RepositoryMetadata{
+		Stars:    repository.GetStargazersCount(),
+		Topics:   repository.Topics,
+		Archived: repository.GetArchived(),
+		License:  repository.GetLicense().GetSPDXID(),
+		ETag:     etag,
+	}
+
+	if plugin.Stars == metadata.Stars &&
+		slices.Equal(plugin.Topics, metadata.Topics) &&
+		plugin.Archived == metadata.Archived &&
+		plugin.License == metadata.License &&
+		plugin.RepositoryETag == metadata.ETag {
+		return refreshUnchanged, nil
+	}
+
+	updated, err := r.store.UpdateRepositoryMetadata(ctx, plugin.ID, metadata)
+	if err != nil {
+		span.RecordError(err)
+		return refreshUnchanged, fmt.Errorf("update plugin: %w", err)
+	}
+
+	if r.emitter != nil && (plugin.Archived != updated.Archived ||
+		!slices.Equal(plugin.Topics, updated.Topics) ||
+		plugin.License != updated.License) {
+		r.emitter.EmitChanges(ctx, plugin, updated)
+	}
+
+	return refreshUpdated, nil
+}
+
+// getRepository gets a repository with a conditional request, and returns a nil repository if it is not modified since the given entity tag.
+func (r *Refresher) getRepository(ctx context.Context, owner, repoName, etag string) (*github.Repository, string, error) {
+	var (
+		repository *github.Repository
+		resp       *github.Response
+	)
+
+	err := withRateLimit(ctx, func() error {
+		req, err := r.gh.NewRequest(http.MethodGet, fmt.Sprintf("repos/%s/%s", owner, repoName), nil)
+		if err != nil {
+			return err
+		}
+
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+
+		repository = new(github.Repository)
+		resp, err = r.gh.Do(ctx, req, repository)
+
+		return err
+	})
+
+	notModified := isNotModified(err)
+	if err != nil && !notModified {
+		return nil, "", err
+	}
+
+	if err = r.waitForBudget(ctx, resp.Rate); err != nil {
+		return nil, "", err
+	}
+
+	if notModified {
+		return nil, etag, nil
+	}
+
+	return repository, resp.Header.Get("ETag"), nil
+}
+
+// waitForBudget waits for the reset of the rate limit when the remaining requests dropped to the reserve.
+func (r *Refresher) waitForBudget(ctx context.Context, rate github.Rate) error {
+	if rate.Limit == 0 || rate.Remaining > r.reserve {
+		return nil
+	}
+
+	wait := time.Until(rate.Reset.Time)
+	if wait <= 0 {
+		return nil
+	}
+
+	log.Warn().Int("remaining", rate.Remaining).Time("reset", rate.Reset.Time).Msg("GitHub rate limit reserve reached, pausing the refresh")
+
+	timer := time.NewTimer(wait)
+
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// repositoryOf returns the owner and the name of the GitHub repository of a module.
+func repositoryOf(moduleName string) (string, string, bool) {
+	parts := strings.Split(moduleName, "/")
+	if len(parts) < 3 || parts[0] != "github.com" || parts[1] == "" || parts[2] == "" {
+		return "", "", false
+	}
+
+	return parts[1], parts[2], true
+}
+
+func isNotModified(err error) bool {
+	var errResp *github.ErrorResponse
+
+	return errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusNotModified
+}
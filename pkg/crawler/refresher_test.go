@@ -0,0 +1,205 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v74/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+func (s *memoryStore) ListAll(_ context.Context) ([]db.Plugin, error) {
+	var plugins []db.Plugin
+	for _, plugin := range s.plugins {
+		plugins = append(plugins, plugin)
+	}
+
+	slices.SortFunc(plugins, func(a, b db.Plugin) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return plugins, nil
+}
+
+func (s *memoryStore) UpdateRepositoryMetadata(_ context.Context, id string, metadata db.RepositoryMetadata) (db.Plugin, error) {
+	for name, plugin := range s.plugins {
+		if plugin.ID == id {
+			s.updates++
+
+			plugin.Stars = metadata.Stars
+			plugin.Topics = metadata.Topics
+			plugin.Archived = metadata.Archived
+			plugin.License = metadata.License
+			plugin.RepositoryETag = metadata.ETag
+
+			s.plugins[name] = plugin
+
+			return plugin, nil
+		}
+	}
+
+	return db.Plugin{}, db.NotFoundError{Err: errors.New("not found")}
+}
+
+// setupRepositoryAPI serves the given repositories, answering the requests with a matching entity tag with a 304.
+func setupRepositoryAPI(t *testing.T, remaining int, reset time.Time, repositories map[string]github.Repository) (*github.Client, *atomic.Int64) {
+	t.Helper()
+
+	var requests atomic.Int64
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /repos/{owner}/{repo}", func(rw http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+
+		rw.Header().Set("X-RateLimit-Limit", "5000")
+		rw.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		rw.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+
+		repo, ok := repositories[req.PathValue("owner")+"/"+req.PathValue("repo")]
+		if !ok {
+			rw.WriteHeader(http.StatusNotFound)
+			writeJSON(t, rw, map[string]string{"message": "Not Found"})
+
+			return
+		}
+
+		etag := `W/"` + strconv.Itoa(repo.GetStargazersCount()) + `"`
+
+		if req.Header.Get("If-None-Match") == etag {
+			rw.Header().Set("ETag", etag)
+			rw.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+
+		rw.Header().Set("ETag", etag)
+		writeJSON(t, rw, repo)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	return client, &requests
+}
+
+func TestRefresher_Refresh(t *testing.T) {
+	gh, requests := setupRepositoryAPI(t, 4000, time.Now().Add(time.Hour), map[string]github.Repository{
+		"traefik/plugindemo": {
+			StargazersCount: github.Ptr(42),
+			Topics:          []string{"traefik-plugin", "middleware"},
+			Archived:        github.Ptr(true),
+			License:         &github.License{SPDXID: github.Ptr("Apache-2.0")},
+		},
+		"traefik/unchanged": {
+			StargazersCount: github.Ptr(3),
+		},
+		"traefik/starred": {
+			StargazersCount: github.Ptr(8),
+		},
+	})
+
+	previous := db.Plugin{ID: "1", Name: "github.com/traefik/plugindemo", Stars: 10}
+
+	store := &memoryStore{plugins: map[string]db.Plugin{
+		"github.com/traefik/plugindemo": previous,
+		"github.com/traefik/unchanged":  {ID: "2", Name: "github.com/traefik/unchanged", Stars: 3, RepositoryETag: `W/"3"`},
+		"github.com/traefik/deleted":    {ID: "3", Name: "github.com/traefik/deleted"},
+		"example.com/plugin":            {ID: "4", Name: "example.com/plugin"},
+		"github.com/traefik/starred":    {ID: "5", Name: "github.com/traefik/starred", Stars: 7},
+	}}
+	emitter := &recordingEmitter{}
+
+	refresher := NewRefresher(gh, store, emitter, DefaultReserve)
+
+	report, err := refresher.Refresh(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, RefreshReport{Plugins: 5, Updated: 2, NotModified: 1, Skipped: 1, Failed: 1}, report)
+
+	plugin := store.plugins["github.com/traefik/plugindemo"]
+
+	assert.Equal(t, 42, plugin.Stars)
+	assert.Equal(t, []string{"traefik-plugin", "middleware"}, plugin.Topics)
+	assert.True(t, plugin.Archived)
+	assert.Equal(t, "Apache-2.0", plugin.License)
+	assert.Equal(t, `W/"42"`, plugin.RepositoryETag)
+
+	assert.Equal(t, 8, store.plugins["github.com/traefik/starred"].Stars)
+
+	assert.EqualValues(t, 4, requests.Load())
+
+	// Only the plugin archived meanwhile is notified, not the plugin which only gained stars.
+	assert.Equal(t, [][2]db.Plugin{{previous, plugin}}, emitter.changes)
+
+	// The second refresh only gets 304, without updating the plugins.
+	report, err = refresher.Refresh(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, RefreshReport{Plugins: 5, NotModified: 3, Skipped: 1, Failed: 1}, report)
+	assert.Equal(t, 2, store.updates)
+}
+
+func TestRefresher_Refresh_reserve(t *testing.T) {
+	gh, requests := setupRepositoryAPI(t, 10, time.Now().Add(time.Hour), map[string]github.Repository{
+		"traefik/plugindemo": {StargazersCount: github.Ptr(42)},
+		"traefik/other":      {StargazersCount: github.Ptr(12)},
+	})
+
+	store := &memoryStore{plugins: map[string]db.Plugin{
+		"github.com/traefik/plugindemo": {ID: "1", Name: "github.com/traefik/plugindemo"},
+		"github.com/traefik/other":      {ID: "2", Name: "github.com/traefik/other"},
+	}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	report, err := NewRefresher(gh, store, nil, 100).Refresh(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The refresh pauses until the reset after the first request, which brings the remaining requests under the reserve.
+	assert.EqualValues(t, 1, requests.Load())
+	assert.Equal(t, RefreshReport{Plugins: 2}, report)
+}
+
+func TestRepositoryOf(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		moduleName string
+		owner      string
+		repoName   string
+		ok         bool
+	}{
+		{moduleName: "github.com/traefik/plugindemo", owner: "traefik", repoName: "plugindemo", ok: true},
+		{moduleName: "github.com/traefik/plugindemo/v2", owner: "traefik", repoName: "plugindemo", ok: true},
+		{moduleName: "github.com/traefik"},
+		{moduleName: "gitlab.com/traefik/plugindemo"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.moduleName, func(t *testing.T) {
+			t.Parallel()
+
+			owner, repoName, ok := repositoryOf(test.moduleName)
+
+			assert.Equal(t, test.owner, owner)
+			assert.Equal(t, test.repoName, repoName)
+			assert.Equal(t, test.ok, ok)
+		})
+	}
+}
@@ -25,13 +25,348 @@ type Plugin struct {
 	Disabled      bool                   `json:"disabled,omitempty" bson:"disabled"`
 	Hidden        bool                   `json:"hidden,omitempty" bson:"hidden"`
 	UseUnsafe     bool                   `json:"useUnsafe,omitempty" bson:"useUnsafe"`
+	Archived      bool                   `json:"archived,omitempty" bson:"archived"`
+
+	// Aliases the previous module names of the plugin, recorded when its repository is renamed.
+	Aliases []string `json:"aliases,omitempty" bson:"aliases,omitempty"`
+
+	// Topics the topics of the GitHub repository.
+	Topics []string `json:"topics,omitempty" bson:"topics,omitempty"`
+	// License the SPDX identifier of the license of the GitHub repository.
+	License string `json:"license,omitempty" bson:"license,omitempty"`
+	// RepositoryETag the entity tag of the last GitHub repository response, sent back to refresh the metadata with a conditional request.
+	RepositoryETag string `json:"-" bson:"repositoryETag,omitempty"`
+
+	Signature *SignaturePolicy `json:"signature,omitempty" bson:"signature,omitempty"`
+}
+
+// RepositoryMetadata The metadata of the GitHub repository of a plugin.
+type RepositoryMetadata struct {
+	Stars    int
+	Topics   []string
+	Archived bool
+	License  string
+	ETag     string
+}
+
+// SignaturePolicy The signers trusted to sign the release assets of a plugin.
+// A plugin declares either a minisign public key, or a keyless (Sigstore) identity.
+type SignaturePolicy struct {
+	// PublicKey the minisign public key (base64).
+	PublicKey string `json:"publicKey,omitempty" bson:"publicKey,omitempty"`
+	// Identity the subject of the signing certificate (workflow URI or email).
+	Identity string `json:"identity,omitempty" bson:"identity,omitempty"`
+	// Issuer the OIDC issuer of the signing certificate.
+	Issuer string `json:"issuer,omitempty" bson:"issuer,omitempty"`
 }
 
 // PluginHash The plugin hash tuple.
 type PluginHash struct {
-	Name     string `json:"name,omitempty" bson:"name"`
-	Hash     string `json:"hash,omitempty" bson:"hash"`
-	Verified *bool  `json:"verified,omitempty" bson:"verified"`
+	Name     string    `json:"name,omitempty" bson:"name"`
+	Hash     string    `json:"hash,omitempty" bson:"hash"`
+	Verified *bool     `json:"verified,omitempty" bson:"verified"`
+	Reasons  []string  `json:"reasons,omitempty" bson:"reasons,omitempty"`
+	Manifest *Manifest `json:"manifest,omitempty" bson:"manifest,omitempty"`
+
+	Quarantined bool   `json:"quarantined,omitempty" bson:"quarantined,omitempty"`
+	Signer      string `json:"signer,omitempty" bson:"signer,omitempty"`
+
+	// Digests the digests of the archive, by algorithm (sha256, h1, sha512).
+	Digests map[string]string `json:"digests,omitempty" bson:"digests,omitempty"`
+
+	Capabilities *CapabilityReport `json:"capabilities,omitempty" bson:"capabilities,omitempty"`
+
+	// CreatedAt the date at which the version has been seen first, unknown for the hashes recorded before it.
+	CreatedAt *time.Time `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
+	// ResetAt the date of the last reset of the hash, the incidents before it don't count toward the quarantine.
+	ResetAt *time.Time `json:"resetAt,omitempty" bson:"resetAt,omitempty"`
+}
+
+// Digest algorithms.
+const (
+	// DigestSHA256 the hex encoded SHA-256 of the archive.
+	DigestSHA256 = "sha256"
+	// DigestH1 the base64 encoded Go dirhash (as in go.sum) of the module contents, only for Go module zips.
+	DigestH1 = "h1"
+	// DigestSHA512 the hex encoded SHA-512 of the archive.
+	DigestSHA512 = "sha512"
+)
+
+// Digest returns the digest of the archive for the given algorithm.
+// The SHA-256 falls back to Hash for the hashes recorded before the digests.
+func (p PluginHash) Digest(algorithm string) (string, bool) {
+	if digest, ok := p.Digests[algorithm]; ok {
+		return digest, true
+	}
+
+	if algorithm == DigestSHA256 && p.Hash != "" {
+		return p.Hash, true
+	}
+
+	return "", false
+}
+
+// Manifest The plugin manifest (.traefik.yml) of a plugin version.
+type Manifest struct {
+	DisplayName   string                 `json:"displayName,omitempty" bson:"displayName" yaml:"displayName"`
+	Type          string                 `json:"type,omitempty" bson:"type" yaml:"type"`
+	Runtime       string                 `json:"runtime,omitempty" bson:"runtime" yaml:"runtime"`
+	WasmPath      string                 `json:"wasmPath,omitempty" bson:"wasmPath" yaml:"wasmPath"`
+	Import        string                 `json:"import,omitempty" bson:"import" yaml:"import"`
+	BasePkg       string                 `json:"basePkg,omitempty" bson:"basePkg" yaml:"basePkg"`
+	Compatibility string                 `json:"compatibility,omitempty" bson:"compatibility" yaml:"compatibility"`
+	Summary       string                 `json:"summary,omitempty" bson:"summary" yaml:"summary"`
+	IconPath      string                 `json:"iconPath,omitempty" bson:"iconPath" yaml:"iconPath"`
+	BannerPath    string                 `json:"bannerPath,omitempty" bson:"bannerPath" yaml:"bannerPath"`
+	TestData      map[string]interface{} `json:"testData,omitempty" bson:"testData" yaml:"testData"`
+}
+
+// Capabilities detected in plugin sources.
+const (
+	CapabilityUnsafe  = "unsafe"
+	CapabilitySyscall = "syscall"
+	CapabilityExec    = "exec"
+	CapabilityReflect = "reflect"
+	CapabilityNetwork = "network"
+)
+
+// CapabilityReport The capabilities used by the sources of a plugin version.
+type CapabilityReport struct {
+	Capabilities []string  `json:"capabilities,omitempty" bson:"capabilities"`
+	Findings     []Finding `json:"findings,omitempty" bson:"findings"`
+	Errors       []string  `json:"errors,omitempty" bson:"errors,omitempty"`
+}
+
+// UseUnsafe returns true if the sources require the unsafe or syscall packages.
+func (r CapabilityReport) UseUnsafe() bool {
+	for _, capability := range r.Capabilities {
+		if capability == CapabilityUnsafe || capability == CapabilitySyscall {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Finding The location of a capability in the plugin sources.
+type Finding struct {
+	Capability string `json:"capability" bson:"capability"`
+	File       string `json:"file" bson:"file"`
+	Line       int    `json:"line" bson:"line"`
+	Detail     string `json:"detail" bson:"detail"`
+}
+
+// Incident kinds.
+const (
+	// IncidentUpstream the archive served by a source differs from the hash recorded on its first download.
+	IncidentUpstream = "upstream"
+	// IncidentClient a client claims a hash (X-Plugin-Hash) different from the hash recorded on the first download.
+	IncidentClient = "client"
+)
+
+// Incident A hash mismatch detected while serving a plugin archive.
+type Incident struct {
+	ID        string    `json:"id,omitempty" bson:"id"`
+	Kind      string    `json:"kind" bson:"kind"`
+	Module    string    `json:"module" bson:"module"`
+	Version   string    `json:"version" bson:"version"`
+	Source    string    `json:"source,omitempty" bson:"source,omitempty"`
+	Expected  string    `json:"expected" bson:"expected"`
+	Received  string    `json:"received" bson:"received"`
+	IP        string    `json:"ip,omitempty" bson:"ip,omitempty"`
+	UserAgent string    `json:"userAgent,omitempty" bson:"userAgent,omitempty"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// IncidentSummary The incidents of a plugin.
+type IncidentSummary struct {
+	Module   string                   `json:"module" bson:"module"`
+	Count    int                      `json:"count" bson:"count"`
+	LastSeen time.Time                `json:"lastSeen" bson:"lastSeen"`
+	Versions []IncidentVersionSummary `json:"versions" bson:"versions"`
+}
+
+// IncidentVersionSummary The incidents of a plugin version.
+type IncidentVersionSummary struct {
+	Version  string    `json:"version" bson:"version"`
+	Count    int       `json:"count" bson:"count"`
+	LastSeen time.Time `json:"lastSeen" bson:"lastSeen"`
+}
+
+// HashReset A replacement, by an administrator, of the hash recorded for a plugin version.
+type HashReset struct {
+	ID            string    `json:"id,omitempty" bson:"id"`
+	Module        string    `json:"module" bson:"module"`
+	Version       string    `json:"version" bson:"version"`
+	PreviousHash  string    `json:"previousHash" bson:"previousHash"`
+	Hash          string    `json:"hash" bson:"hash"`
+	Justification string    `json:"justification" bson:"justification"`
+	Author        string    `json:"author,omitempty" bson:"author,omitempty"`
+	CreatedAt     time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// Job kinds.
+const (
+	// JobPrewarm fetches the archive of a plugin version, and records its hash.
+	JobPrewarm = "prewarm"
+	// JobDeliver sends a webhook delivery to its subscriber.
+	JobDeliver = "deliver"
+)
+
+// Job statuses.
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	// JobDead a job which has failed all its attempts, kept for inspection (dead letter).
+	JobDead = "dead"
+)
+
+// Job An asynchronous task on a plugin version.
+type Job struct {
+	ID      string `json:"id" bson:"id"`
+	Kind    string `json:"kind" bson:"kind"`
+	Module  string `json:"module" bson:"module"`
+	Version string `json:"version" bson:"version"`
+	Status  string `json:"status" bson:"status"`
+
+	// Delivery the webhook delivery sent by a JobDeliver job.
+	Delivery string `json:"delivery,omitempty" bson:"delivery,omitempty"`
+
+	// Attempts the number of times the job has been started.
+	Attempts    int    `json:"attempts" bson:"attempts"`
+	MaxAttempts int    `json:"maxAttempts" bson:"maxAttempts"`
+	Error       string `json:"error,omitempty" bson:"error,omitempty"`
+	// RunAt the time after which the job can be started, delayed after a failure.
+	RunAt time.Time `json:"runAt" bson:"runAt"`
+
+	// LeaseOwner the worker running the job, until LeaseUntil.
+	LeaseOwner string    `json:"leaseOwner,omitempty" bson:"leaseOwner,omitempty"`
+	LeaseUntil time.Time `json:"leaseUntil" bson:"leaseUntil,omitempty"`
+
+	CreatedAt  time.Time  `json:"createdAt" bson:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt" bson:"updatedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty" bson:"finishedAt,omitempty"`
+}
+
+// JobFilter Criteria to list jobs.
+type JobFilter struct {
+	Module string
+	Status string
+}
+
+// Event types.
+const (
+	EventPluginCreated    = "plugin.created"
+	EventPluginUpdated    = "plugin.updated"
+	EventPluginDeleted    = "plugin.deleted"
+	EventPluginHidden     = "plugin.hidden"
+	EventPluginUnhidden   = "plugin.unhidden"
+	EventPluginDisabled   = "plugin.disabled"
+	EventVersionPublished = "version.published"
+	// EventHashVerified the archive of a plugin version has been verified, successfully or not.
+	EventHashVerified = "hash.verified"
+	// EventHashMismatch an archive doesn't match the hash recorded on its first download.
+	EventHashMismatch = "hash.mismatch"
+)
+
+// EventTypes the types of the catalog change events.
+var EventTypes = []string{
+	EventPluginCreated,
+	EventPluginUpdated,
+	EventPluginDeleted,
+	EventPluginHidden,
+	EventPluginUnhidden,
+	EventPluginDisabled,
+	EventVersionPublished,
+	EventHashVerified,
+	EventHashMismatch,
+}
+
+// Event A change of the catalog.
+type Event struct {
+	ID      string `json:"id" bson:"id"`
+	Type    string `json:"type" bson:"type"`
+	Module  string `json:"module,omitempty" bson:"module,omitempty"`
+	Version string `json:"version,omitempty" bson:"version,omitempty"`
+
+	// Plugin the plugin, for the plugin events.
+	Plugin *Plugin `json:"plugin,omitempty" bson:"plugin,omitempty"`
+
+	// Hash, Verified and Reasons the verification outcome, for the hash.verified events.
+	Hash     string   `json:"hash,omitempty" bson:"hash,omitempty"`
+	Verified *bool    `json:"verified,omitempty" bson:"verified,omitempty"`
+	Reasons  []string `json:"reasons,omitempty" bson:"reasons,omitempty"`
+
+	// Incident the mismatch, for the hash.mismatch events.
+	Incident *Incident `json:"incident,omitempty" bson:"incident,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// EventRecord An event of the event stream, the cursor is its position in the stream.
+type EventRecord struct {
+	Cursor string `json:"cursor"`
+	Event  Event  `json:"event"`
+}
+
+// Subscription A webhook receiving the catalog change events.
+type Subscription struct {
+	ID  string `json:"id" bson:"id"`
+	URL string `json:"url" bson:"url"`
+	// Secret the key of the HMAC-SHA256 signatures of the payloads, only returned on creation.
+	Secret string `json:"secret,omitempty" bson:"secret"`
+	// Events the event types sent to the subscriber, all of them when empty.
+	Events    []string  `json:"events,omitempty" bson:"events,omitempty"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// Delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Delivery An event sent to a subscriber, with the history of its attempts.
+type Delivery struct {
+	ID           string            `json:"id" bson:"id"`
+	Subscription string            `json:"subscription" bson:"subscription"`
+	Event        Event             `json:"event" bson:"event"`
+	Status       string            `json:"status" bson:"status"`
+	Attempts     []DeliveryAttempt `json:"attempts,omitempty" bson:"attempts"`
+	CreatedAt    time.Time         `json:"createdAt" bson:"createdAt"`
+	UpdatedAt    time.Time         `json:"updatedAt" bson:"updatedAt"`
+}
+
+// DeliveryAttempt An attempt to send a delivery.
+type DeliveryAttempt struct {
+	StatusCode int    `json:"statusCode,omitempty" bson:"statusCode,omitempty"`
+	Error      string `json:"error,omitempty" bson:"error,omitempty"`
+	// Duration the duration of the request, in milliseconds.
+	Duration int64     `json:"duration" bson:"duration"`
+	At       time.Time `json:"at" bson:"at"`
+}
+
+// LogRecord A record of the transparency log: the first hash seen for a plugin version, or the hash set by a reset.
+type LogRecord struct {
+	Index     int64     `json:"index" bson:"_id"`
+	Module    string    `json:"module" bson:"module"`
+	Version   string    `json:"version" bson:"version"`
+	Hash      string    `json:"hash" bson:"hash"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+
+	// Previous the index of the record of the plugin version replaced by this one after a hash reset, nil for the first record.
+	Previous *int64 `json:"previous,omitempty" bson:"previous"`
+
+	// TreeHashes the hashes of the Merkle tree computed when appending the record.
+	TreeHashes []LogHash `json:"-" bson:"treeHashes"`
+}
+
+// LogHash A stored hash of the transparency log Merkle tree.
+type LogHash struct {
+	Index int64  `bson:"index"`
+	Hash  []byte `bson:"hash"`
 }
 
 // Pagination holds information for requesting page.
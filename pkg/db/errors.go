@@ -18,3 +18,20 @@ func (e NotFoundError) Error() string {
 
 // Unwrap returns the underlying error.
 func (e NotFoundError) Unwrap() error { return e.Err }
+
+// ConflictError represents a document conflicting with an existing document.
+type ConflictError struct {
+	Err error
+}
+
+// Error stringifies the error.
+func (e ConflictError) Error() string {
+	if e.Err == nil {
+		return "conflict"
+	}
+
+	return fmt.Sprintf("conflict: %v", e.Err.Error())
+}
+
+// Unwrap returns the underlying error.
+func (e ConflictError) Unwrap() error { return e.Err }
@@ -2,7 +2,11 @@ package mongodb
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
+
+	"github.com/traefik/plugin-service/pkg/db"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -44,9 +48,159 @@ func (m *MongoDB) Bootstrap() error {
 		return fmt.Errorf("unable to create indexes: %w", err)
 	}
 
+	incidentModels := []mongo.IndexModel{
+		{
+			Options: &options.IndexOptions{
+				Name: stringPtr("_by_module_version"),
+			},
+			Keys: bson.D{{Key: "module", Value: 1}, {Key: "version", Value: 1}},
+		},
+	}
+
+	if _, err := m.client.Collection(incidentCollName).Indexes().CreateMany(context.Background(), incidentModels); err != nil {
+		return fmt.Errorf("unable to create incident indexes: %w", err)
+	}
+
+	resetModels := []mongo.IndexModel{
+		{
+			Options: &options.IndexOptions{
+				Name: stringPtr("_by_module_version"),
+			},
+			Keys: bson.D{{Key: "module", Value: 1}, {Key: "version", Value: 1}},
+		},
+	}
+
+	if _, err := m.client.Collection(hashResetCollName).Indexes().CreateMany(context.Background(), resetModels); err != nil {
+		return fmt.Errorf("unable to create hash reset indexes: %w", err)
+	}
+
+	// A hash reset appends a new record of a plugin version: the records are unique by previous record.
+	if _, err := m.client.Collection(logCollName).Indexes().DropOne(context.Background(), "_uniq_module_version"); err != nil && !isIndexNotFound(err) {
+		return fmt.Errorf("unable to drop log index: %w", err)
+	}
+
+	logModels := []mongo.IndexModel{
+		{
+			Options: &options.IndexOptions{
+				Name:   stringPtr("_uniq_module_version_previous"),
+				Unique: boolPtr(true),
+			},
+			Keys: bson.D{{Key: "module", Value: 1}, {Key: "version", Value: 1}, {Key: "previous", Value: 1}},
+		},
+		{
+			Options: &options.IndexOptions{
+				Name: stringPtr("_by_tree_hash"),
+			},
+			Keys: bson.D{{Key: "treeHashes.index", Value: 1}},
+		},
+	}
+
+	if _, err := m.client.Collection(logCollName).Indexes().CreateMany(context.Background(), logModels); err != nil {
+		return fmt.Errorf("unable to create log indexes: %w", err)
+	}
+
+	jobModels := []mongo.IndexModel{
+		{
+			Options: &options.IndexOptions{
+				Name:   stringPtr("_uniq_id"),
+				Unique: boolPtr(true),
+			},
+			Keys: bson.D{{Key: "id", Value: 1}},
+		},
+		{
+			Options: &options.IndexOptions{
+				Name: stringPtr("_by_status_run_at"),
+			},
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "runAt", Value: 1}},
+		},
+		{
+			Options: &options.IndexOptions{
+				Name: stringPtr("_by_module_created_at"),
+			},
+			Keys: bson.D{{Key: "module", Value: 1}, {Key: "createdAt", Value: -1}},
+		},
+		{
+			// The succeeded jobs are removed after a week, the dead letters are kept.
+			Options: &options.IndexOptions{
+				Name:                    stringPtr("_ttl_succeeded"),
+				ExpireAfterSeconds:      int32Ptr(int32((7 * 24 * time.Hour).Seconds())),
+				PartialFilterExpression: bson.D{{Key: "status", Value: db.JobSucceeded}},
+			},
+			Keys: bson.D{{Key: "finishedAt", Value: 1}},
+		},
+	}
+
+	if _, err := m.client.Collection(jobCollName).Indexes().CreateMany(context.Background(), jobModels); err != nil {
+		return fmt.Errorf("unable to create job indexes: %w", err)
+	}
+
+	subscriptionModels := []mongo.IndexModel{
+		{
+			Options: &options.IndexOptions{
+				Name:   stringPtr("_uniq_id"),
+				Unique: boolPtr(true),
+			},
+			Keys: bson.D{{Key: "id", Value: 1}},
+		},
+	}
+
+	if _, err := m.client.Collection(subscriptionCollName).Indexes().CreateMany(context.Background(), subscriptionModels); err != nil {
+		return fmt.Errorf("unable to create subscription indexes: %w", err)
+	}
+
+	deliveryModels := []mongo.IndexModel{
+		{
+			Options: &options.IndexOptions{
+				Name:   stringPtr("_uniq_id"),
+				Unique: boolPtr(true),
+			},
+			Keys: bson.D{{Key: "id", Value: 1}},
+		},
+		{
+			Options: &options.IndexOptions{
+				Name: stringPtr("_by_subscription_created_at"),
+			},
+			Keys: bson.D{{Key: "subscription", Value: 1}, {Key: "createdAt", Value: -1}},
+		},
+		{
+			// The delivery history is kept for a month.
+			Options: &options.IndexOptions{
+				Name:               stringPtr("_ttl_created_at"),
+				ExpireAfterSeconds: int32Ptr(int32((30 * 24 * time.Hour).Seconds())),
+			},
+			Keys: bson.D{{Key: "createdAt", Value: 1}},
+		},
+	}
+
+	if _, err := m.client.Collection(deliveryCollName).Indexes().CreateMany(context.Background(), deliveryModels); err != nil {
+		return fmt.Errorf("unable to create delivery indexes: %w", err)
+	}
+
+	eventModels := []mongo.IndexModel{
+		{
+			// The events are streamed as soon as they are published, they are only kept a week.
+			Options: &options.IndexOptions{
+				Name:               stringPtr("_ttl_created_at"),
+				ExpireAfterSeconds: int32Ptr(int32((7 * 24 * time.Hour).Seconds())),
+			},
+			Keys: bson.D{{Key: "createdAt", Value: 1}},
+		},
+	}
+
+	if _, err := m.client.Collection(eventCollName).Indexes().CreateMany(context.Background(), eventModels); err != nil {
+		return fmt.Errorf("unable to create event indexes: %w", err)
+	}
+
 	return nil
 }
 
+// isIndexNotFound returns true if the index, or its collection, doesn't exist.
+func isIndexNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+
+	return errors.As(err, &cmdErr) && (cmdErr.Code == 26 || cmdErr.Code == 27)
+}
+
 func stringPtr(val string) *string {
 	return &val
 }
@@ -54,3 +208,7 @@ func stringPtr(val string) *string {
 func boolPtr(val bool) *bool {
 	return &val
 }
+
+func int32Ptr(val int32) *int32 {
+	return &val
+}
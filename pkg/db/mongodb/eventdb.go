@@ -0,0 +1,100 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+
+	"github.com/traefik/plugin-service/pkg/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const eventCollName = "event"
+
+// Error codes of the change streams which can't be resumed from the given token.
+const (
+	codeFailedToParse           = 9
+	codeInvalidResumeToken      = 260
+	codeChangeStreamHistoryLost = 286
+)
+
+// ChangeStreamsSupported reports whether the events can be streamed from the database.
+// The change streams are only available on replica sets and sharded clusters.
+func (m *MongoDB) ChangeStreamsSupported(ctx context.Context) bool {
+	stream, err := m.client.Collection(eventCollName).Watch(ctx, mongo.Pipeline{})
+	if err != nil {
+		return false
+	}
+
+	_ = stream.Close(ctx)
+
+	return true
+}
+
+// PublishEvent records an event, it is streamed to the watchers by the change streams.
+func (m *MongoDB) PublishEvent(ctx context.Context, event db.Event) error {
+	ctx, span := m.tracer.Start(ctx, "db_publish_event")
+	defer span.End()
+
+	if _, err := m.client.Collection(eventCollName).InsertOne(ctx, event); err != nil {
+		span.RecordError(err)
+
+		return fmt.Errorf("unable to publish event: %w", err)
+	}
+
+	return nil
+}
+
+// WatchEvents streams the events published after the given cursor, or the new events without cursor.
+// The cursors are the resume tokens of the change stream,
+// a db.NotFoundError is returned when the cursor is malformed or no longer in the oplog.
+// The stream ends when the context is canceled, it can be iterated once.
+func (m *MongoDB) WatchEvents(ctx context.Context, since string) (iter.Seq2[db.EventRecord, error], error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "operationType", Value: "insert"}}}},
+	}
+
+	opts := options.ChangeStream()
+	if since != "" {
+		opts.SetResumeAfter(bson.D{{Key: "_data", Value: since}})
+	}
+
+	stream, err := m.client.Collection(eventCollName).Watch(ctx, pipeline, opts)
+	if err != nil {
+		var serverErr mongo.ServerError
+		if since != "" && errors.As(err, &serverErr) &&
+			(serverErr.HasErrorCode(codeFailedToParse) || serverErr.HasErrorCode(codeInvalidResumeToken) || serverErr.HasErrorCode(codeChangeStreamHistoryLost)) {
+			return nil, db.NotFoundError{Err: err}
+		}
+
+		return nil, fmt.Errorf("unable to watch events: %w", err)
+	}
+
+	return func(yield func(db.EventRecord, error) bool) {
+		defer func() { _ = stream.Close(context.WithoutCancel(ctx)) }()
+
+		for stream.Next(ctx) {
+			var change struct {
+				FullDocument db.Event `bson:"fullDocument"`
+			}
+
+			if err := stream.Decode(&change); err != nil {
+				yield(db.EventRecord{}, fmt.Errorf("unable to decode event: %w", err))
+				return
+			}
+
+			cursor, _ := stream.ResumeToken().Lookup("_data").StringValueOK()
+
+			if !yield(db.EventRecord{Cursor: cursor, Event: change.FullDocument}, nil) {
+				return
+			}
+		}
+
+		if err := stream.Err(); err != nil && ctx.Err() == nil {
+			yield(db.EventRecord{}, fmt.Errorf("unable to watch events: %w", err))
+		}
+	}, nil
+}
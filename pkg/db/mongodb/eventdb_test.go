@@ -0,0 +1,55 @@
+package mongodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+func TestMongoDB_WatchEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store, _ := createDatabase(t, nil)
+
+	if !store.ChangeStreamsSupported(ctx) {
+		t.Skip("change streams need a replica set")
+	}
+
+	records, err := store.WatchEvents(ctx, "")
+	require.NoError(t, err)
+
+	require.NoError(t, store.PublishEvent(ctx, db.Event{ID: "e1", Type: db.EventPluginCreated}))
+	require.NoError(t, store.PublishEvent(ctx, db.Event{ID: "e2", Type: db.EventPluginUpdated}))
+
+	var first db.EventRecord
+
+	for record, err := range records {
+		require.NoError(t, err)
+
+		first = record
+
+		break
+	}
+
+	assert.Equal(t, "e1", first.Event.ID)
+	assert.NotEmpty(t, first.Cursor)
+
+	// Resumed after the first event.
+	records, err = store.WatchEvents(ctx, first.Cursor)
+	require.NoError(t, err)
+
+	for record, err := range records {
+		require.NoError(t, err)
+
+		assert.Equal(t, "e2", record.Event.ID)
+
+		break
+	}
+
+	_, err = store.WatchEvents(ctx, "unknown")
+	require.ErrorAs(t, err, &db.NotFoundError{})
+}
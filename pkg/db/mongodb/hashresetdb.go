@@ -0,0 +1,61 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/traefik/plugin-service/pkg/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const hashResetCollName = "hash_reset"
+
+// CreateHashReset records the reset of a plugin hash.
+func (m *MongoDB) CreateHashReset(ctx context.Context, reset db.HashReset) (db.HashReset, error) {
+	ctx, span := m.tracer.Start(ctx, "db_create_hash_reset")
+	defer span.End()
+
+	reset.ID = primitive.NewObjectID().Hex()
+	reset.CreatedAt = time.Now().Truncate(time.Millisecond)
+
+	if _, err := m.client.Collection(hashResetCollName).InsertOne(ctx, reset); err != nil {
+		span.RecordError(err)
+
+		return db.HashReset{}, fmt.Errorf("unable to create hash reset: %w", err)
+	}
+
+	return reset, nil
+}
+
+// ListHashResets lists the hash resets of a plugin, the most recent first.
+func (m *MongoDB) ListHashResets(ctx context.Context, module string) ([]db.HashReset, error) {
+	ctx, span := m.tracer.Start(ctx, "db_list_hash_resets")
+	defer span.End()
+
+	criteria := bson.D{
+		{Key: "module", Value: module},
+	}
+
+	opts := &options.FindOptions{}
+	opts.SetSort(bson.D{{Key: "createdAt", Value: -1}})
+
+	cursor, err := m.client.Collection(hashResetCollName).Find(ctx, criteria, opts)
+	if err != nil {
+		span.RecordError(err)
+
+		return nil, fmt.Errorf("unable to find hash resets: %w", err)
+	}
+
+	resets := make([]db.HashReset, 0)
+
+	if err = cursor.All(ctx, &resets); err != nil {
+		span.RecordError(err)
+
+		return nil, fmt.Errorf("unable to unmarshal hash resets: %w", err)
+	}
+
+	return resets, nil
+}
@@ -0,0 +1,72 @@
+package mongodb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+func TestMongoDB_CreateHashReset(t *testing.T) {
+	ctx := context.Background()
+	store, _ := createDatabase(t, nil)
+
+	reset := db.HashReset{
+		Module:        "plugin",
+		Version:       "v1.0.0",
+		PreviousHash:  "123",
+		Hash:          "456",
+		Justification: "tag moved by the author",
+		Author:        "admin",
+	}
+
+	got, err := store.CreateHashReset(ctx, reset)
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, got.ID)
+	assert.False(t, got.CreatedAt.IsZero())
+
+	resets, err := store.ListHashResets(ctx, "plugin")
+	require.NoError(t, err)
+	require.Len(t, resets, 1)
+
+	resets[0].CreatedAt = resets[0].CreatedAt.UTC()
+	got.CreatedAt = got.CreatedAt.UTC()
+
+	assert.Equal(t, got, resets[0])
+}
+
+func TestMongoDB_ListHashResets(t *testing.T) {
+	ctx := context.Background()
+	store, _ := createDatabase(t, nil)
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	resets := []db.HashReset{
+		{ID: "1", Module: "plugin", Version: "v1.0.0", PreviousHash: "123", Hash: "456", Justification: "first", CreatedAt: now.Add(-time.Hour)},
+		{ID: "2", Module: "plugin", Version: "v1.0.0", PreviousHash: "456", Hash: "789", Justification: "second", CreatedAt: now},
+		{ID: "3", Module: "other", Version: "v1.0.0", PreviousHash: "123", Hash: "456", Justification: "other", CreatedAt: now},
+	}
+
+	for _, reset := range resets {
+		_, err := store.client.Collection(hashResetCollName).InsertOne(ctx, reset)
+		require.NoError(t, err)
+	}
+
+	got, err := store.ListHashResets(ctx, "plugin")
+	require.NoError(t, err)
+
+	for i := range got {
+		got[i].CreatedAt = got[i].CreatedAt.UTC()
+	}
+
+	assert.Equal(t, []db.HashReset{resets[1], resets[0]}, got)
+
+	got, err = store.ListHashResets(ctx, "unknown")
+	require.NoError(t, err)
+
+	assert.Empty(t, got)
+}
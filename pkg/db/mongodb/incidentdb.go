@@ -0,0 +1,164 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/traefik/plugin-service/pkg/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const incidentCollName = "incident"
+
+// CreateIncident records a new incident.
+func (m *MongoDB) CreateIncident(ctx context.Context, incident db.Incident) (db.Incident, error) {
+	ctx, span := m.tracer.Start(ctx, "db_create_incident")
+	defer span.End()
+
+	incident.ID = primitive.NewObjectID().Hex()
+	incident.CreatedAt = time.Now().Truncate(time.Millisecond)
+
+	if _, err := m.client.Collection(incidentCollName).InsertOne(ctx, incident); err != nil {
+		span.RecordError(err)
+
+		return db.Incident{}, fmt.Errorf("unable to create incident: %w", err)
+	}
+
+	return incident, nil
+}
+
+// ListIncidents lists the incidents of a plugin, the most recent first.
+func (m *MongoDB) ListIncidents(ctx context.Context, module string) ([]db.Incident, error) {
+	ctx, span := m.tracer.Start(ctx, "db_list_incidents")
+	defer span.End()
+
+	criteria := bson.D{
+		{Key: "module", Value: module},
+	}
+
+	opts := &options.FindOptions{}
+	opts.SetSort(bson.D{{Key: "createdAt", Value: -1}})
+
+	cursor, err := m.client.Collection(incidentCollName).Find(ctx, criteria, opts)
+	if err != nil {
+		span.RecordError(err)
+
+		return nil, fmt.Errorf("unable to find incidents: %w", err)
+	}
+
+	incidents := make([]db.Incident, 0)
+
+	if err = cursor.All(ctx, &incidents); err != nil {
+		span.RecordError(err)
+
+		return nil, fmt.Errorf("unable to unmarshal incidents: %w", err)
+	}
+
+	return incidents, nil
+}
+
+// CountIncidents counts the incidents of a kind of a plugin version created after since.
+func (m *MongoDB) CountIncidents(ctx context.Context, module, version, kind string, since time.Time) (int64, error) {
+	ctx, span := m.tracer.Start(ctx, "db_count_incidents")
+	defer span.End()
+
+	criteria := bson.D{
+		{Key: "module", Value: module},
+		{Key: "version", Value: version},
+		{Key: "kind", Value: kind},
+		{Key: "createdAt", Value: bson.D{{Key: "$gt", Value: since}}},
+	}
+
+	count, err := m.client.Collection(incidentCollName).CountDocuments(ctx, criteria)
+	if err != nil {
+		span.RecordError(err)
+
+		return 0, fmt.Errorf("unable to count incidents: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetLastIncident returns the most recent incident of a kind of a plugin version.
+func (m *MongoDB) GetLastIncident(ctx context.Context, module, version, kind string) (db.Incident, error) {
+	ctx, span := m.tracer.Start(ctx, "db_get_last_incident")
+	defer span.End()
+
+	criteria := bson.D{
+		{Key: "module", Value: module},
+		{Key: "version", Value: version},
+		{Key: "kind", Value: kind},
+	}
+
+	opts := &options.FindOneOptions{}
+	opts.SetSort(bson.D{{Key: "createdAt", Value: -1}})
+
+	var incident db.Incident
+
+	if err := m.client.Collection(incidentCollName).FindOne(ctx, criteria, opts).Decode(&incident); err != nil {
+		span.RecordError(err)
+
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return db.Incident{}, db.NotFoundError{Err: err}
+		}
+
+		return db.Incident{}, fmt.Errorf("unable to get last incident: %w", err)
+	}
+
+	return incident, nil
+}
+
+// AggregateIncidents returns the incidents grouped by plugin, the plugins with the most incidents first.
+func (m *MongoDB) AggregateIncidents(ctx context.Context) ([]db.IncidentSummary, error) {
+	ctx, span := m.tracer.Start(ctx, "db_aggregate_incidents")
+	defer span.End()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "module", Value: "$module"}, {Key: "version", Value: "$version"}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "lastSeen", Value: bson.D{{Key: "$max", Value: "$createdAt"}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id.version", Value: 1}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$_id.module"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: "$count"}}},
+			{Key: "lastSeen", Value: bson.D{{Key: "$max", Value: "$lastSeen"}}},
+			{Key: "versions", Value: bson.D{{Key: "$push", Value: bson.D{
+				{Key: "version", Value: "$_id.version"},
+				{Key: "count", Value: "$count"},
+				{Key: "lastSeen", Value: "$lastSeen"},
+			}}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "module", Value: "$_id"},
+			{Key: "count", Value: 1},
+			{Key: "lastSeen", Value: 1},
+			{Key: "versions", Value: 1},
+		}}},
+	}
+
+	cursor, err := m.client.Collection(incidentCollName).Aggregate(ctx, pipeline)
+	if err != nil {
+		span.RecordError(err)
+
+		return nil, fmt.Errorf("unable to aggregate incidents: %w", err)
+	}
+
+	summaries := make([]db.IncidentSummary, 0)
+
+	if err = cursor.All(ctx, &summaries); err != nil {
+		span.RecordError(err)
+
+		return nil, fmt.Errorf("unable to unmarshal incidents: %w", err)
+	}
+
+	return summaries, nil
+}
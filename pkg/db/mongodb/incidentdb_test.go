@@ -0,0 +1,188 @@
+package mongodb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/plugin-service/pkg/db"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMongoDB_CreateIncident(t *testing.T) {
+	ctx := context.Background()
+	store, _ := createDatabase(t, nil)
+
+	incident := db.Incident{
+		Kind:     db.IncidentUpstream,
+		Module:   "plugin",
+		Version:  "v1.0.0",
+		Source:   "goproxy",
+		Expected: "123",
+		Received: "456",
+	}
+
+	got, err := store.CreateIncident(ctx, incident)
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, got.ID)
+	assert.False(t, got.CreatedAt.IsZero())
+
+	var stored db.Incident
+	err = store.client.Collection(incidentCollName).FindOne(ctx, bson.D{{Key: "id", Value: got.ID}}).Decode(&stored)
+	require.NoError(t, err)
+
+	stored.CreatedAt = stored.CreatedAt.UTC()
+	got.CreatedAt = got.CreatedAt.UTC()
+
+	assert.Equal(t, got, stored)
+}
+
+func TestMongoDB_ListIncidents(t *testing.T) {
+	ctx := context.Background()
+	store, _ := createDatabase(t, nil)
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	incidents := createIncidents(t, store, []db.Incident{
+		{ID: "1", Kind: db.IncidentClient, Module: "plugin", Version: "v1.0.0", Expected: "123", Received: "456", IP: "192.0.2.1", CreatedAt: now.Add(-time.Hour)},
+		{ID: "2", Kind: db.IncidentUpstream, Module: "plugin", Version: "v1.1.0", Source: "goproxy", Expected: "123", Received: "789", CreatedAt: now},
+		{ID: "3", Kind: db.IncidentClient, Module: "other", Version: "v1.0.0", Expected: "123", Received: "456", CreatedAt: now},
+	})
+
+	got, err := store.ListIncidents(ctx, "plugin")
+	require.NoError(t, err)
+
+	assert.Equal(t, []db.Incident{incidents[1], incidents[0]}, toUTCIncidents(got))
+
+	got, err = store.ListIncidents(ctx, "unknown")
+	require.NoError(t, err)
+
+	assert.Empty(t, got)
+}
+
+func TestMongoDB_CountIncidents(t *testing.T) {
+	ctx := context.Background()
+	store, _ := createDatabase(t, nil)
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	createIncidents(t, store, []db.Incident{
+		{ID: "1", Kind: db.IncidentClient, Module: "plugin", Version: "v1.0.0", CreatedAt: now.Add(-3 * time.Hour)},
+		{ID: "2", Kind: db.IncidentUpstream, Module: "plugin", Version: "v1.0.0", CreatedAt: now.Add(-2 * time.Hour)},
+		{ID: "3", Kind: db.IncidentClient, Module: "plugin", Version: "v1.1.0", CreatedAt: now.Add(-2 * time.Hour)},
+		{ID: "4", Kind: db.IncidentUpstream, Module: "plugin", Version: "v1.0.0", CreatedAt: now.Add(-time.Hour)},
+	})
+
+	got, err := store.CountIncidents(ctx, "plugin", "v1.0.0", db.IncidentUpstream, time.Time{})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(2), got)
+
+	got, err = store.CountIncidents(ctx, "plugin", "v1.0.0", db.IncidentClient, time.Time{})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(1), got)
+
+	// Since a hash reset.
+	got, err = store.CountIncidents(ctx, "plugin", "v1.0.0", db.IncidentUpstream, now.Add(-90*time.Minute))
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(1), got)
+
+	got, err = store.CountIncidents(ctx, "plugin", "v2.0.0", db.IncidentUpstream, time.Time{})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(0), got)
+}
+
+func TestMongoDB_GetLastIncident(t *testing.T) {
+	ctx := context.Background()
+	store, _ := createDatabase(t, nil)
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	createIncidents(t, store, []db.Incident{
+		{ID: "1", Kind: db.IncidentUpstream, Module: "plugin", Version: "v1.0.0", Received: "123", CreatedAt: now.Add(-2 * time.Hour)},
+		{ID: "2", Kind: db.IncidentUpstream, Module: "plugin", Version: "v1.0.0", Received: "456", CreatedAt: now.Add(-time.Hour)},
+		{ID: "3", Kind: db.IncidentClient, Module: "plugin", Version: "v1.0.0", Received: "789", CreatedAt: now},
+	})
+
+	got, err := store.GetLastIncident(ctx, "plugin", "v1.0.0", db.IncidentUpstream)
+	require.NoError(t, err)
+
+	assert.Equal(t, "2", got.ID)
+	assert.Equal(t, "456", got.Received)
+
+	_, err = store.GetLastIncident(ctx, "plugin", "v2.0.0", db.IncidentUpstream)
+	require.ErrorAs(t, err, &db.NotFoundError{})
+}
+
+func TestMongoDB_AggregateIncidents(t *testing.T) {
+	ctx := context.Background()
+	store, _ := createDatabase(t, nil)
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	createIncidents(t, store, []db.Incident{
+		{ID: "1", Kind: db.IncidentClient, Module: "plugin", Version: "v1.1.0", CreatedAt: now.Add(-2 * time.Hour)},
+		{ID: "2", Kind: db.IncidentClient, Module: "plugin", Version: "v1.0.0", CreatedAt: now.Add(-time.Hour)},
+		{ID: "3", Kind: db.IncidentUpstream, Module: "plugin", Version: "v1.1.0", CreatedAt: now},
+		{ID: "4", Kind: db.IncidentClient, Module: "other", Version: "v1.0.0", CreatedAt: now},
+	})
+
+	got, err := store.AggregateIncidents(ctx)
+	require.NoError(t, err)
+
+	for i := range got {
+		got[i].LastSeen = got[i].LastSeen.UTC()
+
+		for j := range got[i].Versions {
+			got[i].Versions[j].LastSeen = got[i].Versions[j].LastSeen.UTC()
+		}
+	}
+
+	want := []db.IncidentSummary{
+		{
+			Module:   "plugin",
+			Count:    3,
+			LastSeen: now,
+			Versions: []db.IncidentVersionSummary{
+				{Version: "v1.0.0", Count: 1, LastSeen: now.Add(-time.Hour)},
+				{Version: "v1.1.0", Count: 2, LastSeen: now},
+			},
+		},
+		{
+			Module:   "other",
+			Count:    1,
+			LastSeen: now,
+			Versions: []db.IncidentVersionSummary{
+				{Version: "v1.0.0", Count: 1, LastSeen: now},
+			},
+		},
+	}
+
+	assert.Equal(t, want, got)
+}
+
+func createIncidents(t *testing.T, store *MongoDB, incidents []db.Incident) []db.Incident {
+	t.Helper()
+
+	for _, incident := range incidents {
+		_, err := store.client.Collection(incidentCollName).InsertOne(context.Background(), incident)
+		require.NoError(t, err)
+	}
+
+	return incidents
+}
+
+// toUTCIncidents converts incident dates to UTC.
+func toUTCIncidents(incidents []db.Incident) []db.Incident {
+	for i := range incidents {
+		incidents[i].CreatedAt = incidents[i].CreatedAt.UTC()
+	}
+
+	return incidents
+}
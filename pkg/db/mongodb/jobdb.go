@@ -0,0 +1,266 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/traefik/plugin-service/pkg/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	jobCollName = "job"
+
+	// maxListedJobs is the maximum number of jobs returned by ListJobs.
+	maxListedJobs = 500
+)
+
+// CreateJob creates a pending job.
+func (m *MongoDB) CreateJob(ctx context.Context, job db.Job) (db.Job, error) {
+	ctx, span := m.tracer.Start(ctx, "db_create_job")
+	defer span.End()
+
+	now := time.Now().Truncate(time.Millisecond)
+
+	job.ID = primitive.NewObjectID().Hex()
+	job.Status = db.JobPending
+	job.Attempts = 0
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	if job.RunAt.IsZero() {
+		job.RunAt = now
+	}
+
+	if _, err := m.client.Collection(jobCollName).InsertOne(ctx, job); err != nil {
+		span.RecordError(err)
+
+		return db.Job{}, fmt.Errorf("unable to create job: %w", err)
+	}
+
+	return job, nil
+}
+
+// LeaseJob leases the next job ready to run: a pending job whose run time is reached, or a running job whose lease has expired.
+// The lease is atomic, a job is leased by a single worker at a time.
+func (m *MongoDB) LeaseJob(ctx context.Context, owner string, kinds []string, lease time.Duration) (db.Job, error) {
+	ctx, span := m.tracer.Start(ctx, "db_lease_job")
+	defer span.End()
+
+	now := time.Now().Truncate(time.Millisecond)
+
+	filter := bson.D{
+		{Key: "kind", Value: bson.D{{Key: "$in", Value: kinds}}},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "status", Value: db.JobPending}, {Key: "runAt", Value: bson.D{{Key: "$lte", Value: now}}}},
+			bson.D{{Key: "status", Value: db.JobRunning}, {Key: "leaseUntil", Value: bson.D{{Key: "$lte", Value: now}}}},
+		}},
+	}
+
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "status", Value: db.JobRunning},
+			{Key: "leaseOwner", Value: owner},
+			{Key: "leaseUntil", Value: now.Add(lease)},
+			{Key: "updatedAt", Value: now},
+		}},
+		{Key: "$inc", Value: bson.D{{Key: "attempts", Value: 1}}},
+	}
+
+	opts := &options.FindOneAndUpdateOptions{}
+	opts.SetSort(bson.D{{Key: "runAt", Value: 1}})
+	opts.SetReturnDocument(options.After)
+
+	var job db.Job
+
+	if err := m.client.Collection(jobCollName).FindOneAndUpdate(ctx, filter, update, opts).Decode(&job); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return db.Job{}, db.NotFoundError{Err: err}
+		}
+
+		span.RecordError(err)
+
+		return db.Job{}, fmt.Errorf("unable to lease job: %w", err)
+	}
+
+	return job, nil
+}
+
+// CompleteJob marks a leased job as succeeded.
+func (m *MongoDB) CompleteJob(ctx context.Context, id, owner string) (db.Job, error) {
+	ctx, span := m.tracer.Start(ctx, "db_complete_job")
+	defer span.End()
+
+	now := time.Now().Truncate(time.Millisecond)
+
+	set := bson.D{
+		{Key: "status", Value: db.JobSucceeded},
+		{Key: "error", Value: ""},
+		{Key: "updatedAt", Value: now},
+		{Key: "finishedAt", Value: now},
+	}
+
+	return m.updateLeasedJob(ctx, span, id, owner, set)
+}
+
+// FailJob records the failure of a leased job.
+// The job is retried at retryAt, or moved to the dead letters when dead is true.
+func (m *MongoDB) FailJob(ctx context.Context, id, owner, reason string, retryAt time.Time, dead bool) (db.Job, error) {
+	ctx, span := m.tracer.Start(ctx, "db_fail_job")
+	defer span.End()
+
+	now := time.Now().Truncate(time.Millisecond)
+
+	set := bson.D{
+		{Key: "status", Value: db.JobPending},
+		{Key: "error", Value: reason},
+		{Key: "runAt", Value: retryAt.Truncate(time.Millisecond)},
+		{Key: "updatedAt", Value: now},
+	}
+
+	if dead {
+		set = bson.D{
+			{Key: "status", Value: db.JobDead},
+			{Key: "error", Value: reason},
+			{Key: "updatedAt", Value: now},
+			{Key: "finishedAt", Value: now},
+		}
+	}
+
+	return m.updateLeasedJob(ctx, span, id, owner, set)
+}
+
+// updateLeasedJob updates a job, and releases its lease.
+// A job leased by another worker (the lease has expired) is not updated, and returns a db.ConflictError.
+func (m *MongoDB) updateLeasedJob(ctx context.Context, span trace.Span, id, owner string, set bson.D) (db.Job, error) {
+	filter := bson.D{
+		{Key: "id", Value: id},
+		{Key: "status", Value: db.JobRunning},
+		{Key: "leaseOwner", Value: owner},
+	}
+
+	update := bson.D{
+		{Key: "$set", Value: set},
+		{Key: "$unset", Value: bson.D{{Key: "leaseOwner", Value: ""}, {Key: "leaseUntil", Value: ""}}},
+	}
+
+	opts := &options.FindOneAndUpdateOptions{}
+	opts.SetReturnDocument(options.After)
+
+	var job db.Job
+
+	if err := m.client.Collection(jobCollName).FindOneAndUpdate(ctx, filter, update, opts).Decode(&job); err != nil {
+		span.RecordError(err)
+
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return db.Job{}, db.ConflictError{Err: fmt.Errorf("job %s is not leased by %s", id, owner)}
+		}
+
+		return db.Job{}, fmt.Errorf("unable to update job: %w", err)
+	}
+
+	return job, nil
+}
+
+// RetryJob moves a dead job back to the pending jobs, with new attempts.
+func (m *MongoDB) RetryJob(ctx context.Context, id string) (db.Job, error) {
+	ctx, span := m.tracer.Start(ctx, "db_retry_job")
+	defer span.End()
+
+	now := time.Now().Truncate(time.Millisecond)
+
+	filter := bson.D{
+		{Key: "id", Value: id},
+		{Key: "status", Value: db.JobDead},
+	}
+
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "status", Value: db.JobPending},
+			{Key: "attempts", Value: 0},
+			{Key: "runAt", Value: now},
+			{Key: "updatedAt", Value: now},
+		}},
+		{Key: "$unset", Value: bson.D{{Key: "finishedAt", Value: ""}}},
+	}
+
+	opts := &options.FindOneAndUpdateOptions{}
+	opts.SetReturnDocument(options.After)
+
+	var job db.Job
+
+	if err := m.client.Collection(jobCollName).FindOneAndUpdate(ctx, filter, update, opts).Decode(&job); err != nil {
+		span.RecordError(err)
+
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return db.Job{}, db.NotFoundError{Err: fmt.Errorf("dead job %s not found", id)}
+		}
+
+		return db.Job{}, fmt.Errorf("unable to retry job: %w", err)
+	}
+
+	return job, nil
+}
+
+// GetJob gets a job.
+func (m *MongoDB) GetJob(ctx context.Context, id string) (db.Job, error) {
+	ctx, span := m.tracer.Start(ctx, "db_get_job")
+	defer span.End()
+
+	var job db.Job
+
+	if err := m.client.Collection(jobCollName).FindOne(ctx, bson.D{{Key: "id", Value: id}}).Decode(&job); err != nil {
+		span.RecordError(err)
+
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return db.Job{}, db.NotFoundError{Err: err}
+		}
+
+		return db.Job{}, fmt.Errorf("unable to get job: %w", err)
+	}
+
+	return job, nil
+}
+
+// ListJobs lists the jobs matching the filter, the most recent first.
+func (m *MongoDB) ListJobs(ctx context.Context, filter db.JobFilter) ([]db.Job, error) {
+	ctx, span := m.tracer.Start(ctx, "db_list_jobs")
+	defer span.End()
+
+	criteria := bson.D{}
+
+	if filter.Module != "" {
+		criteria = append(criteria, bson.E{Key: "module", Value: filter.Module})
+	}
+
+	if filter.Status != "" {
+		criteria = append(criteria, bson.E{Key: "status", Value: filter.Status})
+	}
+
+	opts := &options.FindOptions{}
+	opts.SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	opts.SetLimit(maxListedJobs)
+
+	cursor, err := m.client.Collection(jobCollName).Find(ctx, criteria, opts)
+	if err != nil {
+		span.RecordError(err)
+
+		return nil, fmt.Errorf("unable to find jobs: %w", err)
+	}
+
+	jobs := make([]db.Job, 0)
+
+	if err = cursor.All(ctx, &jobs); err != nil {
+		span.RecordError(err)
+
+		return nil, fmt.Errorf("unable to unmarshal jobs: %w", err)
+	}
+
+	return jobs, nil
+}
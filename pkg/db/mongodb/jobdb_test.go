@@ -0,0 +1,181 @@
+package mongodb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+func TestMongoDB_CreateJob(t *testing.T) {
+	ctx := context.Background()
+	store, _ := createDatabase(t, nil)
+
+	got, err := store.CreateJob(ctx, db.Job{Kind: db.JobPrewarm, Module: "plugin", Version: "v1.0.0", MaxAttempts: 5})
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, got.ID)
+	assert.Equal(t, db.JobPending, got.Status)
+	assert.False(t, got.RunAt.IsZero())
+
+	stored, err := store.GetJob(ctx, got.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, got, toUTCJob(stored))
+
+	_, err = store.GetJob(ctx, "unknown")
+	require.ErrorAs(t, err, &db.NotFoundError{})
+}
+
+func TestMongoDB_LeaseJob(t *testing.T) {
+	ctx := context.Background()
+	store, _ := createDatabase(t, nil)
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	createJobs(t, store, []db.Job{
+		{ID: "1", Kind: db.JobPrewarm, Status: db.JobPending, RunAt: now.Add(time.Hour)},
+		{ID: "2", Kind: "other", Status: db.JobPending, RunAt: now.Add(-time.Hour)},
+		{ID: "3", Kind: db.JobPrewarm, Status: db.JobPending, RunAt: now.Add(-time.Minute)},
+		{ID: "4", Kind: db.JobPrewarm, Status: db.JobRunning, RunAt: now.Add(-2 * time.Hour), LeaseOwner: "crashed", LeaseUntil: now.Add(-time.Second)},
+		{ID: "5", Kind: db.JobPrewarm, Status: db.JobRunning, RunAt: now.Add(-3 * time.Hour), LeaseOwner: "alive", LeaseUntil: now.Add(time.Hour)},
+		{ID: "6", Kind: db.JobPrewarm, Status: db.JobDead, RunAt: now.Add(-4 * time.Hour)},
+	})
+
+	// The abandoned job first, then the pending job.
+	for _, id := range []string{"4", "3"} {
+		job, err := store.LeaseJob(ctx, "worker", []string{db.JobPrewarm}, time.Minute)
+		require.NoError(t, err)
+
+		assert.Equal(t, id, job.ID)
+		assert.Equal(t, db.JobRunning, job.Status)
+		assert.Equal(t, "worker", job.LeaseOwner)
+		assert.Equal(t, 1, job.Attempts)
+	}
+
+	_, err := store.LeaseJob(ctx, "worker", []string{db.JobPrewarm}, time.Minute)
+	require.ErrorAs(t, err, &db.NotFoundError{})
+}
+
+func TestMongoDB_CompleteJob(t *testing.T) {
+	ctx := context.Background()
+	store, _ := createDatabase(t, nil)
+
+	job, err := store.CreateJob(ctx, db.Job{Kind: db.JobPrewarm, Module: "plugin", Version: "v1.0.0", MaxAttempts: 5})
+	require.NoError(t, err)
+
+	_, err = store.LeaseJob(ctx, "worker", []string{db.JobPrewarm}, time.Minute)
+	require.NoError(t, err)
+
+	// Only the lease owner can complete the job.
+	_, err = store.CompleteJob(ctx, job.ID, "other")
+	require.ErrorAs(t, err, &db.ConflictError{})
+
+	got, err := store.CompleteJob(ctx, job.ID, "worker")
+	require.NoError(t, err)
+
+	assert.Equal(t, db.JobSucceeded, got.Status)
+	assert.Empty(t, got.LeaseOwner)
+	assert.NotNil(t, got.FinishedAt)
+}
+
+func TestMongoDB_FailJob(t *testing.T) {
+	ctx := context.Background()
+	store, _ := createDatabase(t, nil)
+
+	job, err := store.CreateJob(ctx, db.Job{Kind: db.JobPrewarm, Module: "plugin", Version: "v1.0.0", MaxAttempts: 2})
+	require.NoError(t, err)
+
+	_, err = store.LeaseJob(ctx, "worker", []string{db.JobPrewarm}, time.Minute)
+	require.NoError(t, err)
+
+	retryAt := time.Now().Add(time.Hour).UTC().Truncate(time.Millisecond)
+
+	got, err := store.FailJob(ctx, job.ID, "worker", "boom", retryAt, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, db.JobPending, got.Status)
+	assert.Equal(t, "boom", got.Error)
+	assert.Equal(t, retryAt, got.RunAt.UTC())
+
+	_, err = store.LeaseJob(ctx, "worker", []string{db.JobPrewarm}, time.Minute)
+	require.ErrorAs(t, err, &db.NotFoundError{})
+
+	createJobs(t, store, []db.Job{
+		{ID: "running", Kind: db.JobPrewarm, Status: db.JobRunning, Attempts: 2, MaxAttempts: 2, LeaseOwner: "worker", LeaseUntil: retryAt},
+	})
+
+	got, err = store.FailJob(ctx, "running", "worker", "boom", retryAt, true)
+	require.NoError(t, err)
+
+	assert.Equal(t, db.JobDead, got.Status)
+
+	// Dead jobs can be retried.
+	got, err = store.RetryJob(ctx, "running")
+	require.NoError(t, err)
+
+	assert.Equal(t, db.JobPending, got.Status)
+	assert.Equal(t, 0, got.Attempts)
+	assert.Nil(t, got.FinishedAt)
+
+	_, err = store.RetryJob(ctx, "running")
+	require.ErrorAs(t, err, &db.NotFoundError{})
+}
+
+func TestMongoDB_ListJobs(t *testing.T) {
+	ctx := context.Background()
+	store, _ := createDatabase(t, nil)
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	jobs := createJobs(t, store, []db.Job{
+		{ID: "1", Kind: db.JobPrewarm, Module: "plugin", Version: "v1.0.0", Status: db.JobSucceeded, CreatedAt: now.Add(-time.Hour)},
+		{ID: "2", Kind: db.JobPrewarm, Module: "plugin", Version: "v1.1.0", Status: db.JobDead, CreatedAt: now},
+		{ID: "3", Kind: db.JobPrewarm, Module: "other", Version: "v1.0.0", Status: db.JobDead, CreatedAt: now},
+	})
+
+	got, err := store.ListJobs(ctx, db.JobFilter{Module: "plugin"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []db.Job{jobs[1], jobs[0]}, toUTCJobs(got))
+
+	got, err = store.ListJobs(ctx, db.JobFilter{Module: "plugin", Status: db.JobDead})
+	require.NoError(t, err)
+
+	assert.Equal(t, []db.Job{jobs[1]}, toUTCJobs(got))
+}
+
+func createJobs(t *testing.T, store *MongoDB, jobs []db.Job) []db.Job {
+	t.Helper()
+
+	for _, job := range jobs {
+		_, err := store.client.Collection(jobCollName).InsertOne(context.Background(), job)
+		require.NoError(t, err)
+	}
+
+	return jobs
+}
+
+// toUTCJob converts job dates to UTC.
+func toUTCJob(job db.Job) db.Job {
+	job.RunAt = job.RunAt.UTC()
+	job.CreatedAt = job.CreatedAt.UTC()
+	job.UpdatedAt = job.UpdatedAt.UTC()
+
+	if !job.LeaseUntil.IsZero() {
+		job.LeaseUntil = job.LeaseUntil.UTC()
+	}
+
+	return job
+}
+
+func toUTCJobs(jobs []db.Job) []db.Job {
+	for i := range jobs {
+		jobs[i] = toUTCJob(jobs[i])
+	}
+
+	return jobs
+}
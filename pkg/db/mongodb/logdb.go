@@ -0,0 +1,113 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/traefik/plugin-service/pkg/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const logCollName = "log"
+
+// AppendLogRecord appends a record to the transparency log.
+// The records are never updated: appending a record at an existing index,
+// or replacing a record of a plugin version already replaced (same previous record), returns a db.ConflictError.
+func (m *MongoDB) AppendLogRecord(ctx context.Context, record db.LogRecord) (db.LogRecord, error) {
+	ctx, span := m.tracer.Start(ctx, "db_append_log_record")
+	defer span.End()
+
+	record.CreatedAt = time.Now().Truncate(time.Millisecond)
+
+	if _, err := m.client.Collection(logCollName).InsertOne(ctx, record); err != nil {
+		span.RecordError(err)
+
+		if mongo.IsDuplicateKeyError(err) {
+			return db.LogRecord{}, db.ConflictError{Err: err}
+		}
+
+		return db.LogRecord{}, fmt.Errorf("unable to append log record: %w", err)
+	}
+
+	return record, nil
+}
+
+// GetLogSize returns the number of records of the transparency log.
+func (m *MongoDB) GetLogSize(ctx context.Context) (int64, error) {
+	ctx, span := m.tracer.Start(ctx, "db_get_log_size")
+	defer span.End()
+
+	count, err := m.client.Collection(logCollName).CountDocuments(ctx, bson.D{})
+	if err != nil {
+		span.RecordError(err)
+
+		return 0, fmt.Errorf("unable to count log records: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetLogRecord returns the newest record of a plugin version.
+func (m *MongoDB) GetLogRecord(ctx context.Context, module, version string) (db.LogRecord, error) {
+	ctx, span := m.tracer.Start(ctx, "db_get_log_record")
+	defer span.End()
+
+	filter := bson.D{
+		{Key: "module", Value: module},
+		{Key: "version", Value: version},
+	}
+
+	opts := &options.FindOneOptions{}
+	opts.SetProjection(bson.D{{Key: "treeHashes", Value: 0}})
+	opts.SetSort(bson.D{{Key: "_id", Value: -1}})
+
+	var record db.LogRecord
+
+	if err := m.client.Collection(logCollName).FindOne(ctx, filter, opts).Decode(&record); err != nil {
+		span.RecordError(err)
+
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return db.LogRecord{}, db.NotFoundError{Err: err}
+		}
+
+		return db.LogRecord{}, fmt.Errorf("unable to get log record: %w", err)
+	}
+
+	return record, nil
+}
+
+// GetLogHashes returns the stored hashes of the transparency log Merkle tree at the given indexes.
+func (m *MongoDB) GetLogHashes(ctx context.Context, indexes []int64) ([]db.LogHash, error) {
+	ctx, span := m.tracer.Start(ctx, "db_get_log_hashes")
+	defer span.End()
+
+	match := bson.D{{Key: "treeHashes.index", Value: bson.D{{Key: "$in", Value: indexes}}}}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$unwind", Value: "$treeHashes"}},
+		{{Key: "$match", Value: match}},
+		{{Key: "$replaceRoot", Value: bson.D{{Key: "newRoot", Value: "$treeHashes"}}}},
+	}
+
+	cursor, err := m.client.Collection(logCollName).Aggregate(ctx, pipeline)
+	if err != nil {
+		span.RecordError(err)
+
+		return nil, fmt.Errorf("unable to get log hashes: %w", err)
+	}
+
+	hashes := make([]db.LogHash, 0, len(indexes))
+
+	if err = cursor.All(ctx, &hashes); err != nil {
+		span.RecordError(err)
+
+		return nil, fmt.Errorf("unable to unmarshal log hashes: %w", err)
+	}
+
+	return hashes, nil
+}
@@ -0,0 +1,108 @@
+package mongodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+func TestMongoDB_AppendLogRecord(t *testing.T) {
+	ctx := context.Background()
+	store, _ := createDatabase(t, nil)
+
+	record := db.LogRecord{
+		Index:      0,
+		Module:     "plugin",
+		Version:    "v1.0.0",
+		Hash:       "123",
+		TreeHashes: []db.LogHash{{Index: 0, Hash: []byte("leaf")}},
+	}
+
+	got, err := store.AppendLogRecord(ctx, record)
+	require.NoError(t, err)
+
+	assert.False(t, got.CreatedAt.IsZero())
+
+	size, err := store.GetLogSize(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), size)
+
+	// Same index.
+	_, err = store.AppendLogRecord(ctx, db.LogRecord{Index: 0, Module: "plugin", Version: "v1.1.0", Hash: "456"})
+	require.ErrorAs(t, err, &db.ConflictError{})
+
+	// Same plugin version.
+	_, err = store.AppendLogRecord(ctx, db.LogRecord{Index: 1, Module: "plugin", Version: "v1.0.0", Hash: "456"})
+	require.ErrorAs(t, err, &db.ConflictError{})
+
+	// Same plugin version, replacing the first record (hash reset).
+	previous := int64(0)
+
+	_, err = store.AppendLogRecord(ctx, db.LogRecord{Index: 1, Module: "plugin", Version: "v1.0.0", Hash: "456", Previous: &previous})
+	require.NoError(t, err)
+
+	_, err = store.AppendLogRecord(ctx, db.LogRecord{Index: 2, Module: "plugin", Version: "v1.0.0", Hash: "789", Previous: &previous})
+	require.ErrorAs(t, err, &db.ConflictError{})
+
+	size, err = store.GetLogSize(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), size)
+}
+
+func TestMongoDB_GetLogRecord(t *testing.T) {
+	ctx := context.Background()
+	store, _ := createDatabase(t, nil)
+
+	_, err := store.AppendLogRecord(ctx, db.LogRecord{
+		Index:      0,
+		Module:     "plugin",
+		Version:    "v1.0.0",
+		Hash:       "123",
+		TreeHashes: []db.LogHash{{Index: 0, Hash: []byte("leaf")}},
+	})
+	require.NoError(t, err)
+
+	got, err := store.GetLogRecord(ctx, "plugin", "v1.0.0")
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(0), got.Index)
+	assert.Equal(t, "123", got.Hash)
+	assert.Empty(t, got.TreeHashes)
+
+	previous := int64(0)
+
+	_, err = store.AppendLogRecord(ctx, db.LogRecord{Index: 1, Module: "plugin", Version: "v1.0.0", Hash: "456", Previous: &previous})
+	require.NoError(t, err)
+
+	got, err = store.GetLogRecord(ctx, "plugin", "v1.0.0")
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(1), got.Index)
+	assert.Equal(t, "456", got.Hash)
+
+	_, err = store.GetLogRecord(ctx, "plugin", "v2.0.0")
+	require.ErrorAs(t, err, &db.NotFoundError{})
+}
+
+func TestMongoDB_GetLogHashes(t *testing.T) {
+	ctx := context.Background()
+	store, _ := createDatabase(t, nil)
+
+	records := []db.LogRecord{
+		{Index: 0, Module: "plugin", Version: "v1.0.0", Hash: "123", TreeHashes: []db.LogHash{{Index: 0, Hash: []byte("a")}}},
+		{Index: 1, Module: "plugin", Version: "v1.1.0", Hash: "456", TreeHashes: []db.LogHash{{Index: 1, Hash: []byte("b")}, {Index: 2, Hash: []byte("ab")}}},
+	}
+
+	for _, record := range records {
+		_, err := store.AppendLogRecord(ctx, record)
+		require.NoError(t, err)
+	}
+
+	got, err := store.GetLogHashes(ctx, []int64{0, 2})
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []db.LogHash{{Index: 0, Hash: []byte("a")}, {Index: 2, Hash: []byte("ab")}}, got)
+}
@@ -177,6 +177,37 @@ func (m *MongoDB) List(ctx context.Context, page db.Pagination) ([]db.Plugin, st
 	return plugins, nextPage, nil
 }
 
+// ListAll lists all the enabled plugins, hidden ones included, without their readme and hashes.
+func (m *MongoDB) ListAll(ctx context.Context) ([]db.Plugin, error) {
+	ctx, span := m.tracer.Start(ctx, "db_list_all")
+	defer span.End()
+
+	criteria := bson.D{
+		{Key: "disabled", Value: bson.D{{Key: "$in", Value: bson.A{false, nil}}}},
+	}
+
+	opts := &options.FindOptions{}
+	opts.SetProjection(bson.D{{Key: "hashes", Value: 0}, {Key: "readme", Value: 0}})
+	opts.SetSort(bson.D{{Key: "_id", Value: 1}})
+
+	cursor, err := m.client.Collection(m.collName).Find(ctx, criteria, opts)
+	if err != nil {
+		span.RecordError(err)
+
+		return nil, fmt.Errorf("unable to find plugins: %w", err)
+	}
+
+	plugins := make([]db.Plugin, 0)
+
+	if err = cursor.All(ctx, &plugins); err != nil {
+		span.RecordError(err)
+
+		return nil, fmt.Errorf("unable to unmarshal plugins: %w", err)
+	}
+
+	return plugins, nil
+}
+
 // GetByName gets the plugin with the given name.
 func (m *MongoDB) GetByName(ctx context.Context, name string, filterDisabled, filterHidden bool) (db.Plugin, error) {
 	ctx, span := m.tracer.Start(ctx, "db_get_by_name")
@@ -300,8 +331,36 @@ func (m *MongoDB) Update(ctx context.Context, id string, plugin db.Plugin) (db.P
 		{Key: "id", Value: id},
 	}
 
-	update := bson.D{
-		{Key: "$set", Value: plugin},
+	fields, err := literalFields(plugin)
+	if err != nil {
+		span.RecordError(err)
+
+		return db.Plugin{}, fmt.Errorf("unable to update plugin: %w", err)
+	}
+
+	// The hashes are named after the plugin, they follow a rename (i.e. a transferred repository)
+	// so that the recorded hashes are still checked against the new module name.
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: fields}},
+		{{Key: "$set", Value: bson.D{
+			{Key: "hashes", Value: bson.D{
+				{Key: "$cond", Value: bson.A{
+					bson.D{{Key: "$isArray", Value: "$hashes"}},
+					bson.D{{Key: "$map", Value: bson.D{
+						{Key: "input", Value: "$hashes"},
+						{Key: "as", Value: "h"},
+						{Key: "in", Value: bson.D{{Key: "$mergeObjects", Value: bson.A{
+							"$$h",
+							bson.D{{Key: "name", Value: bson.D{{Key: "$concat", Value: bson.A{
+								"$name", "@",
+								bson.D{{Key: "$arrayElemAt", Value: bson.A{bson.D{{Key: "$split", Value: bson.A{"$$h.name", "@"}}}, -1}}},
+							}}}}},
+						}}}},
+					}}},
+					"$hashes",
+				}},
+			}},
+		}}},
 	}
 
 	opts := &options.FindOneAndUpdateOptions{}
@@ -320,18 +379,45 @@ func (m *MongoDB) Update(ctx context.Context, id string, plugin db.Plugin) (db.P
 	return updated, nil
 }
 
+// literalFields returns the fields of the plugin as the values of an update pipeline stage.
+// The values are wrapped in $literal so that strings starting with a "$" aren't read as field paths.
+func literalFields(plugin db.Plugin) (bson.D, error) {
+	raw, err := bson.Marshal(plugin)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc bson.D
+	if err = bson.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+
+	fields := make(bson.D, 0, len(doc))
+	for _, elem := range doc {
+		fields = append(fields, bson.E{Key: elem.Key, Value: bson.D{{Key: "$literal", Value: elem.Value}}})
+	}
+
+	return fields, nil
+}
+
 // CreateHash creates a new plugin hash.
+// A hash is pinned once: a db.ConflictError is returned if the plugin version already has a hash.
 func (m *MongoDB) CreateHash(ctx context.Context, module, version, hash string) (db.PluginHash, error) {
 	ctx, span := m.tracer.Start(ctx, "db_create_hash")
 	defer span.End()
 
-	filter := bson.D{
-		{Key: "name", Value: module},
-	}
+	createdAt := time.Now().Truncate(time.Millisecond)
 
 	newHash := db.PluginHash{
-		Name: module + "@" + version,
-		Hash: hash,
+		Name:      module + "@" + version,
+		Hash:      hash,
+		Digests:   map[string]string{db.DigestSHA256: hash},
+		CreatedAt: &createdAt,
+	}
+
+	filter := bson.D{
+		{Key: "name", Value: module},
+		{Key: "hashes.name", Value: bson.D{{Key: "$ne", Value: newHash.Name}}},
 	}
 
 	update := bson.D{
@@ -352,7 +438,7 @@ func (m *MongoDB) CreateHash(ctx context.Context, module, version, hash string)
 		span.RecordError(err)
 
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return db.PluginHash{}, db.NotFoundError{Err: err}
+			return db.PluginHash{}, m.createHashError(ctx, module, newHash.Name, err)
 		}
 
 		return db.PluginHash{}, fmt.Errorf("unable to create plugin hash: %w", err)
@@ -361,8 +447,23 @@ func (m *MongoDB) CreateHash(ctx context.Context, module, version, hash string)
 	return newHash, nil
 }
 
-// UpdateHashVerified updates the verified value for a plugin hash.
-func (m *MongoDB) UpdateHashVerified(ctx context.Context, module, version, hash string, verified bool) (db.PluginHash, error) {
+// createHashError returns the error of a hash creation matching no plugin:
+// a db.ConflictError if the plugin exists, the hash has been created meanwhile, or a db.NotFoundError.
+func (m *MongoDB) createHashError(ctx context.Context, module, name string, err error) error {
+	count, errCount := m.client.Collection(collName).CountDocuments(ctx, bson.D{{Key: "name", Value: module}}, options.Count().SetLimit(1))
+	if errCount != nil {
+		return fmt.Errorf("unable to create plugin hash: %w", errCount)
+	}
+
+	if count > 0 {
+		return db.ConflictError{Err: fmt.Errorf("plugin hash %s already exists", name)}
+	}
+
+	return db.NotFoundError{Err: err}
+}
+
+// UpdateHashVerified updates the verified value, and the reasons of a failed verification, for a plugin hash.
+func (m *MongoDB) UpdateHashVerified(ctx context.Context, module, version, hash string, verified bool, reasons []string) (db.PluginHash, error) {
 	ctx, span := m.tracer.Start(ctx, "db_update_hash")
 	defer span.End()
 
@@ -376,6 +477,7 @@ func (m *MongoDB) UpdateHashVerified(ctx context.Context, module, version, hash
 		Name:     module + "@" + version,
 		Hash:     hash,
 		Verified: &verified,
+		Reasons:  reasons,
 	}
 
 	update := bson.D{
@@ -383,6 +485,7 @@ func (m *MongoDB) UpdateHashVerified(ctx context.Context, module, version, hash
 			Key: "$set",
 			Value: bson.D{
 				{Key: "hashes.$.verified", Value: &verified},
+				{Key: "hashes.$.reasons", Value: reasons},
 			},
 		},
 	}
@@ -404,6 +507,243 @@ func (m *MongoDB) UpdateHashVerified(ctx context.Context, module, version, hash
 	return updatedHash, nil
 }
 
+// UpdateHashManifest updates the manifest of a plugin hash.
+func (m *MongoDB) UpdateHashManifest(ctx context.Context, module, version string, manifest db.Manifest) (db.PluginHash, error) {
+	ctx, span := m.tracer.Start(ctx, "db_update_hash_manifest")
+	defer span.End()
+
+	return m.updateHash(ctx, span, module, version, bson.D{{Key: "hashes.$.manifest", Value: manifest}})
+}
+
+// UpdateHashCapabilities updates the capability report of a plugin hash.
+func (m *MongoDB) UpdateHashCapabilities(ctx context.Context, module, version string, report db.CapabilityReport) (db.PluginHash, error) {
+	ctx, span := m.tracer.Start(ctx, "db_update_hash_capabilities")
+	defer span.End()
+
+	return m.updateHash(ctx, span, module, version, bson.D{{Key: "hashes.$.capabilities", Value: report}})
+}
+
+// UpdateHashQuarantined updates the quarantine status of a plugin hash.
+func (m *MongoDB) UpdateHashQuarantined(ctx context.Context, module, version string, quarantined bool) (db.PluginHash, error) {
+	ctx, span := m.tracer.Start(ctx, "db_update_hash_quarantined")
+	defer span.End()
+
+	return m.updateHash(ctx, span, module, version, bson.D{{Key: "hashes.$.quarantined", Value: quarantined}})
+}
+
+// UpdateHashSigner updates the signer of a plugin hash.
+func (m *MongoDB) UpdateHashSigner(ctx context.Context, module, version, signer string) (db.PluginHash, error) {
+	ctx, span := m.tracer.Start(ctx, "db_update_hash_signer")
+	defer span.End()
+
+	return m.updateHash(ctx, span, module, version, bson.D{{Key: "hashes.$.signer", Value: signer}})
+}
+
+// ResetHash replaces the hash of a plugin version, and clears everything recorded about the previous archive.
+func (m *MongoDB) ResetHash(ctx context.Context, module, version, hash string) (db.PluginHash, error) {
+	ctx, span := m.tracer.Start(ctx, "db_reset_hash")
+	defer span.End()
+
+	set := bson.D{
+		{Key: "hashes.$.resetAt", Value: time.Now().Truncate(time.Millisecond)},
+		{Key: "hashes.$.hash", Value: hash},
+		{Key: "hashes.$.digests", Value: map[string]string{db.DigestSHA256: hash}},
+		{Key: "hashes.$.verified", Value: nil},
+		{Key: "hashes.$.reasons", Value: nil},
+		{Key: "hashes.$.manifest", Value: nil},
+		{Key: "hashes.$.capabilities", Value: nil},
+		{Key: "hashes.$.quarantined", Value: false},
+		{Key: "hashes.$.signer", Value: ""},
+	}
+
+	return m.updateHash(ctx, span, module, version, set)
+}
+
+// UpdateHashDigests updates the digests of a plugin hash.
+func (m *MongoDB) UpdateHashDigests(ctx context.Context, module, version string, digests map[string]string) (db.PluginHash, error) {
+	ctx, span := m.tracer.Start(ctx, "db_update_hash_digests")
+	defer span.End()
+
+	return m.updateHash(ctx, span, module, version, bson.D{{Key: "hashes.$.digests", Value: digests}})
+}
+
+// MigrateHashDigests sets the SHA-256 digest of the hashes recorded before the digests, and returns the number of updated plugins.
+// The other digests require the archives, they are computed when the archives are verified again.
+func (m *MongoDB) MigrateHashDigests(ctx context.Context) (int64, error) {
+	ctx, span := m.tracer.Start(ctx, "db_migrate_hash_digests")
+	defer span.End()
+
+	filter := bson.D{
+		{Key: "hashes", Value: bson.D{
+			{Key: "$elemMatch", Value: bson.D{
+				{Key: "digests", Value: bson.D{{Key: "$exists", Value: false}}},
+			}},
+		}},
+	}
+
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.D{
+			{Key: "hashes", Value: bson.D{
+				{Key: "$map", Value: bson.D{
+					{Key: "input", Value: "$hashes"},
+					{Key: "as", Value: "h"},
+					{Key: "in", Value: bson.D{
+						{Key: "$cond", Value: bson.A{
+							bson.D{{Key: "$ifNull", Value: bson.A{"$$h.digests", false}}},
+							"$$h",
+							bson.D{{Key: "$mergeObjects", Value: bson.A{
+								"$$h",
+								bson.D{{Key: "digests", Value: bson.D{{Key: db.DigestSHA256, Value: "$$h.hash"}}}},
+							}}},
+						}},
+					}},
+				}},
+			}},
+		}}},
+	}
+
+	result, err := m.client.Collection(collName).UpdateMany(ctx, filter, update)
+	if err != nil {
+		span.RecordError(err)
+
+		return 0, fmt.Errorf("unable to migrate plugin hash digests: %w", err)
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// UpdateUseUnsafe updates the useUnsafe flag of a plugin.
+func (m *MongoDB) UpdateUseUnsafe(ctx context.Context, id string, useUnsafe bool) (db.Plugin, error) {
+	ctx, span := m.tracer.Start(ctx, "db_update_use_unsafe")
+	defer span.End()
+
+	filter := bson.D{
+		{Key: "id", Value: id},
+	}
+
+	update := bson.D{
+		{Key: "$set", Value: bson.D{{Key: "useUnsafe", Value: useUnsafe}}},
+	}
+
+	opts := &options.FindOneAndUpdateOptions{}
+	opts.SetReturnDocument(options.After)
+	opts.SetProjection(bson.D{{Key: "hashes", Value: 0}})
+
+	var updated db.Plugin
+	if err := m.client.Collection(collName).FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated); err != nil {
+		span.RecordError(err)
+
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return db.Plugin{}, db.NotFoundError{Err: err}
+		}
+
+		return db.Plugin{}, fmt.Errorf("unable to update plugin: %w", err)
+	}
+
+	return updated, nil
+}
+
+// UpdateRepositoryMetadata updates the metadata of the GitHub repository of a plugin.
+func (m *MongoDB) UpdateRepositoryMetadata(ctx context.Context, id string, metadata db.RepositoryMetadata) (db.Plugin, error) {
+	ctx, span := m.tracer.Start(ctx, "db_update_repository_metadata")
+	defer span.End()
+
+	filter := bson.D{
+		{Key: "id", Value: id},
+	}
+
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "stars", Value: metadata.Stars},
+			{Key: "topics", Value: metadata.Topics},
+			{Key: "archived", Value: metadata.Archived},
+			{Key: "license", Value: metadata.License},
+			{Key: "repositoryETag", Value: metadata.ETag},
+		}},
+	}
+
+	opts := &options.FindOneAndUpdateOptions{}
+	opts.SetReturnDocument(options.After)
+	opts.SetProjection(bson.D{{Key: "hashes", Value: 0}})
+
+	var updated db.Plugin
+	if err := m.client.Collection(collName).FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated); err != nil {
+		span.RecordError(err)
+
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return db.Plugin{}, db.NotFoundError{Err: err}
+		}
+
+		return db.Plugin{}, fmt.Errorf("unable to update plugin: %w", err)
+	}
+
+	return updated, nil
+}
+
+// updateHash applies the given $set operations to the hash of a plugin version.
+func (m *MongoDB) updateHash(ctx context.Context, span trace.Span, module, version string, set bson.D) (db.PluginHash, error) {
+	filter := bson.D{
+		{Key: "name", Value: module},
+		{Key: "hashes.name", Value: module + "@" + version},
+	}
+
+	update := bson.D{
+		{Key: "$set", Value: set},
+	}
+
+	opts := &options.FindOneAndUpdateOptions{}
+	opts.SetReturnDocument(options.After)
+
+	var updated pluginDocument
+	if err := m.client.Collection(collName).FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated); err != nil {
+		span.RecordError(err)
+
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return db.PluginHash{}, db.NotFoundError{Err: err}
+		}
+
+		return db.PluginHash{}, fmt.Errorf("unable to update plugin hash: %w", err)
+	}
+
+	for _, hash := range updated.Hashes {
+		if hash.Name == module+"@"+version {
+			return hash, nil
+		}
+	}
+
+	return db.PluginHash{}, errors.New("unable to find plugin hash")
+}
+
+// ListHashes returns all the hashes of a plugin.
+func (m *MongoDB) ListHashes(ctx context.Context, module string) ([]db.PluginHash, error) {
+	ctx, span := m.tracer.Start(ctx, "db_list_hashes")
+	defer span.End()
+
+	filter := bson.D{
+		{Key: "name", Value: module},
+	}
+
+	opts := &options.FindOneOptions{}
+	opts.SetProjection(bson.D{
+		{Key: "hashes", Value: 1},
+		{Key: "_id", Value: 0},
+	})
+
+	var plugin pluginDocument
+
+	if err := m.client.Collection(collName).FindOne(ctx, filter, opts).Decode(&plugin); err != nil {
+		span.RecordError(err)
+
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, db.NotFoundError{Err: err}
+		}
+
+		return nil, fmt.Errorf("unable to list plugin hashes: %w", err)
+	}
+
+	return plugin.Hashes, nil
+}
+
 // GetHashByName returns the hash corresponding the given name.
 func (m *MongoDB) GetHashByName(ctx context.Context, module, version string) (db.PluginHash, error) {
 	ctx, span := m.tracer.Start(ctx, "db_create_hash")
@@ -181,6 +181,48 @@ func TestMongoDB_List(t *testing.T) {
 	assert.Empty(t, next)
 }
 
+func TestMongoDB_ListAll(t *testing.T) {
+	ctx := context.Background()
+	store, fixtures := createDatabase(t, []fixture{
+		{
+			key: "first",
+			plugin: pluginDocument{
+				Plugin: db.Plugin{ID: "123", Name: "first", Versions: []string{"v1.0.0"}},
+			},
+		},
+		{
+			key: "hidden",
+			plugin: pluginDocument{
+				Plugin: db.Plugin{ID: "234", Name: "hidden", Hidden: true},
+			},
+		},
+		{
+			key: "readme",
+			plugin: pluginDocument{
+				Plugin: db.Plugin{ID: "456", Name: "readme", Readme: "# Readme"},
+			},
+		},
+		{
+			key: "disabled",
+			plugin: pluginDocument{
+				Plugin: db.Plugin{ID: "789", Name: "disabled", Disabled: true},
+			},
+		},
+	})
+
+	plugins, err := store.ListAll(ctx)
+	require.NoError(t, err)
+
+	readme := fixtures["readme"].Plugin
+	readme.Readme = ""
+
+	assert.Equal(t, []db.Plugin{
+		fixtures["first"].Plugin,
+		fixtures["hidden"].Plugin,
+		readme,
+	}, plugins)
+}
+
 func TestMongoDB_GetByName(t *testing.T) {
 	ctx := context.Background()
 	store, fixtures := createDatabase(t, []fixture{
@@ -688,7 +730,7 @@ func TestMongoDB_Update(t *testing.T) {
 
 	assert.Equal(t, want, got)
 
-	// Check hashes are not updated
+	// Check hashes follow the new name
 	var pluginWithHashes pluginDocument
 
 	err = store.client.Collection(store.collName).
@@ -696,7 +738,7 @@ func TestMongoDB_Update(t *testing.T) {
 		Decode(&pluginWithHashes)
 	require.NoError(t, err)
 
-	assert.Equal(t, fixtures["plugin"].Hashes, pluginWithHashes.Hashes)
+	assert.Equal(t, []db.PluginHash{{Name: "New Name@v1.1.1", Hash: "123"}}, pluginWithHashes.Hashes)
 
 	// Update with same values
 	got, err = store.Update(ctx, "123", got)
@@ -752,19 +794,31 @@ func TestMongoDB_CreateHash(t *testing.T) {
 		Decode(&pluginWithHashes)
 	require.NoError(t, err)
 
+	require.Len(t, pluginWithHashes.Hashes, 2)
+	require.NotNil(t, pluginWithHashes.Hashes[1].CreatedAt)
+
+	createdAt := pluginWithHashes.Hashes[1].CreatedAt
+
 	want := []db.PluginHash{
 		fixtures["plugin"].Hashes[0],
 		{
-			Name: "plugin@v1.2.3",
-			Hash: "hash",
+			Name:      "plugin@v1.2.3",
+			Hash:      "hash",
+			Digests:   map[string]string{db.DigestSHA256: "hash"},
+			CreatedAt: createdAt,
 		},
 	}
 
 	assert.Equal(t, want, pluginWithHashes.Hashes)
+	assert.WithinDuration(t, time.Now(), *createdAt, time.Minute)
 
 	// With embedded hashes, creating a new one doesn't works if the plugin doesn't exists.
 	_, err = store.CreateHash(ctx, "toto", "v1.2.3", "hash")
 	require.ErrorAs(t, err, &db.NotFoundError{})
+
+	// A hash is pinned once.
+	_, err = store.CreateHash(ctx, "plugin", "v1.2.3", "other")
+	require.ErrorAs(t, err, &db.ConflictError{})
 }
 
 func TestMongoDB_UpdateHashVerified(t *testing.T) {
@@ -802,10 +856,10 @@ func TestMongoDB_UpdateHashVerified(t *testing.T) {
 		},
 	})
 
-	_, err := store.UpdateHashVerified(ctx, "plugin", "v1.1.1", "non-existing", true)
+	_, err := store.UpdateHashVerified(ctx, "plugin", "v1.1.1", "non-existing", true, nil)
 	require.Error(t, err)
 
-	got, err := store.UpdateHashVerified(ctx, "plugin", "v1.1.1", "123", true)
+	got, err := store.UpdateHashVerified(ctx, "plugin", "v1.1.1", "123", true, nil)
 	require.NoError(t, err)
 
 	want := fixtures["plugin"].Hashes[0]
@@ -813,7 +867,7 @@ func TestMongoDB_UpdateHashVerified(t *testing.T) {
 
 	assert.Equal(t, want, got)
 
-	got, err = store.UpdateHashVerified(ctx, "plugin", "v1.1.2", "123", true)
+	got, err = store.UpdateHashVerified(ctx, "plugin", "v1.1.2", "123", true, nil)
 	require.NoError(t, err)
 
 	want = fixtures["plugin"].Hashes[1]
@@ -821,11 +875,12 @@ func TestMongoDB_UpdateHashVerified(t *testing.T) {
 
 	assert.Equal(t, want, got)
 
-	got, err = store.UpdateHashVerified(ctx, "plugin", "v1.1.3", "123", false)
+	got, err = store.UpdateHashVerified(ctx, "plugin", "v1.1.3", "123", false, []string{"missing manifest: .traefik.yml"})
 	require.NoError(t, err)
 
 	want = fixtures["plugin"].Hashes[2]
 	want.Verified = github.Ptr(false)
+	want.Reasons = []string{"missing manifest: .traefik.yml"}
 
 	assert.Equal(t, want, got)
 
@@ -839,7 +894,7 @@ func TestMongoDB_UpdateHashVerified(t *testing.T) {
 	wantHashes := []db.PluginHash{
 		{Name: "plugin@v1.1.1", Hash: "123", Verified: github.Ptr(true)},
 		{Name: "plugin@v1.1.2", Hash: "123", Verified: github.Ptr(true)},
-		{Name: "plugin@v1.1.3", Hash: "123", Verified: github.Ptr(false)},
+		{Name: "plugin@v1.1.3", Hash: "123", Verified: github.Ptr(false), Reasons: []string{"missing manifest: .traefik.yml"}},
 	}
 	assert.Equal(t, wantHashes, pluginWithHashes.Hashes)
 }
@@ -896,6 +951,381 @@ func TestMongoDB_GetHashByName(t *testing.T) {
 	require.ErrorAs(t, err, &db.NotFoundError{})
 }
 
+func TestMongoDB_UpdateHashManifest(t *testing.T) {
+	ctx := context.Background()
+
+	store, fixtures := createDatabase(t, []fixture{
+		{
+			key: "plugin",
+			plugin: pluginDocument{
+				Plugin: db.Plugin{
+					ID:            "123",
+					Name:          "plugin",
+					LatestVersion: "v1.1.2",
+					Versions:      []string{"v1.1.2", "v1.1.1"},
+				},
+				Hashes: []db.PluginHash{
+					{Name: "plugin@v1.1.1", Hash: "123"},
+					{Name: "plugin@v1.1.2", Hash: "456"},
+				},
+			},
+		},
+	})
+
+	manifest := db.Manifest{
+		DisplayName: "Plugin",
+		Type:        "middleware",
+		Import:      "plugin",
+		TestData:    map[string]interface{}{"foo": "bar"},
+	}
+
+	got, err := store.UpdateHashManifest(ctx, "plugin", "v1.1.2", manifest)
+	require.NoError(t, err)
+
+	want := fixtures["plugin"].Hashes[1]
+	want.Manifest = &manifest
+
+	assert.Equal(t, want, got)
+
+	hashes, err := store.ListHashes(ctx, "plugin")
+	require.NoError(t, err)
+
+	assert.Equal(t, []db.PluginHash{fixtures["plugin"].Hashes[0], want}, hashes)
+
+	// Check non existing version
+	_, err = store.UpdateHashManifest(ctx, "plugin", "v1.1.4", manifest)
+	require.ErrorAs(t, err, &db.NotFoundError{})
+}
+
+func TestMongoDB_UpdateHashCapabilities(t *testing.T) {
+	ctx := context.Background()
+
+	store, fixtures := createDatabase(t, []fixture{
+		{
+			key: "plugin",
+			plugin: pluginDocument{
+				Plugin: db.Plugin{ID: "123", Name: "plugin"},
+				Hashes: []db.PluginHash{
+					{Name: "plugin@v1.1.1", Hash: "123"},
+				},
+			},
+		},
+	})
+
+	report := db.CapabilityReport{
+		Capabilities: []string{db.CapabilityUnsafe},
+		Findings: []db.Finding{
+			{Capability: db.CapabilityUnsafe, File: "demo.go", Line: 3, Detail: "import unsafe"},
+		},
+	}
+
+	got, err := store.UpdateHashCapabilities(ctx, "plugin", "v1.1.1", report)
+	require.NoError(t, err)
+
+	want := fixtures["plugin"].Hashes[0]
+	want.Capabilities = &report
+
+	assert.Equal(t, want, got)
+
+	// Check non existing version
+	_, err = store.UpdateHashCapabilities(ctx, "plugin", "v1.1.4", report)
+	require.ErrorAs(t, err, &db.NotFoundError{})
+}
+
+func TestMongoDB_UpdateHashQuarantined(t *testing.T) {
+	ctx := context.Background()
+
+	store, fixtures := createDatabase(t, []fixture{
+		{
+			key: "plugin",
+			plugin: pluginDocument{
+				Plugin: db.Plugin{ID: "123", Name: "plugin"},
+				Hashes: []db.PluginHash{
+					{Name: "plugin@v1.1.1", Hash: "123"},
+					{Name: "plugin@v1.1.2", Hash: "456"},
+				},
+			},
+		},
+	})
+
+	got, err := store.UpdateHashQuarantined(ctx, "plugin", "v1.1.2", true)
+	require.NoError(t, err)
+
+	want := fixtures["plugin"].Hashes[1]
+	want.Quarantined = true
+
+	assert.Equal(t, want, got)
+
+	hashes, err := store.ListHashes(ctx, "plugin")
+	require.NoError(t, err)
+
+	assert.Equal(t, []db.PluginHash{fixtures["plugin"].Hashes[0], want}, hashes)
+
+	// Check non existing version
+	_, err = store.UpdateHashQuarantined(ctx, "plugin", "v1.1.3", true)
+	require.ErrorAs(t, err, &db.NotFoundError{})
+}
+
+func TestMongoDB_UpdateHashSigner(t *testing.T) {
+	ctx := context.Background()
+
+	store, fixtures := createDatabase(t, []fixture{
+		{
+			key: "plugin",
+			plugin: pluginDocument{
+				Plugin: db.Plugin{ID: "123", Name: "plugin"},
+				Hashes: []db.PluginHash{
+					{Name: "plugin@v1.1.1", Hash: "123", Verified: github.Ptr(true)},
+				},
+			},
+		},
+	})
+
+	got, err := store.UpdateHashSigner(ctx, "plugin", "v1.1.1", "minisign:0807060504030201")
+	require.NoError(t, err)
+
+	want := fixtures["plugin"].Hashes[0]
+	want.Signer = "minisign:0807060504030201"
+
+	assert.Equal(t, want, got)
+
+	// Check non existing version
+	_, err = store.UpdateHashSigner(ctx, "plugin", "v1.1.3", "minisign:0807060504030201")
+	require.ErrorAs(t, err, &db.NotFoundError{})
+}
+
+func TestMongoDB_UpdateHashDigests(t *testing.T) {
+	ctx := context.Background()
+
+	store, fixtures := createDatabase(t, []fixture{
+		{
+			key: "plugin",
+			plugin: pluginDocument{
+				Plugin: db.Plugin{ID: "123", Name: "plugin"},
+				Hashes: []db.PluginHash{
+					{Name: "plugin@v1.1.1", Hash: "123", Digests: map[string]string{db.DigestSHA256: "123"}},
+				},
+			},
+		},
+	})
+
+	digests := map[string]string{db.DigestSHA256: "123", db.DigestH1: "AbC=", db.DigestSHA512: "456"}
+
+	got, err := store.UpdateHashDigests(ctx, "plugin", "v1.1.1", digests)
+	require.NoError(t, err)
+
+	want := fixtures["plugin"].Hashes[0]
+	want.Digests = digests
+
+	assert.Equal(t, want, got)
+
+	// Check non existing version
+	_, err = store.UpdateHashDigests(ctx, "plugin", "v1.1.3", digests)
+	require.ErrorAs(t, err, &db.NotFoundError{})
+}
+
+func TestMongoDB_ResetHash(t *testing.T) {
+	ctx := context.Background()
+
+	verified := true
+
+	store, _ := createDatabase(t, []fixture{
+		{
+			key: "plugin",
+			plugin: pluginDocument{
+				Plugin: db.Plugin{ID: "123", Name: "plugin"},
+				Hashes: []db.PluginHash{
+					{
+						Name:        "plugin@v1.1.1",
+						Hash:        "123",
+						Verified:    &verified,
+						Manifest:    &db.Manifest{DisplayName: "Plugin"},
+						Quarantined: true,
+						Signer:      "minisign:RWQ",
+						Digests:     map[string]string{db.DigestSHA256: "123", db.DigestSHA512: "456"},
+					},
+				},
+			},
+		},
+	})
+
+	got, err := store.ResetHash(ctx, "plugin", "v1.1.1", "789")
+	require.NoError(t, err)
+
+	want := db.PluginHash{
+		Name:    "plugin@v1.1.1",
+		Hash:    "789",
+		Digests: map[string]string{db.DigestSHA256: "789"},
+	}
+
+	assert.Equal(t, want, got)
+
+	// Check non existing version
+	_, err = store.ResetHash(ctx, "plugin", "v1.1.3", "789")
+	require.ErrorAs(t, err, &db.NotFoundError{})
+}
+
+func TestMongoDB_MigrateHashDigests(t *testing.T) {
+	ctx := context.Background()
+
+	store, _ := createDatabase(t, []fixture{
+		{
+			key: "legacy",
+			plugin: pluginDocument{
+				Plugin: db.Plugin{ID: "123", Name: "legacy"},
+				Hashes: []db.PluginHash{
+					{Name: "legacy@v1.0.0", Hash: "123"},
+					{Name: "legacy@v1.1.0", Hash: "456", Digests: map[string]string{db.DigestSHA256: "456", db.DigestH1: "AbC="}},
+				},
+			},
+		},
+		{
+			key: "migrated",
+			plugin: pluginDocument{
+				Plugin: db.Plugin{ID: "456", Name: "migrated"},
+				Hashes: []db.PluginHash{
+					{Name: "migrated@v1.0.0", Hash: "789", Digests: map[string]string{db.DigestSHA256: "789"}},
+				},
+			},
+		},
+	})
+
+	count, err := store.MigrateHashDigests(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(1), count)
+
+	hashes, err := store.ListHashes(ctx, "legacy")
+	require.NoError(t, err)
+
+	assert.Equal(t, []db.PluginHash{
+		{Name: "legacy@v1.0.0", Hash: "123", Digests: map[string]string{db.DigestSHA256: "123"}},
+		{Name: "legacy@v1.1.0", Hash: "456", Digests: map[string]string{db.DigestSHA256: "456", db.DigestH1: "AbC="}},
+	}, hashes)
+
+	// The migration is idempotent.
+	count, err = store.MigrateHashDigests(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(0), count)
+}
+
+func TestMongoDB_UpdateUseUnsafe(t *testing.T) {
+	ctx := context.Background()
+
+	store, fixtures := createDatabase(t, []fixture{
+		{
+			key: "plugin",
+			plugin: pluginDocument{
+				Plugin: db.Plugin{ID: "123", Name: "plugin", DisplayName: "Plugin", Stars: 10},
+				Hashes: []db.PluginHash{
+					{Name: "plugin@v1.1.1", Hash: "123"},
+				},
+			},
+		},
+	})
+
+	got, err := store.UpdateUseUnsafe(ctx, "123", true)
+	require.NoError(t, err)
+
+	want := fixtures["plugin"].Plugin
+	want.UseUnsafe = true
+
+	assert.Equal(t, want, toUTCPlugin(got))
+
+	stored, ok := getPlugin(t, store, "123")
+	require.True(t, ok)
+
+	assert.Equal(t, fixtures["plugin"].Hashes, stored.Hashes)
+
+	// Check non existing plugin
+	_, err = store.UpdateUseUnsafe(ctx, "456", true)
+	require.ErrorAs(t, err, &db.NotFoundError{})
+}
+
+func TestMongoDB_UpdateRepositoryMetadata(t *testing.T) {
+	ctx := context.Background()
+
+	store, fixtures := createDatabase(t, []fixture{
+		{
+			key: "plugin",
+			plugin: pluginDocument{
+				Plugin: db.Plugin{ID: "123", Name: "plugin", DisplayName: "Plugin", Stars: 10, Topics: []string{"traefik-plugin"}},
+				Hashes: []db.PluginHash{
+					{Name: "plugin@v1.1.1", Hash: "123"},
+				},
+			},
+		},
+	})
+
+	metadata := db.RepositoryMetadata{
+		Stars:    12,
+		Topics:   []string{"traefik-plugin", "middleware"},
+		Archived: true,
+		License:  "Apache-2.0",
+		ETag:     `W/"abc"`,
+	}
+
+	got, err := store.UpdateRepositoryMetadata(ctx, "123", metadata)
+	require.NoError(t, err)
+
+	want := fixtures["plugin"].Plugin
+	want.Stars = 12
+	want.Topics = []string{"traefik-plugin", "middleware"}
+	want.Archived = true
+	want.License = "Apache-2.0"
+	want.RepositoryETag = `W/"abc"`
+
+	assert.Equal(t, want, toUTCPlugin(got))
+
+	stored, ok := getPlugin(t, store, "123")
+	require.True(t, ok)
+
+	assert.Equal(t, fixtures["plugin"].Hashes, stored.Hashes)
+
+	// Check non existing plugin
+	_, err = store.UpdateRepositoryMetadata(ctx, "456", metadata)
+	require.ErrorAs(t, err, &db.NotFoundError{})
+}
+
+func TestMongoDB_ListHashes(t *testing.T) {
+	ctx := context.Background()
+
+	store, fixtures := createDatabase(t, []fixture{
+		{
+			key: "plugin",
+			plugin: pluginDocument{
+				Plugin: db.Plugin{ID: "123", Name: "plugin"},
+				Hashes: []db.PluginHash{
+					{Name: "plugin@v1.1.2", Hash: "123"},
+					{Name: "plugin@v1.1.1", Hash: "456", Verified: github.Ptr(false), Reasons: []string{"symlink: link"}},
+				},
+			},
+		},
+		{
+			key: "no-hashes",
+			plugin: pluginDocument{
+				Plugin: db.Plugin{ID: "456", Name: "no-hashes"},
+				Hashes: []db.PluginHash{},
+			},
+		},
+	})
+
+	got, err := store.ListHashes(ctx, "plugin")
+	require.NoError(t, err)
+
+	assert.Equal(t, fixtures["plugin"].Hashes, got)
+
+	got, err = store.ListHashes(ctx, "no-hashes")
+	require.NoError(t, err)
+
+	assert.Empty(t, got)
+
+	// Check non existing plugin
+	_, err = store.ListHashes(ctx, "toto")
+	require.ErrorAs(t, err, &db.NotFoundError{})
+}
+
 type fixture struct {
 	key    string
 	plugin pluginDocument
@@ -0,0 +1,206 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/traefik/plugin-service/pkg/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	subscriptionCollName = "subscription"
+	deliveryCollName     = "delivery"
+
+	// maxListedDeliveries is the maximum number of deliveries returned by ListDeliveries.
+	maxListedDeliveries = 500
+)
+
+// CreateSubscription creates a webhook subscription.
+func (m *MongoDB) CreateSubscription(ctx context.Context, subscription db.Subscription) (db.Subscription, error) {
+	ctx, span := m.tracer.Start(ctx, "db_create_subscription")
+	defer span.End()
+
+	subscription.ID = primitive.NewObjectID().Hex()
+	subscription.CreatedAt = time.Now().Truncate(time.Millisecond)
+
+	if _, err := m.client.Collection(subscriptionCollName).InsertOne(ctx, subscription); err != nil {
+		span.RecordError(err)
+
+		return db.Subscription{}, fmt.Errorf("unable to create subscription: %w", err)
+	}
+
+	return subscription, nil
+}
+
+// GetSubscription gets a webhook subscription.
+func (m *MongoDB) GetSubscription(ctx context.Context, id string) (db.Subscription, error) {
+	ctx, span := m.tracer.Start(ctx, "db_get_subscription")
+	defer span.End()
+
+	var subscription db.Subscription
+
+	if err := m.client.Collection(subscriptionCollName).FindOne(ctx, bson.D{{Key: "id", Value: id}}).Decode(&subscription); err != nil {
+		span.RecordError(err)
+
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return db.Subscription{}, db.NotFoundError{Err: err}
+		}
+
+		return db.Subscription{}, fmt.Errorf("unable to get subscription: %w", err)
+	}
+
+	return subscription, nil
+}
+
+// ListSubscriptions lists the webhook subscriptions, the oldest first.
+func (m *MongoDB) ListSubscriptions(ctx context.Context) ([]db.Subscription, error) {
+	ctx, span := m.tracer.Start(ctx, "db_list_subscriptions")
+	defer span.End()
+
+	opts := &options.FindOptions{}
+	opts.SetSort(bson.D{{Key: "createdAt", Value: 1}})
+
+	cursor, err := m.client.Collection(subscriptionCollName).Find(ctx, bson.D{}, opts)
+	if err != nil {
+		span.RecordError(err)
+
+		return nil, fmt.Errorf("unable to find subscriptions: %w", err)
+	}
+
+	subscriptions := make([]db.Subscription, 0)
+
+	if err = cursor.All(ctx, &subscriptions); err != nil {
+		span.RecordError(err)
+
+		return nil, fmt.Errorf("unable to decode subscriptions: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+// DeleteSubscription deletes a webhook subscription, its delivery history is kept until it expires.
+func (m *MongoDB) DeleteSubscription(ctx context.Context, id string) error {
+	ctx, span := m.tracer.Start(ctx, "db_delete_subscription")
+	defer span.End()
+
+	result, err := m.client.Collection(subscriptionCollName).DeleteOne(ctx, bson.D{{Key: "id", Value: id}})
+	if err != nil {
+		span.RecordError(err)
+
+		return fmt.Errorf("unable to delete subscription: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return db.NotFoundError{Err: fmt.Errorf("subscription %s not found", id)}
+	}
+
+	return nil
+}
+
+// CreateDelivery creates a pending webhook delivery.
+func (m *MongoDB) CreateDelivery(ctx context.Context, delivery db.Delivery) (db.Delivery, error) {
+	ctx, span := m.tracer.Start(ctx, "db_create_delivery")
+	defer span.End()
+
+	now := time.Now().Truncate(time.Millisecond)
+
+	delivery.ID = primitive.NewObjectID().Hex()
+	delivery.Status = db.DeliveryPending
+	delivery.Attempts = []db.DeliveryAttempt{}
+	delivery.CreatedAt = now
+	delivery.UpdatedAt = now
+
+	if _, err := m.client.Collection(deliveryCollName).InsertOne(ctx, delivery); err != nil {
+		span.RecordError(err)
+
+		return db.Delivery{}, fmt.Errorf("unable to create delivery: %w", err)
+	}
+
+	return delivery, nil
+}
+
+// GetDelivery gets a webhook delivery.
+func (m *MongoDB) GetDelivery(ctx context.Context, id string) (db.Delivery, error) {
+	ctx, span := m.tracer.Start(ctx, "db_get_delivery")
+	defer span.End()
+
+	var delivery db.Delivery
+
+	if err := m.client.Collection(deliveryCollName).FindOne(ctx, bson.D{{Key: "id", Value: id}}).Decode(&delivery); err != nil {
+		span.RecordError(err)
+
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return db.Delivery{}, db.NotFoundError{Err: err}
+		}
+
+		return db.Delivery{}, fmt.Errorf("unable to get delivery: %w", err)
+	}
+
+	return delivery, nil
+}
+
+// RecordDeliveryAttempt appends an attempt to the history of a delivery, and updates its status.
+func (m *MongoDB) RecordDeliveryAttempt(ctx context.Context, id string, attempt db.DeliveryAttempt, status string) (db.Delivery, error) {
+	ctx, span := m.tracer.Start(ctx, "db_record_delivery_attempt")
+	defer span.End()
+
+	attempt.At = attempt.At.Truncate(time.Millisecond)
+
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "status", Value: status},
+			{Key: "updatedAt", Value: time.Now().Truncate(time.Millisecond)},
+		}},
+		{Key: "$push", Value: bson.D{{Key: "attempts", Value: attempt}}},
+	}
+
+	opts := &options.FindOneAndUpdateOptions{}
+	opts.SetReturnDocument(options.After)
+
+	var delivery db.Delivery
+
+	if err := m.client.Collection(deliveryCollName).FindOneAndUpdate(ctx, bson.D{{Key: "id", Value: id}}, update, opts).Decode(&delivery); err != nil {
+		span.RecordError(err)
+
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return db.Delivery{}, db.NotFoundError{Err: err}
+		}
+
+		return db.Delivery{}, fmt.Errorf("unable to update delivery: %w", err)
+	}
+
+	return delivery, nil
+}
+
+// ListDeliveries lists the deliveries of a subscription, the most recent first.
+func (m *MongoDB) ListDeliveries(ctx context.Context, subscription string) ([]db.Delivery, error) {
+	ctx, span := m.tracer.Start(ctx, "db_list_deliveries")
+	defer span.End()
+
+	opts := &options.FindOptions{}
+	opts.SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	opts.SetLimit(maxListedDeliveries)
+
+	cursor, err := m.client.Collection(deliveryCollName).Find(ctx, bson.D{{Key: "subscription", Value: subscription}}, opts)
+	if err != nil {
+		span.RecordError(err)
+
+		return nil, fmt.Errorf("unable to find deliveries: %w", err)
+	}
+
+	deliveries := make([]db.Delivery, 0)
+
+	if err = cursor.All(ctx, &deliveries); err != nil {
+		span.RecordError(err)
+
+		return nil, fmt.Errorf("unable to decode deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
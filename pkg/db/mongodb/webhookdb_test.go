@@ -0,0 +1,83 @@
+package mongodb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+func TestMongoDB_Subscriptions(t *testing.T) {
+	ctx := context.Background()
+	store, _ := createDatabase(t, nil)
+
+	first, err := store.CreateSubscription(ctx, db.Subscription{URL: "https://example.com/first", Secret: "secret"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, first.ID)
+
+	second, err := store.CreateSubscription(ctx, db.Subscription{URL: "https://example.com/second", Secret: "secret", Events: []string{db.EventHashMismatch}})
+	require.NoError(t, err)
+
+	got, err := store.GetSubscription(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, first.URL, got.URL)
+	assert.Equal(t, "secret", got.Secret)
+
+	subscriptions, err := store.ListSubscriptions(ctx)
+	require.NoError(t, err)
+	require.Len(t, subscriptions, 2)
+	assert.Equal(t, first.ID, subscriptions[0].ID)
+	assert.Equal(t, []string{db.EventHashMismatch}, subscriptions[1].Events)
+
+	err = store.DeleteSubscription(ctx, second.ID)
+	require.NoError(t, err)
+
+	err = store.DeleteSubscription(ctx, second.ID)
+	require.ErrorAs(t, err, &db.NotFoundError{})
+
+	_, err = store.GetSubscription(ctx, second.ID)
+	require.ErrorAs(t, err, &db.NotFoundError{})
+}
+
+func TestMongoDB_Deliveries(t *testing.T) {
+	ctx := context.Background()
+	store, _ := createDatabase(t, nil)
+
+	event := db.Event{ID: "e1", Type: db.EventVersionPublished, Module: "plugin", Version: "v1.0.0"}
+
+	first, err := store.CreateDelivery(ctx, db.Delivery{Subscription: "s1", Event: event})
+	require.NoError(t, err)
+	assert.Equal(t, db.DeliveryPending, first.Status)
+
+	second, err := store.CreateDelivery(ctx, db.Delivery{Subscription: "s1", Event: event})
+	require.NoError(t, err)
+
+	_, err = store.CreateDelivery(ctx, db.Delivery{Subscription: "s2", Event: event})
+	require.NoError(t, err)
+
+	got, err := store.RecordDeliveryAttempt(ctx, first.ID, db.DeliveryAttempt{StatusCode: 500, Error: "boom", At: time.Now()}, db.DeliveryFailed)
+	require.NoError(t, err)
+	assert.Equal(t, db.DeliveryFailed, got.Status)
+	require.Len(t, got.Attempts, 1)
+
+	got, err = store.RecordDeliveryAttempt(ctx, first.ID, db.DeliveryAttempt{StatusCode: 204, At: time.Now()}, db.DeliverySucceeded)
+	require.NoError(t, err)
+	assert.Equal(t, db.DeliverySucceeded, got.Status)
+	require.Len(t, got.Attempts, 2)
+	assert.Equal(t, 500, got.Attempts[0].StatusCode)
+	assert.Equal(t, 204, got.Attempts[1].StatusCode)
+
+	_, err = store.RecordDeliveryAttempt(ctx, "unknown", db.DeliveryAttempt{}, db.DeliveryFailed)
+	require.ErrorAs(t, err, &db.NotFoundError{})
+
+	deliveries, err := store.ListDeliveries(ctx, "s1")
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+
+	// The most recent first.
+	assert.Equal(t, second.ID, deliveries[0].ID)
+	assert.Equal(t, first.ID, deliveries[1].ID)
+}
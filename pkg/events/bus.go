@@ -0,0 +1,165 @@
+// Package events streams the catalog change events.
+//
+// The Bus is an in-memory event stream, used when the database can't stream the events itself:
+// the events are only seen by the watchers of the replica which has published them.
+// The Relay fans out a single stream of the database to the watchers of a replica through a Bus.
+// The Emitter publishes the changes of all the writers of the catalog to the stream and to the webhook subscribers.
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"strconv"
+	"sync"
+
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+// DefaultHistory the number of events kept by the Bus to resume the streams.
+const DefaultHistory = 1000
+
+// watcherBuffer the number of events buffered by a watcher before it is considered too slow.
+const watcherBuffer = 64
+
+// ErrSlowWatcher is returned to a watcher which doesn't consume the events fast enough, it has to resume from its last cursor.
+var ErrSlowWatcher = errors.New("events dropped, the watcher is too slow")
+
+// Bus is an in-memory event stream.
+// The cursors are the sequence numbers of the events, the last events are kept to resume the streams.
+type Bus struct {
+	mu      sync.Mutex
+	seq     uint64
+	history []db.EventRecord
+	size    int
+
+	// cursors the sequence numbers of the kept events, and of the last dropped one which can still be resumed from.
+	cursors map[string]uint64
+	dropped string
+
+	watchers map[chan db.EventRecord]struct{}
+}
+
+// NewBus creates a Bus keeping the given number of events.
+func NewBus(size int) *Bus {
+	// The streams can be resumed from the start (0) until the first event is dropped.
+	return &Bus{
+		size:     size,
+		cursors:  map[string]uint64{"0": 0},
+		dropped:  "0",
+		watchers: make(map[chan db.EventRecord]struct{}),
+	}
+}
+
+// PublishEvent publishes an event to the watchers.
+func (b *Bus) PublishEvent(_ context.Context, event db.Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.publish(db.EventRecord{Cursor: strconv.FormatUint(b.seq+1, 10), Event: event})
+
+	return nil
+}
+
+// publish publishes a record to the watchers, keeping its cursor.
+func (b *Bus) publish(record db.EventRecord) {
+	b.seq++
+
+	b.history = append(b.history, record)
+	b.cursors[record.Cursor] = b.seq
+
+	if over := len(b.history) - b.size; over > 0 {
+		delete(b.cursors, b.dropped)
+
+		for _, dropped := range b.history[:over-1] {
+			delete(b.cursors, dropped.Cursor)
+		}
+
+		b.dropped = b.history[over-1].Cursor
+		b.history = b.history[over:]
+	}
+
+	for watcher := range b.watchers {
+		select {
+		case watcher <- record:
+		default:
+			// The watcher is dropped rather than blocking the publishers.
+			delete(b.watchers, watcher)
+			close(watcher)
+		}
+	}
+}
+
+// WatchEvents streams the events published after the given cursor, or the new events without cursor.
+// A db.NotFoundError is returned when the cursor is unknown or no longer kept.
+// The stream ends when the context is canceled, it can be iterated once.
+func (b *Bus) WatchEvents(ctx context.Context, since string) (iter.Seq2[db.EventRecord, error], error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	backlog, err := b.backlog(since)
+	if err != nil {
+		return nil, err
+	}
+
+	watcher := make(chan db.EventRecord, len(backlog)+watcherBuffer)
+	for _, record := range backlog {
+		watcher <- record
+	}
+
+	b.watchers[watcher] = struct{}{}
+
+	stop := context.AfterFunc(ctx, func() { b.unwatch(watcher) })
+
+	return func(yield func(db.EventRecord, error) bool) {
+		defer func() {
+			stop()
+			b.unwatch(watcher)
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case record, ok := <-watcher:
+				if !ok {
+					if ctx.Err() == nil {
+						yield(db.EventRecord{}, ErrSlowWatcher)
+					}
+
+					return
+				}
+
+				if !yield(record, nil) {
+					return
+				}
+			}
+		}
+	}, nil
+}
+
+// backlog returns the kept events published after the cursor.
+func (b *Bus) backlog(since string) ([]db.EventRecord, error) {
+	if since == "" {
+		return nil, nil
+	}
+
+	// The events published after the cursor must all be kept.
+	seq, ok := b.cursors[since]
+	if !ok {
+		return nil, db.NotFoundError{Err: fmt.Errorf("unknown or expired cursor %q", since)}
+	}
+
+	return append([]db.EventRecord(nil), b.history[len(b.history)-int(b.seq-seq):]...), nil
+}
+
+func (b *Bus) unwatch(watcher chan db.EventRecord) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.watchers[watcher]; ok {
+		delete(b.watchers, watcher)
+		close(watcher)
+	}
+}
@@ -0,0 +1,145 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+func TestBus_WatchEvents(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		since    string
+		expected []string
+		expErr   bool
+	}{
+		{
+			desc:     "new events",
+			expected: []string{"e5", "e6"},
+		},
+		{
+			desc:     "resumed",
+			since:    "3",
+			expected: []string{"e4", "e5", "e6"},
+		},
+		{
+			desc:     "resumed from the last event",
+			since:    "4",
+			expected: []string{"e5", "e6"},
+		},
+		{
+			desc:     "resumed from the oldest kept event",
+			since:    "1",
+			expected: []string{"e2", "e3", "e4", "e5", "e6"},
+		},
+		{
+			desc:   "expired cursor",
+			since:  "0",
+			expErr: true,
+		},
+		{
+			desc:   "unknown cursor",
+			since:  "5",
+			expErr: true,
+		},
+		{
+			desc:   "malformed cursor",
+			since:  "abc",
+			expErr: true,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			bus := NewBus(3)
+
+			for i := range 4 {
+				require.NoError(t, bus.PublishEvent(context.Background(), db.Event{ID: fmt.Sprint("e", i+1)}))
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			records, err := bus.WatchEvents(ctx, test.since)
+			if test.expErr {
+				require.ErrorAs(t, err, &db.NotFoundError{})
+				return
+			}
+
+			require.NoError(t, err)
+
+			require.NoError(t, bus.PublishEvent(context.Background(), db.Event{ID: "e5"}))
+			require.NoError(t, bus.PublishEvent(context.Background(), db.Event{ID: "e6"}))
+
+			var got []string
+
+			for record, err := range records {
+				require.NoError(t, err)
+
+				got = append(got, record.Event.ID)
+				assert.Equal(t, record.Event.ID, "e"+record.Cursor)
+
+				if len(got) == len(test.expected) {
+					break
+				}
+			}
+
+			assert.Equal(t, test.expected, got)
+		})
+	}
+}
+
+func TestBus_WatchEvents_canceled(t *testing.T) {
+	bus := NewBus(DefaultHistory)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	records, err := bus.WatchEvents(ctx, "")
+	require.NoError(t, err)
+
+	cancel()
+
+	for _, err := range records {
+		require.NoError(t, err)
+		t.Fatal("unexpected event")
+	}
+
+	// The publishers are not blocked by the watcher.
+	require.NoError(t, bus.PublishEvent(context.Background(), db.Event{ID: "e1"}))
+
+	assert.Empty(t, bus.watchers)
+}
+
+func TestBus_WatchEvents_slow(t *testing.T) {
+	bus := NewBus(DefaultHistory)
+
+	records, err := bus.WatchEvents(context.Background(), "")
+	require.NoError(t, err)
+
+	for i := range watcherBuffer + 1 {
+		require.NoError(t, bus.PublishEvent(context.Background(), db.Event{ID: fmt.Sprint("e", i+1)}))
+	}
+
+	var (
+		count   int
+		lastErr error
+	)
+
+	for _, err := range records {
+		if err != nil {
+			lastErr = err
+			break
+		}
+
+		count++
+	}
+
+	assert.Equal(t, watcherBuffer, count)
+	require.ErrorIs(t, lastErr, ErrSlowWatcher)
+}
@@ -0,0 +1,115 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+// Publisher is capable of publishing the catalog change events to a stream.
+type Publisher interface {
+	PublishEvent(ctx context.Context, event db.Event) error
+}
+
+// Notifier is capable of notifying the webhook subscribers of the catalog change events.
+type Notifier interface {
+	Notify(ctx context.Context, event db.Event) error
+}
+
+// Emitter emits the catalog change events to the event stream and to the webhook subscribers.
+// It is shared by all the writers of the catalog (API handlers, crawler), a nil Emitter doesn't emit anything.
+type Emitter struct {
+	publisher Publisher
+	notifier  Notifier
+}
+
+// NewEmitter creates an Emitter, the publisher and the notifier are optional.
+func NewEmitter(publisher Publisher, notifier Notifier) *Emitter {
+	if publisher == nil && notifier == nil {
+		return nil
+	}
+
+	return &Emitter{publisher: publisher, notifier: notifier}
+}
+
+// Emit publishes an event to the event stream and to the webhook subscribers.
+// A failure doesn't prevent the change: the event is lost.
+func (e *Emitter) Emit(ctx context.Context, event db.Event) {
+	if e == nil {
+		return
+	}
+
+	id, err := newEventID()
+	if err != nil {
+		log.Error().Err(err).Str("event_type", event.Type).Msg("Unable to emit event")
+		return
+	}
+
+	event.ID = id
+	event.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+
+	// Strips the readme of the payloads.
+	if event.Plugin != nil {
+		plugin := *event.Plugin
+		plugin.Readme = ""
+		event.Plugin = &plugin
+	}
+
+	if e.publisher != nil {
+		if err = e.publisher.PublishEvent(ctx, event); err != nil {
+			log.Error().Err(err).Str("event_type", event.Type).Str("module_name", event.Module).Msg("Unable to publish event")
+		}
+	}
+
+	if e.notifier != nil {
+		if err = e.notifier.Notify(ctx, event); err != nil {
+			log.Error().Err(err).Str("event_type", event.Type).Str("module_name", event.Module).Msg("Unable to notify webhook subscribers")
+		}
+	}
+}
+
+// EmitCreated emits the events of a plugin creation: created, plus its published versions.
+func (e *Emitter) EmitCreated(ctx context.Context, plugin db.Plugin) {
+	e.Emit(ctx, db.Event{Type: db.EventPluginCreated, Module: plugin.Name, Plugin: &plugin})
+
+	for _, version := range plugin.Versions {
+		e.Emit(ctx, db.Event{Type: db.EventVersionPublished, Module: plugin.Name, Version: version, Plugin: &plugin})
+	}
+}
+
+// EmitChanges emits the events of a plugin update: updated, plus the published versions and the visibility changes.
+func (e *Emitter) EmitChanges(ctx context.Context, previous, plugin db.Plugin) {
+	e.Emit(ctx, db.Event{Type: db.EventPluginUpdated, Module: plugin.Name, Plugin: &plugin})
+
+	for _, version := range plugin.Versions {
+		if !slices.Contains(previous.Versions, version) {
+			e.Emit(ctx, db.Event{Type: db.EventVersionPublished, Module: plugin.Name, Version: version, Plugin: &plugin})
+		}
+	}
+
+	switch {
+	case plugin.Hidden && !previous.Hidden:
+		e.Emit(ctx, db.Event{Type: db.EventPluginHidden, Module: plugin.Name, Plugin: &plugin})
+	case !plugin.Hidden && previous.Hidden:
+		e.Emit(ctx, db.Event{Type: db.EventPluginUnhidden, Module: plugin.Name, Plugin: &plugin})
+	}
+
+	if plugin.Disabled && !previous.Disabled {
+		e.Emit(ctx, db.Event{Type: db.EventPluginDisabled, Module: plugin.Name, Plugin: &plugin})
+	}
+}
+
+func newEventID() (string, error) {
+	raw := make([]byte, 12)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("generate event id: %w", err)
+	}
+
+	return hex.EncodeToString(raw), nil
+}
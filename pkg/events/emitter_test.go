@@ -0,0 +1,94 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+// recorder is a Publisher and a Notifier recording the events.
+type recorder struct {
+	events []db.Event
+	err    error
+}
+
+func (r *recorder) PublishEvent(_ context.Context, event db.Event) error {
+	r.events = append(r.events, event)
+	return r.err
+}
+
+func (r *recorder) Notify(_ context.Context, event db.Event) error {
+	r.events = append(r.events, event)
+	return r.err
+}
+
+func TestEmitter_EmitCreated(t *testing.T) {
+	publisher := &recorder{}
+	notifier := &recorder{err: errors.New("unavailable")}
+
+	plugin := db.Plugin{Name: "github.com/traefik/plugindemo", Readme: "README", Versions: []string{"v0.2.0", "v0.1.0"}}
+
+	NewEmitter(publisher, notifier).EmitCreated(context.Background(), plugin)
+
+	stripped := plugin
+	stripped.Readme = ""
+
+	expected := []db.Event{
+		{Type: db.EventPluginCreated, Module: plugin.Name, Plugin: &stripped},
+		{Type: db.EventVersionPublished, Module: plugin.Name, Version: "v0.2.0", Plugin: &stripped},
+		{Type: db.EventVersionPublished, Module: plugin.Name, Version: "v0.1.0", Plugin: &stripped},
+	}
+
+	assert.Equal(t, expected, withoutIdentity(t, publisher.events))
+	// A failure of the notifier doesn't prevent the next events.
+	assert.Equal(t, expected, withoutIdentity(t, notifier.events))
+}
+
+func TestEmitter_EmitChanges(t *testing.T) {
+	publisher := &recorder{}
+
+	previous := db.Plugin{Name: "github.com/traefik/plugindemo", Versions: []string{"v0.1.0"}, Hidden: true}
+	plugin := db.Plugin{Name: "github.com/traefik/plugindemo", Versions: []string{"v0.2.0", "v0.1.0"}, Disabled: true}
+
+	NewEmitter(publisher, nil).EmitChanges(context.Background(), previous, plugin)
+
+	expected := []db.Event{
+		{Type: db.EventPluginUpdated, Module: plugin.Name, Plugin: &plugin},
+		{Type: db.EventVersionPublished, Module: plugin.Name, Version: "v0.2.0", Plugin: &plugin},
+		{Type: db.EventPluginUnhidden, Module: plugin.Name, Plugin: &plugin},
+		{Type: db.EventPluginDisabled, Module: plugin.Name, Plugin: &plugin},
+	}
+
+	assert.Equal(t, expected, withoutIdentity(t, publisher.events))
+}
+
+func TestNewEmitter_disabled(t *testing.T) {
+	emitter := NewEmitter(nil, nil)
+
+	assert.Nil(t, emitter)
+
+	// A nil Emitter doesn't emit anything.
+	emitter.EmitChanges(context.Background(), db.Plugin{}, db.Plugin{Hidden: true})
+}
+
+// withoutIdentity checks that the events have an ID and a creation date, and returns them without.
+func withoutIdentity(t *testing.T, events []db.Event) []db.Event {
+	t.Helper()
+
+	var stripped []db.Event
+
+	for _, event := range events {
+		assert.Len(t, event.ID, 24)
+		assert.False(t, event.CreatedAt.IsZero())
+
+		event.ID = ""
+		event.CreatedAt = time.Time{}
+		stripped = append(stripped, event)
+	}
+
+	return stripped
+}
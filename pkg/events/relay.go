@@ -0,0 +1,145 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+// DefaultMaxStreams the number of watchers which can be resumed by their own stream of the source.
+const DefaultMaxStreams = 100
+
+// relayRetryDelay the delay before the shared stream of the source is opened again after an error.
+const relayRetryDelay = 5 * time.Second
+
+// ErrTooManyStreams is returned to a watcher resumed from a cursor older than the relayed events,
+// when the maximum number of streams of the source has been reached.
+var ErrTooManyStreams = errors.New("too many event streams")
+
+// Source is an event stream shared by the replicas, e.g. the change streams of the database.
+type Source interface {
+	PublishEvent(ctx context.Context, event db.Event) error
+	WatchEvents(ctx context.Context, since string) (iter.Seq2[db.EventRecord, error], error)
+}
+
+// Relay streams the events of a Source to the watchers of a replica.
+// A single stream of the source is shared by the watchers through a Bus, keeping the cursors of the source,
+// only the watchers resumed from a cursor older than the relayed events open their own stream, up to a maximum.
+type Relay struct {
+	source  Source
+	bus     *Bus
+	streams chan struct{}
+
+	mu   sync.Mutex
+	last string
+}
+
+// NewRelay creates a Relay keeping the given number of events, and opening at most maxStreams streams for the resumed watchers.
+func NewRelay(source Source, size, maxStreams int) *Relay {
+	return &Relay{
+		source:  source,
+		bus:     NewBus(size),
+		streams: make(chan struct{}, maxStreams),
+	}
+}
+
+// PublishEvent publishes an event to the source, it is relayed by the shared stream of each replica.
+func (r *Relay) PublishEvent(ctx context.Context, event db.Event) error {
+	return r.source.PublishEvent(ctx, event)
+}
+
+// Run relays the events of the source until the context is canceled.
+// The shared stream is resumed after the last relayed event when it is interrupted.
+func (r *Relay) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		if err := r.relay(ctx); err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("Event stream interrupted, resuming")
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(relayRetryDelay):
+		}
+	}
+}
+
+func (r *Relay) relay(ctx context.Context) error {
+	r.mu.Lock()
+	since := r.last
+	r.mu.Unlock()
+
+	records, err := r.source.WatchEvents(ctx, since)
+	if errors.As(err, &db.NotFoundError{}) {
+		log.Warn().Err(err).Msg("Unable to resume the event stream, the events published meanwhile are not relayed")
+
+		records, err = r.source.WatchEvents(ctx, "")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for record, err := range records {
+		if err != nil {
+			return err
+		}
+
+		r.mu.Lock()
+		r.last = record.Cursor
+		r.mu.Unlock()
+
+		r.bus.mu.Lock()
+		r.bus.publish(record)
+		r.bus.mu.Unlock()
+	}
+
+	return nil
+}
+
+// WatchEvents streams the events published after the given cursor, or the new events without cursor.
+// A db.NotFoundError is returned when the cursor is unknown to the source,
+// and ErrTooManyStreams when the watcher can't be resumed by its own stream.
+// The stream ends when the context is canceled, it can be iterated once.
+func (r *Relay) WatchEvents(ctx context.Context, since string) (iter.Seq2[db.EventRecord, error], error) {
+	records, err := r.bus.WatchEvents(ctx, since)
+	if err == nil || since == "" || !errors.As(err, &db.NotFoundError{}) {
+		return records, err
+	}
+
+	// The cursor is older than the relayed events, the watcher is resumed by its own stream.
+	select {
+	case r.streams <- struct{}{}:
+	default:
+		return nil, ErrTooManyStreams
+	}
+
+	var once sync.Once
+	release := func() { once.Do(func() { <-r.streams }) }
+
+	records, err = r.source.WatchEvents(ctx, since)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	// The stream is released with the context, even if it is never iterated.
+	stop := context.AfterFunc(ctx, release)
+
+	return func(yield func(db.EventRecord, error) bool) {
+		defer func() {
+			stop()
+			release()
+		}()
+
+		for record, err := range records {
+			if !yield(record, err) {
+				return
+			}
+		}
+	}, nil
+}
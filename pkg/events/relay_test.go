@@ -0,0 +1,152 @@
+package events
+
+import (
+	"context"
+	"iter"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+// fakeSource is a Source streaming the live records, and the given records to the resumed watchers.
+type fakeSource struct {
+	mu      sync.Mutex
+	watches []string
+
+	live    chan db.EventRecord
+	resumed map[string][]db.EventRecord
+}
+
+func (f *fakeSource) PublishEvent(_ context.Context, _ db.Event) error {
+	return nil
+}
+
+func (f *fakeSource) WatchEvents(ctx context.Context, since string) (iter.Seq2[db.EventRecord, error], error) {
+	f.mu.Lock()
+	f.watches = append(f.watches, since)
+	f.mu.Unlock()
+
+	backlog, ok := f.resumed[since]
+	if since != "" && !ok {
+		return nil, db.NotFoundError{}
+	}
+
+	return func(yield func(db.EventRecord, error) bool) {
+		for _, record := range backlog {
+			if !yield(record, nil) {
+				return
+			}
+		}
+
+		if since != "" {
+			<-ctx.Done()
+			return
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case record := <-f.live:
+				if !yield(record, nil) {
+					return
+				}
+			}
+		}
+	}, nil
+}
+
+func (f *fakeSource) watched() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return slices.Clone(f.watches)
+}
+
+func TestRelay_WatchEvents(t *testing.T) {
+	source := &fakeSource{
+		live:    make(chan db.EventRecord),
+		resumed: map[string][]db.EventRecord{"c0": {{Cursor: "c1", Event: db.Event{ID: "e1"}}}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	relay := NewRelay(source, DefaultHistory, 1)
+
+	go relay.Run(ctx)
+
+	require.Eventually(t, func() bool { return len(source.watched()) == 1 }, time.Second, 10*time.Millisecond)
+
+	first, err := relay.WatchEvents(ctx, "")
+	require.NoError(t, err)
+
+	second, err := relay.WatchEvents(ctx, "")
+	require.NoError(t, err)
+
+	source.live <- db.EventRecord{Cursor: "c1", Event: db.Event{ID: "e1"}}
+	source.live <- db.EventRecord{Cursor: "c2", Event: db.Event{ID: "e2"}}
+
+	// The watchers share the stream of the source, and keep its cursors.
+	expected := []db.EventRecord{{Cursor: "c1", Event: db.Event{ID: "e1"}}, {Cursor: "c2", Event: db.Event{ID: "e2"}}}
+
+	assert.Equal(t, expected, collect(t, first, 2))
+	assert.Equal(t, expected, collect(t, second, 2))
+
+	// A watcher resumed from a relayed event doesn't open a stream.
+	resumed, err := relay.WatchEvents(ctx, "c1")
+	require.NoError(t, err)
+
+	assert.Equal(t, expected[1:], collect(t, resumed, 1))
+	assert.Equal(t, []string{""}, source.watched())
+
+	// An unknown cursor is not found.
+	_, err = relay.WatchEvents(ctx, "unknown")
+	require.ErrorAs(t, err, &db.NotFoundError{})
+
+	// A watcher resumed from an older event opens its own stream, up to the maximum.
+	old, err := relay.WatchEvents(ctx, "c0")
+	require.NoError(t, err)
+
+	_, err = relay.WatchEvents(ctx, "c0")
+	require.ErrorIs(t, err, ErrTooManyStreams)
+
+	assert.Equal(t, expected[:1], collect(t, old, 1))
+	assert.Equal(t, []string{"", "unknown", "c0"}, source.watched())
+
+	// The stream is released with the watcher, even if it is never iterated.
+	pendingCtx, pendingCancel := context.WithCancel(ctx)
+
+	_, err = relay.WatchEvents(pendingCtx, "c0")
+	require.NoError(t, err)
+
+	pendingCancel()
+
+	require.Eventually(t, func() bool {
+		_, err = relay.WatchEvents(ctx, "c0")
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func collect(t *testing.T, records iter.Seq2[db.EventRecord, error], count int) []db.EventRecord {
+	t.Helper()
+
+	var got []db.EventRecord
+
+	for record, err := range records {
+		require.NoError(t, err)
+
+		got = append(got, record)
+
+		if len(got) == count {
+			break
+		}
+	}
+
+	return got
+}
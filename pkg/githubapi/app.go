@@ -0,0 +1,257 @@
+package githubapi
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v74/github"
+	"github.com/traefik/plugin-service/pkg/internal/httputil"
+)
+
+const (
+	// jwtLifetime the lifetime of the app JWTs, GitHub rejects the JWTs expiring after 10 minutes.
+	jwtLifetime = 9 * time.Minute
+	// jwtClockSkew the backdating of the app JWTs, against the clock drift between the service and GitHub.
+	jwtClockSkew = time.Minute
+	// tokenRefreshMargin the delay before their expiration after which the installation tokens are refreshed.
+	tokenRefreshMargin = 5 * time.Minute
+)
+
+// App a GitHub App, authenticated by JWTs signed with its private key.
+type App struct {
+	ID  int64
+	Key *rsa.PrivateKey
+}
+
+// LoadApp loads the ID and the PEM private key (PKCS #1 or PKCS #8) of a GitHub App from files.
+func LoadApp(idFile, keyFile string) (App, error) {
+	rawID, err := os.ReadFile(idFile)
+	if err != nil {
+		return App{}, fmt.Errorf("read app ID: %w", err)
+	}
+
+	id, err := strconv.ParseInt(strings.TrimSpace(string(rawID)), 10, 64)
+	if err != nil {
+		return App{}, fmt.Errorf("parse app ID: %w", err)
+	}
+
+	rawKey, err := os.ReadFile(keyFile)
+	if err != nil {
+		return App{}, fmt.Errorf("read app private key: %w", err)
+	}
+
+	key, err := parsePrivateKey(rawKey)
+	if err != nil {
+		return App{}, fmt.Errorf("parse app private key: %w", err)
+	}
+
+	return App{ID: id, Key: key}, nil
+}
+
+func parsePrivateKey(raw []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("no PEM block")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+
+		return rsaKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+// JWT mints a JWT (RS256) authenticating the app, valid from now.
+func (a App) JWT(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-jwtClockSkew).Unix(),
+		"exp": now.Add(jwtLifetime).Unix(),
+		"iss": strconv.FormatInt(a.ID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	hashed := sha256.Sum256([]byte(signingInput))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.Key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return "", fmt.Errorf("sign JWT: %w", err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// appJWTTransport authenticates the requests to the app endpoints with the JWTs of the app.
+// It doesn't track the rate limits: the app endpoints have their own budget, unrelated to the installation tokens.
+type appJWTTransport struct {
+	base http.RoundTripper
+	app  App
+	now  func() time.Time
+}
+
+func (t appJWTTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.app.JWT(t.now())
+	if err != nil {
+		httputil.CloseBody(req)
+		return nil, fmt.Errorf("mint app JWT: %w", err)
+	}
+
+	outReq := req.Clone(req.Context())
+	outReq.Header.Set("Authorization", "Bearer "+token)
+
+	return t.base.RoundTrip(outReq)
+}
+
+// InstallationTokenSource provides the tokens of an installation of a GitHub App.
+// The tokens are cached, and refreshed when they get close to their expiration.
+type InstallationTokenSource struct {
+	app            App
+	installationID int64
+	gh             *github.Client
+
+	now func() time.Time
+
+	// refreshing serializes the refreshes, the callers are not blocked while the current token is still valid.
+	refreshing sync.Mutex
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// NewInstallationTokenSource creates an InstallationTokenSource for the given installation of the app,
+// or for its only installation if installationID is 0.
+// The tokens are requested with base, or with http.DefaultTransport if base is nil,
+// from the GitHub API at baseURL, or at https://api.github.com/ if baseURL is nil.
+func NewInstallationTokenSource(app App, installationID int64, base http.RoundTripper, baseURL *url.URL) *InstallationTokenSource {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	s := &InstallationTokenSource{
+		app:            app,
+		installationID: installationID,
+		now:            time.Now,
+	}
+
+	s.gh = github.NewClient(&http.Client{Transport: appJWTTransport{base: base, app: app, now: s.clock}})
+	if baseURL != nil {
+		s.gh.BaseURL = baseURL
+	}
+
+	return s
+}
+
+func (s *InstallationTokenSource) clock() time.Time {
+	return s.now()
+}
+
+// Token returns the installation token, refreshed if it expires within the refresh margin.
+// While a refresh is in progress, the other callers get the current token if it hasn't expired yet.
+func (s *InstallationTokenSource) Token(ctx context.Context) (string, error) {
+	token, fresh, valid := s.current()
+	if fresh {
+		return token, nil
+	}
+
+	if valid {
+		if !s.refreshing.TryLock() {
+			return token, nil
+		}
+	} else {
+		s.refreshing.Lock()
+	}
+	defer s.refreshing.Unlock()
+
+	// The token may have been refreshed while waiting.
+	if token, fresh, _ = s.current(); fresh {
+		return token, nil
+	}
+
+	if s.installationID == 0 {
+		id, err := s.findInstallation(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		s.installationID = id
+	}
+
+	created, _, err := s.gh.Apps.CreateInstallationToken(ctx, s.installationID, nil)
+	if err != nil {
+		return "", fmt.Errorf("create installation token: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.token = created.GetToken()
+	s.expiresAt = created.GetExpiresAt().Time
+
+	return s.token, nil
+}
+
+// current returns the cached token, whether it expires after the refresh margin, and whether it hasn't expired yet.
+func (s *InstallationTokenSource) current() (string, bool, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token == "" {
+		return "", false, false
+	}
+
+	now := s.now()
+
+	return s.token, now.Add(tokenRefreshMargin).Before(s.expiresAt), now.Before(s.expiresAt)
+}
+
+// findInstallation returns the ID of the only installation of the app.
+func (s *InstallationTokenSource) findInstallation(ctx context.Context) (int64, error) {
+	installations, _, err := s.gh.Apps.ListInstallations(ctx, &github.ListOptions{PerPage: 2})
+	if err != nil {
+		return 0, fmt.Errorf("list app installations: %w", err)
+	}
+
+	if len(installations) != 1 {
+		return 0, fmt.Errorf("the app has %d installations, the installation ID is required", len(installations))
+	}
+
+	return installations[0].GetID(), nil
+}
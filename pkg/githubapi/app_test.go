@@ -0,0 +1,317 @@
+package githubapi
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAppAPI is a GitHub API creating installation tokens for the requests authenticated by a valid app JWT.
+type fakeAppAPI struct {
+	t   *testing.T
+	app App
+
+	installations []int64
+	expiresAt     time.Time
+
+	mu sync.Mutex
+	// created the number of created installation tokens.
+	created int
+}
+
+func (f *fakeAppAPI) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.validJWT(strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")) {
+		rw.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+
+	switch {
+	case req.Method == http.MethodGet && req.URL.Path == "/app/installations":
+		var installations []map[string]int64
+		for _, id := range f.installations {
+			installations = append(installations, map[string]int64{"id": id})
+		}
+
+		require.NoError(f.t, json.NewEncoder(rw).Encode(installations))
+
+	case req.Method == http.MethodPost && req.URL.Path == "/app/installations/42/access_tokens":
+		f.created++
+
+		rw.WriteHeader(http.StatusCreated)
+		require.NoError(f.t, json.NewEncoder(rw).Encode(map[string]any{
+			"token":      "installation-token-" + string(rune('0'+f.created)),
+			"expires_at": f.expiresAt.Format(time.RFC3339),
+		}))
+
+	default:
+		rw.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeAppAPI) validJWT(token string) bool {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return false
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+
+	hashed := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if rsa.VerifyPKCS1v15(&f.app.Key.PublicKey, crypto.SHA256, hashed[:], signature) != nil {
+		return false
+	}
+
+	rawClaims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+
+	var claims struct {
+		Iss string `json:"iss"`
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+	}
+	if json.Unmarshal(rawClaims, &claims) != nil {
+		return false
+	}
+
+	return claims.Iss == "1234" && claims.Exp-claims.Iat <= int64((10*time.Minute).Seconds())
+}
+
+func generateApp(t *testing.T) App {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	return App{ID: 1234, Key: key}
+}
+
+func setupAppAPI(t *testing.T, api *fakeAppAPI) *url.URL {
+	t.Helper()
+
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	baseURL, err := url.Parse(server.URL + "/")
+	require.NoError(t, err)
+
+	return baseURL
+}
+
+func TestLoadApp(t *testing.T) {
+	app := generateApp(t)
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(app.Key)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		desc   string
+		id     string
+		key    []byte
+		expErr string
+	}{
+		{
+			desc: "PKCS #1",
+			id:   "1234\n",
+			key:  pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(app.Key)}),
+		},
+		{
+			desc: "PKCS #8",
+			id:   "1234",
+			key:  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}),
+		},
+		{
+			desc:   "invalid ID",
+			id:     "app",
+			key:    pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}),
+			expErr: "parse app ID",
+		},
+		{
+			desc:   "not PEM",
+			id:     "1234",
+			key:    []byte("key"),
+			expErr: "parse app private key: no PEM block",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+
+			idFile := filepath.Join(dir, "app-id")
+			require.NoError(t, os.WriteFile(idFile, []byte(test.id), 0o600))
+
+			keyFile := filepath.Join(dir, "private-key.pem")
+			require.NoError(t, os.WriteFile(keyFile, test.key, 0o600))
+
+			loaded, err := LoadApp(idFile, keyFile)
+			if test.expErr != "" {
+				require.ErrorContains(t, err, test.expErr)
+				return
+			}
+
+			require.NoError(t, err)
+
+			assert.Equal(t, int64(1234), loaded.ID)
+			assert.True(t, app.Key.Equal(loaded.Key))
+		})
+	}
+}
+
+func TestInstallationTokenSource_Token(t *testing.T) {
+	app := generateApp(t)
+
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	api := &fakeAppAPI{t: t, app: app, installations: []int64{42}, expiresAt: now.Add(time.Hour)}
+
+	source := NewInstallationTokenSource(app, 0, nil, setupAppAPI(t, api))
+	source.now = func() time.Time { return now }
+
+	token, err := source.Token(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "installation-token-1", token)
+
+	// The token is cached until it gets close to its expiration.
+	now = now.Add(50 * time.Minute)
+
+	token, err = source.Token(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "installation-token-1", token)
+
+	api.expiresAt = now.Add(time.Hour)
+	now = now.Add(6 * time.Minute)
+
+	token, err = source.Token(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "installation-token-2", token)
+	assert.Equal(t, 2, api.created)
+}
+
+// gatedTransport is an http.RoundTripper holding the requests until they are released.
+type gatedTransport struct {
+	requests chan *http.Request
+	release  chan struct{}
+}
+
+func (g gatedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	g.requests <- req
+	<-g.release
+
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestInstallationTokenSource_Token_refreshing(t *testing.T) {
+	app := generateApp(t)
+
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	api := &fakeAppAPI{t: t, app: app, installations: []int64{42}, expiresAt: now.Add(time.Hour)}
+
+	base := gatedTransport{requests: make(chan *http.Request, 1), release: make(chan struct{}, 1)}
+
+	source := NewInstallationTokenSource(app, 42, base, setupAppAPI(t, api))
+	source.now = func() time.Time { return now }
+
+	base.release <- struct{}{}
+
+	token, err := source.Token(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "installation-token-1", token)
+
+	// The tokens are requested with the given transport.
+	req := <-base.requests
+	assert.True(t, strings.HasPrefix(req.Header.Get("Authorization"), "Bearer "))
+
+	api.expiresAt = now.Add(2 * time.Hour)
+	now = now.Add(56 * time.Minute)
+
+	refreshed := make(chan string)
+
+	go func() {
+		refreshedToken, errRefresh := source.Token(context.Background())
+		assert.NoError(t, errRefresh)
+
+		refreshed <- refreshedToken
+	}()
+
+	<-base.requests
+
+	// The current token is still valid, it is returned while the refresh is in progress.
+	token, err = source.Token(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "installation-token-1", token)
+
+	base.release <- struct{}{}
+
+	assert.Equal(t, "installation-token-2", <-refreshed)
+}
+
+func TestInstallationTokenSource_Token_installations(t *testing.T) {
+	app := generateApp(t)
+
+	api := &fakeAppAPI{t: t, app: app, installations: []int64{42, 43}, expiresAt: time.Now().Add(time.Hour)}
+
+	_, err := NewInstallationTokenSource(app, 0, nil, setupAppAPI(t, api)).Token(context.Background())
+	require.ErrorContains(t, err, "the app has 2 installations, the installation ID is required")
+
+	token, err := NewInstallationTokenSource(app, 42, nil, setupAppAPI(t, api)).Token(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "installation-token-1", token)
+}
+
+func TestTransport_RoundTrip_installationToken(t *testing.T) {
+	app := generateApp(t)
+
+	api := &fakeAppAPI{t: t, app: app, installations: []int64{42}, expiresAt: time.Now().Add(time.Hour)}
+
+	var authorization string
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		authorization = req.Header.Get("Authorization")
+		rw.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	transport := NewTransport(nil, []Credential{{Name: "app", Source: NewInstallationTokenSource(app, 42, nil, setupAppAPI(t, api))}}, DefaultReserve)
+
+	_, err := get(t, transport, server.URL+"/repos/traefik/plugindemo")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Bearer installation-token-1", authorization)
+}
@@ -0,0 +1,336 @@
+// Package githubapi authenticates the requests to the GitHub API with a pool of credentials,
+// and keeps each credential under its rate limits.
+//
+// The rate limit headers (X-RateLimit-*) of the responses are tracked by credential and by resource (core, search, graphql).
+// Every request uses the credential with the largest remaining budget,
+// the rate limited requests are retried with another credential,
+// and the requests are refused without reaching GitHub when all the credentials are down to their reserve.
+package githubapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/plugin-service/pkg/internal/httputil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// DefaultReserve the number of requests of a credential kept unused, to stop before GitHub rejects the requests.
+const DefaultReserve = 20
+
+// Rate limit resources.
+const (
+	ResourceCore    = "core"
+	ResourceSearch  = "search"
+	ResourceGraphQL = "graphql"
+)
+
+// secondaryRateLimitDelay the delay during which a credential is not used after a secondary rate limit without Retry-After.
+const secondaryRateLimitDelay = time.Minute
+
+// TokenSource provides the tokens of a credential.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticToken a personal access token.
+type StaticToken string
+
+// Token returns the token.
+func (t StaticToken) Token(context.Context) (string, error) {
+	return string(t), nil
+}
+
+// Credential a named source of tokens, the name identifies the credential in the logs and the metrics.
+// A credential without source sends anonymous requests.
+type Credential struct {
+	Name   string
+	Source TokenSource
+}
+
+// Budget the rate limit of a credential for a resource, as reported by the last response.
+type Budget struct {
+	Credential string    `json:"credential"`
+	Resource   string    `json:"resource"`
+	Limit      int       `json:"limit"`
+	Remaining  int       `json:"remaining"`
+	Reset      time.Time `json:"reset"`
+}
+
+// RateLimitedError is returned, without sending the request, when all the credentials are down to their reserve.
+type RateLimitedError struct {
+	Resource string
+	// Reset the time after which a credential has a budget again.
+	Reset time.Time
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("GitHub %s rate limit reserve reached for all the credentials until %s", e.Resource, e.Reset.Format(time.RFC3339))
+}
+
+type credential struct {
+	Credential
+
+	// budgets the budgets by resource.
+	budgets map[string]Budget
+}
+
+// Transport is an http.RoundTripper spreading the GitHub requests over a pool of credentials.
+type Transport struct {
+	base    http.RoundTripper
+	reserve int
+
+	mu          sync.Mutex
+	credentials []*credential
+
+	now func() time.Time
+}
+
+// NewTransport creates a Transport sending the requests with base, or with http.DefaultTransport if base is nil.
+// Without credentials, the requests are anonymous.
+func NewTransport(base http.RoundTripper, credentials []Credential, reserve int) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	if len(credentials) == 0 {
+		credentials = []Credential{{Name: "anonymous"}}
+	}
+
+	t := &Transport{
+		base:    base,
+		reserve: reserve,
+		now:     time.Now,
+	}
+
+	for _, c := range credentials {
+		t.credentials = append(t.credentials, &credential{Credential: c, budgets: make(map[string]Budget)})
+	}
+
+	if err := t.registerMetrics(); err != nil {
+		log.Warn().Err(err).Msg("Unable to register the GitHub rate limit metrics")
+	}
+
+	return t
+}
+
+// Budgets returns the known budgets of the credentials.
+func (t *Transport) Budgets() []Budget {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var budgets []Budget
+
+	for _, c := range t.credentials {
+		for _, resource := range []string{ResourceCore, ResourceSearch, ResourceGraphQL} {
+			if budget, ok := c.budgets[resource]; ok {
+				budgets = append(budgets, budget)
+			}
+		}
+	}
+
+	return budgets
+}
+
+// RoundTrip sends the request with the credential having the largest remaining budget.
+// The idempotent requests rejected by a rate limit are sent again with the next credential.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resource := resourceOf(req.URL.Path)
+	retryable := httputil.IsReplayable(req)
+
+	var (
+		tried []*credential
+		resp  *http.Response
+	)
+
+	for {
+		c, err := t.pick(resource, tried)
+		if err != nil {
+			// The rate limited response of the last credential is returned when no other credential is available.
+			if resp != nil {
+				return resp, nil
+			}
+
+			httputil.CloseBody(req)
+
+			return nil, err
+		}
+
+		if resp != nil {
+			log.Warn().Str("credential", tried[len(tried)-1].Name).Str("resource", resource).Msg("GitHub rate limit hit, trying the next credential")
+
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		tried = append(tried, c)
+
+		resp, err = t.send(req, c)
+		if err != nil {
+			return nil, err
+		}
+
+		if limited := t.record(c, resource, resp); !limited || !retryable {
+			return resp, nil
+		}
+	}
+}
+
+func (t *Transport) send(req *http.Request, c *credential) (*http.Response, error) {
+	outReq := req.Clone(req.Context())
+
+	if c.Source != nil {
+		token, err := c.Source.Token(req.Context())
+		if err != nil {
+			httputil.CloseBody(req)
+			return nil, fmt.Errorf("get token of the %s credential: %w", c.Name, err)
+		}
+
+		outReq.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return t.base.RoundTrip(outReq)
+}
+
+// pick returns the credential with the largest remaining budget for the resource,
+// the credentials without known budget first.
+func (t *Transport) pick(resource string, tried []*credential) (*credential, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+
+	var (
+		best          *credential
+		bestRemaining int
+		reset         time.Time
+	)
+
+	for _, c := range t.credentials {
+		if slices.Contains(tried, c) {
+			continue
+		}
+
+		remaining := -1
+
+		budget, ok := c.budgets[resource]
+		if ok && now.Before(budget.Reset) {
+			if budget.Remaining <= t.reserve {
+				if reset.IsZero() || budget.Reset.Before(reset) {
+					reset = budget.Reset
+				}
+
+				continue
+			}
+
+			remaining = budget.Remaining
+		}
+
+		// The credentials without known budget (-1) are used first, to learn their budget.
+		if best == nil || bestRemaining != -1 && (remaining == -1 || remaining > bestRemaining) {
+			best, bestRemaining = c, remaining
+		}
+	}
+
+	if best == nil {
+		return nil, &RateLimitedError{Resource: resource, Reset: reset}
+	}
+
+	return best, nil
+}
+
+// record records the rate limit headers of a response, and returns true if the request has been rejected by a rate limit.
+func (t *Transport) record(c *credential, resource string, resp *http.Response) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if r := resp.Header.Get("X-RateLimit-Resource"); r != "" {
+		resource = r
+	}
+
+	budget, ok := parseBudget(resp.Header)
+
+	limited := resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests
+	limited = limited && (ok && budget.Remaining == 0 || resp.Header.Get("Retry-After") != "")
+
+	if limited && resp.Header.Get("Retry-After") != "" {
+		// Secondary rate limit: the credential is not used for the given delay.
+		delay := secondaryRateLimitDelay
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			delay = time.Duration(seconds) * time.Second
+		}
+
+		budget = Budget{Limit: budget.Limit, Remaining: 0, Reset: t.now().Add(delay)}
+		ok = true
+	}
+
+	if !ok {
+		return limited
+	}
+
+	budget.Credential = c.Name
+	budget.Resource = resource
+	c.budgets[resource] = budget
+
+	return limited
+}
+
+func (t *Transport) registerMetrics() error {
+	meter := otel.Meter("github")
+
+	remaining, err := meter.Int64ObservableGauge("github.ratelimit.remaining",
+		metric.WithDescription("Remaining GitHub API requests of a credential until the reset of its rate limit."),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, observer metric.Observer) error {
+		for _, budget := range t.Budgets() {
+			observer.ObserveInt64(remaining, int64(budget.Remaining), metric.WithAttributes(
+				attribute.String("credential", budget.Credential),
+				attribute.String("resource", budget.Resource),
+			))
+		}
+
+		return nil
+	}, remaining)
+
+	return err
+}
+
+// parseBudget parses the rate limit headers of a response.
+func parseBudget(header http.Header) (Budget, bool) {
+	limit, errLimit := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	remaining, errRemaining := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	reset, errReset := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+
+	if errLimit != nil || errRemaining != nil || errReset != nil {
+		return Budget{}, false
+	}
+
+	return Budget{Limit: limit, Remaining: remaining, Reset: time.Unix(reset, 0)}, true
+}
+
+// resourceOf returns the rate limit resource of an API path.
+func resourceOf(urlPath string) string {
+	switch {
+	case strings.HasSuffix(urlPath, "/graphql"):
+		return ResourceGraphQL
+	case strings.Contains(urlPath, "/search/"):
+		return ResourceSearch
+	default:
+		return ResourceCore
+	}
+}
@@ -0,0 +1,256 @@
+package githubapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPI is a GitHub API answering with the rate limit of the token of the requests.
+type fakeAPI struct {
+	mu sync.Mutex
+	// remaining the remaining requests by token.
+	remaining map[string]int
+	// retryAfter the tokens hitting a secondary rate limit.
+	retryAfter map[string]int
+	reset      time.Time
+	// tokens the tokens of the received requests.
+	tokens []string
+}
+
+func (f *fakeAPI) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	token := req.Header.Get("Authorization")
+	f.tokens = append(f.tokens, token)
+
+	resource := "core"
+	if req.URL.Path == "/search/repositories" {
+		resource = "search"
+	}
+
+	rw.Header().Set("X-RateLimit-Resource", resource)
+	rw.Header().Set("X-RateLimit-Reset", strconv.FormatInt(f.reset.Unix(), 10))
+	rw.Header().Set("X-RateLimit-Limit", "5000")
+
+	if seconds, ok := f.retryAfter[token]; ok {
+		rw.Header().Set("Retry-After", strconv.Itoa(seconds))
+		rw.Header().Set("X-RateLimit-Remaining", strconv.Itoa(f.remaining[token]))
+		rw.WriteHeader(http.StatusForbidden)
+
+		return
+	}
+
+	if f.remaining[token] == 0 {
+		rw.Header().Set("X-RateLimit-Remaining", "0")
+		rw.WriteHeader(http.StatusForbidden)
+
+		return
+	}
+
+	f.remaining[token]--
+
+	rw.Header().Set("X-RateLimit-Remaining", strconv.Itoa(f.remaining[token]))
+	rw.WriteHeader(http.StatusOK)
+}
+
+func (f *fakeAPI) received() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tokens := f.tokens
+	f.tokens = nil
+
+	return tokens
+}
+
+func setupTransport(t *testing.T, api *fakeAPI, reserve int) (*Transport, string) {
+	t.Helper()
+
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	transport := NewTransport(nil, []Credential{
+		{Name: "token-1", Source: StaticToken("one")},
+		{Name: "token-2", Source: StaticToken("two")},
+	}, reserve)
+
+	return transport, server.URL
+}
+
+func get(t *testing.T, transport http.RoundTripper, url string) (*http.Response, error) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, http.NoBody)
+	require.NoError(t, err)
+
+	resp, err := transport.RoundTrip(req)
+	if err == nil {
+		_ = resp.Body.Close()
+	}
+
+	return resp, err
+}
+
+func TestTransport_RoundTrip_largestBudget(t *testing.T) {
+	api := &fakeAPI{
+		remaining: map[string]int{"Bearer one": 100, "Bearer two": 300},
+		reset:     time.Now().Add(time.Hour),
+	}
+
+	transport, serverURL := setupTransport(t, api, 10)
+
+	for range 4 {
+		resp, err := get(t, transport, serverURL+"/repos/traefik/plugindemo")
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	// The credentials without known budget are used first, then the one with the largest budget.
+	assert.Equal(t, []string{"Bearer one", "Bearer two", "Bearer two", "Bearer two"}, api.received())
+
+	budgets := transport.Budgets()
+	require.Len(t, budgets, 2)
+
+	assert.Equal(t, "token-1", budgets[0].Credential)
+	assert.Equal(t, ResourceCore, budgets[0].Resource)
+	assert.Equal(t, 5000, budgets[0].Limit)
+	assert.Equal(t, 99, budgets[0].Remaining)
+	assert.Equal(t, api.reset.Unix(), budgets[0].Reset.Unix())
+
+	assert.Equal(t, "token-2", budgets[1].Credential)
+	assert.Equal(t, 297, budgets[1].Remaining)
+}
+
+func TestTransport_RoundTrip_resources(t *testing.T) {
+	api := &fakeAPI{
+		remaining: map[string]int{"Bearer one": 11, "Bearer two": 11},
+		reset:     time.Now().Add(time.Hour),
+	}
+
+	transport, serverURL := setupTransport(t, api, 10)
+
+	// Both credentials are down to the reserve for the core resource.
+	for range 2 {
+		_, err := get(t, transport, serverURL+"/repos/traefik/plugindemo")
+		require.NoError(t, err)
+	}
+
+	_, err := get(t, transport, serverURL+"/repos/traefik/plugindemo")
+
+	var rateLimitedErr *RateLimitedError
+	require.ErrorAs(t, err, &rateLimitedErr)
+	assert.Equal(t, ResourceCore, rateLimitedErr.Resource)
+	assert.Equal(t, api.reset.Unix(), rateLimitedErr.Reset.Unix())
+
+	assert.Equal(t, []string{"Bearer one", "Bearer two"}, api.received())
+
+	// The search budget is tracked separately.
+	api.remaining["Bearer one"] = 30
+
+	_, err = get(t, transport, serverURL+"/search/repositories")
+	require.NoError(t, err)
+}
+
+func TestTransport_RoundTrip_rotation(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		remaining  map[string]int
+		retryAfter map[string]int
+		expected   []string
+		expStatus  int
+	}{
+		{
+			desc:      "primary rate limit",
+			remaining: map[string]int{"Bearer one": 0, "Bearer two": 100},
+			expected:  []string{"Bearer one", "Bearer two"},
+			expStatus: http.StatusOK,
+		},
+		{
+			desc:       "secondary rate limit",
+			remaining:  map[string]int{"Bearer one": 100, "Bearer two": 100},
+			retryAfter: map[string]int{"Bearer one": 60},
+			expected:   []string{"Bearer one", "Bearer two"},
+			expStatus:  http.StatusOK,
+		},
+		{
+			desc:      "all credentials limited",
+			remaining: map[string]int{"Bearer one": 0, "Bearer two": 0},
+			expected:  []string{"Bearer one", "Bearer two"},
+			expStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			api := &fakeAPI{
+				remaining:  test.remaining,
+				retryAfter: test.retryAfter,
+				reset:      time.Now().Add(time.Hour),
+			}
+
+			transport, serverURL := setupTransport(t, api, 10)
+
+			resp, err := get(t, transport, serverURL+"/repos/traefik/plugindemo")
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expStatus, resp.StatusCode)
+			assert.Equal(t, test.expected, api.received())
+		})
+	}
+}
+
+func TestTransport_RoundTrip_notReplayable(t *testing.T) {
+	api := &fakeAPI{
+		remaining: map[string]int{"Bearer one": 0, "Bearer two": 100},
+		reset:     time.Now().Add(time.Hour),
+	}
+
+	transport, serverURL := setupTransport(t, api, 10)
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, serverURL+"/repos/traefik/plugindemo/issues", http.NoBody)
+	require.NoError(t, err)
+
+	resp, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+
+	_ = resp.Body.Close()
+
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, []string{"Bearer one"}, api.received())
+
+	// The next request uses the other credential.
+	_, err = get(t, transport, serverURL+"/repos/traefik/plugindemo")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Bearer two"}, api.received())
+}
+
+func TestTransport_RoundTrip_anonymous(t *testing.T) {
+	api := &fakeAPI{
+		remaining: map[string]int{"": 60},
+		reset:     time.Now().Add(time.Hour),
+	}
+
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	transport := NewTransport(nil, nil, DefaultReserve)
+
+	_, err := get(t, transport, server.URL+"/repos/traefik/plugindemo")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{""}, api.received())
+	assert.Equal(t, []Budget{{Credential: "anonymous", Resource: ResourceCore, Limit: 5000, Remaining: 59, Reset: time.Unix(api.reset.Unix(), 0)}}, transport.Budgets())
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"container/list"
+	"sync"
+)
+
+type cachedArchive struct {
+	key string
+	raw []byte
+}
+
+// archiveCache keeps the last served archives in memory, up to a total size, the least recently served are evicted first.
+// The archives are served from the cache while the circuit breaker of their upstream is open.
+// A nil cache keeps nothing.
+type archiveCache struct {
+	mu      sync.Mutex
+	maxSize int64
+	size    int64
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+func newArchiveCache(maxSize int64) *archiveCache {
+	if maxSize <= 0 {
+		return nil
+	}
+
+	return &archiveCache{
+		maxSize: maxSize,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (c *archiveCache) get(moduleName, version string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elt, ok := c.entries[moduleName+"@"+version]
+	if !ok {
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elt)
+
+	return elt.Value.(cachedArchive).raw, true
+}
+
+func (c *archiveCache) set(moduleName, version string, raw []byte) {
+	if c == nil || int64(len(raw)) > c.maxSize {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := moduleName + "@" + version
+
+	if elt, ok := c.entries[key]; ok {
+		c.size -= int64(len(elt.Value.(cachedArchive).raw))
+		c.lru.Remove(elt)
+	}
+
+	c.entries[key] = c.lru.PushFront(cachedArchive{key: key, raw: raw})
+	c.size += int64(len(raw))
+
+	for c.size > c.maxSize {
+		oldest := c.lru.Remove(c.lru.Back()).(cachedArchive)
+
+		delete(c.entries, oldest.key)
+		c.size -= int64(len(oldest.raw))
+	}
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchiveCache(t *testing.T) {
+	cache := newArchiveCache(10)
+
+	cache.set("a", "v1", []byte("1234"))
+	cache.set("b", "v1", []byte("1234"))
+
+	// Serving a refreshes it, b is the least recently served.
+	_, ok := cache.get("a", "v1")
+	assert.True(t, ok)
+
+	cache.set("c", "v1", []byte("1234"))
+
+	_, ok = cache.get("b", "v1")
+	assert.False(t, ok)
+
+	raw, ok := cache.get("a", "v1")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1234"), raw)
+
+	// An archive larger than the cache is not kept.
+	cache.set("d", "v1", []byte("12345678901"))
+
+	_, ok = cache.get("d", "v1")
+	assert.False(t, ok)
+
+	_, ok = cache.get("c", "v1")
+	assert.True(t, ok)
+
+	// A disabled cache keeps nothing.
+	var disabled *archiveCache
+
+	disabled.set("a", "v1", []byte("1234"))
+
+	_, ok = disabled.get("a", "v1")
+	assert.False(t, ok)
+}
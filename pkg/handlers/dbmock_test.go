@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"time"
 
 	"github.com/traefik/plugin-service/pkg/db"
 )
@@ -11,14 +12,34 @@ type mockDB struct {
 	deleteFn       func(ctx context.Context, id string) error
 	createFn       func(context.Context, db.Plugin) (db.Plugin, error)
 	listFn         func(context.Context, db.Pagination) ([]db.Plugin, string, error)
+	listAllFn      func(ctx context.Context) ([]db.Plugin, error)
 	getByNameFn    func(context.Context, string, bool) (db.Plugin, error)
 	searchByNameFn func(context.Context, string, db.Pagination) ([]db.Plugin, string, error)
 	updateFn       func(context.Context, string, db.Plugin) (db.Plugin, error)
 
 	deleteHashFn         func(ctx context.Context, id string) error
 	createHashFn         func(ctx context.Context, module, version, hash string) (db.PluginHash, error)
-	updateHashVerifiedFn func(ctx context.Context, module, version, hash string, verified bool) (db.PluginHash, error)
+	updateHashVerifiedFn func(ctx context.Context, module, version, hash string, verified bool, reasons []string) (db.PluginHash, error)
 	getHashByNameFn      func(ctx context.Context, module, version string) (db.PluginHash, error)
+	updateHashManifestFn func(ctx context.Context, module, version string, manifest db.Manifest) (db.PluginHash, error)
+	listHashesFn         func(ctx context.Context, module string) ([]db.PluginHash, error)
+
+	updateHashCapabilitiesFn func(ctx context.Context, module, version string, report db.CapabilityReport) (db.PluginHash, error)
+	updateUseUnsafeFn        func(ctx context.Context, id string, useUnsafe bool) (db.Plugin, error)
+
+	updateHashQuarantinedFn func(ctx context.Context, module, version string, quarantined bool) (db.PluginHash, error)
+	updateHashSignerFn      func(ctx context.Context, module, version, signer string) (db.PluginHash, error)
+	updateHashDigestsFn     func(ctx context.Context, module, version string, digests map[string]string) (db.PluginHash, error)
+	resetHashFn             func(ctx context.Context, module, version, hash string) (db.PluginHash, error)
+
+	createHashResetFn func(ctx context.Context, reset db.HashReset) (db.HashReset, error)
+	listHashResetsFn  func(ctx context.Context, module string) ([]db.HashReset, error)
+
+	createIncidentFn     func(ctx context.Context, incident db.Incident) (db.Incident, error)
+	listIncidentsFn      func(ctx context.Context, module string) ([]db.Incident, error)
+	countIncidentsFn     func(ctx context.Context, module, version, kind string, since time.Time) (int64, error)
+	getLastIncidentFn    func(ctx context.Context, module, version, kind string) (db.Incident, error)
+	aggregateIncidentsFn func(ctx context.Context) ([]db.IncidentSummary, error)
 }
 
 func (m mockDB) Get(ctx context.Context, id string) (db.Plugin, error) {
@@ -37,6 +58,10 @@ func (m mockDB) List(ctx context.Context, pagination db.Pagination) ([]db.Plugin
 	return m.listFn(ctx, pagination)
 }
 
+func (m mockDB) ListAll(ctx context.Context) ([]db.Plugin, error) {
+	return m.listAllFn(ctx)
+}
+
 func (m mockDB) GetByName(ctx context.Context, name string, _, filterHidden bool) (db.Plugin, error) {
 	return m.getByNameFn(ctx, name, filterHidden)
 }
@@ -57,10 +82,70 @@ func (m mockDB) CreateHash(ctx context.Context, module, version, hash string) (d
 	return m.createHashFn(ctx, module, version, hash)
 }
 
-func (m mockDB) UpdateHashVerified(ctx context.Context, module, version, hash string, verified bool) (db.PluginHash, error) {
-	return m.updateHashVerifiedFn(ctx, module, version, hash, verified)
+func (m mockDB) UpdateHashVerified(ctx context.Context, module, version, hash string, verified bool, reasons []string) (db.PluginHash, error) {
+	return m.updateHashVerifiedFn(ctx, module, version, hash, verified, reasons)
 }
 
 func (m mockDB) GetHashByName(ctx context.Context, module, version string) (db.PluginHash, error) {
 	return m.getHashByNameFn(ctx, module, version)
 }
+
+func (m mockDB) UpdateHashManifest(ctx context.Context, module, version string, manifest db.Manifest) (db.PluginHash, error) {
+	return m.updateHashManifestFn(ctx, module, version, manifest)
+}
+
+func (m mockDB) ListHashes(ctx context.Context, module string) ([]db.PluginHash, error) {
+	return m.listHashesFn(ctx, module)
+}
+
+func (m mockDB) UpdateHashCapabilities(ctx context.Context, module, version string, report db.CapabilityReport) (db.PluginHash, error) {
+	return m.updateHashCapabilitiesFn(ctx, module, version, report)
+}
+
+func (m mockDB) UpdateUseUnsafe(ctx context.Context, id string, useUnsafe bool) (db.Plugin, error) {
+	return m.updateUseUnsafeFn(ctx, id, useUnsafe)
+}
+
+func (m mockDB) UpdateHashQuarantined(ctx context.Context, module, version string, quarantined bool) (db.PluginHash, error) {
+	return m.updateHashQuarantinedFn(ctx, module, version, quarantined)
+}
+
+func (m mockDB) UpdateHashSigner(ctx context.Context, module, version, signer string) (db.PluginHash, error) {
+	return m.updateHashSignerFn(ctx, module, version, signer)
+}
+
+func (m mockDB) UpdateHashDigests(ctx context.Context, module, version string, digests map[string]string) (db.PluginHash, error) {
+	return m.updateHashDigestsFn(ctx, module, version, digests)
+}
+
+func (m mockDB) ResetHash(ctx context.Context, module, version, hash string) (db.PluginHash, error) {
+	return m.resetHashFn(ctx, module, version, hash)
+}
+
+func (m mockDB) CreateHashReset(ctx context.Context, reset db.HashReset) (db.HashReset, error) {
+	return m.createHashResetFn(ctx, reset)
+}
+
+func (m mockDB) ListHashResets(ctx context.Context, module string) ([]db.HashReset, error) {
+	return m.listHashResetsFn(ctx, module)
+}
+
+func (m mockDB) CreateIncident(ctx context.Context, incident db.Incident) (db.Incident, error) {
+	return m.createIncidentFn(ctx, incident)
+}
+
+func (m mockDB) ListIncidents(ctx context.Context, module string) ([]db.Incident, error) {
+	return m.listIncidentsFn(ctx, module)
+}
+
+func (m mockDB) CountIncidents(ctx context.Context, module, version, kind string, since time.Time) (int64, error) {
+	return m.countIncidentsFn(ctx, module, version, kind, since)
+}
+
+func (m mockDB) GetLastIncident(ctx context.Context, module, version, kind string) (db.Incident, error) {
+	return m.getLastIncidentFn(ctx, module, version, kind)
+}
+
+func (m mockDB) AggregateIncidents(ctx context.Context) ([]db.IncidentSummary, error) {
+	return m.aggregateIncidentsFn(ctx)
+}
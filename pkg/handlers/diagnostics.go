@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/plugin-service/pkg/archive"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+const defaultRuntime = "yaegi"
+
+type diagnostic struct {
+	Version  string `json:"version"`
+	Field    string `json:"field"`
+	Catalog  string `json:"catalog,omitempty"`
+	Manifest string `json:"manifest,omitempty"`
+	Message  string `json:"message"`
+}
+
+type diagnostics struct {
+	ID          string       `json:"id"`
+	Name        string       `json:"name"`
+	Diagnostics []diagnostic `json:"diagnostics"`
+}
+
+// Diagnostics reports the inconsistencies between the catalog and the manifests of a plugin.
+func (h Handlers) Diagnostics(rw http.ResponseWriter, req *http.Request) {
+	ctx, span := h.tracer.Start(req.Context(), "handler_diagnostics")
+	defer span.End()
+
+	rw.Header().Set("Content-Type", "application/json")
+
+	id, err := getSubPathParam(req.URL, "diagnostics")
+	if err != nil {
+		span.RecordError(err)
+		JSONError(rw, http.StatusBadRequest, "Missing plugin id")
+
+		return
+	}
+
+	logger := log.With().Str("plugin_id", id).Logger()
+
+	plugin, err := h.store.Get(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+
+		if errors.As(err, &db.NotFoundError{}) {
+			NotFound(rw, req)
+			return
+		}
+
+		logger.Error().Err(err).Msg("Error while trying to get plugin")
+		JSONInternalServerError(rw)
+
+		return
+	}
+
+	hashes, err := h.store.ListHashes(ctx, plugin.Name)
+	if err != nil && !errors.As(err, &db.NotFoundError{}) {
+		span.RecordError(err)
+		logger.Error().Err(err).Msg("Error while trying to get plugin hashes")
+		JSONInternalServerError(rw)
+
+		return
+	}
+
+	resp := diagnostics{
+		ID:          plugin.ID,
+		Name:        plugin.Name,
+		Diagnostics: diagnose(plugin, hashes),
+	}
+
+	if err := json.NewEncoder(rw).Encode(resp); err != nil {
+		span.RecordError(err)
+		logger.Error().Err(err).Msg("Failed to encode response")
+		JSONInternalServerError(rw)
+
+		return
+	}
+}
+
+func diagnose(plugin db.Plugin, hashes []db.PluginHash) []diagnostic {
+	manifests := make(map[string]*db.Manifest)
+	for _, hash := range hashes {
+		manifests[hash.Name] = hash.Manifest
+	}
+
+	diags := make([]diagnostic, 0)
+
+	for _, version := range plugin.Versions {
+		manifest := manifests[plugin.Name+"@"+version]
+		if manifest == nil {
+			diags = append(diags, diagnostic{
+				Version: version,
+				Field:   "manifest",
+				Message: "manifest not available",
+			})
+
+			continue
+		}
+
+		diags = append(diags, diagnoseVersion(plugin, version, *manifest)...)
+	}
+
+	return diags
+}
+
+func diagnoseVersion(plugin db.Plugin, version string, manifest db.Manifest) []diagnostic {
+	var diags []diagnostic
+
+	mismatch := func(field, catalog, manifest, msg string) {
+		diags = append(diags, diagnostic{
+			Version:  version,
+			Field:    field,
+			Catalog:  catalog,
+			Manifest: manifest,
+			Message:  msg,
+		})
+	}
+
+	catalogRuntime := runtimeOrDefault(plugin.Runtime)
+	manifestRuntime := runtimeOrDefault(manifest.Runtime)
+
+	if catalogRuntime != manifestRuntime {
+		mismatch("runtime", catalogRuntime, manifestRuntime, "runtime differs from the catalog")
+	}
+
+	if manifest.Import != plugin.Name && !strings.HasPrefix(manifest.Import, plugin.Name+"/") {
+		mismatch("import", plugin.Name, manifest.Import, "import differs from the module path")
+	} else if plugin.Import != "" && manifest.Import != plugin.Import {
+		mismatch("import", plugin.Import, manifest.Import, "import differs from the catalog")
+	}
+
+	if manifest.Type != plugin.Type {
+		mismatch("type", plugin.Type, manifest.Type, "type differs from the catalog")
+	}
+
+	if manifestRuntime == "wasm" && wasmPathOrDefault(manifest.WasmPath) != wasmPathOrDefault(plugin.WasmPath) {
+		mismatch("wasmPath", wasmPathOrDefault(plugin.WasmPath), wasmPathOrDefault(manifest.WasmPath), "WASM path differs from the catalog")
+	}
+
+	// The descriptive fields of the catalog come from the latest version.
+	if version != plugin.LatestVersion {
+		return diags
+	}
+
+	if manifest.DisplayName != plugin.DisplayName {
+		mismatch("displayName", plugin.DisplayName, manifest.DisplayName, "display name differs from the catalog")
+	}
+
+	if manifest.Summary != plugin.Summary {
+		mismatch("summary", plugin.Summary, manifest.Summary, "summary differs from the catalog")
+	}
+
+	if len(manifest.TestData) == 0 && len(plugin.Snippet) > 0 {
+		mismatch("testData", "", "", "snippet is not backed by the manifest testData")
+	}
+
+	return diags
+}
+
+func runtimeOrDefault(runtime string) string {
+	if runtime == "" {
+		return defaultRuntime
+	}
+
+	return strings.ToLower(runtime)
+}
+
+func wasmPathOrDefault(wasmPath string) string {
+	if wasmPath == "" {
+		return archive.DefaultWasmPath
+	}
+
+	return strings.TrimPrefix(wasmPath, "./")
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+func TestHandlers_Diagnostics(t *testing.T) {
+	plugin := db.Plugin{
+		ID:            "276809780784267776",
+		Name:          "github.com/traefik/plugindemo",
+		DisplayName:   "Demo Plugin",
+		Import:        "github.com/traefik/plugindemo",
+		LatestVersion: "v0.2.1",
+		Summary:       "[Demo] Add Request Header",
+		Type:          "middleware",
+		Snippet:       map[string]interface{}{"yaml": "yaml"},
+		Versions:      []string{"v0.2.1", "v0.2.0", "v0.1.0"},
+	}
+
+	manifest := db.Manifest{
+		DisplayName: "Demo Plugin",
+		Type:        "middleware",
+		Import:      "github.com/traefik/plugindemo",
+		Summary:     "[Demo] Add Request Header",
+		TestData:    map[string]interface{}{"Headers": "test"},
+	}
+
+	testCases := []struct {
+		desc           string
+		url            string
+		plugin         db.Plugin
+		pluginErr      error
+		hashes         []db.PluginHash
+		hashesErr      error
+		expectedStatus int
+		expected       []diagnostic
+	}{
+		{
+			desc:           "consistent",
+			plugin:         plugin,
+			hashes:         []db.PluginHash{withManifest("v0.2.1", manifest), withManifest("v0.2.0", manifest), withManifest("v0.1.0", manifest)},
+			expectedStatus: http.StatusOK,
+			expected:       []diagnostic{},
+		},
+		{
+			desc:           "versions not analyzed",
+			plugin:         plugin,
+			hashes:         []db.PluginHash{withManifest("v0.2.1", manifest), {Name: plugin.Name + "@v0.2.0", Hash: "123"}},
+			expectedStatus: http.StatusOK,
+			expected: []diagnostic{
+				{Version: "v0.2.0", Field: "manifest", Message: "manifest not available"},
+				{Version: "v0.1.0", Field: "manifest", Message: "manifest not available"},
+			},
+		},
+		{
+			desc:           "no hashes",
+			plugin:         db.Plugin{ID: "123", Name: "github.com/traefik/plugindemo", Versions: []string{"v0.1.0"}},
+			hashesErr:      db.NotFoundError{},
+			expectedStatus: http.StatusOK,
+			expected: []diagnostic{
+				{Version: "v0.1.0", Field: "manifest", Message: "manifest not available"},
+			},
+		},
+		{
+			desc:   "runtime mismatch",
+			plugin: plugin,
+			hashes: []db.PluginHash{
+				withManifest("v0.2.1", manifest),
+				withManifest("v0.2.0", func() db.Manifest { m := manifest; m.Runtime = "wasm"; return m }()),
+				withManifest("v0.1.0", manifest),
+			},
+			expectedStatus: http.StatusOK,
+			expected: []diagnostic{
+				{Version: "v0.2.0", Field: "runtime", Catalog: "yaegi", Manifest: "wasm", Message: "runtime differs from the catalog"},
+			},
+		},
+		{
+			desc:   "WASM path mismatch",
+			plugin: func() db.Plugin { p := plugin; p.Runtime = "wasm"; return p }(),
+			hashes: []db.PluginHash{
+				withManifest("v0.2.1", func() db.Manifest { m := manifest; m.Runtime = "wasm"; m.WasmPath = "./build/demo.wasm"; return m }()),
+				withManifest("v0.2.0", func() db.Manifest { m := manifest; m.Runtime = "wasm"; m.WasmPath = "plugin.wasm"; return m }()),
+				withManifest("v0.1.0", func() db.Manifest { m := manifest; m.Runtime = "wasm"; return m }()),
+			},
+			expectedStatus: http.StatusOK,
+			expected: []diagnostic{
+				{Version: "v0.2.1", Field: "wasmPath", Catalog: "plugin.wasm", Manifest: "build/demo.wasm", Message: "WASM path differs from the catalog"},
+			},
+		},
+		{
+			desc:   "import mismatch",
+			plugin: plugin,
+			hashes: []db.PluginHash{
+				withManifest("v0.2.1", func() db.Manifest { m := manifest; m.Import = "github.com/someone/plugindemo"; return m }()),
+				withManifest("v0.2.0", func() db.Manifest { m := manifest; m.Import = "github.com/traefik/plugindemo/demo"; return m }()),
+				withManifest("v0.1.0", manifest),
+			},
+			expectedStatus: http.StatusOK,
+			expected: []diagnostic{
+				{Version: "v0.2.1", Field: "import", Catalog: "github.com/traefik/plugindemo", Manifest: "github.com/someone/plugindemo", Message: "import differs from the module path"},
+				{Version: "v0.2.0", Field: "import", Catalog: "github.com/traefik/plugindemo", Manifest: "github.com/traefik/plugindemo/demo", Message: "import differs from the catalog"},
+			},
+		},
+		{
+			desc:   "descriptive fields of the latest version",
+			plugin: plugin,
+			hashes: []db.PluginHash{
+				withManifest("v0.2.1", db.Manifest{Type: "provider", Import: "github.com/traefik/plugindemo"}),
+				withManifest("v0.2.0", db.Manifest{Type: "middleware", Import: "github.com/traefik/plugindemo"}),
+				withManifest("v0.1.0", manifest),
+			},
+			expectedStatus: http.StatusOK,
+			expected: []diagnostic{
+				{Version: "v0.2.1", Field: "type", Catalog: "middleware", Manifest: "provider", Message: "type differs from the catalog"},
+				{Version: "v0.2.1", Field: "displayName", Catalog: "Demo Plugin", Message: "display name differs from the catalog"},
+				{Version: "v0.2.1", Field: "summary", Catalog: "[Demo] Add Request Header", Message: "summary differs from the catalog"},
+				{Version: "v0.2.1", Field: "testData", Message: "snippet is not backed by the manifest testData"},
+			},
+		},
+		{
+			desc:           "missing id",
+			url:            "/diagnostics",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			desc:           "unknown plugin",
+			pluginErr:      db.NotFoundError{},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			desc:           "failed to get plugin",
+			pluginErr:      errors.New("boom"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			desc:           "failed to get hashes",
+			plugin:         plugin,
+			hashesErr:      errors.New("boom"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			testDB := mockDB{
+				getFn: func(_ context.Context, _ string) (db.Plugin, error) {
+					return test.plugin, test.pluginErr
+				},
+				listHashesFn: func(_ context.Context, _ string) ([]db.PluginHash, error) {
+					return test.hashes, test.hashesErr
+				},
+			}
+
+			url := test.url
+			if url == "" {
+				url = "/123/diagnostics"
+			}
+
+			rw := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, url, http.NoBody)
+
+			New(testDB, nil).Diagnostics(rw, req)
+
+			assert.Equal(t, test.expectedStatus, rw.Code)
+
+			if test.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var got diagnostics
+			require.NoError(t, json.NewDecoder(rw.Body).Decode(&got))
+
+			assert.Equal(t, test.plugin.ID, got.ID)
+			assert.Equal(t, test.expected, got.Diagnostics)
+		})
+	}
+}
+
+func withManifest(version string, manifest db.Manifest) db.PluginHash {
+	return db.PluginHash{
+		Name:     "github.com/traefik/plugindemo@" + version,
+		Hash:     "123",
+		Manifest: &manifest,
+	}
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/plugin-service/pkg/db"
+	"github.com/traefik/plugin-service/pkg/events"
+)
+
+// eventsHeartbeat the interval of the comments keeping the idle event streams open through the proxies.
+const eventsHeartbeat = 30 * time.Second
+
+// publicEventTypes the types of the events served by the public events endpoint.
+var publicEventTypes = []string{
+	db.EventPluginCreated,
+	db.EventPluginUpdated,
+	db.EventVersionPublished,
+	db.EventPluginHidden,
+	db.EventPluginUnhidden,
+	db.EventPluginDisabled,
+}
+
+// Events streams the catalog changes as server-sent events.
+// The stream is resumed after the cursor given by the since query parameter, or by the Last-Event-ID header on reconnection.
+func (h Handlers) Events(rw http.ResponseWriter, req *http.Request) {
+	ctx, span := h.tracer.Start(req.Context(), "handler_events")
+	defer span.End()
+
+	if h.events == nil {
+		NotFound(rw, req)
+		return
+	}
+
+	since := req.URL.Query().Get("since")
+	if since == "" {
+		since = req.Header.Get("Last-Event-ID")
+	}
+
+	logger := log.With().Str("events_since", since).Logger()
+
+	records, err := h.events.WatchEvents(ctx, since)
+	if err != nil {
+		span.RecordError(err)
+
+		if errors.As(err, &db.NotFoundError{}) {
+			JSONErrorf(rw, http.StatusGone, "Unknown or expired cursor: %s", since)
+			return
+		}
+
+		if errors.Is(err, events.ErrTooManyStreams) {
+			JSONError(rw, http.StatusServiceUnavailable, "Too many event streams, retry later")
+			return
+		}
+
+		logger.Error().Err(err).Msg("Error while trying to watch events")
+		JSONInternalServerError(rw)
+
+		return
+	}
+
+	rc := http.NewResponseController(rw)
+
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.Header().Set("X-Accel-Buffering", "no")
+	rw.WriteHeader(http.StatusOK)
+
+	if err = rc.Flush(); err != nil {
+		span.RecordError(err)
+		logger.Error().Err(err).Msg("Unable to stream events")
+
+		return
+	}
+
+	pending := make(chan db.EventRecord)
+
+	go func() {
+		defer close(pending)
+
+		for record, err := range records {
+			if err != nil {
+				logger.Warn().Err(err).Msg("Event stream interrupted")
+				return
+			}
+
+			select {
+			case pending <- record:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-heartbeat.C:
+			if _, err = io.WriteString(rw, ": heartbeat\n\n"); err != nil {
+				return
+			}
+
+		case record, ok := <-pending:
+			if !ok {
+				return
+			}
+
+			event, ok := publicEvent(record.Event)
+			if !ok {
+				continue
+			}
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				span.RecordError(err)
+				logger.Error().Err(err).Str("event_id", event.ID).Msg("Failed to encode event")
+
+				continue
+			}
+
+			if _, err = fmt.Fprintf(rw, "id: %s\nevent: %s\ndata: %s\n\n", record.Cursor, event.Type, data); err != nil {
+				return
+			}
+		}
+
+		if err = rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// publicEvent returns an event as it is served publicly.
+// The changes of the hidden and disabled plugins are not served, only the fact that they have been hidden or disabled.
+func publicEvent(event db.Event) (db.Event, bool) {
+	if !slices.Contains(publicEventTypes, event.Type) || event.Plugin == nil {
+		return db.Event{}, false
+	}
+
+	if event.Type == db.EventPluginHidden || event.Type == db.EventPluginDisabled {
+		event.Plugin = nil
+		return event, true
+	}
+
+	if event.Plugin.Hidden || event.Plugin.Disabled {
+		return db.Event{}, false
+	}
+
+	return event, true
+}
+
+// emitVerification emits the verification outcome of a plugin version.
+func (h Handlers) emitVerification(ctx context.Context, moduleName, version, sum string, reasons []string) {
+	verified := len(reasons) == 0
+
+	h.emitter.Emit(ctx, db.Event{
+		Type:     db.EventHashVerified,
+		Module:   moduleName,
+		Version:  version,
+		Hash:     sum,
+		Verified: &verified,
+		Reasons:  reasons,
+	})
+}
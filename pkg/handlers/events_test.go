@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/plugin-service/pkg/db"
+	"github.com/traefik/plugin-service/pkg/events"
+)
+
+func TestHandlers_Events(t *testing.T) {
+	plugin := db.Plugin{ID: "123", Name: "github.com/traefik/plugindemo"}
+	hidden := db.Plugin{ID: "456", Name: "github.com/traefik/hidden", Hidden: true}
+	disabled := db.Plugin{ID: "789", Name: "github.com/traefik/disabled", Disabled: true}
+
+	bus := events.NewBus(events.DefaultHistory)
+
+	for _, event := range []db.Event{
+		{ID: "e1", Type: db.EventPluginCreated, Module: plugin.Name, Plugin: &plugin},
+		{ID: "e2", Type: db.EventHashVerified, Module: plugin.Name, Version: "v0.1.0"},
+		{ID: "e3", Type: db.EventPluginUpdated, Module: hidden.Name, Plugin: &hidden},
+		{ID: "e4", Type: db.EventPluginHidden, Module: hidden.Name, Plugin: &hidden},
+		{ID: "e5", Type: db.EventVersionPublished, Module: plugin.Name, Version: "v0.2.0", Plugin: &plugin},
+		{ID: "e6", Type: db.EventPluginUpdated, Module: disabled.Name, Plugin: &disabled},
+		{ID: "e7", Type: db.EventPluginDisabled, Module: disabled.Name, Plugin: &disabled},
+	} {
+		require.NoError(t, bus.PublishEvent(context.Background(), event))
+	}
+
+	testCases := []struct {
+		desc        string
+		url         string
+		lastEventID string
+		expected    []string
+	}{
+		{
+			desc: "from the first event",
+			url:  "/events?since=0",
+			expected: []string{
+				"id: 1",
+				"event: plugin.created",
+				`data: {"id":"e1","type":"plugin.created","module":"github.com/traefik/plugindemo","plugin":{"id":"123","name":"github.com/traefik/plugindemo","createdAt":"0001-01-01T00:00:00Z"},"createdAt":"0001-01-01T00:00:00Z"}`,
+				"",
+				"id: 4",
+				"event: plugin.hidden",
+				`data: {"id":"e4","type":"plugin.hidden","module":"github.com/traefik/hidden","createdAt":"0001-01-01T00:00:00Z"}`,
+				"",
+				"id: 5",
+				"event: version.published",
+				`data: {"id":"e5","type":"version.published","module":"github.com/traefik/plugindemo","version":"v0.2.0","plugin":{"id":"123","name":"github.com/traefik/plugindemo","createdAt":"0001-01-01T00:00:00Z"},"createdAt":"0001-01-01T00:00:00Z"}`,
+				"",
+				"id: 7",
+				"event: plugin.disabled",
+				`data: {"id":"e7","type":"plugin.disabled","module":"github.com/traefik/disabled","createdAt":"0001-01-01T00:00:00Z"}`,
+				"",
+			},
+		},
+		{
+			desc:        "reconnection",
+			url:         "/events",
+			lastEventID: "4",
+			expected: []string{
+				"id: 5",
+				"event: version.published",
+				`data: {"id":"e5","type":"version.published","module":"github.com/traefik/plugindemo","version":"v0.2.0","plugin":{"id":"123","name":"github.com/traefik/plugindemo","createdAt":"0001-01-01T00:00:00Z"},"createdAt":"0001-01-01T00:00:00Z"}`,
+				"",
+				"id: 7",
+				"event: plugin.disabled",
+				`data: {"id":"e7","type":"plugin.disabled","module":"github.com/traefik/disabled","createdAt":"0001-01-01T00:00:00Z"}`,
+				"",
+			},
+		},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(New(mockDB{}, nil, WithEventStream(bus)).Events))
+	t.Cleanup(server.Close)
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+test.url, http.NoBody)
+			require.NoError(t, err)
+
+			if test.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", test.lastEventID)
+			}
+
+			resp, err := server.Client().Do(req)
+			require.NoError(t, err)
+
+			defer func() { _ = resp.Body.Close() }()
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+			var lines []string
+
+			scanner := bufio.NewScanner(resp.Body)
+			scanner.Buffer(nil, 1<<20)
+
+			for len(lines) < len(test.expected) && scanner.Scan() {
+				lines = append(lines, scanner.Text())
+			}
+
+			assert.Equal(t, test.expected, lines)
+		})
+	}
+}
+
+func TestHandlers_Events_errors(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		url            string
+		stream         EventStream
+		expectedStatus int
+	}{
+		{
+			desc:           "unknown cursor",
+			url:            "/events?since=42",
+			stream:         events.NewBus(events.DefaultHistory),
+			expectedStatus: http.StatusGone,
+		},
+		{
+			desc:           "too many event streams",
+			url:            "/events?since=42",
+			stream:         events.NewRelay(events.NewBus(events.DefaultHistory), events.DefaultHistory, 0),
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			desc:           "without event stream",
+			url:            "/events",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var opts []Option
+			if test.stream != nil {
+				opts = append(opts, WithEventStream(test.stream))
+			}
+
+			rw := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, test.url, http.NoBody)
+
+			New(mockDB{}, nil, opts...).Events(rw, req)
+
+			assert.Equal(t, test.expectedStatus, rw.Code)
+		})
+	}
+}
+
+func TestHandlers_Update_publish(t *testing.T) {
+	testDB := mockDB{
+		getFn: func(_ context.Context, id string) (db.Plugin, error) {
+			return db.Plugin{ID: id, Name: "github.com/traefik/plugindemo", Versions: []string{"v0.2.0"}}, nil
+		},
+		updateFn: func(_ context.Context, id string, plugin db.Plugin) (db.Plugin, error) {
+			plugin.ID = id
+
+			return plugin, nil
+		},
+	}
+
+	bus := events.NewBus(events.DefaultHistory)
+
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/123", strings.NewReader(`{"name": "github.com/traefik/plugindemo", "versions": ["v0.2.1", "v0.2.0"]}`))
+
+	New(testDB, nil, WithEventStream(bus)).Update(rw, req)
+
+	assert.Equal(t, http.StatusOK, rw.Code)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	records, err := bus.WatchEvents(ctx, "0")
+	require.NoError(t, err)
+
+	var types []string
+
+	for record, err := range records {
+		require.NoError(t, err)
+
+		types = append(types, record.Event.Type)
+		if len(types) == 2 {
+			break
+		}
+	}
+
+	assert.Equal(t, []string{db.EventPluginUpdated, db.EventVersionPublished}, types)
+}
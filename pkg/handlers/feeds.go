@@ -0,0 +1,371 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+const (
+	atomContentType = "application/atom+xml; charset=utf-8"
+
+	// maxFeedEntries the number of plugins of the new plugins feed.
+	maxFeedEntries = 50
+
+	// feedsAuthor the author of the feeds, and of the entries whose plugin doesn't have one.
+	feedsAuthor = "Traefik Plugin Catalog"
+
+	// feedsTTL the duration during which a rendered feed is served from the cache, and can be cached by the clients.
+	feedsTTL = 5 * time.Minute
+)
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Author   *atomPerson `xml:"author,omitempty"`
+	Icon     string      `xml:"icon,omitempty"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published,omitempty"`
+	Links     []atomLink  `xml:"link"`
+	Author    *atomPerson `xml:"author,omitempty"`
+	Summary   string      `xml:"summary,omitempty"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+// renderedFeed a feed ready to be served.
+type renderedFeed struct {
+	body     []byte
+	etag     string
+	updated  time.Time
+	expireAt time.Time
+}
+
+// feedCache keeps the rendered feeds for feedsTTL.
+type feedCache struct {
+	mu    sync.Mutex
+	feeds map[string]renderedFeed
+}
+
+func (c *feedCache) get(key string) (renderedFeed, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	feed, ok := c.feeds[key]
+	if !ok || time.Now().After(feed.expireAt) {
+		return renderedFeed{}, false
+	}
+
+	return feed, true
+}
+
+func (c *feedCache) set(key string, feed renderedFeed) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.feeds == nil {
+		c.feeds = make(map[string]renderedFeed)
+	}
+
+	now := time.Now()
+
+	for k, f := range c.feeds {
+		if now.After(f.expireAt) {
+			delete(c.feeds, k)
+		}
+	}
+
+	c.feeds[key] = feed
+}
+
+// PluginsFeed serves the Atom feed of the new plugins.
+func (h Handlers) PluginsFeed(rw http.ResponseWriter, req *http.Request) {
+	ctx, span := h.tracer.Start(req.Context(), "handler_pluginsFeed")
+	defer span.End()
+
+	if h.publicURL == "" {
+		NotFound(rw, req)
+		return
+	}
+
+	feed, err := h.cachedFeed(req.URL.Path, func() (atomFeed, error) {
+		return h.pluginsFeed(ctx, h.publicURL)
+	})
+	if err != nil {
+		span.RecordError(err)
+		log.Error().Err(err).Msg("Error while trying to build the plugins feed")
+		JSONInternalServerError(rw)
+
+		return
+	}
+
+	serveFeed(rw, req, feed)
+}
+
+// PluginFeed serves the Atom feed of the versions of a plugin.
+func (h Handlers) PluginFeed(rw http.ResponseWriter, req *http.Request) {
+	ctx, span := h.tracer.Start(req.Context(), "handler_pluginFeed")
+	defer span.End()
+
+	id, ok := strings.CutSuffix(path.Base(req.URL.Path), ".atom")
+	if !ok || id == "" || h.publicURL == "" {
+		NotFound(rw, req)
+		return
+	}
+
+	logger := log.With().Str("plugin_id", id).Logger()
+
+	feed, err := h.cachedFeed(req.URL.Path, func() (atomFeed, error) {
+		return h.pluginFeed(ctx, h.publicURL, id)
+	})
+	if err != nil {
+		span.RecordError(err)
+
+		if errors.As(err, &db.NotFoundError{}) {
+			NotFound(rw, req)
+			return
+		}
+
+		logger.Error().Err(err).Msg("Error while trying to build the plugin feed")
+		JSONInternalServerError(rw)
+
+		return
+	}
+
+	serveFeed(rw, req, feed)
+}
+
+func (h Handlers) pluginsFeed(ctx context.Context, baseURL string) (atomFeed, error) {
+	plugins, err := h.store.ListAll(ctx)
+	if err != nil {
+		return atomFeed{}, fmt.Errorf("list plugins: %w", err)
+	}
+
+	plugins = slices.DeleteFunc(plugins, func(plugin db.Plugin) bool { return plugin.Hidden || plugin.Disabled })
+
+	slices.SortStableFunc(plugins, func(a, b db.Plugin) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+
+	if len(plugins) > maxFeedEntries {
+		plugins = plugins[:maxFeedEntries]
+	}
+
+	self := baseURL + "/public/feeds/plugins.atom"
+
+	feed := atomFeed{
+		ID:       self,
+		Title:    "New plugins",
+		Subtitle: "The plugins recently added to the catalog.",
+		Links:    []atomLink{{Href: self, Rel: "self", Type: "application/atom+xml"}},
+		Author:   &atomPerson{Name: feedsAuthor},
+	}
+
+	var updated time.Time
+
+	for _, plugin := range plugins {
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:        baseURL + "/public/" + plugin.ID,
+			Title:     pluginTitle(plugin),
+			Updated:   formatAtomTime(plugin.CreatedAt),
+			Published: formatAtomTime(plugin.CreatedAt),
+			Links:     pluginLinks(baseURL, plugin),
+			Author:    pluginAuthor(plugin),
+			Summary:   plugin.Summary,
+		})
+
+		if plugin.CreatedAt.After(updated) {
+			updated = plugin.CreatedAt
+		}
+	}
+
+	feed.Updated = formatAtomTime(updated)
+
+	return feed, nil
+}
+
+func (h Handlers) pluginFeed(ctx context.Context, baseURL, id string) (atomFeed, error) {
+	plugin, err := h.store.Get(ctx, id)
+	if err != nil {
+		return atomFeed{}, fmt.Errorf("get plugin: %w", err)
+	}
+
+	if plugin.Hidden || plugin.Disabled {
+		return atomFeed{}, db.NotFoundError{Err: fmt.Errorf("plugin %s is not listed", id)}
+	}
+
+	hashes, err := h.store.ListHashes(ctx, plugin.Name)
+	if err != nil {
+		return atomFeed{}, fmt.Errorf("list hashes: %w", err)
+	}
+
+	// The versions are dated by their first seen hash, the versions seen before are dated by the creation of the plugin.
+	seenAt := make(map[string]time.Time)
+
+	for _, ph := range hashes {
+		if ph.CreatedAt != nil {
+			seenAt[strings.TrimPrefix(ph.Name, plugin.Name+"@")] = *ph.CreatedAt
+		}
+	}
+
+	self := baseURL + "/public/feeds/" + plugin.ID + ".atom"
+
+	feed := atomFeed{
+		ID:       self,
+		Title:    pluginTitle(plugin) + " releases",
+		Subtitle: plugin.Summary,
+		Links:    append([]atomLink{{Href: self, Rel: "self", Type: "application/atom+xml"}}, pluginLinks(baseURL, plugin)...),
+		Author:   pluginAuthor(plugin),
+		Icon:     plugin.IconURL,
+	}
+
+	updated := plugin.CreatedAt
+
+	for _, version := range plugin.Versions {
+		publishedAt := plugin.CreatedAt
+		if at, ok := seenAt[version]; ok && at.After(publishedAt) {
+			publishedAt = at
+		}
+
+		entry := atomEntry{
+			ID:        baseURL + "/public/" + plugin.ID + "#" + version,
+			Title:     pluginTitle(plugin) + " " + version,
+			Updated:   formatAtomTime(publishedAt),
+			Published: formatAtomTime(publishedAt),
+			Summary:   plugin.Summary,
+		}
+
+		if strings.HasPrefix(plugin.Name, "github.com/") {
+			entry.Links = []atomLink{{Href: "https://" + plugin.Name + "/releases/tag/" + version, Rel: "alternate", Type: "text/html"}}
+		}
+
+		feed.Entries = append(feed.Entries, entry)
+
+		if publishedAt.After(updated) {
+			updated = publishedAt
+		}
+	}
+
+	feed.Updated = formatAtomTime(updated)
+
+	return feed, nil
+}
+
+// cachedFeed returns the feed from the cache, or builds and renders it.
+// The feeds are cached by path, their links are built from the configured public URL.
+func (h Handlers) cachedFeed(key string, build func() (atomFeed, error)) (renderedFeed, error) {
+	if feed, ok := h.feeds.get(key); ok {
+		return feed, nil
+	}
+
+	feed, err := build()
+	if err != nil {
+		return renderedFeed{}, err
+	}
+
+	var buf bytes.Buffer
+
+	buf.WriteString(xml.Header)
+
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+
+	if err = encoder.Encode(feed); err != nil {
+		return renderedFeed{}, fmt.Errorf("encode feed: %w", err)
+	}
+
+	updated, err := time.Parse(time.RFC3339, feed.Updated)
+	if err != nil {
+		return renderedFeed{}, fmt.Errorf("parse feed update date: %w", err)
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+
+	rendered := renderedFeed{
+		body:     buf.Bytes(),
+		etag:     `"` + hex.EncodeToString(sum[:16]) + `"`,
+		updated:  updated,
+		expireAt: time.Now().Add(feedsTTL),
+	}
+
+	h.feeds.set(key, rendered)
+
+	return rendered, nil
+}
+
+// serveFeed serves a rendered feed, the conditional requests are answered by http.ServeContent.
+func serveFeed(rw http.ResponseWriter, req *http.Request, feed renderedFeed) {
+	rw.Header().Set("Content-Type", atomContentType)
+	rw.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(feedsTTL.Seconds())))
+	rw.Header().Set("ETag", feed.etag)
+
+	http.ServeContent(rw, req, "", feed.updated, bytes.NewReader(feed.body))
+}
+
+func pluginTitle(plugin db.Plugin) string {
+	if plugin.DisplayName != "" {
+		return plugin.DisplayName
+	}
+
+	return plugin.Name
+}
+
+func pluginLinks(baseURL string, plugin db.Plugin) []atomLink {
+	links := []atomLink{{Href: baseURL + "/public/" + plugin.ID, Rel: "related", Type: "application/json"}}
+
+	if strings.HasPrefix(plugin.Name, "github.com/") {
+		links = append(links, atomLink{Href: "https://" + plugin.Name, Rel: "alternate", Type: "text/html"})
+	}
+
+	return links
+}
+
+func pluginAuthor(plugin db.Plugin) *atomPerson {
+	if plugin.Author == "" {
+		return &atomPerson{Name: feedsAuthor}
+	}
+
+	author := &atomPerson{Name: plugin.Author}
+	if strings.HasPrefix(plugin.Name, "github.com/") {
+		author.URI = "https://github.com/" + plugin.Author
+	}
+
+	return author
+}
+
+// formatAtomTime formats a date as an Atom date construct, with a second precision.
+func formatAtomTime(t time.Time) string {
+	return t.UTC().Truncate(time.Second).Format(time.RFC3339)
+}
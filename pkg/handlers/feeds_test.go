@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+func TestHandlers_Feeds(t *testing.T) {
+	seenAt := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	plugins := []db.Plugin{
+		{
+			ID:          "123",
+			Name:        "github.com/traefik/plugindemo",
+			DisplayName: "Demo Plugin",
+			Author:      "traefik",
+			Summary:     "[Demo] Add Request Header",
+			Versions:    []string{"v0.2.1", "v0.2.0"},
+			CreatedAt:   time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			ID:        "456",
+			Name:      "example.com/plugin",
+			Summary:   "Example",
+			CreatedAt: time.Date(2021, 1, 1, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			ID:        "789",
+			Name:      "github.com/traefik/hidden",
+			Hidden:    true,
+			CreatedAt: time.Date(2022, 1, 1, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			ID:        "321",
+			Name:      "github.com/traefik/disabled",
+			Disabled:  true,
+			CreatedAt: time.Date(2023, 1, 1, 1, 0, 0, 0, time.UTC),
+		},
+	}
+
+	testDB := mockDB{
+		listAllFn: func(_ context.Context) ([]db.Plugin, error) {
+			return plugins, nil
+		},
+		getFn: func(_ context.Context, id string) (db.Plugin, error) {
+			for _, plugin := range plugins {
+				if plugin.ID == id {
+					return plugin, nil
+				}
+			}
+
+			return db.Plugin{}, db.NotFoundError{}
+		},
+		listHashesFn: func(_ context.Context, module string) ([]db.PluginHash, error) {
+			return []db.PluginHash{
+				{Name: module + "@v0.2.0", Hash: "123"},
+				{Name: module + "@v0.2.1", Hash: "456", CreatedAt: &seenAt},
+			}, nil
+		},
+	}
+
+	testCases := []struct {
+		desc            string
+		handler         func(h Handlers) http.HandlerFunc
+		url             string
+		headers         map[string]string
+		expectedStatus  int
+		expectedHeaders map[string]string
+		expected        string
+	}{
+		{
+			desc:           "new plugins",
+			handler:        func(h Handlers) http.HandlerFunc { return h.PluginsFeed },
+			url:            "/feeds/plugins.atom",
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Content-Type":  "application/atom+xml; charset=utf-8",
+				"Cache-Control": "public, max-age=300",
+				"Last-Modified": "Fri, 01 Jan 2021 01:00:00 GMT",
+			},
+			expected: `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <id>https://plugins.example.com/public/feeds/plugins.atom</id>
+  <title>New plugins</title>
+  <subtitle>The plugins recently added to the catalog.</subtitle>
+  <updated>2021-01-01T01:00:00Z</updated>
+  <link href="https://plugins.example.com/public/feeds/plugins.atom" rel="self" type="application/atom+xml"></link>
+  <author>
+    <name>Traefik Plugin Catalog</name>
+  </author>
+  <entry>
+    <id>https://plugins.example.com/public/456</id>
+    <title>example.com/plugin</title>
+    <updated>2021-01-01T01:00:00Z</updated>
+    <published>2021-01-01T01:00:00Z</published>
+    <link href="https://plugins.example.com/public/456" rel="related" type="application/json"></link>
+    <author>
+      <name>Traefik Plugin Catalog</name>
+    </author>
+    <summary>Example</summary>
+  </entry>
+  <entry>
+    <id>https://plugins.example.com/public/123</id>
+    <title>Demo Plugin</title>
+    <updated>2020-01-01T01:00:00Z</updated>
+    <published>2020-01-01T01:00:00Z</published>
+    <link href="https://plugins.example.com/public/123" rel="related" type="application/json"></link>
+    <link href="https://github.com/traefik/plugindemo" rel="alternate" type="text/html"></link>
+    <author>
+      <name>traefik</name>
+      <uri>https://github.com/traefik</uri>
+    </author>
+    <summary>[Demo] Add Request Header</summary>
+  </entry>
+</feed>`,
+		},
+		{
+			desc:           "plugin releases",
+			handler:        func(h Handlers) http.HandlerFunc { return h.PluginFeed },
+			url:            "/feeds/123.atom",
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Content-Type":  "application/atom+xml; charset=utf-8",
+				"Last-Modified": "Thu, 04 Mar 2021 05:06:07 GMT",
+			},
+			expected: `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <id>https://plugins.example.com/public/feeds/123.atom</id>
+  <title>Demo Plugin releases</title>
+  <subtitle>[Demo] Add Request Header</subtitle>
+  <updated>2021-03-04T05:06:07Z</updated>
+  <link href="https://plugins.example.com/public/feeds/123.atom" rel="self" type="application/atom+xml"></link>
+  <link href="https://plugins.example.com/public/123" rel="related" type="application/json"></link>
+  <link href="https://github.com/traefik/plugindemo" rel="alternate" type="text/html"></link>
+  <author>
+    <name>traefik</name>
+    <uri>https://github.com/traefik</uri>
+  </author>
+  <entry>
+    <id>https://plugins.example.com/public/123#v0.2.1</id>
+    <title>Demo Plugin v0.2.1</title>
+    <updated>2021-03-04T05:06:07Z</updated>
+    <published>2021-03-04T05:06:07Z</published>
+    <link href="https://github.com/traefik/plugindemo/releases/tag/v0.2.1" rel="alternate" type="text/html"></link>
+    <summary>[Demo] Add Request Header</summary>
+  </entry>
+  <entry>
+    <id>https://plugins.example.com/public/123#v0.2.0</id>
+    <title>Demo Plugin v0.2.0</title>
+    <updated>2020-01-01T01:00:00Z</updated>
+    <published>2020-01-01T01:00:00Z</published>
+    <link href="https://github.com/traefik/plugindemo/releases/tag/v0.2.0" rel="alternate" type="text/html"></link>
+    <summary>[Demo] Add Request Header</summary>
+  </entry>
+</feed>`,
+		},
+		{
+			desc:           "not modified since",
+			handler:        func(h Handlers) http.HandlerFunc { return h.PluginFeed },
+			url:            "/feeds/123.atom",
+			headers:        map[string]string{"If-Modified-Since": "Thu, 04 Mar 2021 05:06:07 GMT"},
+			expectedStatus: http.StatusNotModified,
+		},
+		{
+			desc:           "modified since",
+			handler:        func(h Handlers) http.HandlerFunc { return h.PluginFeed },
+			url:            "/feeds/123.atom",
+			headers:        map[string]string{"If-Modified-Since": "Thu, 04 Mar 2021 05:06:06 GMT"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "other entity tag",
+			handler:        func(h Handlers) http.HandlerFunc { return h.PluginFeed },
+			url:            "/feeds/123.atom",
+			headers:        map[string]string{"If-None-Match": `"other"`},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "hidden plugin",
+			handler:        func(h Handlers) http.HandlerFunc { return h.PluginFeed },
+			url:            "/feeds/789.atom",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			desc:           "disabled plugin",
+			handler:        func(h Handlers) http.HandlerFunc { return h.PluginFeed },
+			url:            "/feeds/321.atom",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			desc:           "unknown plugin",
+			handler:        func(h Handlers) http.HandlerFunc { return h.PluginFeed },
+			url:            "/feeds/000.atom",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			rw := httptest.NewRecorder()
+			// The links don't depend on the host of the request.
+			req := httptest.NewRequest(http.MethodGet, "http://other.example.com"+test.url, http.NoBody)
+
+			for name, value := range test.headers {
+				req.Header.Set(name, value)
+			}
+
+			test.handler(New(testDB, nil, WithPublicURL("https://plugins.example.com/")))(rw, req)
+
+			assert.Equal(t, test.expectedStatus, rw.Code)
+
+			for name, value := range test.expectedHeaders {
+				assert.Equal(t, value, rw.Header().Get(name), name)
+			}
+
+			if test.expected != "" {
+				assert.Equal(t, test.expected, rw.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlers_Feeds_entityTag(t *testing.T) {
+	calls := 0
+
+	testDB := mockDB{
+		listAllFn: func(_ context.Context) ([]db.Plugin, error) {
+			calls++
+
+			return []db.Plugin{{ID: "123", Name: "github.com/traefik/plugindemo", CreatedAt: time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC)}}, nil
+		},
+	}
+
+	handler := New(testDB, nil, WithPublicURL("https://plugins.example.com"))
+
+	rw := httptest.NewRecorder()
+	handler.PluginsFeed(rw, httptest.NewRequest(http.MethodGet, "/feeds/plugins.atom", http.NoBody))
+
+	assert.Equal(t, http.StatusOK, rw.Code)
+
+	etag := rw.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	// The cache doesn't depend on the host of the request.
+	req := httptest.NewRequest(http.MethodGet, "http://other.example.com/feeds/plugins.atom", http.NoBody)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set("If-None-Match", etag)
+
+	rw = httptest.NewRecorder()
+	handler.PluginsFeed(rw, req)
+
+	assert.Equal(t, http.StatusNotModified, rw.Code)
+	assert.Empty(t, rw.Body.String())
+
+	// The second request is served from the cache.
+	assert.Equal(t, 1, calls)
+}
+
+func TestHandlers_Feeds_disabled(t *testing.T) {
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/feeds/plugins.atom", http.NoBody)
+
+	New(mockDB{}, nil).PluginsFeed(rw, req)
+
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/traefik/plugin-service/pkg/archive"
+	"golang.org/x/mod/modfile"
+)
+
+// fakeFetcher is an in-memory ArchiveFetcher, all maps are indexed by module@version.
+type fakeFetcher struct {
+	noGitHub bool
+	modFiles map[string]string
+	sources  map[string][]byte
+	// mirrors the module zips of the second upstream of the Go proxy.
+	mirrors  map[string][]byte
+	zipballs map[string][]byte
+	assets   map[string]fakeAsset
+
+	// gitHubErr the error returned by the GitHub sources.
+	gitHubErr error
+	// goProxyErr the error returned by the Go proxy.
+	goProxyErr error
+}
+
+type fakeAsset struct {
+	digest     string
+	content    []byte
+	signatures map[string][]byte
+}
+
+func (f fakeFetcher) GitHubEnabled() bool {
+	return !f.noGitHub
+}
+
+func (f fakeFetcher) GetModFile(_ context.Context, moduleName, version string) (*modfile.File, error) {
+	if f.goProxyErr != nil {
+		return nil, f.goProxyErr
+	}
+
+	content, ok := f.modFiles[moduleName+"@"+version]
+	if !ok {
+		return nil, notFound(moduleName, version)
+	}
+
+	return modfile.Parse("go.mod", []byte(content), nil)
+}
+
+func (f fakeFetcher) DownloadSourcesMatching(_ context.Context, moduleName, version string, match func(raw []byte) bool) ([]byte, error) {
+	if f.goProxyErr != nil {
+		return nil, f.goProxyErr
+	}
+
+	err := notFound(moduleName, version)
+
+	for _, upstream := range []map[string][]byte{f.sources, f.mirrors} {
+		raw, ok := upstream[moduleName+"@"+version]
+		if !ok {
+			continue
+		}
+
+		if match(raw) {
+			return raw, nil
+		}
+
+		err = fmt.Errorf("%s@%s: module zip rejected", moduleName, version)
+	}
+
+	return nil, err
+}
+
+func (f fakeFetcher) DownloadZipball(_ context.Context, moduleName, version string) (io.ReadCloser, error) {
+	if f.noGitHub {
+		return nil, archive.ErrGitHubDisabled
+	}
+
+	if f.gitHubErr != nil {
+		return nil, f.gitHubErr
+	}
+
+	return readCloser(f.zipballs, moduleName, version)
+}
+
+func (f fakeFetcher) GetReleaseAsset(_ context.Context, moduleName, version string) (archive.Asset, error) {
+	if f.noGitHub {
+		return archive.Asset{}, archive.ErrGitHubDisabled
+	}
+
+	if f.gitHubErr != nil {
+		return archive.Asset{}, f.gitHubErr
+	}
+
+	asset, ok := f.assets[moduleName+"@"+version]
+	if !ok {
+		return archive.Asset{}, notFound(moduleName, version)
+	}
+
+	signatures := make(map[string]archive.Asset)
+	for ext := range asset.signatures {
+		signatures[ext] = archive.Asset{Name: "plugin.zip" + ext, URL: moduleName + "@" + version + ext}
+	}
+
+	return archive.Asset{Name: "plugin.zip", URL: moduleName + "@" + version, Digest: asset.digest, Signatures: signatures}, nil
+}
+
+func (f fakeFetcher) DownloadAsset(_ context.Context, asset archive.Asset) (io.ReadCloser, error) {
+	for name, a := range f.assets {
+		if asset.URL == name {
+			return io.NopCloser(bytes.NewReader(a.content)), nil
+		}
+
+		for ext, signature := range a.signatures {
+			if asset.URL == name+ext {
+				return io.NopCloser(bytes.NewReader(signature)), nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%s: not found", asset.URL)
+}
+
+func readCloser(contents map[string][]byte, moduleName, version string) (io.ReadCloser, error) {
+	content, ok := contents[moduleName+"@"+version]
+	if !ok {
+		return nil, notFound(moduleName, version)
+	}
+
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func notFound(moduleName, version string) error {
+	return fmt.Errorf("%s@%s: not found", moduleName, version)
+}
@@ -2,20 +2,27 @@ package handlers
 
 import (
 	"context"
+	"crypto/ed25519"
 	"encoding/json"
 	"errors"
 	"io"
+	"iter"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
-	"github.com/google/go-github/v74/github"
-	"github.com/ldez/grignotin/goproxy"
 	"github.com/rs/zerolog/log"
+	"github.com/traefik/plugin-service/pkg/archive"
 	"github.com/traefik/plugin-service/pkg/db"
+	"github.com/traefik/plugin-service/pkg/events"
+	"github.com/traefik/plugin-service/pkg/signing"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/sumdb/tlog"
 )
 
 const (
@@ -29,31 +36,237 @@ type PluginStorer interface {
 	Delete(ctx context.Context, id string) error
 	Create(context.Context, db.Plugin) (db.Plugin, error)
 	List(context.Context, db.Pagination) ([]db.Plugin, string, error)
+	ListAll(ctx context.Context) ([]db.Plugin, error)
 	GetByName(context.Context, string, bool, bool) (db.Plugin, error)
 	SearchByName(context.Context, string, db.Pagination) ([]db.Plugin, string, error)
 	Update(context.Context, string, db.Plugin) (db.Plugin, error)
 
 	CreateHash(ctx context.Context, module, version, hash string) (db.PluginHash, error)
-	UpdateHashVerified(ctx context.Context, module, version, hash string, verified bool) (db.PluginHash, error)
+	UpdateHashVerified(ctx context.Context, module, version, hash string, verified bool, reasons []string) (db.PluginHash, error)
 	GetHashByName(ctx context.Context, module, version string) (db.PluginHash, error)
+	UpdateHashManifest(ctx context.Context, module, version string, manifest db.Manifest) (db.PluginHash, error)
+	UpdateHashCapabilities(ctx context.Context, module, version string, report db.CapabilityReport) (db.PluginHash, error)
+	UpdateUseUnsafe(ctx context.Context, id string, useUnsafe bool) (db.Plugin, error)
+	ListHashes(ctx context.Context, module string) ([]db.PluginHash, error)
+
+	UpdateHashQuarantined(ctx context.Context, module, version string, quarantined bool) (db.PluginHash, error)
+	UpdateHashSigner(ctx context.Context, module, version, signer string) (db.PluginHash, error)
+	UpdateHashDigests(ctx context.Context, module, version string, digests map[string]string) (db.PluginHash, error)
+	ResetHash(ctx context.Context, module, version, hash string) (db.PluginHash, error)
+
+	CreateHashReset(ctx context.Context, reset db.HashReset) (db.HashReset, error)
+	ListHashResets(ctx context.Context, module string) ([]db.HashReset, error)
+
+	CreateIncident(ctx context.Context, incident db.Incident) (db.Incident, error)
+	ListIncidents(ctx context.Context, module string) ([]db.Incident, error)
+	CountIncidents(ctx context.Context, module, version, kind string, since time.Time) (int64, error)
+	GetLastIncident(ctx context.Context, module, version, kind string) (db.Incident, error)
+	AggregateIncidents(ctx context.Context) ([]db.IncidentSummary, error)
+}
+
+// ArchiveFetcher is capable of fetching plugin archives from their sources.
+type ArchiveFetcher interface {
+	GitHubEnabled() bool
+	GetModFile(ctx context.Context, moduleName, version string) (*modfile.File, error)
+	DownloadSourcesMatching(ctx context.Context, moduleName, version string, match func(raw []byte) bool) ([]byte, error)
+	DownloadZipball(ctx context.Context, moduleName, version string) (io.ReadCloser, error)
+	GetReleaseAsset(ctx context.Context, moduleName, version string) (archive.Asset, error)
+	DownloadAsset(ctx context.Context, asset archive.Asset) (io.ReadCloser, error)
+}
+
+// SignatureVerifier is capable of verifying the signatures of release assets.
+type SignatureVerifier interface {
+	Verify(content, signature []byte, policy db.SignaturePolicy) (string, error)
+}
+
+// StatementSigner is capable of signing statements about the served archives.
+type StatementSigner interface {
+	KeyID() string
+	PublicKey() ed25519.PublicKey
+	Sign(statement signing.Statement) (string, error)
+}
+
+// TransparencyLog is capable of recording the plugin hashes in an append-only log.
+type TransparencyLog interface {
+	Append(ctx context.Context, module, version, hash string) (db.LogRecord, error)
+	AppendReset(ctx context.Context, module, version, hash string) (db.LogRecord, error)
+	Lookup(ctx context.Context, module, version string) ([]byte, error)
+	SignedTreeHead(ctx context.Context) ([]byte, error)
+	ProveRecord(ctx context.Context, index, treeSize int64) (tlog.RecordProof, error)
+	ProveTree(ctx context.Context, treeSize, oldSize int64) (tlog.TreeProof, error)
+}
+
+// JobQueue is capable of running jobs asynchronously.
+type JobQueue interface {
+	Enqueue(ctx context.Context, job db.Job) (db.Job, error)
+	GetJob(ctx context.Context, id string) (db.Job, error)
+	ListJobs(ctx context.Context, filter db.JobFilter) ([]db.Job, error)
+	RetryJob(ctx context.Context, id string) (db.Job, error)
+}
+
+// Webhooks is capable of managing the webhook subscriptions and of notifying them of the catalog changes.
+type Webhooks interface {
+	Subscribe(ctx context.Context, subscription db.Subscription) (db.Subscription, error)
+	Unsubscribe(ctx context.Context, id string) error
+	GetSubscription(ctx context.Context, id string) (db.Subscription, error)
+	ListSubscriptions(ctx context.Context) ([]db.Subscription, error)
+	ListDeliveries(ctx context.Context, subscription string) ([]db.Delivery, error)
+	Notify(ctx context.Context, event db.Event) error
+}
+
+// EventStream is capable of publishing the catalog change events and of streaming them from a cursor.
+type EventStream interface {
+	PublishEvent(ctx context.Context, event db.Event) error
+	WatchEvents(ctx context.Context, since string) (iter.Seq2[db.EventRecord, error], error)
+}
+
+// ManifestReader is capable of reading the manifest of a plugin at a given ref.
+type ManifestReader interface {
+	ReadManifest(ctx context.Context, moduleName, ref string) (db.Manifest, []string, error)
+}
+
+// pluginDetail is a plugin with the capabilities of its latest version.
+type pluginDetail struct {
+	db.Plugin
+
+	Capabilities *db.CapabilityReport `json:"capabilities,omitempty"`
 }
 
 // Handlers a set of handlers.
 type Handlers struct {
-	store   PluginStorer
-	goProxy *goproxy.Client
-	gh      *github.Client
-	tracer  trace.Tracer
+	store    PluginStorer
+	fetcher  ArchiveFetcher
+	verifier SignatureVerifier
+	signer   StatementSigner
+	tlog     TransparencyLog
+	jobs     JobQueue
+	webhooks Webhooks
+	events   EventStream
+	emitter  *events.Emitter
+	tracer   trace.Tracer
+
+	// rateLimit pauses the background fetches while GitHub rate limits the requests.
+	rateLimit *rateLimitGate
+
+	// feeds caches the rendered Atom feeds.
+	feeds *feedCache
+
+	// archives caches the served archives, they are served while the circuit breaker of their upstream is open.
+	archives *archiveCache
+
+	// publicURL the URL through which the public API is served, the feeds are disabled without it.
+	publicURL string
+
+	// clientReports throttles the hash mismatches reported by the clients.
+	clientReports *reportThrottle
+
+	incidentThreshold int64
+
+	// webhookSecret the secret of the GitHub webhooks, the webhook endpoint is disabled without it.
+	webhookSecret []byte
+
+	// manifests reads the manifests of the versions added by the GitHub webhook events.
+	manifests ManifestReader
+}
+
+// Option configures the handlers.
+type Option func(*Handlers)
+
+// WithIncidentThreshold quarantines a plugin version once it has reached the given number of upstream incidents.
+// A threshold of 0 disables the quarantine.
+func WithIncidentThreshold(threshold int) Option {
+	return func(h *Handlers) {
+		h.incidentThreshold = int64(threshold)
+	}
+}
+
+// WithSignatureVerifier sets the verifier of the release asset signatures.
+// By default, only the minisign signatures can be verified.
+func WithSignatureVerifier(verifier SignatureVerifier) Option {
+	return func(h *Handlers) {
+		h.verifier = verifier
+	}
+}
+
+// WithStatementSigner signs the statements {module, version, sha256} of the served archives.
+func WithStatementSigner(signer StatementSigner) Option {
+	return func(h *Handlers) {
+		h.signer = signer
+	}
+}
+
+// WithTransparencyLog appends the first seen hash of every plugin version, and the hashes set by a reset, to a transparency log.
+func WithTransparencyLog(transparencyLog TransparencyLog) Option {
+	return func(h *Handlers) {
+		h.tlog = transparencyLog
+	}
+}
+
+// WithJobQueue fetches and records the hashes of the new plugin versions as soon as they are created or updated.
+func WithJobQueue(queue JobQueue) Option {
+	return func(h *Handlers) {
+		h.jobs = queue
+	}
+}
+
+// WithArchiveCache keeps the last served archives in memory, up to the given size in bytes.
+// They are served while the circuit breaker of their upstream is open, instead of a 503.
+func WithArchiveCache(size int64) Option {
+	return func(h *Handlers) {
+		h.archives = newArchiveCache(size)
+	}
+}
+
+// WithPublicURL enables the Atom feeds, their links and identifiers are built from the given public URL.
+func WithPublicURL(publicURL string) Option {
+	return func(h *Handlers) {
+		h.publicURL = strings.TrimSuffix(publicURL, "/")
+	}
+}
+
+// WithGitHubWebhook enables the GitHub webhook endpoint, the events are authenticated with the given secret.
+// The new versions are only added once their manifest has been read and validated.
+func WithGitHubWebhook(secret string, manifests ManifestReader) Option {
+	return func(h *Handlers) {
+		h.webhookSecret = []byte(secret)
+		h.manifests = manifests
+	}
+}
+
+// WithWebhooks notifies the webhook subscribers of the plugin changes and of the hash verification outcomes.
+func WithWebhooks(webhooks Webhooks) Option {
+	return func(h *Handlers) {
+		h.webhooks = webhooks
+	}
+}
+
+// WithEventStream publishes the catalog change events to a stream, served by the public events endpoint.
+func WithEventStream(stream EventStream) Option {
+	return func(h *Handlers) {
+		h.events = stream
+	}
 }
 
 // New creates all HTTP handlers.
-func New(store PluginStorer, goProxy *goproxy.Client, gh *github.Client) Handlers {
-	return Handlers{
-		store:   store,
-		goProxy: goProxy,
-		gh:      gh,
-		tracer:  otel.GetTracerProvider().Tracer("handler"),
+func New(store PluginStorer, fetcher ArchiveFetcher, opts ...Option) Handlers {
+	h := Handlers{
+		store:     store,
+		fetcher:   fetcher,
+		verifier:  archive.NewVerifier(archive.TrustRoots{}),
+		tracer:    otel.GetTracerProvider().Tracer("handler"),
+		rateLimit: &rateLimitGate{},
+		feeds:     &feedCache{},
+
+		clientReports: &reportThrottle{},
 	}
+
+	for _, opt := range opts {
+		opt(&h)
+	}
+
+	h.emitter = events.NewEmitter(h.events, h.webhooks)
+
+	return h
 }
 
 // Get gets a plugin.
@@ -88,7 +301,19 @@ func (h Handlers) Get(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	if err := json.NewEncoder(rw).Encode(plugin); err != nil {
+	detail := pluginDetail{Plugin: plugin}
+
+	if plugin.LatestVersion != "" {
+		ph, err := h.store.GetHashByName(ctx, plugin.Name, plugin.LatestVersion)
+		if err != nil && !errors.As(err, &db.NotFoundError{}) {
+			span.RecordError(err)
+			logger.Warn().Err(err).Msg("Unable to get the capabilities of the latest version")
+		}
+
+		detail.Capabilities = ph.Capabilities
+	}
+
+	if err := json.NewEncoder(rw).Encode(detail); err != nil {
 		span.RecordError(err)
 		logger.Error().Err(err).Msg("Failed to get plugin")
 		JSONInternalServerError(rw)
@@ -152,6 +377,16 @@ func (h Handlers) Create(rw http.ResponseWriter, req *http.Request) {
 
 	logger := log.With().Str("module_name", pl.Name).Logger()
 
+	if pl.Signature != nil {
+		if err = archive.ValidatePolicy(*pl.Signature); err != nil {
+			span.RecordError(err)
+			logger.Error().Err(err).Msg("Invalid signature policy")
+			JSONError(rw, http.StatusBadRequest, err.Error())
+
+			return
+		}
+	}
+
 	created, err := h.store.Create(ctx, pl)
 	if err != nil {
 		span.RecordError(err)
@@ -161,6 +396,10 @@ func (h Handlers) Create(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	h.enqueuePrewarm(ctx, created.Name, created.Versions)
+
+	h.emitter.EmitCreated(ctx, created)
+
 	rw.WriteHeader(http.StatusCreated)
 
 	if err := json.NewEncoder(rw).Encode(created); err != nil {
@@ -201,6 +440,29 @@ func (h Handlers) Update(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if input.Signature != nil {
+		if err = archive.ValidatePolicy(*input.Signature); err != nil {
+			span.RecordError(err)
+			logger.Error().Err(err).Msg("Invalid signature policy")
+			JSONError(rw, http.StatusBadRequest, err.Error())
+
+			return
+		}
+	}
+
+	// The previous state is only needed to pre-warm the hashes of the new versions and to notify the changes.
+	var previous db.Plugin
+	if h.jobs != nil || h.webhooks != nil || h.events != nil {
+		previous, err = h.store.Get(ctx, id)
+		if err != nil && !errors.As(err, &db.NotFoundError{}) {
+			span.RecordError(err)
+			logger.Error().Err(err).Msg("Error while trying to get plugin")
+			JSONInternalServerError(rw)
+
+			return
+		}
+	}
+
 	pg, err := h.store.Update(ctx, id, input)
 	if err != nil {
 		span.RecordError(err)
@@ -219,6 +481,9 @@ func (h Handlers) Update(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	h.enqueuePrewarm(ctx, pg.Name, newVersions(previous.Versions, pg.Versions))
+	h.emitter.EmitChanges(ctx, previous, pg)
+
 	rw.WriteHeader(http.StatusOK)
 
 	if err := json.NewEncoder(rw).Encode(pg); err != nil {
@@ -246,7 +511,7 @@ func (h Handlers) Delete(rw http.ResponseWriter, req *http.Request) {
 
 	logger := log.With().Str("plugin_id", id).Logger()
 
-	_, err = h.store.Get(ctx, id)
+	plugin, err := h.store.Get(ctx, id)
 	if err != nil {
 		span.RecordError(err)
 		logger.Error().Err(err).Msg("Failed to get plugin information")
@@ -263,6 +528,8 @@ func (h Handlers) Delete(rw http.ResponseWriter, req *http.Request) {
 
 		return
 	}
+
+	h.emitter.Emit(ctx, db.Event{Type: db.EventPluginDeleted, Module: plugin.Name, Plugin: &plugin})
 }
 
 func (h Handlers) searchByName(rw http.ResponseWriter, req *http.Request) {
@@ -425,6 +692,17 @@ func getPathParam(uri *url.URL) (string, error) {
 	return parts[1], nil
 }
 
+func getSubPathParam(uri *url.URL, sub string) (string, error) {
+	exp := regexp.MustCompile(`^/([\w-]+)/` + regexp.QuoteMeta(sub) + `/?$`)
+	parts := exp.FindStringSubmatch(uri.Path)
+
+	if len(parts) != 2 {
+		return "", errors.New("missing id")
+	}
+
+	return parts[1], nil
+}
+
 func unquote(value string) string {
 	unquote, err := strconv.Unquote(value)
 	if err != nil {
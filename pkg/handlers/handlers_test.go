@@ -59,7 +59,7 @@ func TestHandlers_List(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
 
-	New(testDB, nil, nil).List(rw, req)
+	New(testDB, nil).List(rw, req)
 
 	assert.Equal(t, http.StatusOK, rw.Code)
 	assert.Equal(t, "next", rw.Header().Get(nextPageHeader))
@@ -98,7 +98,7 @@ func TestHandlers_List_GetByName(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodGet, "/?name=Demo%20Plugin", http.NoBody)
 
-	New(testDB, nil, nil).getByName(rw, req)
+	New(testDB, nil).getByName(rw, req)
 
 	assert.Equal(t, http.StatusOK, rw.Code)
 
@@ -140,7 +140,7 @@ func TestHandlers_List_GetByName_hidden(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodGet, "/?name=Demo%20Plugin", http.NoBody)
 
-	New(testDB, nil, nil).getByName(rw, req)
+	New(testDB, nil).getByName(rw, req)
 
 	assert.Equal(t, http.StatusNotFound, rw.Code)
 
@@ -148,7 +148,7 @@ func TestHandlers_List_GetByName_hidden(t *testing.T) {
 
 	req = httptest.NewRequest(http.MethodGet, "/?name=Demo%20Plugin&filterHidden=true", http.NoBody)
 
-	New(testDB, nil, nil).getByName(rw, req)
+	New(testDB, nil).getByName(rw, req)
 
 	assert.Equal(t, http.StatusOK, rw.Code)
 
@@ -186,7 +186,7 @@ func TestHandlers_List_SearchByName(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodGet, "/?query=demo", http.NoBody)
 
-	New(testDB, nil, nil).getByName(rw, req)
+	New(testDB, nil).getByName(rw, req)
 
 	assert.Equal(t, http.StatusOK, rw.Code)
 
@@ -195,3 +195,82 @@ func TestHandlers_List_SearchByName(t *testing.T) {
 
 	assert.JSONEq(t, string(file), rw.Body.String())
 }
+
+func TestHandlers_Get(t *testing.T) {
+	plugin := db.Plugin{
+		ID:            "276809780784267776",
+		Name:          "github.com/traefik/plugindemo",
+		DisplayName:   "Demo Plugin",
+		LatestVersion: "v0.2.1",
+		Versions:      []string{"v0.2.1"},
+		CreatedAt:     time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC),
+	}
+
+	testCases := []struct {
+		desc           string
+		hash           db.PluginHash
+		hashErr        error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			desc: "with capabilities",
+			hash: db.PluginHash{
+				Name: "github.com/traefik/plugindemo@v0.2.1",
+				Capabilities: &db.CapabilityReport{
+					Capabilities: []string{db.CapabilityUnsafe},
+					Findings:     []db.Finding{{Capability: db.CapabilityUnsafe, File: "demo.go", Line: 3, Detail: "import unsafe"}},
+				},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{
+				"id": "276809780784267776",
+				"name": "github.com/traefik/plugindemo",
+				"displayName": "Demo Plugin",
+				"latestVersion": "v0.2.1",
+				"versions": ["v0.2.1"],
+				"createdAt": "2020-01-01T01:00:00Z",
+				"capabilities": {
+					"capabilities": ["unsafe"],
+					"findings": [{"capability": "unsafe", "file": "demo.go", "line": 3, "detail": "import unsafe"}]
+				}
+			}`,
+		},
+		{
+			desc:           "without hash",
+			hashErr:        db.NotFoundError{},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{
+				"id": "276809780784267776",
+				"name": "github.com/traefik/plugindemo",
+				"displayName": "Demo Plugin",
+				"latestVersion": "v0.2.1",
+				"versions": ["v0.2.1"],
+				"createdAt": "2020-01-01T01:00:00Z"
+			}`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			testDB := mockDB{
+				getFn: func(_ context.Context, _ string) (db.Plugin, error) {
+					return plugin, nil
+				},
+				getHashByNameFn: func(_ context.Context, _, _ string) (db.PluginHash, error) {
+					return test.hash, test.hashErr
+				},
+			}
+
+			rw := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/276809780784267776", http.NoBody)
+
+			New(testDB, nil).Get(rw, req)
+
+			assert.Equal(t, test.expectedStatus, rw.Code)
+			assert.JSONEq(t, test.expectedBody, rw.Body.String())
+		})
+	}
+}
@@ -0,0 +1,526 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/plugin-service/pkg/archive"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+type pluginHashes struct {
+	ID     string          `json:"id"`
+	Name   string          `json:"name"`
+	Hashes []db.PluginHash `json:"hashes"`
+	Resets []db.HashReset  `json:"resets"`
+}
+
+type hashResetRequest struct {
+	// Justification why the hash is reset, mandatory.
+	Justification string `json:"justification"`
+	// Hash the expected SHA-256 of the new archive, optional.
+	Hash   string `json:"hash,omitempty"`
+	Author string `json:"author,omitempty"`
+}
+
+// fetchedArchive is a plugin archive downloaded from the source used to serve it.
+type fetchedArchive struct {
+	source    string
+	raw       []byte
+	sum       string
+	moduleZip bool
+	// repacked true if the module zip has the contents of the recorded one, but not its bytes.
+	repacked bool
+	// reasons why the archive is not verified.
+	reasons []string
+	signer  string
+}
+
+// publishedDigestError is returned when a release asset doesn't match the digest published by GitHub.
+type publishedDigestError struct {
+	expected string
+	received string
+}
+
+func (e publishedDigestError) Error() string {
+	return fmt.Sprintf("archive %s doesn't match the published digest %s", e.received, e.expected)
+}
+
+// matchHash returns true if a fetched archive is the one of the recorded hash.
+// The module zips of the same contents aren't byte-identical from one upstream to another (e.g. rebuilt from the repository by the direct mode):
+// they are compared by their h1: dirhash, and a matching module zip is then identified by the recorded hash.
+// Such a repacked module zip has the recorded contents, but it can't be served in place of the recorded one.
+func matchHash(pluginHash db.PluginHash, fetched *fetchedArchive) bool {
+	if fetched.sum == pluginHash.Hash {
+		return true
+	}
+
+	if !fetched.moduleZip || !sameModuleContents(pluginHash, fetched.raw) {
+		return false
+	}
+
+	fetched.sum = pluginHash.Hash
+	fetched.repacked = true
+
+	return true
+}
+
+// sameModuleContents returns true if a module zip has the recorded h1: dirhash.
+func sameModuleContents(pluginHash db.PluginHash, raw []byte) bool {
+	expected, ok := pluginHash.Digests[db.DigestH1]
+	if !ok {
+		return false
+	}
+
+	h1, err := archive.ModuleHash(raw)
+
+	return err == nil && h1 == expected
+}
+
+// Hashes lists the hashes of a plugin, and their resets.
+func (h Handlers) Hashes(rw http.ResponseWriter, req *http.Request) {
+	ctx, span := h.tracer.Start(req.Context(), "handler_hashes")
+	defer span.End()
+
+	id, err := getSubPathParam(req.URL, "hashes")
+	if err != nil {
+		span.RecordError(err)
+		JSONError(rw, http.StatusBadRequest, "Missing plugin id")
+
+		return
+	}
+
+	logger := log.With().Str("plugin_id", id).Logger()
+
+	plugin, err := h.store.Get(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+
+		if errors.As(err, &db.NotFoundError{}) {
+			NotFound(rw, req)
+			return
+		}
+
+		logger.Error().Err(err).Msg("Error while trying to get plugin")
+		JSONInternalServerError(rw)
+
+		return
+	}
+
+	hashes, err := h.store.ListHashes(ctx, plugin.Name)
+	if err != nil && !errors.As(err, &db.NotFoundError{}) {
+		span.RecordError(err)
+		logger.Error().Err(err).Msg("Error while trying to get plugin hashes")
+		JSONInternalServerError(rw)
+
+		return
+	}
+
+	resets, err := h.store.ListHashResets(ctx, plugin.Name)
+	if err != nil {
+		span.RecordError(err)
+		logger.Error().Err(err).Msg("Error while trying to get plugin hash resets")
+		JSONInternalServerError(rw)
+
+		return
+	}
+
+	if hashes == nil {
+		hashes = make([]db.PluginHash, 0)
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+
+	resp := pluginHashes{ID: plugin.ID, Name: plugin.Name, Hashes: hashes, Resets: resets}
+
+	if err := json.NewEncoder(rw).Encode(resp); err != nil {
+		span.RecordError(err)
+		logger.Error().Err(err).Msg("Failed to encode response")
+		JSONInternalServerError(rw)
+
+		return
+	}
+}
+
+// VerifyHash downloads again the archive of a plugin version, and verifies it as on its first download.
+// The archive must match the recorded hash, a modified archive is reported as an incident and requires a reset.
+func (h Handlers) VerifyHash(rw http.ResponseWriter, req *http.Request) {
+	ctx, span := h.tracer.Start(req.Context(), "handler_verifyHash")
+	defer span.End()
+
+	id, version, err := getHashPathParams(req.URL, "verify")
+	if err != nil {
+		span.RecordError(err)
+		JSONError(rw, http.StatusBadRequest, "Missing plugin id or version")
+
+		return
+	}
+
+	logger := log.With().Str("plugin_id", id).Str("plugin_version", version).Logger()
+
+	plugin, pluginHash, ok := h.getPluginHash(ctx, rw, req, id, version)
+	if !ok {
+		return
+	}
+
+	fetched, err := h.fetchArchive(ctx, plugin, version, pluginHash.Hash)
+
+	var mismatch publishedDigestError
+
+	switch {
+	case errors.As(err, &mismatch):
+		span.RecordError(err)
+		h.rejectModified(ctx, rw, plugin.Name, version, sourceAsset, mismatch.expected, mismatch.received)
+
+		return
+
+	case err != nil:
+		span.RecordError(err)
+		logger.Error().Err(err).Msg("Failed to download plugin archive")
+		fetchError(rw, err, http.StatusBadGateway, "Failed to download plugin %s@%s", plugin.Name, version)
+
+		return
+
+	case !matchHash(pluginHash, &fetched):
+		h.rejectModified(ctx, rw, plugin.Name, version, fetched.source, pluginHash.Hash, fetched.sum)
+
+		return
+	}
+
+	h.appendLog(ctx, plugin.Name, version, pluginHash.Hash)
+
+	h.writeVerification(ctx, rw, plugin, version, fetched)
+}
+
+// ResetHash replaces the recorded hash of a plugin version by the hash of the archive currently served by its source.
+// The reset is recorded with its justification.
+// The new hash is appended to the transparency log after the previous one: the reset is visible to the clients verifying the hashes against the log.
+func (h Handlers) ResetHash(rw http.ResponseWriter, req *http.Request) {
+	ctx, span := h.tracer.Start(req.Context(), "handler_resetHash")
+	defer span.End()
+
+	id, version, err := getHashPathParams(req.URL, "reset")
+	if err != nil {
+		span.RecordError(err)
+		JSONError(rw, http.StatusBadRequest, "Missing plugin id or version")
+
+		return
+	}
+
+	logger := log.With().Str("plugin_id", id).Str("plugin_version", version).Logger()
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		span.RecordError(err)
+		logger.Error().Err(err).Msg("Error reading body")
+		JSONError(rw, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	var resetReq hashResetRequest
+	if err = json.Unmarshal(body, &resetReq); err != nil {
+		span.RecordError(err)
+		JSONErrorf(rw, http.StatusBadRequest, "Invalid request: %v", err)
+
+		return
+	}
+
+	if strings.TrimSpace(resetReq.Justification) == "" {
+		span.RecordError(errors.New("missing justification"))
+		JSONError(rw, http.StatusBadRequest, "A justification is required to reset a hash")
+
+		return
+	}
+
+	plugin, pluginHash, ok := h.getPluginHash(ctx, rw, req, id, version)
+	if !ok {
+		return
+	}
+
+	fetched, err := h.fetchArchive(ctx, plugin, version, strings.ToLower(resetReq.Hash))
+	if err != nil {
+		span.RecordError(err)
+		logger.Error().Err(err).Msg("Failed to download plugin archive")
+		fetchError(rw, err, http.StatusBadGateway, "Failed to download plugin %s@%s", plugin.Name, version)
+
+		return
+	}
+
+	if resetReq.Hash != "" && !strings.EqualFold(resetReq.Hash, fetched.sum) {
+		JSONErrorf(rw, http.StatusConflict, "Plugin archive %s@%s hash %s doesn't match the expected hash %s.",
+			plugin.Name, version, fetched.sum, resetReq.Hash)
+
+		return
+	}
+
+	// The reset is recorded before being applied: a failed reset is audited too.
+	reset, err := h.store.CreateHashReset(ctx, db.HashReset{
+		Module:        plugin.Name,
+		Version:       version,
+		PreviousHash:  pluginHash.Hash,
+		Hash:          fetched.sum,
+		Justification: resetReq.Justification,
+		Author:        resetReq.Author,
+	})
+	if err != nil {
+		span.RecordError(err)
+		logger.Error().Err(err).Msg("Error persisting hash reset")
+		JSONInternalServerError(rw)
+
+		return
+	}
+
+	if _, err = h.store.ResetHash(ctx, plugin.Name, version, fetched.sum); err != nil {
+		span.RecordError(err)
+		logger.Error().Err(err).Msg("Error resetting plugin hash")
+		JSONInternalServerError(rw)
+
+		return
+	}
+
+	logger.Warn().
+		Str("reset_id", reset.ID).
+		Str("previous_hash", reset.PreviousHash).
+		Str("hash", reset.Hash).
+		Str("justification", reset.Justification).
+		Msg("Plugin hash reset")
+
+	h.appendResetLog(ctx, plugin.Name, version, fetched.sum)
+
+	h.writeVerification(ctx, rw, plugin, version, fetched)
+}
+
+// getPluginHash gets a plugin and the hash of one of its versions, and writes the error response if they can't be found.
+func (h Handlers) getPluginHash(ctx context.Context, rw http.ResponseWriter, req *http.Request, id, version string) (db.Plugin, db.PluginHash, bool) {
+	logger := log.With().Str("plugin_id", id).Str("plugin_version", version).Logger()
+
+	plugin, err := h.store.Get(ctx, id)
+	if err != nil {
+		if errors.As(err, &db.NotFoundError{}) {
+			NotFound(rw, req)
+			return db.Plugin{}, db.PluginHash{}, false
+		}
+
+		logger.Error().Err(err).Msg("Error while trying to get plugin")
+		JSONInternalServerError(rw)
+
+		return db.Plugin{}, db.PluginHash{}, false
+	}
+
+	pluginHash, err := h.store.GetHashByName(ctx, plugin.Name, version)
+	if err != nil {
+		if errors.As(err, &db.NotFoundError{}) {
+			JSONErrorf(rw, http.StatusNotFound, "Plugin hash not found %s@%s", plugin.Name, version)
+			return db.Plugin{}, db.PluginHash{}, false
+		}
+
+		logger.Error().Err(err).Msg("Failed to get plugin hash")
+		JSONInternalServerError(rw)
+
+		return db.Plugin{}, db.PluginHash{}, false
+	}
+
+	return plugin, pluginHash, true
+}
+
+// writeVerification records the verification of an archive, and writes the updated hash.
+func (h Handlers) writeVerification(ctx context.Context, rw http.ResponseWriter, plugin db.Plugin, version string, fetched fetchedArchive) {
+	logger := log.With().Str("module_name", plugin.Name).Str("module_version", version).Logger()
+
+	if err := h.recordVerification(ctx, plugin.Name, version, fetched); err != nil {
+		logger.Error().Err(err).Msg("Error persisting plugin verification")
+		JSONInternalServerError(rw)
+
+		return
+	}
+
+	pluginHash, err := h.store.GetHashByName(ctx, plugin.Name, version)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to get plugin hash")
+		JSONInternalServerError(rw)
+
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+
+	if err = json.NewEncoder(rw).Encode(pluginHash); err != nil {
+		logger.Error().Err(err).Msg("Failed to encode response")
+		JSONInternalServerError(rw)
+	}
+}
+
+// recordVerification stores the verification result, the signer and the content (digests, manifest, capabilities) of an archive.
+func (h Handlers) recordVerification(ctx context.Context, moduleName, version string, fetched fetchedArchive) error {
+	for _, reason := range fetched.reasons {
+		log.Error().Str("module_name", moduleName).Str("module_version", version).Str("reason", reason).Msg("Invalid archive")
+	}
+
+	_, err := h.store.UpdateHashVerified(ctx, moduleName, version, fetched.sum, len(fetched.reasons) == 0, fetched.reasons)
+	if err != nil {
+		return err
+	}
+
+	h.emitVerification(ctx, moduleName, version, fetched.sum, fetched.reasons)
+
+	if fetched.signer != "" {
+		if _, err = h.store.UpdateHashSigner(ctx, moduleName, version, fetched.signer); err != nil {
+			return err
+		}
+	}
+
+	// The recorded digests are the ones of the pinned archive.
+	if !fetched.repacked {
+		h.recordDigests(ctx, moduleName, version, fetched.raw, fetched.moduleZip)
+	}
+
+	// The content of an archive is only recorded once verified, the release assets are WASM plugins without sources.
+	if len(fetched.reasons) > 0 {
+		return nil
+	}
+
+	h.recordManifest(ctx, moduleName, version, fetched.raw)
+
+	if fetched.source != sourceAsset {
+		h.recordCapabilities(ctx, moduleName, version, fetched.raw)
+	}
+
+	return nil
+}
+
+// pinHash records the hash of an archive fetched for the first time, and appends it to the transparency log.
+// A hash pinned meanwhile (e.g. by a concurrent download or pre-warm job) is authoritative: it is returned instead, and created is false.
+func (h Handlers) pinHash(ctx context.Context, moduleName, version string, fetched fetchedArchive) (pluginHash db.PluginHash, created bool, err error) {
+	pluginHash, err = h.store.CreateHash(ctx, moduleName, version, fetched.sum)
+	if errors.As(err, &db.ConflictError{}) {
+		pluginHash, err = h.store.GetHashByName(ctx, moduleName, version)
+
+		return pluginHash, false, err
+	}
+
+	if err != nil {
+		return db.PluginHash{}, false, err
+	}
+
+	h.appendLog(ctx, moduleName, version, fetched.sum)
+
+	return pluginHash, true, nil
+}
+
+// fetchArchive downloads the archive of a plugin version from the source used to serve it (see Download),
+// and verifies it as on its first download.
+// The module zip of the pinned hash, if any, is looked for through all the upstreams of the Go proxy,
+// the first module zip served is returned when none of them serves it.
+func (h Handlers) fetchArchive(ctx context.Context, plugin db.Plugin, version, pinned string) (fetchedArchive, error) {
+	ctx, span := h.tracer.Start(ctx, "handler_fetchArchive")
+	defer span.End()
+
+	if strings.ToLower(plugin.Runtime) == "wasm" {
+		return h.fetchAsset(ctx, plugin, version)
+	}
+
+	modFile, err := h.fetcher.GetModFile(ctx, plugin.Name, version)
+	if err != nil {
+		span.RecordError(err)
+		return fetchedArchive{}, fmt.Errorf("get module file: %w", err)
+	}
+
+	// Uses GitHub when there are dependencies because Go proxy archives don't contain vendor folder.
+	if h.fetcher.GitHubEnabled() && len(modFile.Require) > 0 {
+		raw, err := readAll(h.fetcher.DownloadZipball(ctx, plugin.Name, version))
+		if err != nil {
+			span.RecordError(err)
+			return fetchedArchive{}, fmt.Errorf("download zipball: %w", err)
+		}
+
+		return fetchedArchive{source: sourceGitHub, raw: raw, sum: digest(raw), reasons: archive.ValidateVendor(raw)}, nil
+	}
+
+	var first []byte
+
+	raw, err := h.fetcher.DownloadSourcesMatching(ctx, plugin.Name, version, func(raw []byte) bool {
+		if first == nil {
+			first = raw
+		}
+
+		return pinned == "" || digest(raw) == pinned
+	})
+	if err != nil && first != nil {
+		raw, err = first, nil
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		return fetchedArchive{}, fmt.Errorf("download sources: %w", err)
+	}
+
+	return fetchedArchive{source: sourceGoProxy, raw: raw, sum: digest(raw), moduleZip: true}, nil
+}
+
+func (h Handlers) fetchAsset(ctx context.Context, plugin db.Plugin, version string) (fetchedArchive, error) {
+	if !h.fetcher.GitHubEnabled() {
+		return fetchedArchive{}, archive.ErrGitHubDisabled
+	}
+
+	asset, err := h.fetcher.GetReleaseAsset(ctx, plugin.Name, version)
+	if err != nil {
+		return fetchedArchive{}, fmt.Errorf("get release asset: %w", err)
+	}
+
+	raw, err := h.readAsset(ctx, asset)
+	if err != nil {
+		return fetchedArchive{}, fmt.Errorf("download release asset: %w", err)
+	}
+
+	fetched := fetchedArchive{source: sourceAsset, raw: raw, sum: digest(raw)}
+
+	// The digest published by GitHub must match the downloaded content.
+	if asset.Digest != "" && asset.Digest != fetched.sum {
+		return fetchedArchive{}, publishedDigestError{expected: asset.Digest, received: fetched.sum}
+	}
+
+	fetched.reasons = archive.ValidateWasm(raw, plugin.WasmPath)
+
+	signer, reason, err := h.verifySignature(ctx, plugin, asset, raw)
+	if err != nil {
+		return fetchedArchive{}, fmt.Errorf("get release asset signature: %w", err)
+	}
+
+	if reason != "" {
+		fetched.reasons = append(fetched.reasons, reason)
+	}
+
+	fetched.signer = signer
+
+	return fetched, nil
+}
+
+func readAll(rc io.ReadCloser, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rc.Close() }()
+
+	return io.ReadAll(rc)
+}
+
+func getHashPathParams(uri *url.URL, action string) (string, string, error) {
+	exp := regexp.MustCompile(`^/([\w-]+)/hashes/([^/]+)/` + regexp.QuoteMeta(action) + `/?$`)
+	parts := exp.FindStringSubmatch(uri.Path)
+
+	if len(parts) != 3 {
+		return "", "", errors.New("missing id or version")
+	}
+
+	return parts[1], parts[2], nil
+}
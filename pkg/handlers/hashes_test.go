@@ -0,0 +1,455 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v74/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/plugin-service/pkg/archive"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+func TestHandlers_Hashes(t *testing.T) {
+	createdAt := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	testDB := mockDB{
+		getFn: func(_ context.Context, id string) (db.Plugin, error) {
+			if id != "123" {
+				return db.Plugin{}, db.NotFoundError{}
+			}
+
+			return db.Plugin{ID: "123", Name: "github.com/traefik/plugindemo"}, nil
+		},
+		listHashesFn: func(_ context.Context, module string) ([]db.PluginHash, error) {
+			return []db.PluginHash{{Name: module + "@v0.2.1", Hash: "456"}}, nil
+		},
+		listHashResetsFn: func(_ context.Context, module string) ([]db.HashReset, error) {
+			return []db.HashReset{{
+				ID:            "1",
+				Module:        module,
+				Version:       "v0.2.1",
+				PreviousHash:  "123",
+				Hash:          "456",
+				Justification: "tag moved by the author",
+				CreatedAt:     createdAt,
+			}}, nil
+		},
+	}
+
+	testCases := []struct {
+		desc           string
+		url            string
+		expectedStatus int
+		expected       string
+	}{
+		{
+			desc:           "hashes and resets",
+			url:            "/123/hashes",
+			expectedStatus: http.StatusOK,
+			expected: `{
+				"id": "123",
+				"name": "github.com/traefik/plugindemo",
+				"hashes": [{"name": "github.com/traefik/plugindemo@v0.2.1", "hash": "456"}],
+				"resets": [{
+					"id": "1",
+					"module": "github.com/traefik/plugindemo",
+					"version": "v0.2.1",
+					"previousHash": "123",
+					"hash": "456",
+					"justification": "tag moved by the author",
+					"createdAt": "2026-10-18T12:00:00Z"
+				}]
+			}`,
+		},
+		{
+			desc:           "unknown plugin",
+			url:            "/456/hashes",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			rw := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, test.url, http.NoBody)
+
+			New(testDB, nil).Hashes(rw, req)
+
+			assert.Equal(t, test.expectedStatus, rw.Code)
+
+			if test.expected != "" {
+				assert.JSONEq(t, test.expected, rw.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlers_VerifyHash(t *testing.T) {
+	const (
+		moduleName = "github.com/traefik/plugindemo"
+		version    = "v0.2.1"
+		hashName   = moduleName + "@" + version
+	)
+
+	sources := buildZip(t, map[string]string{
+		hashName + "/.traefik.yml": "displayName: Demo Plugin\ntype: middleware\nimport: github.com/traefik/plugindemo\n",
+		hashName + "/demo.go":      "package plugindemo",
+	})
+
+	fetcher := fakeFetcher{
+		modFiles: map[string]string{hashName: "module github.com/traefik/plugindemo\n\ngo 1.22\n"},
+		sources:  map[string][]byte{hashName: sources},
+	}
+
+	// The same module zip, but not the same bytes, as served by another upstream.
+	repacked := repackZip(t, sources)
+
+	h1, err := archive.ModuleHash(sources)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		desc             string
+		url              string
+		hash             string
+		digests          map[string]string
+		expectedStatus   int
+		expectedVerified bool
+		expectedIncident bool
+	}{
+		{
+			desc:             "unchanged archive",
+			url:              "/123/hashes/" + version + "/verify",
+			hash:             sha256Sum(sources),
+			expectedStatus:   http.StatusOK,
+			expectedVerified: true,
+		},
+		{
+			desc:             "module zip with the same contents",
+			url:              "/123/hashes/" + version + "/verify",
+			hash:             sha256Sum(repacked),
+			digests:          map[string]string{db.DigestH1: h1},
+			expectedStatus:   http.StatusOK,
+			expectedVerified: true,
+		},
+		{
+			desc:             "module zip with other contents",
+			url:              "/123/hashes/" + version + "/verify",
+			hash:             "123",
+			digests:          map[string]string{db.DigestH1: "456"},
+			expectedStatus:   http.StatusConflict,
+			expectedIncident: true,
+		},
+		{
+			desc:             "modified archive",
+			url:              "/123/hashes/" + version + "/verify",
+			hash:             "123",
+			expectedStatus:   http.StatusConflict,
+			expectedIncident: true,
+		},
+		{
+			desc:           "unknown version",
+			url:            "/123/hashes/v0.1.0/verify",
+			hash:           sha256Sum(sources),
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var verified, incident bool
+
+			testDB := mockDB{
+				getFn: func(_ context.Context, _ string) (db.Plugin, error) {
+					return db.Plugin{ID: "123", Name: moduleName, Runtime: "yaegi"}, nil
+				},
+				getHashByNameFn: func(_ context.Context, module, v string) (db.PluginHash, error) {
+					if v != version {
+						return db.PluginHash{}, db.NotFoundError{}
+					}
+
+					return db.PluginHash{Name: module + "@" + v, Hash: test.hash, Verified: &verified, Digests: test.digests}, nil
+				},
+				updateHashVerifiedFn: func(_ context.Context, _, _, hash string, ok bool, reasons []string) (db.PluginHash, error) {
+					assert.Equal(t, test.hash, hash)
+					assert.Empty(t, reasons)
+
+					verified = ok
+
+					return db.PluginHash{}, nil
+				},
+				updateHashDigestsFn: func(_ context.Context, _, _ string, digests map[string]string) (db.PluginHash, error) {
+					// The recorded digests are the ones of the pinned archive.
+					assert.Equal(t, test.hash, digests[db.DigestSHA256])
+
+					return db.PluginHash{}, nil
+				},
+				updateHashManifestFn: func(_ context.Context, _, _ string, _ db.Manifest) (db.PluginHash, error) {
+					return db.PluginHash{}, nil
+				},
+				updateHashCapabilitiesFn: func(_ context.Context, _, _ string, _ db.CapabilityReport) (db.PluginHash, error) {
+					return db.PluginHash{}, nil
+				},
+				getByNameFn: func(_ context.Context, _ string, _ bool) (db.Plugin, error) {
+					return db.Plugin{ID: "123", Name: moduleName}, nil
+				},
+				createIncidentFn: func(_ context.Context, inc db.Incident) (db.Incident, error) {
+					assert.Equal(t, db.IncidentUpstream, inc.Kind)
+					assert.Equal(t, sha256Sum(sources), inc.Received)
+
+					incident = true
+
+					return inc, nil
+				},
+				countIncidentsFn: func(_ context.Context, _, _, _ string, _ time.Time) (int64, error) {
+					return 1, nil
+				},
+			}
+
+			rw := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, test.url, http.NoBody)
+
+			New(testDB, fetcher).VerifyHash(rw, req)
+
+			assert.Equal(t, test.expectedStatus, rw.Code)
+			assert.Equal(t, test.expectedVerified, verified)
+			assert.Equal(t, test.expectedIncident, incident)
+
+			if test.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var got db.PluginHash
+			require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &got))
+
+			require.NotNil(t, got.Verified)
+			assert.True(t, *got.Verified)
+		})
+	}
+}
+
+func TestHandlers_VerifyHash_publishedDigest(t *testing.T) {
+	const (
+		moduleName = "github.com/traefik/plugindemo"
+		version    = "v0.2.1"
+		hashName   = moduleName + "@" + version
+	)
+
+	wasmArchive := buildZip(t, map[string]string{".traefik.yml": "runtime: wasm\n", "plugin.wasm": "\x00asm\x01\x00\x00\x00"})
+	modified := buildZip(t, map[string]string{".traefik.yml": "runtime: wasm\n", "plugin.wasm": "\x00asm\x02\x00\x00\x00"})
+
+	fetcher := fakeFetcher{
+		assets: map[string]fakeAsset{hashName: {digest: sha256Sum(wasmArchive), content: modified}},
+	}
+
+	var incidents []db.Incident
+
+	testDB := mockDB{
+		getFn: func(_ context.Context, _ string) (db.Plugin, error) {
+			return db.Plugin{ID: "123", Name: moduleName, Runtime: "wasm"}, nil
+		},
+		getHashByNameFn: func(_ context.Context, module, v string) (db.PluginHash, error) {
+			return db.PluginHash{Name: module + "@" + v, Hash: sha256Sum(wasmArchive), Verified: github.Ptr(true)}, nil
+		},
+		createIncidentFn: func(_ context.Context, inc db.Incident) (db.Incident, error) {
+			incidents = append(incidents, inc)
+
+			return inc, nil
+		},
+		countIncidentsFn: func(_ context.Context, _, _, _ string, _ time.Time) (int64, error) {
+			return 1, nil
+		},
+	}
+
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/123/hashes/"+version+"/verify", http.NoBody)
+
+	New(testDB, fetcher).VerifyHash(rw, req)
+
+	// The release asset doesn't match the digest published by GitHub: it is rejected as on a download, the hash isn't verified.
+	assert.Equal(t, http.StatusConflict, rw.Code)
+	assert.Equal(t, []db.Incident{{
+		Kind:     db.IncidentUpstream,
+		Module:   moduleName,
+		Version:  version,
+		Source:   sourceAsset,
+		Expected: sha256Sum(wasmArchive),
+		Received: sha256Sum(modified),
+	}}, incidents)
+}
+
+func TestHandlers_ResetHash(t *testing.T) {
+	const (
+		moduleName = "github.com/traefik/plugindemo"
+		version    = "v0.2.1"
+		hashName   = moduleName + "@" + version
+	)
+
+	sources := buildZip(t, map[string]string{
+		hashName + "/.traefik.yml": "displayName: Demo Plugin\ntype: middleware\nimport: github.com/traefik/plugindemo\n",
+		hashName + "/demo.go":      "package plugindemo",
+	})
+
+	fetcher := fakeFetcher{
+		modFiles: map[string]string{hashName: "module github.com/traefik/plugindemo\n\ngo 1.22\n"},
+		sources:  map[string][]byte{hashName: sources},
+	}
+
+	testCases := []struct {
+		desc           string
+		body           string
+		expectedStatus int
+		expectedReset  *db.HashReset
+	}{
+		{
+			desc:           "reset",
+			body:           `{"justification": "tag moved by the author", "author": "admin"}`,
+			expectedStatus: http.StatusOK,
+			expectedReset: &db.HashReset{
+				Module:        moduleName,
+				Version:       version,
+				PreviousHash:  "123",
+				Hash:          sha256Sum(sources),
+				Justification: "tag moved by the author",
+				Author:        "admin",
+			},
+		},
+		{
+			desc:           "reset to the expected hash",
+			body:           `{"justification": "tag moved by the author", "hash": "` + strings.ToUpper(sha256Sum(sources)) + `"}`,
+			expectedStatus: http.StatusOK,
+			expectedReset: &db.HashReset{
+				Module:        moduleName,
+				Version:       version,
+				PreviousHash:  "123",
+				Hash:          sha256Sum(sources),
+				Justification: "tag moved by the author",
+			},
+		},
+		{
+			desc:           "unexpected hash",
+			body:           `{"justification": "tag moved by the author", "hash": "456"}`,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			desc:           "missing justification",
+			body:           `{"justification": "  "}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			desc:           "invalid body",
+			body:           `{`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				reset    *db.HashReset
+				hash     = "123"
+				verified bool
+			)
+
+			testDB := mockDB{
+				getFn: func(_ context.Context, _ string) (db.Plugin, error) {
+					return db.Plugin{ID: "123", Name: moduleName, Runtime: "yaegi"}, nil
+				},
+				getHashByNameFn: func(_ context.Context, module, v string) (db.PluginHash, error) {
+					return db.PluginHash{Name: module + "@" + v, Hash: hash, Verified: &verified}, nil
+				},
+				createHashResetFn: func(_ context.Context, r db.HashReset) (db.HashReset, error) {
+					reset = &r
+
+					return r, nil
+				},
+				resetHashFn: func(_ context.Context, _, _, h string) (db.PluginHash, error) {
+					require.NotNil(t, reset, "the reset must be recorded first")
+
+					hash = h
+
+					return db.PluginHash{}, nil
+				},
+				updateHashVerifiedFn: func(_ context.Context, _, _, _ string, ok bool, _ []string) (db.PluginHash, error) {
+					verified = ok
+
+					return db.PluginHash{}, nil
+				},
+				updateHashDigestsFn: func(_ context.Context, _, _ string, _ map[string]string) (db.PluginHash, error) {
+					return db.PluginHash{}, nil
+				},
+				updateHashManifestFn: func(_ context.Context, _, _ string, _ db.Manifest) (db.PluginHash, error) {
+					return db.PluginHash{}, nil
+				},
+				updateHashCapabilitiesFn: func(_ context.Context, _, _ string, _ db.CapabilityReport) (db.PluginHash, error) {
+					return db.PluginHash{}, nil
+				},
+				getByNameFn: func(_ context.Context, _ string, _ bool) (db.Plugin, error) {
+					return db.Plugin{ID: "123", Name: moduleName}, nil
+				},
+			}
+
+			transparencyLog := &fakeLog{records: make(map[string]string)}
+
+			rw := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/123/hashes/"+version+"/reset", strings.NewReader(test.body))
+
+			New(testDB, fetcher, WithTransparencyLog(transparencyLog)).ResetHash(rw, req)
+
+			assert.Equal(t, test.expectedStatus, rw.Code)
+			assert.Equal(t, test.expectedReset, reset)
+
+			if test.expectedStatus != http.StatusOK {
+				assert.Equal(t, "123", hash)
+				assert.Empty(t, transparencyLog.records)
+
+				return
+			}
+
+			// The new hash is appended to the transparency log.
+			assert.Equal(t, map[string]string{moduleName + "@" + version: sha256Sum(sources)}, transparencyLog.records)
+
+			var got db.PluginHash
+			require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &got))
+
+			assert.Equal(t, sha256Sum(sources), got.Hash)
+			require.NotNil(t, got.Verified)
+			assert.True(t, *got.Verified)
+		})
+	}
+}
+
+// repackZip returns a zip with the files of another zip, but other bytes.
+func repackZip(t *testing.T, raw []byte) []byte {
+	t.Helper()
+
+	reader, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	w := zip.NewWriter(buf)
+
+	require.NoError(t, w.SetComment("repacked"))
+
+	for _, file := range reader.File {
+		require.NoError(t, w.Copy(file))
+	}
+
+	require.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
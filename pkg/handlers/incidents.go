@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+// Incidents lists the incidents grouped by plugin, or the incidents of a plugin when the module query parameter is set.
+func (h Handlers) Incidents(rw http.ResponseWriter, req *http.Request) {
+	ctx, span := h.tracer.Start(req.Context(), "handler_incidents")
+	defer span.End()
+
+	rw.Header().Set("Content-Type", "application/json")
+
+	var (
+		resp any
+		err  error
+	)
+
+	if module := req.FormValue("module"); module != "" {
+		resp, err = h.store.ListIncidents(ctx, module)
+	} else {
+		resp, err = h.store.AggregateIncidents(ctx)
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		log.Error().Err(err).Msg("Error while trying to get incidents")
+		JSONInternalServerError(rw)
+
+		return
+	}
+
+	if err := json.NewEncoder(rw).Encode(resp); err != nil {
+		span.RecordError(err)
+		log.Error().Err(err).Msg("Failed to encode response")
+		JSONInternalServerError(rw)
+
+		return
+	}
+}
+
+// clientReportInterval the minimum interval between two recorded client reports of a plugin version.
+const clientReportInterval = time.Hour
+
+// reportThrottle limits the client reports of a plugin version to one per interval.
+// The clients are not authenticated, the throttle prevents them from flooding the incidents and the webhooks.
+type reportThrottle struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+func (t *reportThrottle) allow(key string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if last, ok := t.seen[key]; ok && now.Sub(last) < clientReportInterval {
+		return false
+	}
+
+	for k, last := range t.seen {
+		if now.Sub(last) >= clientReportInterval {
+			delete(t.seen, k)
+		}
+	}
+
+	if t.seen == nil {
+		t.seen = make(map[string]time.Time)
+	}
+
+	t.seen[key] = now
+
+	return true
+}
+
+// recordIncident stores an incident for the administrators,
+// and quarantines the plugin version once it has reached the incident threshold.
+// Only the upstream incidents since the last reset of the hash count toward the threshold,
+// the client reports are informational and throttled.
+func (h Handlers) recordIncident(ctx context.Context, incident db.Incident) {
+	ctx, span := h.tracer.Start(ctx, "handler_recordIncident")
+	defer span.End()
+
+	logger := log.With().
+		Str("module_name", incident.Module).
+		Str("module_version", incident.Version).
+		Str("incident_kind", incident.Kind).
+		Str("incident_source", incident.Source).
+		Str("expected_hash", incident.Expected).
+		Str("received_hash", incident.Received).
+		Str("client_ip", incident.IP).
+		Logger()
+
+	logger.Error().Msg("Plugin archive hash mismatch")
+
+	if incident.Kind == db.IncidentClient && !h.clientReports.allow(incident.Module+"@"+incident.Version, time.Now()) {
+		logger.Debug().Msg("Client report throttled")
+
+		return
+	}
+
+	recorded, err := h.store.CreateIncident(ctx, incident)
+	if err != nil {
+		span.RecordError(err)
+		logger.Error().Err(err).Msg("Error persisting incident")
+
+		return
+	}
+
+	h.emitter.Emit(ctx, db.Event{
+		Type:     db.EventHashMismatch,
+		Module:   incident.Module,
+		Version:  incident.Version,
+		Hash:     incident.Expected,
+		Incident: &recorded,
+	})
+
+	if h.incidentThreshold <= 0 || incident.Kind != db.IncidentUpstream {
+		return
+	}
+
+	pluginHash, err := h.store.GetHashByName(ctx, incident.Module, incident.Version)
+	if err != nil {
+		span.RecordError(err)
+		logger.Error().Err(err).Msg("Failed to get plugin hash")
+
+		return
+	}
+
+	var since time.Time
+	if pluginHash.ResetAt != nil {
+		since = *pluginHash.ResetAt
+	}
+
+	count, err := h.store.CountIncidents(ctx, incident.Module, incident.Version, db.IncidentUpstream, since)
+	if err != nil {
+		span.RecordError(err)
+		logger.Error().Err(err).Msg("Failed to count incidents")
+
+		return
+	}
+
+	if count < h.incidentThreshold {
+		return
+	}
+
+	if _, err = h.store.UpdateHashQuarantined(ctx, incident.Module, incident.Version, true); err != nil {
+		span.RecordError(err)
+		logger.Error().Err(err).Msg("Error persisting plugin quarantine")
+
+		return
+	}
+
+	logger.Warn().Int64("incidents", count).Msg("Plugin version quarantined")
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+func TestHandlers_Incidents(t *testing.T) {
+	lastSeen := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	summaries := []db.IncidentSummary{
+		{
+			Module:   "github.com/traefik/plugindemo",
+			Count:    3,
+			LastSeen: lastSeen,
+			Versions: []db.IncidentVersionSummary{
+				{Version: "v0.1.0", Count: 1, LastSeen: lastSeen.Add(-time.Hour)},
+				{Version: "v0.2.1", Count: 2, LastSeen: lastSeen},
+			},
+		},
+	}
+
+	incidents := []db.Incident{
+		{
+			ID:        "1",
+			Kind:      db.IncidentClient,
+			Module:    "github.com/traefik/plugindemo",
+			Version:   "v0.2.1",
+			Expected:  "123",
+			Received:  "456",
+			IP:        "192.0.2.1",
+			UserAgent: "Go-http-client/1.1",
+			CreatedAt: lastSeen,
+		},
+	}
+
+	testCases := []struct {
+		desc           string
+		url            string
+		err            error
+		expectedStatus int
+		expected       any
+	}{
+		{
+			desc:           "aggregated by plugin",
+			url:            "/incidents",
+			expectedStatus: http.StatusOK,
+			expected:       summaries,
+		},
+		{
+			desc:           "incidents of a plugin",
+			url:            "/incidents?module=github.com/traefik/plugindemo",
+			expectedStatus: http.StatusOK,
+			expected:       incidents,
+		},
+		{
+			desc:           "failed to get incidents",
+			url:            "/incidents",
+			err:            errors.New("boom"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			testDB := mockDB{
+				aggregateIncidentsFn: func(_ context.Context) ([]db.IncidentSummary, error) {
+					return summaries, test.err
+				},
+				listIncidentsFn: func(_ context.Context, module string) ([]db.Incident, error) {
+					assert.Equal(t, "github.com/traefik/plugindemo", module)
+
+					return incidents, test.err
+				},
+			}
+
+			rw := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, test.url, http.NoBody)
+
+			New(testDB, nil).Incidents(rw, req)
+
+			assert.Equal(t, test.expectedStatus, rw.Code)
+
+			if test.expectedStatus != http.StatusOK {
+				return
+			}
+
+			expected, err := json.Marshal(test.expected)
+			require.NoError(t, err)
+
+			assert.JSONEq(t, string(expected), rw.Body.String())
+		})
+	}
+}
+
+func TestHandlers_recordIncident_clientReports(t *testing.T) {
+	var incidents []db.Incident
+
+	testDB := mockDB{
+		createIncidentFn: func(_ context.Context, incident db.Incident) (db.Incident, error) {
+			incidents = append(incidents, incident)
+
+			return incident, nil
+		},
+		countIncidentsFn: func(_ context.Context, _, _, kind string, _ time.Time) (int64, error) {
+			assert.Equal(t, db.IncidentUpstream, kind)
+
+			return 1, nil
+		},
+		updateHashQuarantinedFn: func(_ context.Context, _, _ string, _ bool) (db.PluginHash, error) {
+			t.Fatal("the client reports must not quarantine a version")
+
+			return db.PluginHash{}, nil
+		},
+	}
+
+	webhooks := &fakeWebhooks{}
+
+	h := New(testDB, nil, WithIncidentThreshold(1), WithWebhooks(webhooks))
+
+	report := db.Incident{Kind: db.IncidentClient, Module: "github.com/traefik/plugindemo", Version: "v0.2.1", Expected: "123"}
+
+	// Only the first report of a version is recorded and notified until the interval has elapsed.
+	for _, received := range []string{"456", "789", "456"} {
+		report.Received = received
+		h.recordIncident(context.Background(), report)
+	}
+
+	report.Version = "v0.2.0"
+	h.recordIncident(context.Background(), report)
+
+	require.Len(t, incidents, 2)
+	assert.Equal(t, "v0.2.1", incidents[0].Version)
+	assert.Equal(t, "v0.2.0", incidents[1].Version)
+
+	assert.Len(t, webhooks.events, 2)
+
+	assert.True(t, h.clientReports.allow("github.com/traefik/plugindemo@v0.2.1", time.Now().Add(clientReportInterval)))
+}
+
+func TestHandlers_recordIncident_sinceReset(t *testing.T) {
+	resetAt := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	var quarantined bool
+
+	testDB := mockDB{
+		createIncidentFn: func(_ context.Context, incident db.Incident) (db.Incident, error) {
+			return incident, nil
+		},
+		getHashByNameFn: func(_ context.Context, module, version string) (db.PluginHash, error) {
+			return db.PluginHash{Name: module + "@" + version, Hash: "123", ResetAt: &resetAt}, nil
+		},
+		countIncidentsFn: func(_ context.Context, _, _, _ string, since time.Time) (int64, error) {
+			// The incidents before the reset don't count.
+			assert.Equal(t, resetAt, since)
+
+			return 1, nil
+		},
+		updateHashQuarantinedFn: func(_ context.Context, _, _ string, value bool) (db.PluginHash, error) {
+			quarantined = value
+
+			return db.PluginHash{}, nil
+		},
+	}
+
+	h := New(testDB, nil, WithIncidentThreshold(2))
+
+	h.recordIncident(context.Background(), db.Incident{
+		Kind:     db.IncidentUpstream,
+		Module:   "github.com/traefik/plugindemo",
+		Version:  "v0.2.1",
+		Expected: "123",
+		Received: "456",
+	})
+
+	assert.False(t, quarantined)
+}
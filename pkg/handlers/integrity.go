@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/plugin-service/pkg/archive"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+// maxRateLimitRetries is the number of times a version is fetched again after hitting a GitHub rate limit.
+const maxRateLimitRetries = 3
+
+// IntegrityReport the result of an integrity check of all the known plugin versions.
+type IntegrityReport struct {
+	Plugins  int `json:"plugins"`
+	Versions int `json:"versions"`
+	// Recorded the number of versions without hash, recorded by the check.
+	Recorded int `json:"recorded"`
+	// Verified the number of versions matching their recorded hash.
+	Verified int `json:"verified"`
+	// Failed the number of versions which couldn't be fetched.
+	Failed int              `json:"failed"`
+	Drifts []IntegrityDrift `json:"drifts,omitempty"`
+}
+
+// IntegrityDrift a plugin version whose archive doesn't match the recorded hash anymore.
+type IntegrityDrift struct {
+	Module   string `json:"module"`
+	Version  string `json:"version"`
+	Source   string `json:"source"`
+	Expected string `json:"expected"`
+	Received string `json:"received"`
+}
+
+type integrityResult int
+
+const (
+	integrityVerified integrityResult = iota
+	integrityRecorded
+	integrityDrifted
+	integrityFailed
+)
+
+// rateLimitGate pauses the background fetches until a GitHub rate limit is reset.
+type rateLimitGate struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+func (g *rateLimitGate) pause(until time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if until.After(g.until) {
+		g.until = until
+	}
+}
+
+func (g *rateLimitGate) wait(ctx context.Context) error {
+	g.mu.Lock()
+	delay := time.Until(g.until)
+	g.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// CheckIntegrity fetches the archive of every known plugin version from its source, and compares it with the recorded hash.
+// The versions without hash are recorded, and the drifts are reported as upstream incidents.
+// At most concurrency archives are fetched at the same time, and the check is paused while GitHub rate limits the requests.
+func (h Handlers) CheckIntegrity(ctx context.Context, concurrency int) (IntegrityReport, error) {
+	ctx, span := h.tracer.Start(ctx, "handler_checkIntegrity")
+	defer span.End()
+
+	plugins, err := h.store.ListAll(ctx)
+	if err != nil {
+		span.RecordError(err)
+		return IntegrityReport{}, fmt.Errorf("list plugins: %w", err)
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		report = IntegrityReport{Plugins: len(plugins)}
+		sem    = make(chan struct{}, concurrency)
+	)
+
+	for _, plugin := range plugins {
+		for _, version := range plugin.Versions {
+			if ctx.Err() != nil {
+				break
+			}
+
+			sem <- struct{}{}
+
+			wg.Add(1)
+
+			go func() {
+				defer func() { <-sem; wg.Done() }()
+
+				result, drift, err := h.checkVersion(ctx, plugin, version)
+				if err != nil {
+					log.Error().Err(err).Str("module_name", plugin.Name).Str("module_version", version).Msg("Failed to check plugin version")
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				report.Versions++
+
+				switch result {
+				case integrityVerified:
+					report.Verified++
+				case integrityRecorded:
+					report.Recorded++
+				case integrityDrifted:
+					report.Drifts = append(report.Drifts, drift)
+				case integrityFailed:
+					report.Failed++
+				}
+			}()
+		}
+	}
+
+	wg.Wait()
+
+	if err = ctx.Err(); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// checkVersion fetches the archive of a plugin version, and records its hash or compares it with the recorded one.
+// The verification is only recorded, and notified, when its outcome changes.
+func (h Handlers) checkVersion(ctx context.Context, plugin db.Plugin, version string) (integrityResult, IntegrityDrift, error) {
+	ctx, span := h.tracer.Start(ctx, "handler_checkVersion")
+	defer span.End()
+
+	pluginHash, err := h.store.GetHashByName(ctx, plugin.Name, version)
+	if err != nil && !errors.As(err, &db.NotFoundError{}) {
+		span.RecordError(err)
+		return integrityFailed, IntegrityDrift{}, fmt.Errorf("get plugin hash: %w", err)
+	}
+
+	pinned := err == nil
+
+	fetched, err := h.fetchWithRateLimit(ctx, plugin, version, pluginHash.Hash)
+	if err != nil {
+		span.RecordError(err)
+
+		var mismatch publishedDigestError
+		if errors.As(err, &mismatch) {
+			return h.recordDrift(ctx, pluginHash, IntegrityDrift{
+				Module:   plugin.Name,
+				Version:  version,
+				Source:   sourceAsset,
+				Expected: mismatch.expected,
+				Received: mismatch.received,
+			})
+		}
+
+		return integrityFailed, IntegrityDrift{}, fmt.Errorf("download plugin archive: %w", err)
+	}
+
+	if !pinned {
+		var created bool
+
+		pluginHash, created, err = h.pinHash(ctx, plugin.Name, version, fetched)
+		if err != nil {
+			span.RecordError(err)
+			return integrityFailed, IntegrityDrift{}, fmt.Errorf("persist plugin hash: %w", err)
+		}
+
+		if created {
+			if err = h.recordVerification(ctx, plugin.Name, version, fetched); err != nil {
+				span.RecordError(err)
+				return integrityFailed, IntegrityDrift{}, fmt.Errorf("persist plugin verification: %w", err)
+			}
+
+			return integrityRecorded, IntegrityDrift{}, nil
+		}
+	}
+
+	if !matchHash(pluginHash, &fetched) {
+		return h.recordDrift(ctx, pluginHash, IntegrityDrift{
+			Module:   plugin.Name,
+			Version:  version,
+			Source:   fetched.source,
+			Expected: pluginHash.Hash,
+			Received: fetched.sum,
+		})
+	}
+
+	// The hashes recorded before the transparency log was enabled enter it once verified.
+	h.appendLog(ctx, plugin.Name, version, pluginHash.Hash)
+
+	if sameVerification(pluginHash, fetched) {
+		return integrityVerified, IntegrityDrift{}, nil
+	}
+
+	if err = h.recordVerification(ctx, plugin.Name, version, fetched); err != nil {
+		span.RecordError(err)
+		return integrityFailed, IntegrityDrift{}, fmt.Errorf("persist plugin verification: %w", err)
+	}
+
+	return integrityVerified, IntegrityDrift{}, nil
+}
+
+// recordDrift records a drift of the archive of a plugin version as an incident: a known drift is reported by every check, but recorded once.
+func (h Handlers) recordDrift(ctx context.Context, pluginHash db.PluginHash, drift IntegrityDrift) (integrityResult, IntegrityDrift, error) {
+	if h.isNewDrift(ctx, pluginHash, drift) {
+		h.recordIncident(ctx, db.Incident{
+			Kind:     db.IncidentUpstream,
+			Module:   drift.Module,
+			Version:  drift.Version,
+			Source:   drift.Source,
+			Expected: drift.Expected,
+			Received: drift.Received,
+		})
+	}
+
+	return integrityDrifted, drift, nil
+}
+
+// isNewDrift returns true unless the drift has already been recorded as the last upstream incident since the last reset of the hash.
+func (h Handlers) isNewDrift(ctx context.Context, pluginHash db.PluginHash, drift IntegrityDrift) bool {
+	last, err := h.store.GetLastIncident(ctx, drift.Module, drift.Version, db.IncidentUpstream)
+	if err != nil {
+		if !errors.As(err, &db.NotFoundError{}) {
+			log.Error().Err(err).Str("module_name", drift.Module).Str("module_version", drift.Version).Msg("Failed to get last incident")
+		}
+
+		return true
+	}
+
+	if pluginHash.ResetAt != nil && !last.CreatedAt.After(*pluginHash.ResetAt) {
+		return true
+	}
+
+	return last.Received != drift.Received
+}
+
+// sameVerification returns true if the verification of an archive has the outcome already recorded for its hash,
+// and if its digests have already been recorded.
+func sameVerification(pluginHash db.PluginHash, fetched fetchedArchive) bool {
+	if pluginHash.Verified == nil || *pluginHash.Verified != (len(fetched.reasons) == 0) {
+		return false
+	}
+
+	if _, ok := pluginHash.Digests[db.DigestSHA512]; !ok {
+		return false
+	}
+
+	return slices.Equal(pluginHash.Reasons, fetched.reasons) && pluginHash.Signer == fetched.signer
+}
+
+// fetchWithRateLimit fetches an archive, waiting for the reset of the GitHub rate limits when they are hit.
+func (h Handlers) fetchWithRateLimit(ctx context.Context, plugin db.Plugin, version, pinned string) (fetchedArchive, error) {
+	for attempt := 0; ; attempt++ {
+		if err := h.rateLimit.wait(ctx); err != nil {
+			return fetchedArchive{}, err
+		}
+
+		fetched, err := h.fetchArchive(ctx, plugin, version, pinned)
+		if err == nil {
+			return fetched, nil
+		}
+
+		reset, limited := archive.RateLimitReset(err, time.Now())
+		if !limited || attempt >= maxRateLimitRetries {
+			return fetchedArchive{}, err
+		}
+
+		log.Warn().Err(err).Time("reset", reset).Msg("GitHub rate limit hit, pausing the background fetches")
+
+		h.rateLimit.pause(reset)
+	}
+}
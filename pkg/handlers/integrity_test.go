@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v74/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+// rateLimitedFetcher is an ArchiveFetcher hitting the GitHub rate limit on the first download.
+type rateLimitedFetcher struct {
+	ArchiveFetcher
+
+	calls *atomic.Int32
+}
+
+func (f rateLimitedFetcher) DownloadSourcesMatching(ctx context.Context, moduleName, version string, match func(raw []byte) bool) ([]byte, error) {
+	if f.calls.Add(1) == 1 {
+		return nil, &github.RateLimitError{Rate: github.Rate{Reset: github.Timestamp{Time: time.Now().Add(10 * time.Millisecond)}}}
+	}
+
+	return f.ArchiveFetcher.DownloadSourcesMatching(ctx, moduleName, version, match)
+}
+
+func TestHandlers_CheckIntegrity(t *testing.T) {
+	const modFile = "module github.com/traefik/plugindemo\n\ngo 1.22\n"
+
+	archives := make(map[string][]byte)
+	for _, name := range []string{"recorded@v1.0.0", "verified@v1.0.0", "unchanged@v1.0.0", "drifted@v1.0.0", "redrifted@v1.0.0"} {
+		archives[name] = buildZip(t, map[string]string{
+			name + "/.traefik.yml": "displayName: Demo Plugin\ntype: middleware\nimport: github.com/traefik/plugindemo\n",
+			name + "/demo.go":      "package plugindemo",
+		})
+	}
+
+	fetcher := fakeFetcher{
+		modFiles: map[string]string{
+			"recorded@v1.0.0":  modFile,
+			"verified@v1.0.0":  modFile,
+			"unchanged@v1.0.0": modFile,
+			"drifted@v1.0.0":   modFile,
+			"redrifted@v1.0.0": modFile,
+			"failed@v1.0.0":    modFile,
+		},
+		sources: archives,
+	}
+
+	ok := true
+
+	var (
+		mu        sync.Mutex
+		created   = make(map[string]string)
+		verified  = make(map[string]bool)
+		incidents []db.Incident
+	)
+
+	testDB := mockDB{
+		listAllFn: func(_ context.Context) ([]db.Plugin, error) {
+			return []db.Plugin{
+				{ID: "1", Name: "recorded", Runtime: "yaegi", Versions: []string{"v1.0.0"}},
+				{ID: "2", Name: "verified", Runtime: "yaegi", Versions: []string{"v1.0.0"}},
+				{ID: "3", Name: "drifted", Runtime: "yaegi", Versions: []string{"v1.0.0"}},
+				{ID: "4", Name: "failed", Runtime: "yaegi", Versions: []string{"v1.0.0"}},
+				{ID: "5", Name: "unchanged", Runtime: "yaegi", Versions: []string{"v1.0.0"}},
+				{ID: "6", Name: "redrifted", Runtime: "yaegi", Versions: []string{"v1.0.0"}},
+			}, nil
+		},
+		getHashByNameFn: func(_ context.Context, module, version string) (db.PluginHash, error) {
+			switch module {
+			case "verified":
+				return db.PluginHash{Name: module + "@" + version, Hash: sha256Sum(archives["verified@v1.0.0"])}, nil
+			case "unchanged":
+				sum := sha256Sum(archives["unchanged@v1.0.0"])
+
+				return db.PluginHash{
+					Name:     module + "@" + version,
+					Hash:     sum,
+					Verified: &ok,
+					Digests:  map[string]string{db.DigestSHA256: sum, db.DigestSHA512: "512"},
+				}, nil
+			case "drifted", "redrifted":
+				return db.PluginHash{Name: module + "@" + version, Hash: "123"}, nil
+			default:
+				return db.PluginHash{}, db.NotFoundError{}
+			}
+		},
+		createHashFn: func(_ context.Context, module, version, hash string) (db.PluginHash, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			created[module+"@"+version] = hash
+
+			return db.PluginHash{Name: module + "@" + version, Hash: hash}, nil
+		},
+		updateHashVerifiedFn: func(_ context.Context, module, version, _ string, ok bool, _ []string) (db.PluginHash, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			verified[module+"@"+version] = ok
+
+			return db.PluginHash{}, nil
+		},
+		updateHashDigestsFn: func(_ context.Context, _, _ string, _ map[string]string) (db.PluginHash, error) {
+			return db.PluginHash{}, nil
+		},
+		updateHashManifestFn: func(_ context.Context, _, _ string, _ db.Manifest) (db.PluginHash, error) {
+			return db.PluginHash{}, nil
+		},
+		updateHashCapabilitiesFn: func(_ context.Context, _, _ string, _ db.CapabilityReport) (db.PluginHash, error) {
+			return db.PluginHash{}, nil
+		},
+		getByNameFn: func(_ context.Context, name string, _ bool) (db.Plugin, error) {
+			return db.Plugin{Name: name}, nil
+		},
+		createIncidentFn: func(_ context.Context, incident db.Incident) (db.Incident, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			incidents = append(incidents, incident)
+
+			return incident, nil
+		},
+		getLastIncidentFn: func(_ context.Context, module, _, _ string) (db.Incident, error) {
+			// The drift of redrifted has already been recorded by a previous check.
+			if module == "redrifted" {
+				return db.Incident{Received: sha256Sum(archives["redrifted@v1.0.0"])}, nil
+			}
+
+			return db.Incident{}, db.NotFoundError{}
+		},
+	}
+
+	calls := &atomic.Int32{}
+
+	transparencyLog := &fakeLog{records: make(map[string]string)}
+
+	handler := New(testDB, rateLimitedFetcher{ArchiveFetcher: fetcher, calls: calls}, WithTransparencyLog(transparencyLog))
+
+	report, err := handler.CheckIntegrity(context.Background(), 2)
+	require.NoError(t, err)
+
+	expected := IntegrityReport{
+		Plugins:  6,
+		Versions: 6,
+		Recorded: 1,
+		Verified: 2,
+		Failed:   1,
+		Drifts: []IntegrityDrift{{
+			Module:   "drifted",
+			Version:  "v1.0.0",
+			Source:   sourceGoProxy,
+			Expected: "123",
+			Received: sha256Sum(archives["drifted@v1.0.0"]),
+		}, {
+			Module:   "redrifted",
+			Version:  "v1.0.0",
+			Source:   sourceGoProxy,
+			Expected: "123",
+			Received: sha256Sum(archives["redrifted@v1.0.0"]),
+		}},
+	}
+
+	assert.Equal(t, expected.Plugins, report.Plugins)
+	assert.Equal(t, expected.Versions, report.Versions)
+	assert.Equal(t, expected.Recorded, report.Recorded)
+	assert.Equal(t, expected.Verified, report.Verified)
+	assert.Equal(t, expected.Failed, report.Failed)
+	assert.ElementsMatch(t, expected.Drifts, report.Drifts)
+	assert.Equal(t, map[string]string{"recorded@v1.0.0": sha256Sum(archives["recorded@v1.0.0"])}, created)
+	// The verification of unchanged has the recorded outcome, it isn't recorded again.
+	assert.Equal(t, map[string]bool{"recorded@v1.0.0": true, "verified@v1.0.0": true}, verified)
+
+	// The verified hashes, recorded before the transparency log, are appended to it. The drifted ones aren't.
+	assert.Equal(t, map[string]string{
+		"recorded@v1.0.0":  sha256Sum(archives["recorded@v1.0.0"]),
+		"verified@v1.0.0":  sha256Sum(archives["verified@v1.0.0"]),
+		"unchanged@v1.0.0": sha256Sum(archives["unchanged@v1.0.0"]),
+	}, transparencyLog.records)
+
+	require.Len(t, incidents, 1)
+	assert.Equal(t, db.IncidentUpstream, incidents[0].Kind)
+	assert.Equal(t, "drifted", incidents[0].Module)
+
+	// 6 versions, one of them fetched again after the rate limit.
+	assert.Equal(t, int32(7), calls.Load())
+}
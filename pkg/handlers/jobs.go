@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/plugin-service/pkg/db"
+	"github.com/traefik/plugin-service/pkg/jobs"
+)
+
+// Jobs lists the jobs, filtered by the module and status query parameters.
+// The dead letters are listed with status=dead.
+func (h Handlers) Jobs(rw http.ResponseWriter, req *http.Request) {
+	ctx, span := h.tracer.Start(req.Context(), "handler_jobs")
+	defer span.End()
+
+	if h.jobs == nil {
+		NotFound(rw, req)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+
+	filter := db.JobFilter{
+		Module: req.FormValue("module"),
+		Status: req.FormValue("status"),
+	}
+
+	jobs, err := h.jobs.ListJobs(ctx, filter)
+	if err != nil {
+		span.RecordError(err)
+		log.Error().Err(err).Msg("Error while trying to get jobs")
+		JSONInternalServerError(rw)
+
+		return
+	}
+
+	if err := json.NewEncoder(rw).Encode(jobs); err != nil {
+		span.RecordError(err)
+		log.Error().Err(err).Msg("Failed to encode response")
+		JSONInternalServerError(rw)
+
+		return
+	}
+}
+
+// Job gets a job.
+func (h Handlers) Job(rw http.ResponseWriter, req *http.Request) {
+	ctx, span := h.tracer.Start(req.Context(), "handler_job")
+	defer span.End()
+
+	if h.jobs == nil {
+		NotFound(rw, req)
+		return
+	}
+
+	id, err := getPathParam(req.URL)
+	if err != nil {
+		span.RecordError(err)
+		JSONError(rw, http.StatusBadRequest, "Missing job id")
+
+		return
+	}
+
+	job, err := h.jobs.GetJob(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+
+		if errors.As(err, &db.NotFoundError{}) {
+			NotFound(rw, req)
+			return
+		}
+
+		log.Error().Err(err).Str("job_id", id).Msg("Error while trying to get job")
+		JSONInternalServerError(rw)
+
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(rw).Encode(job); err != nil {
+		span.RecordError(err)
+		log.Error().Err(err).Msg("Failed to encode response")
+		JSONInternalServerError(rw)
+
+		return
+	}
+}
+
+// RetryJob moves a dead job back to the pending jobs.
+func (h Handlers) RetryJob(rw http.ResponseWriter, req *http.Request) {
+	ctx, span := h.tracer.Start(req.Context(), "handler_retryJob")
+	defer span.End()
+
+	if h.jobs == nil {
+		NotFound(rw, req)
+		return
+	}
+
+	id, err := getSubPathParam(req.URL, "retry")
+	if err != nil {
+		span.RecordError(err)
+		JSONError(rw, http.StatusBadRequest, "Missing job id")
+
+		return
+	}
+
+	job, err := h.jobs.RetryJob(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+
+		if errors.As(err, &db.NotFoundError{}) {
+			JSONErrorf(rw, http.StatusNotFound, "Dead job not found: %s", id)
+			return
+		}
+
+		log.Error().Err(err).Str("job_id", id).Msg("Error while trying to retry job")
+		JSONInternalServerError(rw)
+
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(rw).Encode(job); err != nil {
+		span.RecordError(err)
+		log.Error().Err(err).Msg("Failed to encode response")
+		JSONInternalServerError(rw)
+
+		return
+	}
+}
+
+// Prewarm runs a pre-warm job: it fetches the archive of a plugin version and records its hash,
+// so that the hash is pinned before the first download. A drift from the recorded hash is final, the job isn't retried.
+func (h Handlers) Prewarm(ctx context.Context, job db.Job) error {
+	plugin, err := h.store.GetByName(ctx, job.Module, false, false)
+	if err != nil {
+		return fmt.Errorf("get plugin: %w", err)
+	}
+
+	result, drift, err := h.checkVersion(ctx, plugin, job.Version)
+	if err != nil {
+		return err
+	}
+
+	// The drift has been recorded as an incident, retrying the job would only record it again.
+	if result == integrityDrifted {
+		return jobs.Permanent(fmt.Errorf("plugin archive has been modified: recorded hash %s, received %s", drift.Expected, drift.Received))
+	}
+
+	return nil
+}
+
+// enqueuePrewarm enqueues the pre-warm jobs of plugin versions.
+// A failure doesn't prevent the plugin creation or update: the hash is recorded on the first download.
+func (h Handlers) enqueuePrewarm(ctx context.Context, moduleName string, versions []string) {
+	if h.jobs == nil {
+		return
+	}
+
+	for _, version := range versions {
+		job, err := h.jobs.Enqueue(ctx, db.Job{Kind: db.JobPrewarm, Module: moduleName, Version: version})
+		if err != nil {
+			log.Error().Err(err).Str("module_name", moduleName).Str("module_version", version).Msg("Unable to enqueue pre-warm job")
+			continue
+		}
+
+		log.Debug().Str("job_id", job.ID).Str("module_name", moduleName).Str("module_version", version).Msg("Pre-warm job enqueued")
+	}
+}
+
+// newVersions returns the versions which are not known yet.
+func newVersions(known, versions []string) []string {
+	var added []string
+
+	for _, version := range versions {
+		if !slices.Contains(known, version) {
+			added = append(added, version)
+		}
+	}
+
+	return added
+}
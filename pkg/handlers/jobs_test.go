@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/plugin-service/pkg/db"
+	"github.com/traefik/plugin-service/pkg/jobs"
+)
+
+// fakeQueue is a JobQueue recording the enqueued jobs.
+type fakeQueue struct {
+	mu   sync.Mutex
+	jobs []db.Job
+}
+
+func (f *fakeQueue) Enqueue(_ context.Context, job db.Job) (db.Job, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	job.ID = job.Module + "@" + job.Version
+	job.Status = db.JobPending
+	f.jobs = append(f.jobs, job)
+
+	return job, nil
+}
+
+func (f *fakeQueue) GetJob(_ context.Context, id string) (db.Job, error) {
+	for _, job := range f.jobs {
+		if job.ID == id {
+			return job, nil
+		}
+	}
+
+	return db.Job{}, db.NotFoundError{}
+}
+
+func (f *fakeQueue) ListJobs(_ context.Context, filter db.JobFilter) ([]db.Job, error) {
+	var jobs []db.Job
+
+	for _, job := range f.jobs {
+		if (filter.Module == "" || job.Module == filter.Module) && (filter.Status == "" || job.Status == filter.Status) {
+			jobs = append(jobs, job)
+		}
+	}
+
+	return jobs, nil
+}
+
+func (f *fakeQueue) RetryJob(_ context.Context, id string) (db.Job, error) {
+	for _, job := range f.jobs {
+		if job.ID == id && job.Status == db.JobDead {
+			job.Status = db.JobPending
+			job.Attempts = 0
+
+			return job, nil
+		}
+	}
+
+	return db.Job{}, db.NotFoundError{}
+}
+
+func TestHandlers_Create_prewarm(t *testing.T) {
+	testDB := mockDB{
+		createFn: func(_ context.Context, plugin db.Plugin) (db.Plugin, error) {
+			plugin.ID = "123"
+
+			return plugin, nil
+		},
+	}
+
+	queue := &fakeQueue{}
+
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "github.com/traefik/plugindemo", "versions": ["v0.2.1", "v0.2.0"]}`))
+
+	New(testDB, nil, WithJobQueue(queue)).Create(rw, req)
+
+	assert.Equal(t, http.StatusCreated, rw.Code)
+	assert.Equal(t, []db.Job{
+		{ID: "github.com/traefik/plugindemo@v0.2.1", Kind: db.JobPrewarm, Module: "github.com/traefik/plugindemo", Version: "v0.2.1", Status: db.JobPending},
+		{ID: "github.com/traefik/plugindemo@v0.2.0", Kind: db.JobPrewarm, Module: "github.com/traefik/plugindemo", Version: "v0.2.0", Status: db.JobPending},
+	}, queue.jobs)
+}
+
+func TestHandlers_Update_prewarm(t *testing.T) {
+	testDB := mockDB{
+		getFn: func(_ context.Context, id string) (db.Plugin, error) {
+			return db.Plugin{ID: id, Name: "github.com/traefik/plugindemo", Versions: []string{"v0.2.0"}}, nil
+		},
+		updateFn: func(_ context.Context, id string, plugin db.Plugin) (db.Plugin, error) {
+			plugin.ID = id
+
+			return plugin, nil
+		},
+	}
+
+	queue := &fakeQueue{}
+
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/123", strings.NewReader(`{"name": "github.com/traefik/plugindemo", "versions": ["v0.2.1", "v0.2.0"]}`))
+
+	New(testDB, nil, WithJobQueue(queue)).Update(rw, req)
+
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, []db.Job{
+		{ID: "github.com/traefik/plugindemo@v0.2.1", Kind: db.JobPrewarm, Module: "github.com/traefik/plugindemo", Version: "v0.2.1", Status: db.JobPending},
+	}, queue.jobs)
+}
+
+func TestHandlers_Prewarm(t *testing.T) {
+	const (
+		moduleName = "github.com/traefik/plugindemo"
+		version    = "v0.2.1"
+		hashName   = moduleName + "@" + version
+	)
+
+	sources := buildZip(t, map[string]string{
+		hashName + "/.traefik.yml": "displayName: Demo Plugin\ntype: middleware\nimport: github.com/traefik/plugindemo\n",
+		hashName + "/demo.go":      "package plugindemo",
+	})
+
+	fetcher := fakeFetcher{
+		modFiles: map[string]string{hashName: "module github.com/traefik/plugindemo\n\ngo 1.22\n"},
+		sources:  map[string][]byte{hashName: sources},
+	}
+
+	testCases := []struct {
+		desc            string
+		hash            string
+		pinnedMeanwhile string
+		expectedHash    string
+		expectedError   string
+	}{
+		{
+			desc:         "new version",
+			expectedHash: sha256Sum(sources),
+		},
+		{
+			desc: "known version",
+			hash: sha256Sum(sources),
+		},
+		{
+			desc:          "modified archive",
+			hash:          "123",
+			expectedError: "plugin archive has been modified: recorded hash 123, received " + sha256Sum(sources),
+		},
+		{
+			desc:            "other hash pinned meanwhile",
+			pinnedMeanwhile: "123",
+			expectedError:   "plugin archive has been modified: recorded hash 123, received " + sha256Sum(sources),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var created string
+
+			pinned := test.hash
+
+			testDB := mockDB{
+				getByNameFn: func(_ context.Context, name string, _ bool) (db.Plugin, error) {
+					return db.Plugin{ID: "123", Name: name, Runtime: "yaegi"}, nil
+				},
+				getHashByNameFn: func(_ context.Context, module, v string) (db.PluginHash, error) {
+					if pinned == "" {
+						return db.PluginHash{}, db.NotFoundError{}
+					}
+
+					return db.PluginHash{Name: module + "@" + v, Hash: pinned}, nil
+				},
+				createHashFn: func(_ context.Context, module, v, hash string) (db.PluginHash, error) {
+					if test.pinnedMeanwhile != "" {
+						pinned = test.pinnedMeanwhile
+
+						return db.PluginHash{}, db.ConflictError{}
+					}
+
+					created = hash
+
+					return db.PluginHash{Name: module + "@" + v, Hash: hash}, nil
+				},
+				updateHashVerifiedFn: func(_ context.Context, _, _, _ string, _ bool, _ []string) (db.PluginHash, error) {
+					return db.PluginHash{}, nil
+				},
+				updateHashDigestsFn: func(_ context.Context, _, _ string, _ map[string]string) (db.PluginHash, error) {
+					return db.PluginHash{}, nil
+				},
+				updateHashManifestFn: func(_ context.Context, _, _ string, _ db.Manifest) (db.PluginHash, error) {
+					return db.PluginHash{}, nil
+				},
+				updateHashCapabilitiesFn: func(_ context.Context, _, _ string, _ db.CapabilityReport) (db.PluginHash, error) {
+					return db.PluginHash{}, nil
+				},
+				createIncidentFn: func(_ context.Context, incident db.Incident) (db.Incident, error) {
+					return incident, nil
+				},
+				getLastIncidentFn: func(_ context.Context, _, _, _ string) (db.Incident, error) {
+					return db.Incident{}, db.NotFoundError{}
+				},
+			}
+
+			err := New(testDB, fetcher).Prewarm(context.Background(), db.Job{Kind: db.JobPrewarm, Module: moduleName, Version: version})
+
+			if test.expectedError != "" {
+				require.EqualError(t, err, test.expectedError)
+
+				// A drift isn't retried.
+				assert.True(t, jobs.IsPermanent(err))
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expectedHash, created)
+		})
+	}
+}
+
+func TestHandlers_Jobs(t *testing.T) {
+	queue := &fakeQueue{jobs: []db.Job{
+		{ID: "1", Kind: db.JobPrewarm, Module: "github.com/traefik/plugindemo", Version: "v0.2.1", Status: db.JobSucceeded},
+		{ID: "2", Kind: db.JobPrewarm, Module: "github.com/traefik/other", Version: "v0.1.0", Status: db.JobDead, Attempts: 5, MaxAttempts: 5, Error: "boom"},
+	}}
+
+	testCases := []struct {
+		desc           string
+		handler        func(h Handlers) http.HandlerFunc
+		url            string
+		queue          JobQueue
+		expectedStatus int
+		expected       string
+	}{
+		{
+			desc:           "jobs of a plugin",
+			handler:        func(h Handlers) http.HandlerFunc { return h.Jobs },
+			url:            "/jobs?module=github.com/traefik/other",
+			queue:          queue,
+			expectedStatus: http.StatusOK,
+			expected:       `[{"id":"2","kind":"prewarm","module":"github.com/traefik/other","version":"v0.1.0","status":"dead","attempts":5,"maxAttempts":5,"error":"boom","runAt":"0001-01-01T00:00:00Z","leaseUntil":"0001-01-01T00:00:00Z","createdAt":"0001-01-01T00:00:00Z","updatedAt":"0001-01-01T00:00:00Z"}]`,
+		},
+		{
+			desc:           "dead letters",
+			handler:        func(h Handlers) http.HandlerFunc { return h.Jobs },
+			url:            "/jobs?status=dead",
+			queue:          queue,
+			expectedStatus: http.StatusOK,
+			expected:       `[{"id":"2","kind":"prewarm","module":"github.com/traefik/other","version":"v0.1.0","status":"dead","attempts":5,"maxAttempts":5,"error":"boom","runAt":"0001-01-01T00:00:00Z","leaseUntil":"0001-01-01T00:00:00Z","createdAt":"0001-01-01T00:00:00Z","updatedAt":"0001-01-01T00:00:00Z"}]`,
+		},
+		{
+			desc:           "job",
+			handler:        func(h Handlers) http.HandlerFunc { return h.Job },
+			url:            "/1",
+			queue:          queue,
+			expectedStatus: http.StatusOK,
+			expected:       `{"id":"1","kind":"prewarm","module":"github.com/traefik/plugindemo","version":"v0.2.1","status":"succeeded","attempts":0,"maxAttempts":0,"runAt":"0001-01-01T00:00:00Z","leaseUntil":"0001-01-01T00:00:00Z","createdAt":"0001-01-01T00:00:00Z","updatedAt":"0001-01-01T00:00:00Z"}`,
+		},
+		{
+			desc:           "retry dead job",
+			handler:        func(h Handlers) http.HandlerFunc { return h.RetryJob },
+			url:            "/2/retry",
+			queue:          queue,
+			expectedStatus: http.StatusOK,
+			expected:       `{"id":"2","kind":"prewarm","module":"github.com/traefik/other","version":"v0.1.0","status":"pending","attempts":0,"maxAttempts":5,"error":"boom","runAt":"0001-01-01T00:00:00Z","leaseUntil":"0001-01-01T00:00:00Z","createdAt":"0001-01-01T00:00:00Z","updatedAt":"0001-01-01T00:00:00Z"}`,
+		},
+		{
+			desc:           "retry job which is not dead",
+			handler:        func(h Handlers) http.HandlerFunc { return h.RetryJob },
+			url:            "/1/retry",
+			queue:          queue,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			desc:           "unknown job",
+			handler:        func(h Handlers) http.HandlerFunc { return h.Job },
+			url:            "/3",
+			queue:          queue,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			desc:           "without job queue",
+			handler:        func(h Handlers) http.HandlerFunc { return h.Jobs },
+			url:            "/jobs",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var opts []Option
+			if test.queue != nil {
+				opts = append(opts, WithJobQueue(test.queue))
+			}
+
+			rw := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, test.url, http.NoBody)
+
+			test.handler(New(mockDB{}, nil, opts...))(rw, req)
+
+			assert.Equal(t, test.expectedStatus, rw.Code)
+
+			if test.expected != "" {
+				assert.JSONEq(t, test.expected, rw.Body.String())
+			}
+		})
+	}
+}
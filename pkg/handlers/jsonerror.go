@@ -2,10 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/traefik/plugin-service/pkg/archive"
+	"github.com/traefik/plugin-service/pkg/upstream"
 )
 
 type apiError struct {
@@ -29,6 +35,33 @@ func JSONErrorf(rw http.ResponseWriter, code int, errMsg string, args ...interfa
 	JSONError(rw, code, fmt.Sprintf(errMsg, args...))
 }
 
+// fetchError handles the JSON error of a failed upstream fetch:
+// a 503 with a Retry-After header when GitHub rate limits the requests or when the circuit breaker of the upstream is open,
+// the given code otherwise.
+func fetchError(rw http.ResponseWriter, err error, code int, errMsg string, args ...interface{}) {
+	if reset, limited := archive.RateLimitReset(err, time.Now()); limited {
+		setRetryAfter(rw, reset)
+		JSONErrorf(rw, http.StatusServiceUnavailable, "GitHub rate limit exceeded: "+errMsg, args...)
+
+		return
+	}
+
+	var openErr *upstream.OpenError
+	if errors.As(err, &openErr) {
+		setRetryAfter(rw, openErr.RetryAt)
+		JSONErrorf(rw, http.StatusServiceUnavailable, openErr.Upstream+" unavailable: "+errMsg, args...)
+
+		return
+	}
+
+	JSONErrorf(rw, code, errMsg, args...)
+}
+
+func setRetryAfter(rw http.ResponseWriter, at time.Time) {
+	retryAfter := int(math.Ceil(time.Until(at).Seconds()))
+	rw.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+}
+
 // JSONError handles an JSON error.
 func JSONError(rw http.ResponseWriter, code int, errMsg string) {
 	rw.Header().Set("Content-Type", "application/json")
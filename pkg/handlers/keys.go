@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+type signingKey struct {
+	KeyID     string `json:"keyId"`
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"publicKey"`
+}
+
+type signingKeys struct {
+	Keys []signingKey `json:"keys"`
+}
+
+// SigningKeys publishes the public keys verifying the signatures of the X-Plugin-Signature header.
+func (h Handlers) SigningKeys(rw http.ResponseWriter, req *http.Request) {
+	_, span := h.tracer.Start(req.Context(), "handler_signingKeys")
+	defer span.End()
+
+	if h.signer == nil {
+		NotFound(rw, req)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Cache-Control", "public, max-age=3600")
+
+	resp := signingKeys{
+		Keys: []signingKey{{
+			KeyID:     h.signer.KeyID(),
+			Algorithm: "Ed25519",
+			PublicKey: base64.StdEncoding.EncodeToString(h.signer.PublicKey()),
+		}},
+	}
+
+	if err := json.NewEncoder(rw).Encode(resp); err != nil {
+		span.RecordError(err)
+		log.Error().Err(err).Msg("Failed to encode response")
+		JSONInternalServerError(rw)
+
+		return
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-github/v74/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/plugin-service/pkg/db"
+	"github.com/traefik/plugin-service/pkg/signing"
+)
+
+func TestHandlers_SigningKeys(t *testing.T) {
+	signer := signing.NewSigner(ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, ed25519.SeedSize)))
+
+	testCases := []struct {
+		desc           string
+		signer         StatementSigner
+		expectedStatus int
+		expected       signingKeys
+	}{
+		{
+			desc:           "signing key",
+			signer:         signer,
+			expectedStatus: http.StatusOK,
+			expected: signingKeys{Keys: []signingKey{{
+				KeyID:     signer.KeyID(),
+				Algorithm: "Ed25519",
+				PublicKey: base64.StdEncoding.EncodeToString(signer.PublicKey()),
+			}}},
+		},
+		{
+			desc:           "no signing key",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/.well-known/plugin-signing-keys", http.NoBody)
+			rw := httptest.NewRecorder()
+
+			var opts []Option
+			if test.signer != nil {
+				opts = append(opts, WithStatementSigner(test.signer))
+			}
+
+			New(mockDB{}, nil, opts...).SigningKeys(rw, req)
+
+			assert.Equal(t, test.expectedStatus, rw.Code)
+
+			if test.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var got signingKeys
+			require.NoError(t, json.NewDecoder(rw.Body).Decode(&got))
+
+			assert.Equal(t, test.expected, got)
+		})
+	}
+}
+
+func TestHandlers_Download_signed(t *testing.T) {
+	const (
+		moduleName = "github.com/traefik/plugindemo"
+		version    = "v0.2.1"
+		hashName   = moduleName + "@" + version
+	)
+
+	sources := buildZip(t, map[string]string{
+		hashName + "/.traefik.yml": "displayName: Demo Plugin\ntype: middleware\nimport: github.com/traefik/plugindemo\n",
+		hashName + "/demo.go":      "package plugindemo",
+	})
+
+	signer := signing.NewSigner(ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, ed25519.SeedSize)))
+
+	testCases := []struct {
+		desc           string
+		sum            string
+		expectedStatus int
+	}{
+		{
+			desc:           "download",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "not modified",
+			sum:            sha256Sum(sources),
+			expectedStatus: http.StatusNotModified,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			testDB := mockDB{
+				getByNameFn: func(_ context.Context, _ string, _ bool) (db.Plugin, error) {
+					return db.Plugin{Name: moduleName, Runtime: "yaegi"}, nil
+				},
+				getHashByNameFn: func(_ context.Context, module, version string) (db.PluginHash, error) {
+					return db.PluginHash{Name: module + "@" + version, Hash: sha256Sum(sources), Verified: github.Ptr(true)}, nil
+				},
+			}
+
+			fetcher := fakeFetcher{
+				modFiles: map[string]string{hashName: "module github.com/traefik/plugindemo\n\ngo 1.22\n"},
+				sources:  map[string][]byte{hashName: sources},
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/download/"+moduleName+"/"+version, http.NoBody)
+			if test.sum != "" {
+				req.Header.Set(hashHeader, test.sum)
+			}
+
+			rw := httptest.NewRecorder()
+
+			New(testDB, fetcher, WithStatementSigner(signer)).Download(rw, req)
+
+			assert.Equal(t, test.expectedStatus, rw.Code)
+			assert.Equal(t, signer.KeyID(), rw.Header().Get(keyIDHeader))
+
+			statement := signing.Statement{Module: moduleName, Version: version, SHA256: sha256Sum(sources)}
+			assert.NoError(t, signing.Verify(signer.PublicKey(), statement, rw.Header().Get(signatureHeader)))
+		})
+	}
+}
@@ -1,8 +1,6 @@
 package handlers
 
 import (
-	"archive/zip"
-	"bytes"
 	"context"
 	"crypto/sha256"
 	"errors"
@@ -12,18 +10,29 @@ import (
 	"net/http"
 	"net/url"
 	"path"
-	"path/filepath"
 	"strings"
 
-	"github.com/google/go-github/v74/github"
 	"github.com/rs/zerolog/log"
+	"github.com/traefik/plugin-service/pkg/archive"
 	"github.com/traefik/plugin-service/pkg/db"
+	"github.com/traefik/plugin-service/pkg/signing"
+	"github.com/traefik/plugin-service/pkg/upstream"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
 
 const (
-	hashHeader = "X-Plugin-Hash"
+	hashHeader      = "X-Plugin-Hash"
+	digestHeader    = "X-Plugin-Digest"
+	signatureHeader = "X-Plugin-Signature"
+	keyIDHeader     = "X-Plugin-Key-Id"
+)
+
+// Sources of the plugin archives.
+const (
+	sourceGoProxy = "goproxy"
+	sourceGitHub  = "github"
+	sourceAsset   = "asset"
 )
 
 // Download a plugin archive.
@@ -81,51 +90,31 @@ func (h Handlers) Download(rw http.ResponseWriter, req *http.Request) {
 				return
 			}
 		} else if ph.Hash == sum {
+			h.signStatement(ctx, rw, pluginName, version, ph.Hash)
 			rw.WriteHeader(http.StatusNotModified)
 
 			return
 		}
 
+		// The client has an archive different from the one downloaded the first time.
+		if errH == nil {
+			h.recordIncident(ctx, db.Incident{
+				Kind:      db.IncidentClient,
+				Module:    pluginName,
+				Version:   version,
+				Expected:  ph.Hash,
+				Received:  sum,
+				IP:        getUserIP(req),
+				UserAgent: req.UserAgent(),
+			})
+		}
+
 		span.AddEvent("module.download", trace.WithAttributes(attributes...))
-		logger.Error().Msgf("Someone is trying to hack the archive: %v", sum)
 	}
 
 	span.SetAttributes(attributes...)
 
-	switch strings.ToLower(plugin.Runtime) {
-	case "wasm":
-		// WASM plugins
-		if h.gh == nil {
-			logger.Error().Msg("Failed to get plugin: missing GitHub client.")
-			JSONErrorf(rw, http.StatusInternalServerError, "Failed to get plugin %s@%s", pluginName, version)
-
-			return
-		}
-
-		h.downloadGitHubFromAssets(ctx, pluginName, version)(rw, req)
-
-		return
-
-	default:
-		// Yaegi plugins
-		modFile, err := h.goProxy.GetModFile(pluginName, version)
-		if err != nil {
-			span.RecordError(err)
-			logger.Error().Err(err).Msg("Failed to get module file")
-			JSONErrorf(rw, http.StatusInternalServerError, "Failed to get plugin %s@%s", pluginName, version)
-
-			return
-		}
-
-		// Uses GitHub when there are dependencies because Go proxy archives don't contain vendor folder.
-		if h.gh != nil && len(modFile.Require) > 0 {
-			h.downloadGitHub(ctx, pluginName, version)(rw, req)
-
-			return
-		}
-
-		h.downloadGoProxy(ctx, pluginName, version)(rw, req)
-	}
+	h.downloadArchive(ctx, plugin, version)(rw, req)
 }
 
 func getUserIP(req *http.Request) string {
@@ -142,398 +131,325 @@ func getUserIP(req *http.Request) string {
 	return ip
 }
 
-func (h Handlers) downloadGoProxy(ctx context.Context, moduleName, version string) http.HandlerFunc {
+// downloadArchive serves the archive of a plugin version from its source (see fetchArchive), with trust on first use:
+// the hash of the archive is recorded on its first download, and the archive is then served only if it matches this hash.
+// The archive is verified once, on its first download, and it is served only if it is verified.
+func (h Handlers) downloadArchive(ctx context.Context, plugin db.Plugin, version string) http.HandlerFunc {
 	return func(rw http.ResponseWriter, _ *http.Request) {
-		ctxDownload, span := h.tracer.Start(ctx, "handler_downloadGoProxy")
+		ctxDownload, span := h.tracer.Start(ctx, "handler_downloadArchive")
 		defer span.End()
 
+		moduleName := plugin.Name
+
 		logger := log.With().Str("module_name", moduleName).Str("module_version", version).Logger()
 
-		sources, err := h.goProxy.DownloadSources(moduleName, version)
-		if err != nil {
+		pluginHash, err := h.store.GetHashByName(ctxDownload, moduleName, version)
+		if err != nil && !errors.As(err, &db.NotFoundError{}) {
 			span.RecordError(err)
-			logger.Error().Err(err).Msg("Failed to download sources")
+			logger.Error().Err(err).Msg("Failed to get plugin hash")
 			JSONErrorf(rw, http.StatusInternalServerError, "Failed to get plugin %s@%s", moduleName, version)
 
 			return
 		}
 
-		defer func() { _ = sources.Close() }()
+		pinned := err == nil
 
-		_, err = h.store.GetHashByName(ctxDownload, moduleName, version)
-		if err != nil && !errors.As(err, &db.NotFoundError{}) {
+		fetched, err := h.fetchArchive(ctxDownload, plugin, version, pluginHash.Hash)
+		if err != nil {
 			span.RecordError(err)
-			logger.Error().Err(err).Msg("Failed to get plugin hash")
-			JSONErrorf(rw, http.StatusInternalServerError, "Failed to get plugin %s@%s", moduleName, version)
 
-			return
-		}
-
-		if err == nil {
-			_, err = io.Copy(rw, sources)
-			if err != nil {
-				span.RecordError(err)
-				logger.Error().Err(err).Msg("Failed to write response body")
-				JSONErrorf(rw, http.StatusInternalServerError, "Failed to get plugin %s@%s", moduleName, version)
+			var mismatch publishedDigestError
+			if errors.As(err, &mismatch) {
+				h.rejectModified(ctxDownload, rw, moduleName, version, sourceAsset, mismatch.expected, mismatch.received)
 
 				return
 			}
 
-			return
-		}
-
-		raw, err := io.ReadAll(sources)
-		if err != nil {
-			span.RecordError(err)
-			logger.Error().Err(err).Msg("Failed to read response body")
-			JSONErrorf(rw, http.StatusInternalServerError, "Failed to get plugin %s@%s", moduleName, version)
-
-			return
-		}
+			var openErr *upstream.OpenError
+			if errors.As(err, &openErr) && pinned && h.serveCachedArchive(ctxDownload, rw, moduleName, version, pluginHash) {
+				logger.Warn().Err(err).Msg("Upstream unavailable, cached plugin archive served")
 
-		hash := sha256.New()
+				return
+			}
 
-		_, err = hash.Write(raw)
-		if err != nil {
-			span.RecordError(err)
-			logger.Error().Err(err).Msg("Failed to compute hash")
-			JSONErrorf(rw, http.StatusInternalServerError, "Failed to get plugin %s@%s", moduleName, version)
+			logger.Error().Err(err).Msg("Failed to download plugin archive")
+			fetchError(rw, err, http.StatusInternalServerError, "Failed to get plugin %s@%s", moduleName, version)
 
 			return
 		}
 
-		sum := fmt.Sprintf("%x", hash.Sum(nil))
-
-		_, err = h.store.CreateHash(ctxDownload, moduleName, version, sum)
-		if err != nil {
-			span.RecordError(err)
-			logger.Error().Err(err).Msg("Error persisting plugin hash")
-			JSONErrorf(rw, http.StatusInternalServerError, "Could not persist data: %s@%s", moduleName, version)
+		// The plugin hash does not exist, we create it.
+		if !pinned {
+			pluginHash, _, err = h.pinHash(ctxDownload, moduleName, version, fetched)
+			if err != nil {
+				span.RecordError(err)
+				logger.Error().Err(err).Msg("Error persisting plugin hash")
+				JSONErrorf(rw, http.StatusInternalServerError, "Could not persist data: %s@%s", moduleName, version)
 
-			return
+				return
+			}
 		}
 
-		_, err = rw.Write(raw)
-		if err != nil {
-			span.RecordError(err)
-			logger.Error().Err(err).Msg("failed to write response body")
-			JSONErrorf(rw, http.StatusInternalServerError, "failed to get plugin %s@%s", moduleName, version)
+		if pluginHash.Quarantined {
+			JSONErrorf(rw, http.StatusForbidden, "Plugin archive %s@%s is quarantined.", moduleName, version)
 
 			return
 		}
-	}
-}
-
-func (h Handlers) downloadGitHub(ctx context.Context, moduleName, version string) http.HandlerFunc {
-	return func(rw http.ResponseWriter, _ *http.Request) {
-		ctxDownload, span := h.tracer.Start(ctx, "handler_downloadGitHub")
-		defer span.End()
-
-		logger := log.With().Str("module_name", moduleName).Str("module_version", version).Logger()
 
-		request, err := h.getArchiveLinkRequest(ctxDownload, moduleName, version)
-		if err != nil {
-			span.RecordError(err)
-			logger.Error().Err(err).Msg("Failed to get archive link")
-			JSONErrorf(rw, http.StatusInternalServerError, "Failed to get plugin %s@%s", moduleName, version)
+		// We reject the request if the archive has been modified.
+		// The served archive must be the pinned one, not only have its contents: its hash is the one validated and signed for the clients.
+		switch {
+		case !matchHash(pluginHash, &fetched):
+			h.rejectModified(ctxDownload, rw, moduleName, version, fetched.source, pluginHash.Hash, fetched.sum)
 
 			return
-		}
 
-		_, err = h.store.GetHashByName(ctxDownload, moduleName, version)
-		if err != nil && !errors.As(err, &db.NotFoundError{}) {
-			span.RecordError(err)
-			logger.Error().Err(err).Msg("Failed to get plugin hash")
-			JSONErrorf(rw, http.StatusInternalServerError, "Failed to get plugin %s@%s", moduleName, version)
+		case fetched.repacked:
+			logger.Warn().Str("hash", pluginHash.Hash).Msg("Pinned module zip not served by the Go proxy upstreams")
+			JSONErrorf(rw, http.StatusConflict, "Plugin archive %s@%s with hash %s is not served by the upstreams.", moduleName, version, pluginHash.Hash)
 
 			return
 		}
 
-		if err == nil {
-			_, err = h.gh.Do(ctxDownload, request, rw)
-			if err != nil {
+		// The archive is verified on its first download, or on the next one when its hash has been recorded without verification.
+		if pluginHash.Verified == nil {
+			if err = h.recordVerification(ctxDownload, moduleName, version, fetched); err != nil {
 				span.RecordError(err)
-				logger.Error().Err(err).Msg("Failed to write response body")
+				logger.Error().Err(err).Msg("Error persisting plugin verification")
 				JSONErrorf(rw, http.StatusInternalServerError, "Failed to get plugin %s@%s", moduleName, version)
 
 				return
 			}
 
-			return
+			verified := len(fetched.reasons) == 0
+			pluginHash.Verified = &verified
 		}
 
-		sources := bytes.NewBufferString("")
-
-		_, err = h.gh.Do(ctxDownload, request, sources)
-		if err != nil {
-			span.RecordError(err)
-			logger.Error().Err(err).Msg("Failed to get archive content")
-			JSONErrorf(rw, http.StatusInternalServerError, "Failed to get plugin %s@%s", moduleName, version)
-
-			return
-		}
-
-		raw, err := io.ReadAll(sources)
-		if err != nil {
-			span.RecordError(err)
-			logger.Error().Err(err).Msg("Failed to read response body")
-			JSONErrorf(rw, http.StatusInternalServerError, "Failed to get plugin %s@%s", moduleName, version)
-
-			return
-		}
-
-		hash := sha256.New()
-
-		_, err = hash.Write(raw)
-		if err != nil {
-			span.RecordError(err)
-			logger.Error().Err(err).Msg("Failed to compute hash")
-			JSONErrorf(rw, http.StatusInternalServerError, "Failed to get plugin %s@%s", moduleName, version)
-
-			return
-		}
-
-		sum := fmt.Sprintf("%x", hash.Sum(nil))
-
-		_, err = h.store.CreateHash(ctxDownload, moduleName, version, sum)
-		if err != nil {
-			span.RecordError(err)
-			logger.Error().Err(err).Msg("Error persisting plugin hash")
-			JSONErrorf(rw, http.StatusInternalServerError, "Failed to get plugin %s@%s", moduleName, version)
+		// We reject the request.
+		if !*pluginHash.Verified {
+			JSONErrorf(rw, http.StatusNotFound, "Plugin archive %s@%s is not verified.", moduleName, version)
 
 			return
 		}
 
-		_, err = rw.Write(raw)
-		if err != nil {
-			span.RecordError(err)
-			logger.Error().Err(err).Msg("Failed to write response body")
-			JSONErrorf(rw, http.StatusInternalServerError, "Failed to get plugin %s@%s", moduleName, version)
+		h.archives.set(moduleName, version, fetched.raw)
 
-			return
-		}
+		h.writeArchive(ctxDownload, rw, moduleName, version, fetched.raw)
 	}
 }
 
-func (h Handlers) downloadGitHubFromAssets(ctx context.Context, moduleName, version string) http.HandlerFunc {
-	return func(rw http.ResponseWriter, _ *http.Request) {
-		ctxDownload, span := h.tracer.Start(ctx, "handler_downloadGitHubFromAssets")
-		defer span.End()
-
-		logger := log.With().Str("module_name", moduleName).Str("module_version", version).Logger()
-
-		request, digest, err := h.getAssetLinkRequest(ctxDownload, moduleName, version)
-		if err != nil {
-			span.RecordError(err)
-			logger.Error().Err(err).Msg("Failed to get archive link")
-			JSONErrorf(rw, http.StatusInternalServerError, "Failed to get plugin %s@%s", moduleName, version)
-
-			return
-		}
-
-		var assetBytes []byte
-
-		// If the asset has no digest, we need to download it to compute the digest.
-		if digest == "" {
-			sources := bytes.NewBufferString("")
-
-			_, err = h.gh.Do(ctxDownload, request, sources)
-			if err != nil {
-				span.RecordError(err)
-				logger.Error().Err(err).Msg("Failed to get archive content")
-				JSONErrorf(rw, http.StatusInternalServerError, "Failed to get plugin %s@%s", moduleName, version)
-
-				return
-			}
-
-			assetBytes, err = io.ReadAll(sources)
-			if err != nil {
-				span.RecordError(err)
-				logger.Error().Err(err).Msg("Failed to read response body")
-				JSONErrorf(rw, http.StatusInternalServerError, "Failed to get plugin %s@%s", moduleName, version)
+// serveCachedArchive serves the cached archive of a plugin version, and returns false if it can't be served:
+// only the archive matching the pinned hash of a verified and not quarantined version is served.
+func (h Handlers) serveCachedArchive(ctx context.Context, rw http.ResponseWriter, moduleName, version string, pluginHash db.PluginHash) bool {
+	raw, ok := h.archives.get(moduleName, version)
+	if !ok || pluginHash.Quarantined || pluginHash.Verified == nil || !*pluginHash.Verified || pluginHash.Hash != digest(raw) {
+		return false
+	}
 
-				return
-			}
+	h.writeArchive(ctx, rw, moduleName, version, raw)
 
-			// Compute the digest of the archive.
-			hash := sha256.New()
-			_, _ = hash.Write(assetBytes)
-			digest = fmt.Sprintf("%x", hash.Sum(nil))
-		}
+	return true
+}
 
-		pluginHash, err := h.store.GetHashByName(ctxDownload, moduleName, version)
-		if err != nil && !errors.As(err, &db.NotFoundError{}) {
-			span.RecordError(err)
-			logger.Error().Err(err).Msg("Failed to get plugin hash")
-			JSONErrorf(rw, http.StatusInternalServerError, "Failed to get plugin %s@%s", moduleName, version)
+// verifySignature verifies the signature of a release asset when the plugin declares a signature policy.
+// It returns the signer, or the reason why the signature is rejected.
+func (h Handlers) verifySignature(ctx context.Context, plugin db.Plugin, asset archive.Asset, content []byte) (string, string, error) {
+	if plugin.Signature == nil || *plugin.Signature == (db.SignaturePolicy{}) {
+		return "", "", nil
+	}
 
-			return
+	for _, ext := range archive.SignatureExtensions(*plugin.Signature) {
+		sigAsset, ok := asset.Signatures[ext]
+		if !ok {
+			continue
 		}
 
-		// The plugin hash does not exist, we create it.
+		signature, err := h.readAsset(ctx, sigAsset)
 		if err != nil {
-			pluginHash, err = h.store.CreateHash(ctxDownload, moduleName, version, digest)
-			if err != nil {
-				span.RecordError(err)
-				logger.Error().Err(err).Msg("Error persisting plugin hash")
-				JSONErrorf(rw, http.StatusInternalServerError, "Failed to get plugin %s@%s", moduleName, version)
-
-				return
-			}
+			return "", "", fmt.Errorf("failed to download %s: %w", sigAsset.Name, err)
 		}
 
-		// We reject the request if the archive has been modified.
-		if pluginHash.Hash != digest || (pluginHash.Verified != nil && !*pluginHash.Verified) {
-			JSONErrorf(rw, http.StatusNotFound, "Plugin archive %s@%s has been modified or is not verified.", moduleName, version)
-
-			return
+		signer, err := h.verifier.Verify(content, signature, *plugin.Signature)
+		if err != nil {
+			return "", fmt.Sprintf("invalid signature: %s: %v", sigAsset.Name, err), nil
 		}
 
-		// The plugin hash exists, it is verified, and the digest matches.
-		// We can return the archive.
-		if pluginHash.Verified != nil && *pluginHash.Verified {
-			_, err = h.gh.Do(ctxDownload, request, rw)
-			if err != nil {
-				span.RecordError(err)
-				logger.Error().Err(err).Msg("Failed to write response body")
-				JSONErrorf(rw, http.StatusInternalServerError, "Failed to get plugin %s@%s", moduleName, version)
+		return signer, "", nil
+	}
 
-				return
-			}
+	return "", "missing signature: " + strings.Join(archive.SignatureExtensions(*plugin.Signature), ", "), nil
+}
 
-			return
-		}
+// signStatement adds the signature of the statement {module, version, sha256} to the response headers.
+func (h Handlers) signStatement(ctx context.Context, rw http.ResponseWriter, moduleName, version, sum string) {
+	if h.signer == nil {
+		return
+	}
 
-		if assetBytes == nil {
-			// If the plugin hash is not verified, we download the archive and verify it.
-			sources := bytes.NewBufferString("")
+	signature, err := h.signer.Sign(signing.Statement{Module: moduleName, Version: version, SHA256: sum})
+	if err != nil {
+		trace.SpanFromContext(ctx).RecordError(err)
+		log.Error().Err(err).Str("module_name", moduleName).Str("module_version", version).Msg("Failed to sign statement")
 
-			_, err = h.gh.Do(ctxDownload, request, sources)
-			if err != nil {
-				span.RecordError(err)
-				logger.Error().Err(err).Msg("Failed to get archive content")
-				JSONErrorf(rw, http.StatusInternalServerError, "Failed to get plugin %s@%s", moduleName, version)
+		return
+	}
 
-				return
-			}
+	rw.Header().Set(signatureHeader, signature)
+	rw.Header().Set(keyIDHeader, h.signer.KeyID())
+}
 
-			assetBytes, err = io.ReadAll(sources)
-			if err != nil {
-				span.RecordError(err)
-				logger.Error().Err(err).Msg("Failed to read response body")
-				JSONErrorf(rw, http.StatusInternalServerError, "Failed to get plugin %s@%s", moduleName, version)
+// rejectModified rejects the request of a modified archive: trust on first use serves an archive only if its digest is the hash recorded on its first download,
+// and a release asset only if its digest is the one published by GitHub.
+// The modification is recorded as an incident.
+func (h Handlers) rejectModified(ctx context.Context, rw http.ResponseWriter, moduleName, version, source, expected, received string) {
+	h.recordIncident(ctx, db.Incident{
+		Kind:     db.IncidentUpstream,
+		Module:   moduleName,
+		Version:  version,
+		Source:   source,
+		Expected: expected,
+		Received: received,
+	})
+
+	JSONErrorf(rw, http.StatusConflict, "Plugin archive %s@%s has been modified: expected hash %s, received %s.",
+		moduleName, version, expected, received)
+}
 
-				return
-			}
-		}
+func (h Handlers) writeArchive(ctx context.Context, rw http.ResponseWriter, moduleName, version string, raw []byte) {
+	h.signStatement(ctx, rw, moduleName, version, digest(raw))
 
-		verified := true
+	if _, err := rw.Write(raw); err != nil {
+		trace.SpanFromContext(ctx).RecordError(err)
+		log.Error().Err(err).Str("module_name", moduleName).Str("module_version", version).Msg("Failed to write response body")
+		JSONErrorf(rw, http.StatusInternalServerError, "Failed to get plugin %s@%s", moduleName, version)
+	}
+}
 
-		reader, err := zip.NewReader(bytes.NewReader(assetBytes), int64(len(assetBytes)))
-		if err != nil {
-			verified = false
+// appendLog appends the first seen hash of a plugin version to the transparency log,
+// or the verified hash of a plugin version recorded before the log, appending an already logged hash is a no-op.
+// The hash is already persisted, a failure doesn't prevent the download.
+func (h Handlers) appendLog(ctx context.Context, moduleName, version, sum string) {
+	if h.tlog == nil {
+		return
+	}
 
-			logger.Error().Err(err).Msg("Failed to unzip archive")
-		} else {
-			// Reject path traversal attacks
-			for _, file := range reader.File {
-				if strings.Contains(file.Name, "..") {
-					verified = false
+	ctx, span := h.tracer.Start(ctx, "handler_appendLog")
+	defer span.End()
 
-					logger.Error().Err(err).Msg("Invalid archive containing dotdot")
+	if _, err := h.tlog.Append(ctx, moduleName, version, sum); err != nil {
+		span.RecordError(err)
+		log.Error().Err(err).Str("module_name", moduleName).Str("module_version", version).Msg("Error appending plugin hash to the transparency log")
+	}
+}
 
-					break
-				}
-			}
-		}
+// appendResetLog appends the hash set by a reset of a plugin version to the transparency log.
+// The hash is already persisted, a failure doesn't prevent the reset.
+func (h Handlers) appendResetLog(ctx context.Context, moduleName, version, sum string) {
+	if h.tlog == nil {
+		return
+	}
 
-		_, err = h.store.UpdateHashVerified(ctxDownload, moduleName, version, digest, verified)
-		if err != nil {
-			span.RecordError(err)
-			logger.Error().Err(err).Msg("Error persisting plugin hash")
-			JSONErrorf(rw, http.StatusInternalServerError, "Failed to get plugin %s@%s", moduleName, version)
+	ctx, span := h.tracer.Start(ctx, "handler_appendResetLog")
+	defer span.End()
 
-			return
-		}
+	if _, err := h.tlog.AppendReset(ctx, moduleName, version, sum); err != nil {
+		span.RecordError(err)
+		log.Error().Err(err).Str("module_name", moduleName).Str("module_version", version).Msg("Error appending reset plugin hash to the transparency log")
+	}
+}
 
-		// We reject the request.
-		if !verified {
-			JSONErrorf(rw, http.StatusNotFound, "Plugin archive %s@%s is not verified.", moduleName, version)
+// recordDigests stores the digests of a plugin version archive.
+// The SHA-256 is already persisted, a failure doesn't prevent the download.
+func (h Handlers) recordDigests(ctx context.Context, moduleName, version string, raw []byte, moduleZip bool) {
+	ctx, span := h.tracer.Start(ctx, "handler_recordDigests")
+	defer span.End()
 
-			return
-		}
+	logger := log.With().Str("module_name", moduleName).Str("module_version", version).Logger()
 
-		_, err = rw.Write(assetBytes)
-		if err != nil {
-			span.RecordError(err)
-			logger.Error().Err(err).Msg("Failed to write response body")
-			JSONErrorf(rw, http.StatusInternalServerError, "Failed to get plugin %s@%s", moduleName, version)
+	digests, err := archive.Digests(raw, moduleZip)
+	if err != nil {
+		span.RecordError(err)
+		logger.Warn().Err(err).Msg("Unable to compute all the plugin digests")
+	}
 
-			return
-		}
+	if _, err = h.store.UpdateHashDigests(ctx, moduleName, version, digests); err != nil {
+		span.RecordError(err)
+		logger.Error().Err(err).Msg("Error persisting plugin digests")
 	}
 }
 
-func (h Handlers) getArchiveLinkRequest(ctx context.Context, moduleName, version string) (*http.Request, error) {
-	ctx, span := h.tracer.Start(ctx, "handler_getArchiveLinkRequest")
+// recordManifest stores the manifest of a plugin version.
+// A missing or invalid manifest doesn't prevent the download, it is reported by the diagnostics.
+func (h Handlers) recordManifest(ctx context.Context, moduleName, version string, content []byte) {
+	ctx, span := h.tracer.Start(ctx, "handler_recordManifest")
 	defer span.End()
 
-	opts := &github.RepositoryContentGetOptions{Ref: version}
-
-	owner, repoName := path.Split(strings.TrimPrefix(moduleName, "github.com/"))
-	owner = strings.TrimSuffix(owner, "/")
+	logger := log.With().Str("module_name", moduleName).Str("module_version", version).Logger()
 
-	link, _, err := h.gh.Repositories.GetArchiveLink(ctx, owner, repoName, github.Zipball, opts, 3)
+	manifest, err := archive.ReadManifest(content)
 	if err != nil {
 		span.RecordError(err)
+		logger.Warn().Err(err).Msg("Unable to read plugin manifest")
 
-		return nil, fmt.Errorf("failed to get archive link: %w", err)
+		return
 	}
 
-	return http.NewRequestWithContext(ctx, http.MethodGet, link.String(), http.NoBody)
+	if _, err = h.store.UpdateHashManifest(ctx, moduleName, version, manifest); err != nil {
+		span.RecordError(err)
+		logger.Error().Err(err).Msg("Error persisting plugin manifest")
+	}
 }
 
-func (h Handlers) getAssetLinkRequest(ctx context.Context, moduleName, version string) (*http.Request, string, error) {
-	ctx, span := h.tracer.Start(ctx, "handler_getAssetLinkRequest")
+// recordCapabilities stores the capability report of a Yaegi plugin version,
+// and keeps the useUnsafe flag of the plugin in sync with its latest version.
+func (h Handlers) recordCapabilities(ctx context.Context, moduleName, version string, content []byte) {
+	ctx, span := h.tracer.Start(ctx, "handler_recordCapabilities")
 	defer span.End()
 
-	owner, repoName := path.Split(strings.TrimPrefix(moduleName, "github.com/"))
-	owner = strings.TrimSuffix(owner, "/")
+	logger := log.With().Str("module_name", moduleName).Str("module_version", version).Logger()
 
-	release, _, err := h.gh.Repositories.GetReleaseByTag(ctx, owner, repoName, version)
+	report, err := archive.ScanSources(content)
 	if err != nil {
 		span.RecordError(err)
+		logger.Warn().Err(err).Msg("Unable to scan plugin sources")
 
-		return nil, "", fmt.Errorf("failed to get release: %w", err)
+		return
 	}
 
-	assets := map[*github.ReleaseAsset]struct{}{}
-	// Find the zip archive in the release assets.
-	for _, asset := range release.Assets {
-		if filepath.Ext(asset.GetName()) == ".zip" {
-			assets[asset] = struct{}{}
-		}
-	}
+	if _, err = h.store.UpdateHashCapabilities(ctx, moduleName, version, report); err != nil {
+		span.RecordError(err)
+		logger.Error().Err(err).Msg("Error persisting plugin capabilities")
 
-	if len(assets) > 1 {
-		return nil, "", fmt.Errorf("too many zip archive (%d)", len(assets))
+		return
 	}
 
-	for asset := range assets {
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, asset.GetURL(), http.NoBody)
-		if err != nil {
-			return nil, "", fmt.Errorf("failed to create request: %w", err)
-		}
+	plugin, err := h.store.GetByName(ctx, moduleName, false, false)
+	if err != nil {
+		span.RecordError(err)
+		logger.Error().Err(err).Msg("Failed to get plugin")
+
+		return
+	}
 
-		req.Header.Set("Accept", "application/octet-stream")
+	if plugin.LatestVersion != version || plugin.UseUnsafe == report.UseUnsafe() {
+		return
+	}
 
-		digest := asset.GetDigest()
-		digest = strings.ReplaceAll(digest, "sha256:", "")
+	if _, err = h.store.UpdateUseUnsafe(ctx, plugin.ID, report.UseUnsafe()); err != nil {
+		span.RecordError(err)
+		logger.Error().Err(err).Msg("Error persisting plugin useUnsafe flag")
+	}
+}
 
-		return req, digest, nil
+func (h Handlers) readAsset(ctx context.Context, asset archive.Asset) ([]byte, error) {
+	sources, err := h.fetcher.DownloadAsset(ctx, asset)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, "", errors.New("zip archive not found")
+	defer func() { _ = sources.Close() }()
+
+	return io.ReadAll(sources)
 }
 
 // Validate validates a plugin archive.
@@ -555,6 +471,14 @@ func (h Handlers) Validate(rw http.ResponseWriter, req *http.Request) {
 
 	headerSum := req.Header.Get(hashHeader)
 
+	digests, err := parseDigests(req.Header.Values(digestHeader))
+	if err != nil {
+		span.RecordError(err)
+		JSONErrorf(rw, http.StatusBadRequest, "Invalid %s header: %v", digestHeader, err)
+
+		return
+	}
+
 	ph, err := h.store.GetHashByName(ctx, moduleName, version)
 	if err != nil {
 		if errors.As(err, &db.NotFoundError{}) {
@@ -572,7 +496,13 @@ func (h Handlers) Validate(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	if ph.Hash == headerSum {
+	valid := ph.Hash == headerSum
+	if len(digests) > 0 {
+		valid = matchDigests(ph, digests)
+	}
+
+	if valid {
+		h.signStatement(ctx, rw, moduleName, version, ph.Hash)
 		rw.WriteHeader(http.StatusOK)
 
 		return
@@ -581,6 +511,50 @@ func (h Handlers) Validate(rw http.ResponseWriter, req *http.Request) {
 	rw.WriteHeader(http.StatusNotFound)
 }
 
+// parseDigests parses the typed digests of the X-Plugin-Digest headers, indexed by algorithm.
+// A header can hold several comma separated digests.
+func parseDigests(values []string) (map[string]string, error) {
+	digests := make(map[string]string)
+
+	for _, value := range values {
+		for _, typed := range strings.Split(value, ",") {
+			algorithm, digest, err := archive.ParseDigest(typed)
+			if err != nil {
+				return nil, err
+			}
+
+			digests[algorithm] = digest
+		}
+	}
+
+	return digests, nil
+}
+
+// matchDigests checks that all the given digests match the recorded ones, and that at least one of them has been recorded.
+func matchDigests(ph db.PluginHash, digests map[string]string) bool {
+	var matched bool
+
+	for algorithm, digest := range digests {
+		recorded, ok := ph.Digest(algorithm)
+		if !ok {
+			continue
+		}
+
+		if recorded != digest {
+			return false
+		}
+
+		matched = true
+	}
+
+	return matched
+}
+
+// digest returns the hex encoded SHA-256 of an archive.
+func digest(raw []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(raw))
+}
+
 func extractPluginInfo(endpoint *url.URL, sep string) (string, string) {
 	_, after, _ := strings.Cut(strings.TrimSuffix(endpoint.Path, "/"), sep)
 	moduleName, version := path.Split(after)
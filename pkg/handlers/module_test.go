@@ -1,11 +1,30 @@
 package handlers
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"slices"
 	"testing"
+	"time"
 
+	"github.com/google/go-github/v74/github"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/traefik/plugin-service/pkg/archive"
+	"github.com/traefik/plugin-service/pkg/db"
+	"github.com/traefik/plugin-service/pkg/githubapi"
+	"github.com/traefik/plugin-service/pkg/signing"
+	"github.com/traefik/plugin-service/pkg/upstream"
 )
 
 func Test_cleanModuleName(t *testing.T) {
@@ -102,3 +121,1182 @@ func Test_extractPluginInfo(t *testing.T) {
 		})
 	}
 }
+
+func TestHandlers_Download(t *testing.T) {
+	const (
+		moduleName = "github.com/traefik/plugindemo"
+		version    = "v0.2.1"
+		hashName   = moduleName + "@" + version
+	)
+
+	goMod := "module github.com/traefik/plugindemo\n\ngo 1.22\n"
+	goModWithRequires := goMod + "\nrequire github.com/foo/bar v1.0.0\n"
+
+	manifest := "displayName: Demo Plugin\ntype: middleware\nimport: github.com/traefik/plugindemo\n"
+	wasmManifest := manifest + "runtime: wasm\n"
+
+	sources := buildZip(t, map[string]string{
+		hashName + "/.traefik.yml": manifest,
+		hashName + "/demo.go":      "package plugindemo",
+	})
+	// The same module zip, as pinned from another upstream.
+	repacked := repackZip(t, sources)
+
+	sourcesH1, err := archive.ModuleHash(sources)
+	require.NoError(t, err)
+
+	zipball := buildZip(t, map[string]string{
+		"traefik-plugindemo-123/.traefik.yml":                     manifest,
+		"traefik-plugindemo-123/go.mod":                           goModWithRequires,
+		"traefik-plugindemo-123/demo.go":                          "package plugindemo",
+		"traefik-plugindemo-123/vendor/modules.txt":               "# github.com/foo/bar v1.0.0\n## explicit\ngithub.com/foo/bar\n",
+		"traefik-plugindemo-123/vendor/github.com/foo/bar/bar.go": "package bar",
+	})
+	unvendoredZipball := buildZip(t, map[string]string{
+		"traefik-plugindemo-123/.traefik.yml": manifest,
+		"traefik-plugindemo-123/go.mod":       goModWithRequires,
+		"traefik-plugindemo-123/demo.go":      "package plugindemo",
+	})
+	wasmArchive := buildZip(t, map[string]string{".traefik.yml": wasmManifest, "plugin.wasm": "\x00asm\x01\x00\x00\x00"})
+
+	yaegiManifest := &db.Manifest{DisplayName: "Demo Plugin", Type: "middleware", Import: moduleName}
+	wasmPluginManifest := &db.Manifest{DisplayName: "Demo Plugin", Type: "middleware", Import: moduleName, Runtime: "wasm"}
+	dotdotArchive := buildZip(t, map[string]string{"../plugin.wasm": "\x00asm"})
+	invalidArchive := []byte("not a zip")
+	unsafeSources := buildZip(t, map[string]string{
+		hashName + "/.traefik.yml": manifest,
+		hashName + "/demo.go":      "package plugindemo\n\nimport \"unsafe\"\n\nvar _ = unsafe.Sizeof(0)\n",
+	})
+	unsafeReport := &db.CapabilityReport{
+		Capabilities: []string{db.CapabilityUnsafe},
+		Findings:     []db.Finding{{Capability: db.CapabilityUnsafe, File: "demo.go", Line: 3, Detail: "import unsafe"}},
+	}
+
+	yaegiPlugin := &db.Plugin{Name: moduleName, Runtime: "yaegi"}
+	latestYaegiPlugin := &db.Plugin{ID: "123", Name: moduleName, Runtime: "yaegi", LatestVersion: version}
+	wasmPlugin := &db.Plugin{Name: moduleName, Runtime: "wasm"}
+
+	signingKey := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, ed25519.SeedSize))
+	signedWasmPlugin := &db.Plugin{Name: moduleName, Runtime: "wasm", Signature: &db.SignaturePolicy{PublicKey: minisignPublicKey(signingKey)}}
+
+	testCases := []struct {
+		desc              string
+		method            string
+		sum               string
+		plugin            *db.Plugin
+		pluginErr         error
+		hashErr           error
+		hashes            map[string]db.PluginHash
+		pinnedMeanwhile   *db.PluginHash
+		threshold         int
+		previousIncidents int64
+		fetcher           fakeFetcher
+		expectedStatus    int
+		expectedBody      []byte
+		expectedHashes    map[string]db.PluginHash
+		expectedUseUnsafe *bool
+		expectedIncidents []db.Incident
+		expectedDigests   map[string][]string
+	}{
+		{
+			desc:           "unsupported method",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			desc:           "unknown plugin",
+			pluginErr:      db.NotFoundError{},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			desc:           "failed to get plugin",
+			pluginErr:      errors.New("boom"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			desc:           "hash header matches",
+			sum:            sha256Sum(sources),
+			plugin:         yaegiPlugin,
+			hashes:         map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(sources)}},
+			expectedStatus: http.StatusNotModified,
+			expectedHashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(sources)}},
+		},
+		{
+			desc:           "failed to get hash for hash header",
+			sum:            sha256Sum(sources),
+			plugin:         yaegiPlugin,
+			hashErr:        errors.New("boom"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			desc:   "hash header mismatch",
+			sum:    "tampered",
+			plugin: yaegiPlugin,
+			hashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(sources), Verified: github.Ptr(true)}},
+			fetcher: fakeFetcher{
+				modFiles: map[string]string{hashName: goMod},
+				sources:  map[string][]byte{hashName: sources},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   sources,
+			expectedHashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(sources), Verified: github.Ptr(true)}},
+			expectedIncidents: []db.Incident{{
+				Kind:     db.IncidentClient,
+				Module:   moduleName,
+				Version:  version,
+				Expected: sha256Sum(sources),
+				Received: "tampered",
+				IP:       "192.0.2.1",
+			}},
+		},
+		{
+			desc:              "hash header mismatch not counting toward the incident threshold",
+			sum:               "tampered",
+			plugin:            yaegiPlugin,
+			threshold:         3,
+			previousIncidents: 2,
+			hashes:            map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(sources), Verified: github.Ptr(true)}},
+			fetcher: fakeFetcher{
+				modFiles: map[string]string{hashName: goMod},
+				sources:  map[string][]byte{hashName: sources},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   sources,
+			expectedHashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(sources), Verified: github.Ptr(true)}},
+			expectedIncidents: []db.Incident{{
+				Kind:     db.IncidentClient,
+				Module:   moduleName,
+				Version:  version,
+				Expected: sha256Sum(sources),
+				Received: "tampered",
+				IP:       "192.0.2.1",
+			}},
+		},
+		{
+			desc:           "yaegi: failed to get module file",
+			plugin:         yaegiPlugin,
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			desc:   "yaegi: go proxy, first download",
+			plugin: yaegiPlugin,
+			fetcher: fakeFetcher{
+				modFiles: map[string]string{hashName: goMod},
+				sources:  map[string][]byte{hashName: sources},
+			},
+			expectedStatus:  http.StatusOK,
+			expectedBody:    sources,
+			expectedHashes:  map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(sources), Verified: github.Ptr(true), Manifest: yaegiManifest, Capabilities: &db.CapabilityReport{}}},
+			expectedDigests: map[string][]string{hashName: {db.DigestH1, db.DigestSHA256, db.DigestSHA512}},
+		},
+		{
+			desc:   "yaegi: go proxy, latest version using unsafe",
+			plugin: latestYaegiPlugin,
+			fetcher: fakeFetcher{
+				modFiles: map[string]string{hashName: goMod},
+				sources:  map[string][]byte{hashName: unsafeSources},
+			},
+			expectedStatus:    http.StatusOK,
+			expectedBody:      unsafeSources,
+			expectedHashes:    map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(unsafeSources), Verified: github.Ptr(true), Manifest: yaegiManifest, Capabilities: unsafeReport}},
+			expectedUseUnsafe: github.Ptr(true),
+			expectedDigests:   map[string][]string{hashName: {db.DigestH1, db.DigestSHA256, db.DigestSHA512}},
+		},
+		{
+			desc:   "yaegi: go proxy, known hash",
+			plugin: yaegiPlugin,
+			hashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(sources), Verified: github.Ptr(true)}},
+			fetcher: fakeFetcher{
+				modFiles: map[string]string{hashName: goMod},
+				sources:  map[string][]byte{hashName: sources},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   sources,
+			expectedHashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(sources), Verified: github.Ptr(true)}},
+		},
+		{
+			desc:   "yaegi: go proxy, quarantined hash",
+			plugin: yaegiPlugin,
+			hashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(sources), Quarantined: true}},
+			fetcher: fakeFetcher{
+				modFiles: map[string]string{hashName: goMod},
+				sources:  map[string][]byte{hashName: sources},
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedHashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(sources), Quarantined: true}},
+		},
+		{
+			desc:   "yaegi: go proxy, hash mismatch",
+			plugin: yaegiPlugin,
+			hashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: "original"}},
+			fetcher: fakeFetcher{
+				modFiles: map[string]string{hashName: goMod},
+				sources:  map[string][]byte{hashName: sources},
+			},
+			expectedStatus: http.StatusConflict,
+			expectedHashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: "original"}},
+			expectedIncidents: []db.Incident{{
+				Kind:     db.IncidentUpstream,
+				Module:   moduleName,
+				Version:  version,
+				Source:   "goproxy",
+				Expected: "original",
+				Received: sha256Sum(sources),
+			}},
+		},
+		{
+			desc:   "yaegi: go proxy, recorded module zip from another upstream",
+			plugin: yaegiPlugin,
+			hashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(repacked), Verified: github.Ptr(true), Digests: map[string]string{db.DigestH1: sourcesH1}}},
+			fetcher: fakeFetcher{
+				modFiles: map[string]string{hashName: goMod},
+				sources:  map[string][]byte{hashName: sources},
+				mirrors:  map[string][]byte{hashName: repacked},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   repacked,
+			expectedHashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(repacked), Verified: github.Ptr(true), Digests: map[string]string{db.DigestH1: sourcesH1}}},
+		},
+		{
+			desc:   "yaegi: go proxy, recorded contents without the recorded module zip",
+			plugin: yaegiPlugin,
+			hashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(repacked), Verified: github.Ptr(true), Digests: map[string]string{db.DigestH1: sourcesH1}}},
+			fetcher: fakeFetcher{
+				modFiles: map[string]string{hashName: goMod},
+				sources:  map[string][]byte{hashName: sources},
+			},
+			// The module zip has not been modified, but it can't be served in place of the recorded one.
+			expectedStatus: http.StatusConflict,
+			expectedHashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(repacked), Verified: github.Ptr(true), Digests: map[string]string{db.DigestH1: sourcesH1}}},
+		},
+		{
+			desc:            "yaegi: go proxy, hash pinned meanwhile",
+			plugin:          yaegiPlugin,
+			pinnedMeanwhile: &db.PluginHash{Name: hashName, Hash: sha256Sum(sources), Verified: github.Ptr(true)},
+			fetcher: fakeFetcher{
+				modFiles: map[string]string{hashName: goMod},
+				sources:  map[string][]byte{hashName: sources},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   sources,
+			expectedHashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(sources), Verified: github.Ptr(true)}},
+		},
+		{
+			desc:            "yaegi: go proxy, other hash pinned meanwhile",
+			plugin:          yaegiPlugin,
+			pinnedMeanwhile: &db.PluginHash{Name: hashName, Hash: "original", Verified: github.Ptr(true)},
+			fetcher: fakeFetcher{
+				modFiles: map[string]string{hashName: goMod},
+				sources:  map[string][]byte{hashName: sources},
+			},
+			expectedStatus: http.StatusConflict,
+			expectedHashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: "original", Verified: github.Ptr(true)}},
+			expectedIncidents: []db.Incident{{
+				Kind:     db.IncidentUpstream,
+				Module:   moduleName,
+				Version:  version,
+				Source:   "goproxy",
+				Expected: "original",
+				Received: sha256Sum(sources),
+			}},
+		},
+		{
+			desc:              "yaegi: go proxy, hash mismatch reaching the incident threshold",
+			plugin:            yaegiPlugin,
+			threshold:         3,
+			previousIncidents: 2,
+			hashes:            map[string]db.PluginHash{hashName: {Name: hashName, Hash: "original"}},
+			fetcher: fakeFetcher{
+				modFiles: map[string]string{hashName: goMod},
+				sources:  map[string][]byte{hashName: sources},
+			},
+			expectedStatus: http.StatusConflict,
+			expectedHashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: "original", Quarantined: true}},
+			expectedIncidents: []db.Incident{{
+				Kind:     db.IncidentUpstream,
+				Module:   moduleName,
+				Version:  version,
+				Source:   "goproxy",
+				Expected: "original",
+				Received: sha256Sum(sources),
+			}},
+		},
+		{
+			desc:              "yaegi: go proxy, hash mismatch below the incident threshold",
+			plugin:            yaegiPlugin,
+			threshold:         3,
+			previousIncidents: 1,
+			hashes:            map[string]db.PluginHash{hashName: {Name: hashName, Hash: "original"}},
+			fetcher: fakeFetcher{
+				modFiles: map[string]string{hashName: goMod},
+				sources:  map[string][]byte{hashName: sources},
+			},
+			expectedStatus: http.StatusConflict,
+			expectedHashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: "original"}},
+			expectedIncidents: []db.Incident{{
+				Kind:     db.IncidentUpstream,
+				Module:   moduleName,
+				Version:  version,
+				Source:   "goproxy",
+				Expected: "original",
+				Received: sha256Sum(sources),
+			}},
+		},
+		{
+			desc:   "yaegi: go proxy, failed to get hash",
+			plugin: yaegiPlugin,
+			fetcher: fakeFetcher{
+				modFiles: map[string]string{hashName: goMod},
+				sources:  map[string][]byte{hashName: sources},
+			},
+			hashErr:        errors.New("boom"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			desc:   "yaegi: go proxy, failed to download sources",
+			plugin: yaegiPlugin,
+			fetcher: fakeFetcher{
+				modFiles: map[string]string{hashName: goMod},
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			desc:   "yaegi: requires without GitHub uses go proxy",
+			plugin: yaegiPlugin,
+			fetcher: fakeFetcher{
+				noGitHub: true,
+				modFiles: map[string]string{hashName: goModWithRequires},
+				sources:  map[string][]byte{hashName: sources},
+			},
+			expectedStatus:  http.StatusOK,
+			expectedBody:    sources,
+			expectedHashes:  map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(sources), Verified: github.Ptr(true), Manifest: yaegiManifest, Capabilities: &db.CapabilityReport{}}},
+			expectedDigests: map[string][]string{hashName: {db.DigestH1, db.DigestSHA256, db.DigestSHA512}},
+		},
+		{
+			desc:   "yaegi: GitHub zipball, first download",
+			plugin: yaegiPlugin,
+			fetcher: fakeFetcher{
+				modFiles: map[string]string{hashName: goModWithRequires},
+				zipballs: map[string][]byte{hashName: zipball},
+			},
+			expectedStatus:  http.StatusOK,
+			expectedBody:    zipball,
+			expectedHashes:  map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(zipball), Verified: github.Ptr(true), Manifest: yaegiManifest, Capabilities: &db.CapabilityReport{}}},
+			expectedDigests: map[string][]string{hashName: {db.DigestSHA256, db.DigestSHA512}},
+		},
+		{
+			desc:   "yaegi: GitHub zipball, first download without vendor",
+			plugin: yaegiPlugin,
+			fetcher: fakeFetcher{
+				modFiles: map[string]string{hashName: goModWithRequires},
+				zipballs: map[string][]byte{hashName: unvendoredZipball},
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedHashes: map[string]db.PluginHash{hashName: {
+				Name:     hashName,
+				Hash:     sha256Sum(unvendoredZipball),
+				Verified: github.Ptr(false),
+				Reasons:  []string{"missing vendor/modules.txt"},
+			}},
+			expectedDigests: map[string][]string{hashName: {db.DigestSHA256, db.DigestSHA512}},
+		},
+		{
+			desc:   "yaegi: GitHub zipball, unverified hash",
+			plugin: yaegiPlugin,
+			hashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(unvendoredZipball), Verified: github.Ptr(false)}},
+			fetcher: fakeFetcher{
+				modFiles: map[string]string{hashName: goModWithRequires},
+				zipballs: map[string][]byte{hashName: unvendoredZipball},
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedHashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(unvendoredZipball), Verified: github.Ptr(false)}},
+		},
+		{
+			desc:   "yaegi: GitHub zipball, known hash",
+			plugin: yaegiPlugin,
+			hashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(zipball), Verified: github.Ptr(true)}},
+			fetcher: fakeFetcher{
+				modFiles: map[string]string{hashName: goModWithRequires},
+				zipballs: map[string][]byte{hashName: zipball},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   zipball,
+			expectedHashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(zipball), Verified: github.Ptr(true)}},
+		},
+		{
+			desc:   "yaegi: GitHub zipball, hash recorded without verification",
+			plugin: yaegiPlugin,
+			hashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(zipball)}},
+			fetcher: fakeFetcher{
+				modFiles: map[string]string{hashName: goModWithRequires},
+				zipballs: map[string][]byte{hashName: zipball},
+			},
+			expectedStatus:  http.StatusOK,
+			expectedBody:    zipball,
+			expectedHashes:  map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(zipball), Verified: github.Ptr(true), Manifest: yaegiManifest, Capabilities: &db.CapabilityReport{}}},
+			expectedDigests: map[string][]string{hashName: {db.DigestSHA256, db.DigestSHA512}},
+		},
+		{
+			desc:   "yaegi: GitHub zipball, quarantined hash",
+			plugin: yaegiPlugin,
+			hashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(zipball), Verified: github.Ptr(true), Quarantined: true}},
+			fetcher: fakeFetcher{
+				modFiles: map[string]string{hashName: goModWithRequires},
+				zipballs: map[string][]byte{hashName: zipball},
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedHashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(zipball), Verified: github.Ptr(true), Quarantined: true}},
+		},
+		{
+			desc:   "yaegi: GitHub zipball, hash mismatch",
+			plugin: yaegiPlugin,
+			hashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: "original", Verified: github.Ptr(true)}},
+			fetcher: fakeFetcher{
+				modFiles: map[string]string{hashName: goModWithRequires},
+				zipballs: map[string][]byte{hashName: zipball},
+			},
+			expectedStatus: http.StatusConflict,
+			expectedHashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: "original", Verified: github.Ptr(true)}},
+			expectedIncidents: []db.Incident{{
+				Kind:     db.IncidentUpstream,
+				Module:   moduleName,
+				Version:  version,
+				Source:   "github",
+				Expected: "original",
+				Received: sha256Sum(zipball),
+			}},
+		},
+		{
+			desc:   "yaegi: GitHub zipball, failed to download",
+			plugin: yaegiPlugin,
+			fetcher: fakeFetcher{
+				modFiles: map[string]string{hashName: goModWithRequires},
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			desc:   "wasm: missing GitHub client",
+			plugin: wasmPlugin,
+			fetcher: fakeFetcher{
+				noGitHub: true,
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			desc:           "wasm: failed to get release asset",
+			plugin:         wasmPlugin,
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			desc:   "wasm: first download without digest",
+			plugin: wasmPlugin,
+			fetcher: fakeFetcher{
+				assets: map[string]fakeAsset{hashName: {content: wasmArchive}},
+			},
+			expectedStatus:  http.StatusOK,
+			expectedBody:    wasmArchive,
+			expectedHashes:  map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(wasmArchive), Verified: github.Ptr(true), Manifest: wasmPluginManifest}},
+			expectedDigests: map[string][]string{hashName: {db.DigestSHA256, db.DigestSHA512}},
+		},
+		{
+			desc:   "wasm: first download with digest",
+			plugin: wasmPlugin,
+			fetcher: fakeFetcher{
+				assets: map[string]fakeAsset{hashName: {digest: sha256Sum(wasmArchive), content: wasmArchive}},
+			},
+			expectedStatus:  http.StatusOK,
+			expectedBody:    wasmArchive,
+			expectedHashes:  map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(wasmArchive), Verified: github.Ptr(true), Manifest: wasmPluginManifest}},
+			expectedDigests: map[string][]string{hashName: {db.DigestSHA256, db.DigestSHA512}},
+		},
+		{
+			desc:   "wasm: verified hash",
+			plugin: wasmPlugin,
+			hashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(wasmArchive), Verified: github.Ptr(true)}},
+			fetcher: fakeFetcher{
+				assets: map[string]fakeAsset{hashName: {digest: sha256Sum(wasmArchive), content: wasmArchive}},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   wasmArchive,
+			expectedHashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(wasmArchive), Verified: github.Ptr(true)}},
+		},
+		{
+			desc:   "wasm: failed to get hash",
+			plugin: wasmPlugin,
+			fetcher: fakeFetcher{
+				assets: map[string]fakeAsset{hashName: {digest: sha256Sum(wasmArchive), content: wasmArchive}},
+			},
+			hashErr:        errors.New("boom"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			desc:   "wasm: hash mismatch",
+			plugin: wasmPlugin,
+			hashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: "original", Verified: github.Ptr(true)}},
+			fetcher: fakeFetcher{
+				assets: map[string]fakeAsset{hashName: {content: wasmArchive}},
+			},
+			expectedStatus: http.StatusConflict,
+			expectedHashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: "original", Verified: github.Ptr(true)}},
+			expectedIncidents: []db.Incident{{
+				Kind:     db.IncidentUpstream,
+				Module:   moduleName,
+				Version:  version,
+				Source:   "asset",
+				Expected: "original",
+				Received: sha256Sum(wasmArchive),
+			}},
+		},
+		{
+			desc:   "wasm: content differs from the published digest",
+			plugin: wasmPlugin,
+			hashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(wasmArchive), Verified: github.Ptr(true)}},
+			fetcher: fakeFetcher{
+				assets: map[string]fakeAsset{hashName: {digest: sha256Sum(wasmArchive), content: dotdotArchive}},
+			},
+			expectedStatus: http.StatusConflict,
+			expectedHashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(wasmArchive), Verified: github.Ptr(true)}},
+			expectedIncidents: []db.Incident{{
+				Kind:     db.IncidentUpstream,
+				Module:   moduleName,
+				Version:  version,
+				Source:   "asset",
+				Expected: sha256Sum(wasmArchive),
+				Received: sha256Sum(dotdotArchive),
+			}},
+		},
+		{
+			desc:   "wasm: quarantined hash",
+			plugin: wasmPlugin,
+			hashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(wasmArchive), Verified: github.Ptr(true), Quarantined: true}},
+			fetcher: fakeFetcher{
+				assets: map[string]fakeAsset{hashName: {digest: sha256Sum(wasmArchive), content: wasmArchive}},
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedHashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(wasmArchive), Verified: github.Ptr(true), Quarantined: true}},
+		},
+		{
+			desc:   "wasm: unverified hash",
+			plugin: wasmPlugin,
+			hashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(wasmArchive), Verified: github.Ptr(false)}},
+			fetcher: fakeFetcher{
+				assets: map[string]fakeAsset{hashName: {digest: sha256Sum(wasmArchive), content: wasmArchive}},
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedHashes: map[string]db.PluginHash{hashName: {Name: hashName, Hash: sha256Sum(wasmArchive), Verified: github.Ptr(false)}},
+		},
+		{
+			desc:   "wasm: signed archive",
+			plugin: signedWasmPlugin,
+			fetcher: fakeFetcher{
+				assets: map[string]fakeAsset{hashName: {content: wasmArchive, signatures: map[string][]byte{".minisig": minisign(signingKey, wasmArchive)}}},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   wasmArchive,
+			expectedHashes: map[string]db.PluginHash{hashName: {
+				Name:     hashName,
+				Hash:     sha256Sum(wasmArchive),
+				Verified: github.Ptr(true),
+				Manifest: wasmPluginManifest,
+				Signer:   "minisign:0807060504030201",
+			}},
+			expectedDigests: map[string][]string{hashName: {db.DigestSHA256, db.DigestSHA512}},
+		},
+		{
+			desc:   "wasm: invalid signature",
+			plugin: signedWasmPlugin,
+			fetcher: fakeFetcher{
+				assets: map[string]fakeAsset{hashName: {content: wasmArchive, signatures: map[string][]byte{".minisig": minisign(signingKey, dotdotArchive)}}},
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedHashes: map[string]db.PluginHash{hashName: {
+				Name:     hashName,
+				Hash:     sha256Sum(wasmArchive),
+				Verified: github.Ptr(false),
+				Reasons:  []string{"invalid signature: plugin.zip.minisig: invalid signature"},
+			}},
+			expectedDigests: map[string][]string{hashName: {db.DigestSHA256, db.DigestSHA512}},
+		},
+		{
+			desc:   "wasm: missing signature",
+			plugin: signedWasmPlugin,
+			fetcher: fakeFetcher{
+				assets: map[string]fakeAsset{hashName: {content: wasmArchive}},
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedHashes: map[string]db.PluginHash{hashName: {
+				Name:     hashName,
+				Hash:     sha256Sum(wasmArchive),
+				Verified: github.Ptr(false),
+				Reasons:  []string{"missing signature: .minisig, .sig"},
+			}},
+			expectedDigests: map[string][]string{hashName: {db.DigestSHA256, db.DigestSHA512}},
+		},
+		{
+			desc:   "wasm: archive containing dotdot",
+			plugin: wasmPlugin,
+			fetcher: fakeFetcher{
+				assets: map[string]fakeAsset{hashName: {content: dotdotArchive}},
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedHashes: map[string]db.PluginHash{hashName: {
+				Name:     hashName,
+				Hash:     sha256Sum(dotdotArchive),
+				Verified: github.Ptr(false),
+				Reasons: []string{
+					"path traversal: ../plugin.wasm",
+					"missing manifest: .traefik.yml",
+					"missing WASM file: plugin.wasm",
+				},
+			}},
+			expectedDigests: map[string][]string{hashName: {db.DigestSHA256, db.DigestSHA512}},
+		},
+		{
+			desc:   "wasm: invalid archive",
+			plugin: wasmPlugin,
+			fetcher: fakeFetcher{
+				assets: map[string]fakeAsset{hashName: {content: invalidArchive}},
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedHashes: map[string]db.PluginHash{hashName: {
+				Name:     hashName,
+				Hash:     sha256Sum(invalidArchive),
+				Verified: github.Ptr(false),
+				Reasons:  []string{"invalid zip archive: zip: not a valid zip file"},
+			}},
+			expectedDigests: map[string][]string{hashName: {db.DigestSHA256, db.DigestSHA512}},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			hashes := make(map[string]db.PluginHash)
+			for k, v := range test.hashes {
+				hashes[k] = v
+			}
+
+			var (
+				useUnsafe *bool
+				incidents []db.Incident
+				digests   map[string][]string
+			)
+
+			testDB := mockDB{
+				getByNameFn: func(_ context.Context, _ string, _ bool) (db.Plugin, error) {
+					if test.pluginErr != nil {
+						return db.Plugin{}, test.pluginErr
+					}
+
+					return *test.plugin, nil
+				},
+				updateHashManifestFn: func(_ context.Context, module, version string, manifest db.Manifest) (db.PluginHash, error) {
+					ph, ok := hashes[module+"@"+version]
+					if !ok {
+						return db.PluginHash{}, db.NotFoundError{}
+					}
+
+					ph.Manifest = &manifest
+					hashes[ph.Name] = ph
+
+					return ph, nil
+				},
+				updateHashCapabilitiesFn: func(_ context.Context, module, version string, report db.CapabilityReport) (db.PluginHash, error) {
+					ph, ok := hashes[module+"@"+version]
+					if !ok {
+						return db.PluginHash{}, db.NotFoundError{}
+					}
+
+					ph.Capabilities = &report
+					hashes[ph.Name] = ph
+
+					return ph, nil
+				},
+				updateUseUnsafeFn: func(_ context.Context, _ string, value bool) (db.Plugin, error) {
+					useUnsafe = &value
+
+					return db.Plugin{}, nil
+				},
+				getHashByNameFn: func(_ context.Context, module, version string) (db.PluginHash, error) {
+					if test.hashErr != nil {
+						return db.PluginHash{}, test.hashErr
+					}
+
+					ph, ok := hashes[module+"@"+version]
+					if !ok {
+						return db.PluginHash{}, db.NotFoundError{}
+					}
+
+					return ph, nil
+				},
+				createHashFn: func(_ context.Context, module, version, hash string) (db.PluginHash, error) {
+					if test.pinnedMeanwhile != nil {
+						hashes[test.pinnedMeanwhile.Name] = *test.pinnedMeanwhile
+
+						return db.PluginHash{}, db.ConflictError{}
+					}
+
+					ph := db.PluginHash{Name: module + "@" + version, Hash: hash}
+					hashes[ph.Name] = ph
+
+					return ph, nil
+				},
+				updateHashVerifiedFn: func(_ context.Context, module, version, hash string, verified bool, reasons []string) (db.PluginHash, error) {
+					ph, ok := hashes[module+"@"+version]
+					if !ok || ph.Hash != hash {
+						return db.PluginHash{}, db.NotFoundError{}
+					}
+
+					ph.Verified = &verified
+					ph.Reasons = reasons
+					hashes[ph.Name] = ph
+
+					return ph, nil
+				},
+				createIncidentFn: func(_ context.Context, incident db.Incident) (db.Incident, error) {
+					incidents = append(incidents, incident)
+
+					return incident, nil
+				},
+				countIncidentsFn: func(_ context.Context, _, _, kind string, _ time.Time) (int64, error) {
+					count := test.previousIncidents
+
+					for _, incident := range incidents {
+						if incident.Kind == kind {
+							count++
+						}
+					}
+
+					return count, nil
+				},
+				updateHashSignerFn: func(_ context.Context, module, version, signer string) (db.PluginHash, error) {
+					ph, ok := hashes[module+"@"+version]
+					if !ok {
+						return db.PluginHash{}, db.NotFoundError{}
+					}
+
+					ph.Signer = signer
+					hashes[ph.Name] = ph
+
+					return ph, nil
+				},
+				updateHashDigestsFn: func(_ context.Context, module, version string, values map[string]string) (db.PluginHash, error) {
+					ph, ok := hashes[module+"@"+version]
+					if !ok {
+						return db.PluginHash{}, db.NotFoundError{}
+					}
+
+					if values[db.DigestSHA256] != ph.Hash {
+						return db.PluginHash{}, fmt.Errorf("sha256 digest %s doesn't match hash %s", values[db.DigestSHA256], ph.Hash)
+					}
+
+					if digests == nil {
+						digests = make(map[string][]string)
+					}
+
+					digests[ph.Name] = slices.Sorted(maps.Keys(values))
+
+					return ph, nil
+				},
+				updateHashQuarantinedFn: func(_ context.Context, module, version string, quarantined bool) (db.PluginHash, error) {
+					ph, ok := hashes[module+"@"+version]
+					if !ok {
+						return db.PluginHash{}, db.NotFoundError{}
+					}
+
+					ph.Quarantined = quarantined
+					hashes[ph.Name] = ph
+
+					return ph, nil
+				},
+			}
+
+			method := test.method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			req := httptest.NewRequest(method, "/download/"+moduleName+"/"+version, http.NoBody)
+			if test.sum != "" {
+				req.Header.Set(hashHeader, test.sum)
+			}
+
+			rw := httptest.NewRecorder()
+
+			New(testDB, test.fetcher, WithIncidentThreshold(test.threshold)).Download(rw, req)
+
+			assert.Equal(t, test.expectedStatus, rw.Code)
+
+			if test.expectedBody != nil {
+				assert.Equal(t, test.expectedBody, rw.Body.Bytes())
+			}
+
+			if test.expectedHashes == nil {
+				test.expectedHashes = map[string]db.PluginHash{}
+			}
+
+			assert.Equal(t, test.expectedHashes, hashes)
+			assert.Equal(t, test.expectedUseUnsafe, useUnsafe)
+			assert.Equal(t, test.expectedIncidents, incidents)
+			assert.Equal(t, test.expectedDigests, digests)
+		})
+	}
+}
+
+func TestHandlers_Download_rateLimited(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		runtime            string
+		modFile            string
+		err                error
+		goProxyErr         error
+		expectedStatus     int
+		expectedRetryAfter string
+	}{
+		{
+			desc:    "wasm: GitHub rate limit",
+			runtime: "wasm",
+			err: &github.RateLimitError{
+				Rate:     github.Rate{Reset: github.Timestamp{Time: time.Now().Add(time.Minute)}},
+				Response: &http.Response{Request: httptest.NewRequest(http.MethodGet, "/repos/traefik/plugindemo/releases/tags/v0.2.1", http.NoBody)},
+			},
+			expectedStatus:     http.StatusServiceUnavailable,
+			expectedRetryAfter: "60",
+		},
+		{
+			desc:               "yaegi: credentials reserve reached",
+			runtime:            "yaegi",
+			modFile:            "module github.com/traefik/plugindemo\n\nrequire github.com/foo/bar v1.0.0\n",
+			err:                fmt.Errorf("failed to get archive link: %w", &githubapi.RateLimitedError{Resource: githubapi.ResourceCore, Reset: time.Now().Add(-time.Second)}),
+			expectedStatus:     http.StatusServiceUnavailable,
+			expectedRetryAfter: "1",
+		},
+		{
+			desc:               "yaegi: Go proxy circuit breaker open",
+			runtime:            "yaegi",
+			goProxyErr:         fmt.Errorf("failed to get module file: %w", &upstream.OpenError{Upstream: "goproxy", RetryAt: time.Now().Add(30 * time.Second)}),
+			expectedStatus:     http.StatusServiceUnavailable,
+			expectedRetryAfter: "30",
+		},
+		{
+			desc:           "wasm: other error",
+			runtime:        "wasm",
+			err:            errors.New("boom"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			testDB := mockDB{
+				getByNameFn: func(_ context.Context, name string, _ bool) (db.Plugin, error) {
+					return db.Plugin{Name: name, Runtime: test.runtime}, nil
+				},
+				getHashByNameFn: func(_ context.Context, _, _ string) (db.PluginHash, error) {
+					return db.PluginHash{}, db.NotFoundError{}
+				},
+			}
+
+			fetcher := fakeFetcher{
+				modFiles:   map[string]string{"github.com/traefik/plugindemo@v0.2.1": test.modFile},
+				gitHubErr:  test.err,
+				goProxyErr: test.goProxyErr,
+			}
+
+			rw := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/download/github.com/traefik/plugindemo/v0.2.1", http.NoBody)
+
+			New(testDB, fetcher).Download(rw, req)
+
+			assert.Equal(t, test.expectedStatus, rw.Code)
+			assert.Equal(t, test.expectedRetryAfter, rw.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func TestHandlers_Download_cachedArchive(t *testing.T) {
+	const (
+		moduleName = "github.com/traefik/plugindemo"
+		version    = "v0.2.1"
+	)
+
+	sources := buildZip(t, map[string]string{moduleName + "@" + version + "/demo.go": "package plugindemo"})
+
+	verified := true
+	pluginHash := db.PluginHash{Name: moduleName + "@" + version, Hash: sha256Sum(sources), Verified: &verified}
+
+	testDB := mockDB{
+		getByNameFn: func(_ context.Context, name string, _ bool) (db.Plugin, error) {
+			return db.Plugin{Name: name, Runtime: "yaegi"}, nil
+		},
+		getHashByNameFn: func(_ context.Context, _, _ string) (db.PluginHash, error) {
+			return pluginHash, nil
+		},
+	}
+
+	handler := New(testDB, fakeFetcher{
+		modFiles: map[string]string{moduleName + "@" + version: "module github.com/traefik/plugindemo\n"},
+		sources:  map[string][]byte{moduleName + "@" + version: sources},
+	}, WithArchiveCache(1<<20))
+
+	download := func() *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		handler.Download(rw, httptest.NewRequest(http.MethodGet, "/download/"+moduleName+"/"+version, http.NoBody))
+
+		return rw
+	}
+
+	rw := download()
+	require.Equal(t, http.StatusOK, rw.Code)
+
+	// The archive is served from the cache while the circuit breaker of the Go proxy is open.
+	handler.fetcher = fakeFetcher{
+		goProxyErr: fmt.Errorf("failed to get module file: %w", &upstream.OpenError{Upstream: "goproxy", RetryAt: time.Now().Add(30 * time.Second)}),
+	}
+
+	rw = download()
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, sources, rw.Body.Bytes())
+
+	// A quarantined version is not served from the cache.
+	pluginHash.Quarantined = true
+
+	rw = download()
+	assert.Equal(t, http.StatusServiceUnavailable, rw.Code)
+	assert.Equal(t, "30", rw.Header().Get("Retry-After"))
+}
+
+func TestHandlers_Download_otherUpstream(t *testing.T) {
+	const (
+		moduleName = "github.com/traefik/plugindemo"
+		version    = "v0.2.1"
+	)
+
+	sources := buildZip(t, map[string]string{moduleName + "@" + version + "/demo.go": "package plugindemo"})
+	// The module zip pinned from the second upstream, with the same contents.
+	pinned := repackZip(t, sources)
+
+	h1, err := archive.ModuleHash(sources)
+	require.NoError(t, err)
+
+	pluginHash := db.PluginHash{
+		Name:     moduleName + "@" + version,
+		Hash:     sha256Sum(pinned),
+		Verified: github.Ptr(true),
+		Digests:  map[string]string{db.DigestSHA256: sha256Sum(pinned), db.DigestH1: h1},
+	}
+
+	testDB := mockDB{
+		getByNameFn: func(_ context.Context, name string, _ bool) (db.Plugin, error) {
+			return db.Plugin{Name: name, Runtime: "yaegi"}, nil
+		},
+		getHashByNameFn: func(_ context.Context, _, _ string) (db.PluginHash, error) {
+			return pluginHash, nil
+		},
+	}
+
+	signer := signing.NewSigner(ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, ed25519.SeedSize)))
+
+	handler := New(testDB, fakeFetcher{
+		modFiles: map[string]string{moduleName + "@" + version: "module github.com/traefik/plugindemo\n"},
+		sources:  map[string][]byte{moduleName + "@" + version: sources},
+		mirrors:  map[string][]byte{moduleName + "@" + version: pinned},
+	}, WithStatementSigner(signer))
+
+	rw := httptest.NewRecorder()
+	handler.Download(rw, httptest.NewRequest(http.MethodGet, "/download/"+moduleName+"/"+version, http.NoBody))
+
+	require.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, pinned, rw.Body.Bytes())
+
+	statement := rw.Header().Get(signatureHeader)
+	assert.NotEmpty(t, statement)
+
+	// The served archive is the pinned one: the client validates its hash, and gets the same statement.
+	req := httptest.NewRequest(http.MethodGet, "/validate/"+moduleName+"/"+version, http.NoBody)
+	req.Header.Set(hashHeader, sha256Sum(rw.Body.Bytes()))
+
+	rw = httptest.NewRecorder()
+	handler.Validate(rw, req)
+
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, statement, rw.Header().Get(signatureHeader))
+}
+
+func TestHandlers_Validate(t *testing.T) {
+	const (
+		moduleName = "github.com/traefik/plugindemo"
+		version    = "v0.2.1"
+	)
+
+	signer := signing.NewSigner(ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, ed25519.SeedSize)))
+
+	pluginHash := db.PluginHash{
+		Name:    moduleName + "@" + version,
+		Hash:    "123",
+		Digests: map[string]string{db.DigestSHA256: "123", db.DigestH1: "AbC=", db.DigestSHA512: "456"},
+	}
+
+	testCases := []struct {
+		desc           string
+		sum            string
+		digests        []string
+		hash           db.PluginHash
+		hashErr        error
+		expectedStatus int
+	}{
+		{
+			desc:           "valid hash",
+			sum:            "123",
+			hash:           pluginHash,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "invalid hash",
+			sum:            "456",
+			hash:           pluginHash,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			desc:           "unknown hash",
+			sum:            "123",
+			hashErr:        db.NotFoundError{},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			desc:           "failed to get hash",
+			sum:            "123",
+			hashErr:        errors.New("boom"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			desc:           "valid h1 digest",
+			digests:        []string{"h1:AbC="},
+			hash:           pluginHash,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "valid digests",
+			digests:        []string{"sha256:123, sha512:456", "h1:AbC="},
+			hash:           pluginHash,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "one invalid digest",
+			digests:        []string{"sha256:123,sha512:789"},
+			hash:           pluginHash,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			desc:           "digest takes precedence over the hash header",
+			sum:            "123",
+			digests:        []string{"h1:XyZ="},
+			hash:           pluginHash,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			desc:           "digest not recorded",
+			digests:        []string{"h1:AbC="},
+			hash:           db.PluginHash{Name: moduleName + "@" + version, Hash: "123"},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			desc:           "sha256 digest of a hash recorded before the digests",
+			digests:        []string{"sha256:123"},
+			hash:           db.PluginHash{Name: moduleName + "@" + version, Hash: "123"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "unsupported digest",
+			digests:        []string{"md5:123"},
+			hash:           pluginHash,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			testDB := mockDB{
+				getHashByNameFn: func(_ context.Context, _, _ string) (db.PluginHash, error) {
+					if test.hashErr != nil {
+						return db.PluginHash{}, test.hashErr
+					}
+
+					return test.hash, nil
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/validate/"+moduleName+"/"+version, http.NoBody)
+			if test.sum != "" {
+				req.Header.Set(hashHeader, test.sum)
+			}
+
+			for _, digest := range test.digests {
+				req.Header.Add(digestHeader, digest)
+			}
+
+			rw := httptest.NewRecorder()
+
+			New(testDB, nil, WithStatementSigner(signer)).Validate(rw, req)
+
+			assert.Equal(t, test.expectedStatus, rw.Code)
+
+			if test.expectedStatus != http.StatusOK {
+				assert.Empty(t, rw.Header().Get(signatureHeader))
+				return
+			}
+
+			statement := signing.Statement{Module: moduleName, Version: version, SHA256: test.hash.Hash}
+			assert.NoError(t, signing.Verify(signer.PublicKey(), statement, rw.Header().Get(signatureHeader)))
+			assert.Equal(t, signer.KeyID(), rw.Header().Get(keyIDHeader))
+		})
+	}
+}
+
+func buildZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	w := zip.NewWriter(buf)
+
+	for name, content := range files {
+		f, err := w.Create(name)
+		require.NoError(t, err)
+
+		_, err = f.Write([]byte(content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
+
+func sha256Sum(content []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(content))
+}
+
+// minisignPublicKey returns the minisign public key of a signing key, using 0102030405060708 as key ID.
+func minisignPublicKey(key ed25519.PrivateKey) string {
+	raw := append([]byte("Ed\x01\x02\x03\x04\x05\x06\x07\x08"), key.Public().(ed25519.PublicKey)...)
+
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+// minisign signs content as minisign does with the legacy algorithm.
+func minisign(key ed25519.PrivateKey, content []byte) []byte {
+	signature := ed25519.Sign(key, content)
+	trustedComment := "timestamp:1760000000"
+
+	return []byte(fmt.Sprintf("untrusted comment: signature\n%s\ntrusted comment: %s\n%s\n",
+		base64.StdEncoding.EncodeToString(append([]byte("Ed\x01\x02\x03\x04\x05\x06\x07\x08"), signature...)),
+		trustedComment,
+		base64.StdEncoding.EncodeToString(ed25519.Sign(key, append(signature, trustedComment...))),
+	))
+}
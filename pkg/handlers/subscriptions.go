@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+// Subscriptions lists the webhook subscriptions.
+func (h Handlers) Subscriptions(rw http.ResponseWriter, req *http.Request) {
+	ctx, span := h.tracer.Start(req.Context(), "handler_subscriptions")
+	defer span.End()
+
+	if h.webhooks == nil {
+		NotFound(rw, req)
+		return
+	}
+
+	subscriptions, err := h.webhooks.ListSubscriptions(ctx)
+	if err != nil {
+		span.RecordError(err)
+		log.Error().Err(err).Msg("Error while trying to get subscriptions")
+		JSONInternalServerError(rw)
+
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(rw).Encode(subscriptions); err != nil {
+		span.RecordError(err)
+		log.Error().Err(err).Msg("Failed to encode response")
+		JSONInternalServerError(rw)
+
+		return
+	}
+}
+
+// Subscribe creates a webhook subscription.
+// The secret signing the payloads is generated when it is not provided, and only returned in the response.
+func (h Handlers) Subscribe(rw http.ResponseWriter, req *http.Request) {
+	ctx, span := h.tracer.Start(req.Context(), "handler_subscribe")
+	defer span.End()
+
+	if h.webhooks == nil {
+		NotFound(rw, req)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+
+	var input db.Subscription
+	if err := json.NewDecoder(req.Body).Decode(&input); err != nil {
+		span.RecordError(err)
+		JSONError(rw, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	if err := validateSubscription(input); err != nil {
+		span.RecordError(err)
+		JSONError(rw, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	subscription, err := h.webhooks.Subscribe(ctx, db.Subscription{URL: input.URL, Secret: input.Secret, Events: input.Events})
+	if err != nil {
+		span.RecordError(err)
+		log.Error().Err(err).Str("webhook_url", input.URL).Msg("Error persisting subscription")
+		JSONInternalServerError(rw)
+
+		return
+	}
+
+	rw.WriteHeader(http.StatusCreated)
+
+	if err := json.NewEncoder(rw).Encode(subscription); err != nil {
+		span.RecordError(err)
+		log.Error().Err(err).Msg("Failed to encode response")
+		JSONInternalServerError(rw)
+
+		return
+	}
+}
+
+// Subscription gets a webhook subscription.
+func (h Handlers) Subscription(rw http.ResponseWriter, req *http.Request) {
+	ctx, span := h.tracer.Start(req.Context(), "handler_subscription")
+	defer span.End()
+
+	if h.webhooks == nil {
+		NotFound(rw, req)
+		return
+	}
+
+	id, err := getPathParam(req.URL)
+	if err != nil {
+		span.RecordError(err)
+		JSONError(rw, http.StatusBadRequest, "Missing subscription id")
+
+		return
+	}
+
+	subscription, err := h.webhooks.GetSubscription(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+
+		if errors.As(err, &db.NotFoundError{}) {
+			NotFound(rw, req)
+			return
+		}
+
+		log.Error().Err(err).Str("subscription_id", id).Msg("Error while trying to get subscription")
+		JSONInternalServerError(rw)
+
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(rw).Encode(subscription); err != nil {
+		span.RecordError(err)
+		log.Error().Err(err).Msg("Failed to encode response")
+		JSONInternalServerError(rw)
+
+		return
+	}
+}
+
+// Unsubscribe deletes a webhook subscription.
+func (h Handlers) Unsubscribe(rw http.ResponseWriter, req *http.Request) {
+	ctx, span := h.tracer.Start(req.Context(), "handler_unsubscribe")
+	defer span.End()
+
+	if h.webhooks == nil {
+		NotFound(rw, req)
+		return
+	}
+
+	id, err := getPathParam(req.URL)
+	if err != nil {
+		span.RecordError(err)
+		JSONError(rw, http.StatusBadRequest, "Missing subscription id")
+
+		return
+	}
+
+	if err = h.webhooks.Unsubscribe(ctx, id); err != nil {
+		span.RecordError(err)
+
+		if errors.As(err, &db.NotFoundError{}) {
+			NotFound(rw, req)
+			return
+		}
+
+		log.Error().Err(err).Str("subscription_id", id).Msg("Failed to delete the subscription")
+		JSONInternalServerError(rw)
+
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// Deliveries lists the deliveries of a webhook subscription, with the history of their attempts.
+func (h Handlers) Deliveries(rw http.ResponseWriter, req *http.Request) {
+	ctx, span := h.tracer.Start(req.Context(), "handler_deliveries")
+	defer span.End()
+
+	if h.webhooks == nil {
+		NotFound(rw, req)
+		return
+	}
+
+	id, err := getSubPathParam(req.URL, "deliveries")
+	if err != nil {
+		span.RecordError(err)
+		JSONError(rw, http.StatusBadRequest, "Missing subscription id")
+
+		return
+	}
+
+	deliveries, err := h.webhooks.ListDeliveries(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+
+		if errors.As(err, &db.NotFoundError{}) {
+			NotFound(rw, req)
+			return
+		}
+
+		log.Error().Err(err).Str("subscription_id", id).Msg("Error while trying to get deliveries")
+		JSONInternalServerError(rw)
+
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(rw).Encode(deliveries); err != nil {
+		span.RecordError(err)
+		log.Error().Err(err).Msg("Failed to encode response")
+		JSONInternalServerError(rw)
+
+		return
+	}
+}
+
+func validateSubscription(subscription db.Subscription) error {
+	endpoint, err := url.Parse(subscription.URL)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return fmt.Errorf("invalid webhook URL: %q", subscription.URL)
+	}
+
+	for _, eventType := range subscription.Events {
+		if !slices.Contains(db.EventTypes, eventType) {
+			return fmt.Errorf("unknown event type: %q", eventType)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,355 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+// fakeWebhooks is a Webhooks recording the notified events.
+type fakeWebhooks struct {
+	mu            sync.Mutex
+	subscriptions []db.Subscription
+	deliveries    []db.Delivery
+	events        []db.Event
+}
+
+func (f *fakeWebhooks) Subscribe(_ context.Context, subscription db.Subscription) (db.Subscription, error) {
+	subscription.ID = "sub"
+	if subscription.Secret == "" {
+		subscription.Secret = "generated"
+	}
+
+	return subscription, nil
+}
+
+func (f *fakeWebhooks) Unsubscribe(_ context.Context, id string) error {
+	for _, subscription := range f.subscriptions {
+		if subscription.ID == id {
+			return nil
+		}
+	}
+
+	return db.NotFoundError{}
+}
+
+func (f *fakeWebhooks) GetSubscription(_ context.Context, id string) (db.Subscription, error) {
+	for _, subscription := range f.subscriptions {
+		if subscription.ID == id {
+			return subscription, nil
+		}
+	}
+
+	return db.Subscription{}, db.NotFoundError{}
+}
+
+func (f *fakeWebhooks) ListSubscriptions(_ context.Context) ([]db.Subscription, error) {
+	return f.subscriptions, nil
+}
+
+func (f *fakeWebhooks) ListDeliveries(ctx context.Context, subscription string) ([]db.Delivery, error) {
+	if _, err := f.GetSubscription(ctx, subscription); err != nil {
+		return nil, err
+	}
+
+	var deliveries []db.Delivery
+
+	for _, delivery := range f.deliveries {
+		if delivery.Subscription == subscription {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+
+	return deliveries, nil
+}
+
+func (f *fakeWebhooks) Notify(_ context.Context, event db.Event) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.events = append(f.events, event)
+
+	return nil
+}
+
+// notified returns the type and the version of the notified events.
+func (f *fakeWebhooks) notified() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var notified []string
+
+	for _, event := range f.events {
+		notified = append(notified, strings.TrimSuffix(event.Type+" "+event.Version, " "))
+	}
+
+	return notified
+}
+
+func TestHandlers_Subscriptions(t *testing.T) {
+	webhooks := &fakeWebhooks{
+		subscriptions: []db.Subscription{
+			{ID: "1", URL: "https://example.com/hook", Events: []string{db.EventVersionPublished}},
+		},
+		deliveries: []db.Delivery{
+			{ID: "d1", Subscription: "1", Event: db.Event{ID: "e1", Type: db.EventVersionPublished, Module: "github.com/traefik/plugindemo", Version: "v0.2.1"}, Status: db.DeliveryFailed, Attempts: []db.DeliveryAttempt{{StatusCode: 500, Error: "unexpected status code 500: boom", Duration: 12}}},
+		},
+	}
+
+	testCases := []struct {
+		desc           string
+		handler        func(h Handlers) http.HandlerFunc
+		method         string
+		url            string
+		body           string
+		webhooks       Webhooks
+		expectedStatus int
+		expected       string
+	}{
+		{
+			desc:           "subscriptions",
+			handler:        func(h Handlers) http.HandlerFunc { return h.Subscriptions },
+			method:         http.MethodGet,
+			url:            "/",
+			webhooks:       webhooks,
+			expectedStatus: http.StatusOK,
+			expected:       `[{"id":"1","url":"https://example.com/hook","events":["version.published"],"createdAt":"0001-01-01T00:00:00Z"}]`,
+		},
+		{
+			desc:           "subscribe",
+			handler:        func(h Handlers) http.HandlerFunc { return h.Subscribe },
+			method:         http.MethodPost,
+			url:            "/",
+			body:           `{"url": "https://example.com/hook", "events": ["plugin.created", "hash.mismatch"]}`,
+			webhooks:       webhooks,
+			expectedStatus: http.StatusCreated,
+			expected:       `{"id":"sub","url":"https://example.com/hook","secret":"generated","events":["plugin.created","hash.mismatch"],"createdAt":"0001-01-01T00:00:00Z"}`,
+		},
+		{
+			desc:           "subscribe with a secret",
+			handler:        func(h Handlers) http.HandlerFunc { return h.Subscribe },
+			method:         http.MethodPost,
+			url:            "/",
+			body:           `{"url": "http://example.com/hook", "secret": "s3cr3t"}`,
+			webhooks:       webhooks,
+			expectedStatus: http.StatusCreated,
+			expected:       `{"id":"sub","url":"http://example.com/hook","secret":"s3cr3t","createdAt":"0001-01-01T00:00:00Z"}`,
+		},
+		{
+			desc:           "subscribe with an invalid URL",
+			handler:        func(h Handlers) http.HandlerFunc { return h.Subscribe },
+			method:         http.MethodPost,
+			url:            "/",
+			body:           `{"url": "ftp://example.com/hook"}`,
+			webhooks:       webhooks,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			desc:           "subscribe to an unknown event",
+			handler:        func(h Handlers) http.HandlerFunc { return h.Subscribe },
+			method:         http.MethodPost,
+			url:            "/",
+			body:           `{"url": "https://example.com/hook", "events": ["plugin.starred"]}`,
+			webhooks:       webhooks,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			desc:           "subscription",
+			handler:        func(h Handlers) http.HandlerFunc { return h.Subscription },
+			method:         http.MethodGet,
+			url:            "/1",
+			webhooks:       webhooks,
+			expectedStatus: http.StatusOK,
+			expected:       `{"id":"1","url":"https://example.com/hook","events":["version.published"],"createdAt":"0001-01-01T00:00:00Z"}`,
+		},
+		{
+			desc:           "unknown subscription",
+			handler:        func(h Handlers) http.HandlerFunc { return h.Subscription },
+			method:         http.MethodGet,
+			url:            "/2",
+			webhooks:       webhooks,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			desc:           "unsubscribe",
+			handler:        func(h Handlers) http.HandlerFunc { return h.Unsubscribe },
+			method:         http.MethodDelete,
+			url:            "/1",
+			webhooks:       webhooks,
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			desc:           "unsubscribe unknown subscription",
+			handler:        func(h Handlers) http.HandlerFunc { return h.Unsubscribe },
+			method:         http.MethodDelete,
+			url:            "/2",
+			webhooks:       webhooks,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			desc:           "deliveries",
+			handler:        func(h Handlers) http.HandlerFunc { return h.Deliveries },
+			method:         http.MethodGet,
+			url:            "/1/deliveries",
+			webhooks:       webhooks,
+			expectedStatus: http.StatusOK,
+			expected:       `[{"id":"d1","subscription":"1","event":{"id":"e1","type":"version.published","module":"github.com/traefik/plugindemo","version":"v0.2.1","createdAt":"0001-01-01T00:00:00Z"},"status":"failed","attempts":[{"statusCode":500,"error":"unexpected status code 500: boom","duration":12,"at":"0001-01-01T00:00:00Z"}],"createdAt":"0001-01-01T00:00:00Z","updatedAt":"0001-01-01T00:00:00Z"}]`,
+		},
+		{
+			desc:           "deliveries of an unknown subscription",
+			handler:        func(h Handlers) http.HandlerFunc { return h.Deliveries },
+			method:         http.MethodGet,
+			url:            "/2/deliveries",
+			webhooks:       webhooks,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			desc:           "without webhooks",
+			handler:        func(h Handlers) http.HandlerFunc { return h.Subscriptions },
+			method:         http.MethodGet,
+			url:            "/",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var opts []Option
+			if test.webhooks != nil {
+				opts = append(opts, WithWebhooks(test.webhooks))
+			}
+
+			rw := httptest.NewRecorder()
+			req := httptest.NewRequest(test.method, test.url, strings.NewReader(test.body))
+
+			test.handler(New(mockDB{}, nil, opts...))(rw, req)
+
+			assert.Equal(t, test.expectedStatus, rw.Code)
+
+			if test.expected != "" {
+				assert.JSONEq(t, test.expected, rw.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlers_Create_notify(t *testing.T) {
+	testDB := mockDB{
+		createFn: func(_ context.Context, plugin db.Plugin) (db.Plugin, error) {
+			plugin.ID = "123"
+
+			return plugin, nil
+		},
+	}
+
+	webhooks := &fakeWebhooks{}
+
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "github.com/traefik/plugindemo", "readme": "# Demo", "versions": ["v0.2.1", "v0.2.0"]}`))
+
+	New(testDB, nil, WithWebhooks(webhooks)).Create(rw, req)
+
+	assert.Equal(t, http.StatusCreated, rw.Code)
+	assert.Equal(t, []string{"plugin.created", "version.published v0.2.1", "version.published v0.2.0"}, webhooks.notified())
+
+	for _, event := range webhooks.events {
+		assert.NotEmpty(t, event.ID)
+		assert.False(t, event.CreatedAt.IsZero())
+		assert.Equal(t, "123", event.Plugin.ID)
+		assert.Empty(t, event.Plugin.Readme)
+	}
+}
+
+func TestHandlers_Update_notify(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		previous db.Plugin
+		body     string
+		expected []string
+	}{
+		{
+			desc:     "new version",
+			previous: db.Plugin{Name: "github.com/traefik/plugindemo", Versions: []string{"v0.2.0"}},
+			body:     `{"name": "github.com/traefik/plugindemo", "versions": ["v0.2.1", "v0.2.0"]}`,
+			expected: []string{"plugin.updated", "version.published v0.2.1"},
+		},
+		{
+			desc:     "hidden",
+			previous: db.Plugin{Name: "github.com/traefik/plugindemo"},
+			body:     `{"name": "github.com/traefik/plugindemo", "hidden": true}`,
+			expected: []string{"plugin.updated", "plugin.hidden"},
+		},
+		{
+			desc:     "unhidden",
+			previous: db.Plugin{Name: "github.com/traefik/plugindemo", Hidden: true},
+			body:     `{"name": "github.com/traefik/plugindemo"}`,
+			expected: []string{"plugin.updated", "plugin.unhidden"},
+		},
+		{
+			desc:     "disabled",
+			previous: db.Plugin{Name: "github.com/traefik/plugindemo"},
+			body:     `{"name": "github.com/traefik/plugindemo", "disabled": true}`,
+			expected: []string{"plugin.updated", "plugin.disabled"},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			testDB := mockDB{
+				getFn: func(_ context.Context, id string) (db.Plugin, error) {
+					previous := test.previous
+					previous.ID = id
+
+					return previous, nil
+				},
+				updateFn: func(_ context.Context, id string, plugin db.Plugin) (db.Plugin, error) {
+					plugin.ID = id
+
+					return plugin, nil
+				},
+			}
+
+			webhooks := &fakeWebhooks{}
+
+			rw := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, "/123", strings.NewReader(test.body))
+
+			New(testDB, nil, WithWebhooks(webhooks)).Update(rw, req)
+
+			assert.Equal(t, http.StatusOK, rw.Code)
+			assert.Equal(t, test.expected, webhooks.notified())
+		})
+	}
+}
+
+func TestHandlers_Delete_notify(t *testing.T) {
+	testDB := mockDB{
+		getFn: func(_ context.Context, id string) (db.Plugin, error) {
+			return db.Plugin{ID: id, Name: "github.com/traefik/plugindemo"}, nil
+		},
+		deleteFn: func(_ context.Context, _ string) error {
+			return nil
+		},
+	}
+
+	webhooks := &fakeWebhooks{}
+
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodDelete, "/123", http.NoBody)
+
+	New(testDB, nil, WithWebhooks(webhooks)).Delete(rw, req)
+
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, []string{"plugin.deleted"}, webhooks.notified())
+	assert.Equal(t, "github.com/traefik/plugindemo", webhooks.events[0].Module)
+}
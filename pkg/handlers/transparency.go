@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/plugin-service/pkg/db"
+	"github.com/traefik/plugin-service/pkg/transparency"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/mod/sumdb/tlog"
+)
+
+type recordProof struct {
+	Index    int64            `json:"index"`
+	TreeSize int64            `json:"treeSize"`
+	Proof    tlog.RecordProof `json:"proof"`
+}
+
+type treeProof struct {
+	OldSize  int64          `json:"oldSize"`
+	TreeSize int64          `json:"treeSize"`
+	Proof    tlog.TreeProof `json:"proof"`
+}
+
+// TreeHead returns the signed tree head of the transparency log.
+func (h Handlers) TreeHead(rw http.ResponseWriter, req *http.Request) {
+	ctx, span := h.tracer.Start(req.Context(), "handler_treeHead")
+	defer span.End()
+
+	if h.tlog == nil {
+		NotFound(rw, req)
+		return
+	}
+
+	head, err := h.tlog.SignedTreeHead(ctx)
+	if err != nil {
+		writeLogError(rw, span, err)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	rw.Header().Set("Cache-Control", "no-cache")
+
+	_, _ = rw.Write(head)
+}
+
+// Lookup returns the transparency log record of a plugin version, followed by a signed tree head including it.
+func (h Handlers) Lookup(rw http.ResponseWriter, req *http.Request) {
+	ctx, span := h.tracer.Start(req.Context(), "handler_lookup")
+	defer span.End()
+
+	if h.tlog == nil {
+		NotFound(rw, req)
+		return
+	}
+
+	_, target, _ := strings.Cut(req.URL.Path, "/lookup/")
+
+	i := strings.LastIndex(target, "@")
+	if i <= 0 || i == len(target)-1 {
+		span.RecordError(fmt.Errorf("invalid plugin version: %s", target))
+		JSONErrorf(rw, http.StatusBadRequest, "Invalid plugin version %q, expected module@version", target)
+
+		return
+	}
+
+	msg, err := h.tlog.Lookup(ctx, target[:i], target[i+1:])
+	if err != nil {
+		writeLogError(rw, span, err)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	rw.Header().Set("Cache-Control", "no-cache")
+
+	_, _ = rw.Write(msg)
+}
+
+// RecordProof returns the proof that a record is contained in a tree of the transparency log.
+func (h Handlers) RecordProof(rw http.ResponseWriter, req *http.Request) {
+	ctx, span := h.tracer.Start(req.Context(), "handler_recordProof")
+	defer span.End()
+
+	if h.tlog == nil {
+		NotFound(rw, req)
+		return
+	}
+
+	index, err := strconv.ParseInt(req.URL.Query().Get("index"), 10, 64)
+	if err != nil {
+		span.RecordError(err)
+		JSONError(rw, http.StatusBadRequest, "Invalid index")
+
+		return
+	}
+
+	treeSize, err := strconv.ParseInt(req.URL.Query().Get("treeSize"), 10, 64)
+	if err != nil {
+		span.RecordError(err)
+		JSONError(rw, http.StatusBadRequest, "Invalid treeSize")
+
+		return
+	}
+
+	proof, err := h.tlog.ProveRecord(ctx, index, treeSize)
+	if err != nil {
+		writeLogError(rw, span, err)
+		return
+	}
+
+	writeProof(rw, span, recordProof{Index: index, TreeSize: treeSize, Proof: proof})
+}
+
+// TreeProof returns the proof that a tree of the transparency log is a prefix of a larger tree.
+func (h Handlers) TreeProof(rw http.ResponseWriter, req *http.Request) {
+	ctx, span := h.tracer.Start(req.Context(), "handler_treeProof")
+	defer span.End()
+
+	if h.tlog == nil {
+		NotFound(rw, req)
+		return
+	}
+
+	oldSize, err := strconv.ParseInt(req.URL.Query().Get("oldSize"), 10, 64)
+	if err != nil {
+		span.RecordError(err)
+		JSONError(rw, http.StatusBadRequest, "Invalid oldSize")
+
+		return
+	}
+
+	treeSize, err := strconv.ParseInt(req.URL.Query().Get("treeSize"), 10, 64)
+	if err != nil {
+		span.RecordError(err)
+		JSONError(rw, http.StatusBadRequest, "Invalid treeSize")
+
+		return
+	}
+
+	proof, err := h.tlog.ProveTree(ctx, treeSize, oldSize)
+	if err != nil {
+		writeLogError(rw, span, err)
+		return
+	}
+
+	writeProof(rw, span, treeProof{OldSize: oldSize, TreeSize: treeSize, Proof: proof})
+}
+
+func writeProof(rw http.ResponseWriter, span trace.Span, proof any) {
+	rw.Header().Set("Content-Type", "application/json")
+	// The proofs between two given tree sizes never change.
+	rw.Header().Set("Cache-Control", "public, max-age=86400, immutable")
+
+	if err := json.NewEncoder(rw).Encode(proof); err != nil {
+		span.RecordError(err)
+		log.Error().Err(err).Msg("Failed to encode response")
+		JSONInternalServerError(rw)
+	}
+}
+
+func writeLogError(rw http.ResponseWriter, span trace.Span, err error) {
+	span.RecordError(err)
+
+	switch {
+	case errors.Is(err, transparency.ErrUnsigned):
+		JSONError(rw, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+	case errors.As(err, &db.NotFoundError{}):
+		JSONError(rw, http.StatusNotFound, "Plugin version not recorded")
+	case errors.Is(err, transparency.ErrOutOfRange):
+		JSONError(rw, http.StatusBadRequest, err.Error())
+	default:
+		log.Error().Err(err).Msg("Transparency log error")
+		JSONInternalServerError(rw)
+	}
+}
@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/plugin-service/pkg/db"
+	"github.com/traefik/plugin-service/pkg/transparency"
+	"golang.org/x/mod/sumdb/tlog"
+)
+
+// fakeLog is a TransparencyLog recording the appended hashes.
+type fakeLog struct {
+	mu       sync.Mutex
+	records  map[string]string
+	head     []byte
+	err      error
+	proof    []tlog.Hash
+	proveErr error
+}
+
+func (f *fakeLog) Append(_ context.Context, module, version, hash string) (db.LogRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.records[module+"@"+version] = hash
+
+	return db.LogRecord{Module: module, Version: version, Hash: hash}, nil
+}
+
+func (f *fakeLog) AppendReset(ctx context.Context, module, version, hash string) (db.LogRecord, error) {
+	return f.Append(ctx, module, version, hash)
+}
+
+func (f *fakeLog) Lookup(_ context.Context, module, version string) ([]byte, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	hash, ok := f.records[module+"@"+version]
+	if !ok {
+		return nil, db.NotFoundError{}
+	}
+
+	return append([]byte(fmt.Sprintf("0\n%s %s sha256:%s\n\n", module, version, hash)), f.head...), nil
+}
+
+func (f *fakeLog) SignedTreeHead(_ context.Context) ([]byte, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return f.head, nil
+}
+
+func (f *fakeLog) ProveRecord(_ context.Context, _, _ int64) (tlog.RecordProof, error) {
+	return f.proof, f.proveErr
+}
+
+func (f *fakeLog) ProveTree(_ context.Context, _, _ int64) (tlog.TreeProof, error) {
+	return f.proof, f.proveErr
+}
+
+func TestHandlers_Download_appendLog(t *testing.T) {
+	const (
+		moduleName = "github.com/traefik/plugindemo"
+		version    = "v0.2.1"
+		hashName   = moduleName + "@" + version
+	)
+
+	sources := buildZip(t, map[string]string{
+		hashName + "/.traefik.yml": "displayName: Demo Plugin\ntype: middleware\nimport: github.com/traefik/plugindemo\n",
+		hashName + "/demo.go":      "package plugindemo",
+	})
+
+	hashes := make(map[string]db.PluginHash)
+
+	testDB := mockDB{
+		getByNameFn: func(_ context.Context, _ string, _ bool) (db.Plugin, error) {
+			return db.Plugin{Name: moduleName, Runtime: "yaegi"}, nil
+		},
+		getHashByNameFn: func(_ context.Context, module, version string) (db.PluginHash, error) {
+			ph, ok := hashes[module+"@"+version]
+			if !ok {
+				return db.PluginHash{}, db.NotFoundError{}
+			}
+
+			return ph, nil
+		},
+		createHashFn: func(_ context.Context, module, version, hash string) (db.PluginHash, error) {
+			hashes[module+"@"+version] = db.PluginHash{Name: module + "@" + version, Hash: hash}
+
+			return hashes[module+"@"+version], nil
+		},
+		updateHashManifestFn: func(_ context.Context, _, _ string, _ db.Manifest) (db.PluginHash, error) {
+			return db.PluginHash{}, nil
+		},
+		updateHashCapabilitiesFn: func(_ context.Context, _, _ string, _ db.CapabilityReport) (db.PluginHash, error) {
+			return db.PluginHash{}, nil
+		},
+		updateUseUnsafeFn: func(_ context.Context, _ string, _ bool) (db.Plugin, error) {
+			return db.Plugin{}, nil
+		},
+		updateHashDigestsFn: func(_ context.Context, _, _ string, _ map[string]string) (db.PluginHash, error) {
+			return db.PluginHash{}, nil
+		},
+		updateHashVerifiedFn: func(_ context.Context, module, version, _ string, verified bool, reasons []string) (db.PluginHash, error) {
+			ph := hashes[module+"@"+version]
+			ph.Verified = &verified
+			ph.Reasons = reasons
+			hashes[ph.Name] = ph
+
+			return ph, nil
+		},
+	}
+
+	fetcher := fakeFetcher{
+		modFiles: map[string]string{hashName: "module github.com/traefik/plugindemo\n\ngo 1.22\n"},
+		sources:  map[string][]byte{hashName: sources},
+	}
+
+	transparencyLog := &fakeLog{records: make(map[string]string)}
+
+	handler := New(testDB, fetcher, WithTransparencyLog(transparencyLog))
+
+	for range 2 {
+		req := httptest.NewRequest(http.MethodGet, "/download/"+moduleName+"/"+version, http.NoBody)
+		rw := httptest.NewRecorder()
+
+		handler.Download(rw, req)
+
+		assert.Equal(t, http.StatusOK, rw.Code)
+	}
+
+	assert.Equal(t, map[string]string{hashName: sha256Sum(sources)}, transparencyLog.records)
+}
+
+func TestHandlers_Lookup(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		path           string
+		log            *fakeLog
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			desc:           "recorded version",
+			path:           "/lookup/github.com/traefik/plugindemo@v0.2.1",
+			log:            &fakeLog{records: map[string]string{"github.com/traefik/plugindemo@v0.2.1": "123"}, head: []byte("head\n")},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "0\ngithub.com/traefik/plugindemo v0.2.1 sha256:123\n\nhead\n",
+		},
+		{
+			desc:           "unknown version",
+			path:           "/lookup/github.com/traefik/plugindemo@v0.3.0",
+			log:            &fakeLog{records: map[string]string{}},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			desc:           "missing version",
+			path:           "/lookup/github.com/traefik/plugindemo",
+			log:            &fakeLog{records: map[string]string{}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			desc:           "unsigned log",
+			path:           "/lookup/github.com/traefik/plugindemo@v0.2.1",
+			log:            &fakeLog{err: transparency.ErrUnsigned},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			desc:           "no log",
+			path:           "/lookup/github.com/traefik/plugindemo@v0.2.1",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var opts []Option
+			if test.log != nil {
+				opts = append(opts, WithTransparencyLog(test.log))
+			}
+
+			req := httptest.NewRequest(http.MethodGet, test.path, http.NoBody)
+			rw := httptest.NewRecorder()
+
+			New(mockDB{}, nil, opts...).Lookup(rw, req)
+
+			assert.Equal(t, test.expectedStatus, rw.Code)
+
+			if test.expectedBody != "" {
+				assert.Equal(t, test.expectedBody, rw.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlers_TreeHead(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/tlog/latest", http.NoBody)
+	rw := httptest.NewRecorder()
+
+	New(mockDB{}, nil, WithTransparencyLog(&fakeLog{head: []byte("head\n")})).TreeHead(rw, req)
+
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, "head\n", rw.Body.String())
+}
+
+func TestHandlers_RecordProof(t *testing.T) {
+	proof := []tlog.Hash{tlog.RecordHash([]byte("a")), tlog.RecordHash([]byte("b"))}
+
+	testCases := []struct {
+		desc           string
+		query          string
+		proveErr       error
+		expectedStatus int
+	}{
+		{
+			desc:           "proof",
+			query:          "index=1&treeSize=3",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "invalid index",
+			query:          "index=a&treeSize=3",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			desc:           "missing tree size",
+			query:          "index=1",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			desc:           "out of range",
+			query:          "index=5&treeSize=3",
+			proveErr:       fmt.Errorf("record 5 of tree 3: %w", transparency.ErrOutOfRange),
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			desc:           "error",
+			query:          "index=1&treeSize=3",
+			proveErr:       fmt.Errorf("boom"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/tlog/proof/record?"+test.query, http.NoBody)
+			rw := httptest.NewRecorder()
+
+			New(mockDB{}, nil, WithTransparencyLog(&fakeLog{proof: proof, proveErr: test.proveErr})).RecordProof(rw, req)
+
+			assert.Equal(t, test.expectedStatus, rw.Code)
+
+			if test.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var got recordProof
+			require.NoError(t, json.NewDecoder(rw.Body).Decode(&got))
+
+			assert.Equal(t, recordProof{Index: 1, TreeSize: 3, Proof: proof}, got)
+		})
+	}
+}
+
+func TestHandlers_TreeProof(t *testing.T) {
+	proof := []tlog.Hash{tlog.RecordHash([]byte("a"))}
+
+	req := httptest.NewRequest(http.MethodGet, "/tlog/proof/tree?oldSize=2&treeSize=3", http.NoBody)
+	rw := httptest.NewRecorder()
+
+	New(mockDB{}, nil, WithTransparencyLog(&fakeLog{proof: proof})).TreeProof(rw, req)
+
+	require.Equal(t, http.StatusOK, rw.Code)
+
+	var got treeProof
+	require.NoError(t, json.NewDecoder(rw.Body).Decode(&got))
+
+	assert.Equal(t, treeProof{OldSize: 2, TreeSize: 3, Proof: proof}, got)
+}
@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/google/go-github/v74/github"
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/plugin-service/pkg/crawler"
+	"github.com/traefik/plugin-service/pkg/db"
+	"golang.org/x/mod/semver"
+)
+
+const (
+	webhookSignatureHeader = "X-Hub-Signature-256"
+	webhookEventHeader     = "X-GitHub-Event"
+	webhookDeliveryHeader  = "X-GitHub-Delivery"
+
+	maxWebhookPayloadSize = 25 << 20 // 25MB, the maximum size of the GitHub webhook payloads.
+)
+
+// GitHubWebhook receives the GitHub webhook events of the plugin repositories.
+// The release, create (tag), repository and star events update the corresponding plugin, the other events are ignored.
+func (h Handlers) GitHubWebhook(rw http.ResponseWriter, req *http.Request) {
+	ctx, span := h.tracer.Start(req.Context(), "handler_githubWebhook")
+	defer span.End()
+
+	if len(h.webhookSecret) == 0 || h.manifests == nil {
+		NotFound(rw, req)
+		return
+	}
+
+	logger := log.With().
+		Str("github_event", req.Header.Get(webhookEventHeader)).
+		Str("github_delivery", req.Header.Get(webhookDeliveryHeader)).
+		Logger()
+
+	payload, err := io.ReadAll(io.LimitReader(req.Body, maxWebhookPayloadSize))
+	if err != nil {
+		span.RecordError(err)
+		logger.Error().Err(err).Msg("Error reading webhook payload")
+		JSONError(rw, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	if err = verifyWebhookSignature(payload, req.Header.Get(webhookSignatureHeader), h.webhookSecret); err != nil {
+		span.RecordError(err)
+		logger.Warn().Err(err).Msg("Invalid webhook signature")
+		JSONError(rw, http.StatusUnauthorized, "Invalid signature")
+
+		return
+	}
+
+	event, err := github.ParseWebHook(req.Header.Get(webhookEventHeader), payload)
+	if err != nil {
+		span.RecordError(err)
+		logger.Error().Err(err).Msg("Error decoding webhook payload")
+		JSONError(rw, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	switch event := event.(type) {
+	case *github.ReleaseEvent:
+		err = h.handleReleaseEvent(ctx, event)
+	case *github.CreateEvent:
+		err = h.handleCreateEvent(ctx, event)
+	case *github.RepositoryEvent:
+		err = h.handleRepositoryEvent(ctx, event)
+	case *github.StarEvent:
+		err = h.handleStarEvent(ctx, event)
+	default:
+		logger.Debug().Msg("Webhook event ignored")
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		logger.Error().Err(err).Msg("Error handling webhook event")
+		JSONInternalServerError(rw)
+
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func (h Handlers) handleReleaseEvent(ctx context.Context, event *github.ReleaseEvent) error {
+	switch event.GetAction() {
+	case "published", "released", "prereleased", "created":
+	default:
+		return nil
+	}
+
+	if event.GetRelease().GetDraft() {
+		return nil
+	}
+
+	return h.addVersion(ctx, event.GetRepo(), event.GetRelease().GetTagName())
+}
+
+func (h Handlers) handleCreateEvent(ctx context.Context, event *github.CreateEvent) error {
+	if event.GetRefType() != "tag" {
+		return nil
+	}
+
+	return h.addVersion(ctx, event.GetRepo(), event.GetRef())
+}
+
+// addVersion adds a tag to the versions of the plugin, if it is a valid semantic version with a valid manifest.
+func (h Handlers) addVersion(ctx context.Context, repository *github.Repository, tag string) error {
+	if len(crawler.ValidVersions([]string{tag})) == 0 {
+		return nil
+	}
+
+	moduleName := repositoryModuleName(repository)
+
+	var (
+		reasons     []string
+		errManifest error
+	)
+
+	updated, err := h.updatePlugin(ctx, moduleName, func(plugin *db.Plugin) bool {
+		if slices.Contains(plugin.Versions, tag) {
+			return false
+		}
+
+		// The Go proxy doesn't serve the major versions above v1 of a module path without suffix.
+		if runtimeOrDefault(plugin.Runtime) != "wasm" && semver.Major(tag) != "v0" && semver.Major(tag) != "v1" {
+			return false
+		}
+
+		// The crawler only publishes the versions with a valid manifest, so does the webhook.
+		_, reasons, errManifest = h.manifests.ReadManifest(ctx, moduleName, tag)
+		if errManifest != nil || len(reasons) > 0 {
+			return false
+		}
+
+		plugin.Versions = append(plugin.Versions, tag)
+		slices.SortStableFunc(plugin.Versions, func(a, b string) int {
+			return semver.Compare(b, a)
+		})
+
+		plugin.LatestVersion = crawler.LatestVersion(plugin.Versions)
+
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	if errManifest != nil {
+		return fmt.Errorf("read manifest: %w", errManifest)
+	}
+
+	if len(reasons) > 0 {
+		log.Warn().Str("module_name", moduleName).Str("version", tag).Strs("reasons", reasons).
+			Msg("Version with an invalid manifest ignored")
+
+		return nil
+	}
+
+	if updated {
+		h.enqueuePrewarm(ctx, moduleName, []string{tag})
+	}
+
+	return nil
+}
+
+func (h Handlers) handleRepositoryEvent(ctx context.Context, event *github.RepositoryEvent) error {
+	moduleName := repositoryModuleName(event.GetRepo())
+
+	var err error
+
+	switch event.GetAction() {
+	case "renamed", "transferred":
+		previous := previousModuleName(event)
+		if previous == "" || previous == moduleName {
+			return nil
+		}
+
+		_, err = h.updatePlugin(ctx, previous, func(plugin *db.Plugin) bool {
+			plugin.Name = moduleName
+			plugin.Aliases = append(slices.DeleteFunc(plugin.Aliases, func(alias string) bool { return alias == moduleName }), previous)
+
+			if plugin.Import == previous || strings.HasPrefix(plugin.Import, previous+"/") {
+				plugin.Import = moduleName + strings.TrimPrefix(plugin.Import, previous)
+			}
+
+			return true
+		})
+
+	case "archived", "unarchived":
+		archived := event.GetAction() == "archived"
+
+		_, err = h.updatePlugin(ctx, moduleName, func(plugin *db.Plugin) bool {
+			changed := plugin.Archived != archived
+			plugin.Archived = archived
+
+			return changed
+		})
+
+	case "deleted":
+		_, err = h.updatePlugin(ctx, moduleName, func(plugin *db.Plugin) bool {
+			changed := !plugin.Disabled
+			plugin.Disabled = true
+
+			return changed
+		})
+	}
+
+	return err
+}
+
+func (h Handlers) handleStarEvent(ctx context.Context, event *github.StarEvent) error {
+	stars := event.GetRepo().GetStargazersCount()
+
+	_, err := h.updatePlugin(ctx, repositoryModuleName(event.GetRepo()), func(plugin *db.Plugin) bool {
+		changed := plugin.Stars != stars
+		plugin.Stars = stars
+
+		return changed
+	})
+
+	return err
+}
+
+// updatePlugin applies a change to the plugin with the given module name, and persists it if it has changed.
+// The events of the repositories which are not plugins are ignored.
+func (h Handlers) updatePlugin(ctx context.Context, moduleName string, change func(plugin *db.Plugin) bool) (bool, error) {
+	plugin, err := h.store.GetByName(ctx, moduleName, false, false)
+	if err != nil {
+		if errors.As(err, &db.NotFoundError{}) {
+			log.Debug().Str("module_name", moduleName).Msg("Webhook event of an unknown plugin ignored")
+			return false, nil
+		}
+
+		return false, fmt.Errorf("get plugin: %w", err)
+	}
+
+	previous := plugin
+	previous.Versions = slices.Clone(plugin.Versions)
+
+	if !change(&plugin) {
+		return false, nil
+	}
+
+	updated, err := h.store.Update(ctx, plugin.ID, plugin)
+	if err != nil {
+		return false, fmt.Errorf("update plugin: %w", err)
+	}
+
+	h.emitter.EmitChanges(ctx, previous, updated)
+
+	log.Info().Str("module_name", moduleName).Str("plugin_id", plugin.ID).Msg("Plugin updated from webhook event")
+
+	return true, nil
+}
+
+// verifyWebhookSignature verifies the HMAC-SHA256 signature (sha256=<hex>) of a webhook payload.
+func verifyWebhookSignature(payload []byte, signature string, secret []byte) error {
+	sig, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return fmt.Errorf("missing %s header", webhookSignatureHeader)
+	}
+
+	received, err := hex.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("malformed signature: %w", err)
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+
+	if !hmac.Equal(received, mac.Sum(nil)) {
+		return errors.New("signature mismatch")
+	}
+
+	return nil
+}
+
+func repositoryModuleName(repository *github.Repository) string {
+	return "github.com/" + repository.GetFullName()
+}
+
+// previousModuleName returns the module name of a repository before it was renamed or transferred.
+func previousModuleName(event *github.RepositoryEvent) string {
+	owner, name := event.GetRepo().GetOwner().GetLogin(), event.GetRepo().GetName()
+
+	changes := event.GetChanges()
+	if changes == nil {
+		return ""
+	}
+
+	if from := changes.GetRepo().GetName().GetFrom(); from != "" {
+		name = from
+	}
+
+	if from := changes.GetOwner().GetOwnerInfo(); from != nil {
+		switch {
+		case from.GetUser().GetLogin() != "":
+			owner = from.GetUser().GetLogin()
+		case from.GetOrg().GetLogin() != "":
+			owner = from.GetOrg().GetLogin()
+		}
+	}
+
+	return "github.com/" + owner + "/" + name
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+const testWebhookSecret = "secret"
+
+// fakeManifests is a ManifestReader returning the rejection reasons of the manifests by ref.
+type fakeManifests map[string][]string
+
+func (f fakeManifests) ReadManifest(_ context.Context, _, ref string) (db.Manifest, []string, error) {
+	reasons, ok := f[ref]
+	if !ok {
+		return db.Manifest{}, nil, errors.New("boom")
+	}
+
+	return db.Manifest{DisplayName: "Demo Plugin"}, reasons, nil
+}
+
+func TestHandlers_GitHubWebhook(t *testing.T) {
+	plugin := db.Plugin{
+		ID:            "123",
+		Name:          "github.com/traefik/plugindemo",
+		Import:        "github.com/traefik/plugindemo/pkg",
+		LatestVersion: "v0.2.0",
+		Versions:      []string{"v0.2.0", "v0.1.0"},
+		Stars:         22,
+	}
+
+	testCases := []struct {
+		desc      string
+		event     string
+		payload   string
+		signature string
+		expCode   int
+		expected  *db.Plugin
+		expJobs   []db.Job
+	}{
+		{
+			desc:     "release published",
+			event:    "release",
+			payload:  `{"action": "published", "release": {"tag_name": "v0.3.0"}, "repository": {"full_name": "traefik/plugindemo"}}`,
+			expCode:  http.StatusNoContent,
+			expected: &db.Plugin{ID: "123", Name: plugin.Name, Import: plugin.Import, LatestVersion: "v0.3.0", Versions: []string{"v0.3.0", "v0.2.0", "v0.1.0"}, Stars: 22},
+			expJobs: []db.Job{
+				{ID: "github.com/traefik/plugindemo@v0.3.0", Kind: db.JobPrewarm, Module: "github.com/traefik/plugindemo", Version: "v0.3.0", Status: db.JobPending},
+			},
+		},
+		{
+			desc:     "pre-release tag created",
+			event:    "create",
+			payload:  `{"ref": "v0.3.0-rc1", "ref_type": "tag", "repository": {"full_name": "traefik/plugindemo"}}`,
+			expCode:  http.StatusNoContent,
+			expected: &db.Plugin{ID: "123", Name: plugin.Name, Import: plugin.Import, LatestVersion: "v0.2.0", Versions: []string{"v0.3.0-rc1", "v0.2.0", "v0.1.0"}, Stars: 22},
+			expJobs: []db.Job{
+				{ID: "github.com/traefik/plugindemo@v0.3.0-rc1", Kind: db.JobPrewarm, Module: "github.com/traefik/plugindemo", Version: "v0.3.0-rc1", Status: db.JobPending},
+			},
+		},
+		{
+			desc:    "known tag created",
+			event:   "create",
+			payload: `{"ref": "v0.2.0", "ref_type": "tag", "repository": {"full_name": "traefik/plugindemo"}}`,
+			expCode: http.StatusNoContent,
+		},
+		{
+			desc:    "invalid tag created",
+			event:   "create",
+			payload: `{"ref": "latest", "ref_type": "tag", "repository": {"full_name": "traefik/plugindemo"}}`,
+			expCode: http.StatusNoContent,
+		},
+		{
+			desc:    "tag with an invalid manifest",
+			event:   "create",
+			payload: `{"ref": "v0.4.0", "ref_type": "tag", "repository": {"full_name": "traefik/plugindemo"}}`,
+			expCode: http.StatusNoContent,
+		},
+		{
+			desc:    "manifest read error",
+			event:   "create",
+			payload: `{"ref": "v0.5.0", "ref_type": "tag", "repository": {"full_name": "traefik/plugindemo"}}`,
+			expCode: http.StatusInternalServerError,
+		},
+		{
+			desc:    "major version without module path suffix",
+			event:   "create",
+			payload: `{"ref": "v2.0.0", "ref_type": "tag", "repository": {"full_name": "traefik/plugindemo"}}`,
+			expCode: http.StatusNoContent,
+		},
+		{
+			desc:    "branch created",
+			event:   "create",
+			payload: `{"ref": "v0.3.0", "ref_type": "branch", "repository": {"full_name": "traefik/plugindemo"}}`,
+			expCode: http.StatusNoContent,
+		},
+		{
+			desc:     "repository renamed",
+			event:    "repository",
+			payload:  `{"action": "renamed", "changes": {"repository": {"name": {"from": "plugindemo"}}}, "repository": {"name": "demo", "full_name": "traefik/demo", "owner": {"login": "traefik"}}}`,
+			expCode:  http.StatusNoContent,
+			expected: &db.Plugin{ID: "123", Name: "github.com/traefik/demo", Import: "github.com/traefik/demo/pkg", LatestVersion: "v0.2.0", Versions: plugin.Versions, Stars: 22, Aliases: []string{"github.com/traefik/plugindemo"}},
+		},
+		{
+			desc:     "repository transferred",
+			event:    "repository",
+			payload:  `{"action": "transferred", "changes": {"owner": {"from": {"organization": {"login": "traefik"}}}}, "repository": {"name": "plugindemo", "full_name": "traefik-contrib/plugindemo", "owner": {"login": "traefik-contrib"}}}`,
+			expCode:  http.StatusNoContent,
+			expected: &db.Plugin{ID: "123", Name: "github.com/traefik-contrib/plugindemo", Import: "github.com/traefik-contrib/plugindemo/pkg", LatestVersion: "v0.2.0", Versions: plugin.Versions, Stars: 22, Aliases: []string{"github.com/traefik/plugindemo"}},
+		},
+		{
+			desc:     "repository archived",
+			event:    "repository",
+			payload:  `{"action": "archived", "repository": {"full_name": "traefik/plugindemo"}}`,
+			expCode:  http.StatusNoContent,
+			expected: &db.Plugin{ID: "123", Name: plugin.Name, Import: plugin.Import, LatestVersion: "v0.2.0", Versions: plugin.Versions, Stars: 22, Archived: true},
+		},
+		{
+			desc:     "repository deleted",
+			event:    "repository",
+			payload:  `{"action": "deleted", "repository": {"full_name": "traefik/plugindemo"}}`,
+			expCode:  http.StatusNoContent,
+			expected: &db.Plugin{ID: "123", Name: plugin.Name, Import: plugin.Import, LatestVersion: "v0.2.0", Versions: plugin.Versions, Stars: 22, Disabled: true},
+		},
+		{
+			desc:     "star created",
+			event:    "star",
+			payload:  `{"action": "created", "repository": {"full_name": "traefik/plugindemo", "stargazers_count": 23}}`,
+			expCode:  http.StatusNoContent,
+			expected: &db.Plugin{ID: "123", Name: plugin.Name, Import: plugin.Import, LatestVersion: "v0.2.0", Versions: plugin.Versions, Stars: 23},
+		},
+		{
+			desc:    "unknown plugin",
+			event:   "star",
+			payload: `{"action": "created", "repository": {"full_name": "traefik/other", "stargazers_count": 23}}`,
+			expCode: http.StatusNoContent,
+		},
+		{
+			desc:    "ignored event",
+			event:   "ping",
+			payload: `{"zen": "Keep it logically awesome."}`,
+			expCode: http.StatusNoContent,
+		},
+		{
+			desc:      "invalid signature",
+			event:     "star",
+			payload:   `{"action": "created", "repository": {"full_name": "traefik/plugindemo", "stargazers_count": 23}}`,
+			signature: "sha256=" + strings.Repeat("0", 64),
+			expCode:   http.StatusUnauthorized,
+		},
+		{
+			desc:      "SHA-1 signature",
+			event:     "star",
+			payload:   `{"action": "created", "repository": {"full_name": "traefik/plugindemo", "stargazers_count": 23}}`,
+			signature: "sha1=" + strings.Repeat("0", 40),
+			expCode:   http.StatusUnauthorized,
+		},
+		{
+			desc:    "invalid payload",
+			event:   "star",
+			payload: `{`,
+			expCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var updated *db.Plugin
+
+			testDB := mockDB{
+				getByNameFn: func(_ context.Context, name string, _ bool) (db.Plugin, error) {
+					if name != plugin.Name {
+						return db.Plugin{}, db.NotFoundError{}
+					}
+
+					// The versions are copied, the handler must not modify the stored plugin.
+					found := plugin
+					found.Versions = append([]string(nil), plugin.Versions...)
+
+					return found, nil
+				},
+				updateFn: func(_ context.Context, id string, p db.Plugin) (db.Plugin, error) {
+					assert.Equal(t, plugin.ID, id)
+
+					updated = &p
+
+					return p, nil
+				},
+			}
+
+			queue := &fakeQueue{}
+
+			signature := test.signature
+			if signature == "" {
+				signature = sign(test.payload)
+			}
+
+			rw := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/hooks/github", strings.NewReader(test.payload))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(webhookEventHeader, test.event)
+			req.Header.Set(webhookSignatureHeader, signature)
+
+			manifests := fakeManifests{
+				"v0.3.0":     nil,
+				"v0.3.0-rc1": nil,
+				"v0.4.0":     {"missing manifest .traefik.yml at v0.4.0"},
+			}
+
+			New(testDB, nil, WithGitHubWebhook(testWebhookSecret, manifests), WithJobQueue(queue)).GitHubWebhook(rw, req)
+
+			assert.Equal(t, test.expCode, rw.Code)
+			assert.Equal(t, test.expected, updated)
+			assert.Equal(t, test.expJobs, queue.jobs)
+		})
+	}
+}
+
+func TestHandlers_GitHubWebhook_disabled(t *testing.T) {
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/hooks/github", strings.NewReader(`{}`))
+	req.Header.Set(webhookEventHeader, "ping")
+	req.Header.Set(webhookSignatureHeader, sign(`{}`))
+
+	New(mockDB{}, nil).GitHubWebhook(rw, req)
+
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+}
+
+func sign(payload string) string {
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write([]byte(payload))
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -2,9 +2,11 @@ package healthcheck
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 
 	"github.com/rs/zerolog/log"
+	"github.com/traefik/plugin-service/pkg/upstream"
 )
 
 // Pinger is capable of pinging.
@@ -15,6 +17,13 @@ type Pinger interface {
 // Client is the healthcheck client.
 type Client struct {
 	DB Pinger
+	// Breakers the circuit breakers of the upstreams, reported without affecting the readiness:
+	// the service still serves the requests which don't need a failing upstream.
+	Breakers []*upstream.Breaker
+}
+
+type readiness struct {
+	Upstreams []upstream.Status `json:"upstreams"`
 }
 
 // Live is the liveness handler.
@@ -30,4 +39,15 @@ func (c *Client) Ready(rw http.ResponseWriter, req *http.Request) {
 
 		return
 	}
+
+	status := readiness{Upstreams: make([]upstream.Status, 0, len(c.Breakers))}
+	for _, breaker := range c.Breakers {
+		status.Upstreams = append(status.Upstreams, breaker.Status())
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(rw).Encode(status); err != nil {
+		log.Error().Err(err).Msg("failed to write readiness")
+	}
 }
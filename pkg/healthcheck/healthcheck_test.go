@@ -2,12 +2,16 @@ package healthcheck
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/plugin-service/pkg/upstream"
 )
 
 func TestClient_Live(t *testing.T) {
@@ -58,6 +62,46 @@ func TestClient_Ready(t *testing.T) {
 	}
 }
 
+func TestClient_Ready_breakers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.WriteHeader(http.StatusBadGateway)
+	}))
+	t.Cleanup(server.Close)
+
+	// The GitHub breaker opens on the first failure.
+	transport := upstream.NewTransport("github", nil, upstream.Config{FailureThreshold: 1, Cooldown: time.Hour})
+
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+	require.NoError(t, err)
+
+	_ = resp.Body.Close()
+
+	client := Client{
+		DB:       pingerMock(func(_ context.Context) error { return nil }),
+		Breakers: []*upstream.Breaker{upstream.NewBreaker("goproxy", 5, time.Minute), transport.Breaker()},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/ready", http.NoBody)
+	rw := httptest.NewRecorder()
+
+	client.Ready(rw, req)
+
+	// An open breaker doesn't make the service unready.
+	assert.Equal(t, http.StatusOK, rw.Code)
+
+	var status readiness
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &status))
+
+	require.Len(t, status.Upstreams, 2)
+
+	assert.Equal(t, upstream.Status{Name: "goproxy", State: "closed"}, status.Upstreams[0])
+
+	assert.Equal(t, "github", status.Upstreams[1].Name)
+	assert.Equal(t, "open", status.Upstreams[1].State)
+	assert.Equal(t, 1, status.Upstreams[1].Failures)
+	assert.NotNil(t, status.Upstreams[1].RetryAt)
+}
+
 type pingerMock func(ctx context.Context) error
 
 // Ping calls the mock.
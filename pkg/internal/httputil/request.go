@@ -0,0 +1,20 @@
+// Package httputil provides the helpers shared by the HTTP transports of the upstreams.
+package httputil
+
+import "net/http"
+
+// IsReplayable returns true if the request can be sent again.
+func IsReplayable(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+
+	return req.Body == nil || req.Body == http.NoBody
+}
+
+// CloseBody closes the body of a request which is not sent, as a RoundTripper must.
+func CloseBody(req *http.Request) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+}
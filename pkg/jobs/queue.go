@@ -0,0 +1,300 @@
+// Package jobs runs asynchronous tasks on plugin versions.
+//
+// The jobs are persisted in a Store, and leased by the workers of every replica: a job is run by a single worker at a time,
+// and a job whose worker has crashed is leased again once its lease has expired.
+// A failed job is retried with an exponential backoff, and moved to the dead letters after its last attempt,
+// or right away when its error is permanent.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/plugin-service/pkg/db"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Handler runs a job.
+type Handler func(ctx context.Context, job db.Job) error
+
+// permanentError an error which won't be fixed by retrying the job.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks the error of a job as permanent: the job is moved to the dead letters without being retried.
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// IsPermanent returns true if the error of a job is permanent.
+func IsPermanent(err error) bool {
+	return errors.As(err, &permanentError{})
+}
+
+// Store persists the jobs.
+type Store interface {
+	CreateJob(ctx context.Context, job db.Job) (db.Job, error)
+	LeaseJob(ctx context.Context, owner string, kinds []string, lease time.Duration) (db.Job, error)
+	CompleteJob(ctx context.Context, id, owner string) (db.Job, error)
+	FailJob(ctx context.Context, id, owner, reason string, retryAt time.Time, dead bool) (db.Job, error)
+	RetryJob(ctx context.Context, id string) (db.Job, error)
+	GetJob(ctx context.Context, id string) (db.Job, error)
+	ListJobs(ctx context.Context, filter db.JobFilter) ([]db.Job, error)
+}
+
+// Config configures the queue.
+type Config struct {
+	// Owner identifies the replica in the job leases, defaults to the hostname.
+	Owner string
+	// Lease the duration after which a running job is considered abandoned, and leased again.
+	Lease time.Duration
+	// PollInterval the delay between two leases when no job is ready.
+	PollInterval time.Duration
+	// MaxAttempts the number of attempts of a job before it is moved to the dead letters.
+	MaxAttempts int
+	// MinBackoff the delay before the first retry, doubled on each attempt up to MaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// DefaultConfig returns the default queue configuration.
+func DefaultConfig() Config {
+	return Config{
+		Lease:        5 * time.Minute,
+		PollInterval: time.Second,
+		MaxAttempts:  5,
+		MinBackoff:   30 * time.Second,
+		MaxBackoff:   time.Hour,
+	}
+}
+
+// Queue runs the jobs persisted in a Store.
+type Queue struct {
+	store  Store
+	cfg    Config
+	tracer trace.Tracer
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+// NewQueue creates a Queue.
+func NewQueue(store Store, cfg Config) (*Queue, error) {
+	if cfg.Owner == "" {
+		owner, err := newOwner()
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.Owner = owner
+	}
+
+	return &Queue{
+		store:    store,
+		cfg:      cfg,
+		tracer:   otel.Tracer("jobs"),
+		handlers: make(map[string]Handler),
+	}, nil
+}
+
+// Handle registers the handler of a job kind.
+// Only the jobs of the registered kinds are leased.
+func (q *Queue) Handle(kind string, handler Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.handlers[kind] = handler
+}
+
+// Enqueue adds a job to the queue.
+func (q *Queue) Enqueue(ctx context.Context, job db.Job) (db.Job, error) {
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = q.cfg.MaxAttempts
+	}
+
+	return q.store.CreateJob(ctx, job)
+}
+
+// GetJob gets a job.
+func (q *Queue) GetJob(ctx context.Context, id string) (db.Job, error) {
+	return q.store.GetJob(ctx, id)
+}
+
+// ListJobs lists the jobs matching the filter, the most recent first.
+func (q *Queue) ListJobs(ctx context.Context, filter db.JobFilter) ([]db.Job, error) {
+	return q.store.ListJobs(ctx, filter)
+}
+
+// RetryJob moves a dead job back to the pending jobs.
+func (q *Queue) RetryJob(ctx context.Context, id string) (db.Job, error) {
+	return q.store.RetryJob(ctx, id)
+}
+
+// Run runs the jobs with the given number of workers, until the context is canceled.
+func (q *Queue) Run(ctx context.Context, workers int) {
+	var wg sync.WaitGroup
+
+	for range max(workers, 1) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			q.work(ctx)
+		}()
+	}
+
+	wg.Wait()
+}
+
+func (q *Queue) work(ctx context.Context) {
+	for {
+		ran, err := q.RunNext(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("Unable to run job")
+		}
+
+		if ran {
+			continue
+		}
+
+		timer := time.NewTimer(q.cfg.PollInterval)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// RunNext leases and runs the next job ready to run, and returns false when there is none.
+func (q *Queue) RunNext(ctx context.Context) (bool, error) {
+	if ctx.Err() != nil {
+		return false, nil
+	}
+
+	q.mu.RLock()
+	kinds := make([]string, 0, len(q.handlers))
+	for kind := range q.handlers {
+		kinds = append(kinds, kind)
+	}
+	q.mu.RUnlock()
+
+	if len(kinds) == 0 {
+		return false, nil
+	}
+
+	slices.Sort(kinds)
+
+	job, err := q.store.LeaseJob(ctx, q.cfg.Owner, kinds, q.cfg.Lease)
+	if err != nil {
+		if errors.As(err, &db.NotFoundError{}) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("lease job: %w", err)
+	}
+
+	q.run(ctx, job)
+
+	return true, nil
+}
+
+func (q *Queue) run(ctx context.Context, job db.Job) {
+	ctx, span := q.tracer.Start(ctx, "jobs_run")
+	defer span.End()
+
+	logger := log.With().
+		Str("job_id", job.ID).
+		Str("job_kind", job.Kind).
+		Str("module_name", job.Module).
+		Str("module_version", job.Version).
+		Int("job_attempt", job.Attempts).
+		Logger()
+
+	q.mu.RLock()
+	handler := q.handlers[job.Kind]
+	q.mu.RUnlock()
+
+	// The job must not outlive its lease, it would be run by another worker.
+	runCtx, cancel := context.WithTimeout(ctx, q.cfg.Lease)
+	err := handler(runCtx, job)
+	cancel()
+
+	// The job is released even when the queue is stopping.
+	releaseCtx := context.WithoutCancel(ctx)
+
+	if err == nil {
+		if _, err = q.store.CompleteJob(releaseCtx, job.ID, q.cfg.Owner); err != nil {
+			span.RecordError(err)
+			logger.Error().Err(err).Msg("Unable to complete job")
+		}
+
+		return
+	}
+
+	span.RecordError(err)
+
+	dead := job.Attempts >= job.MaxAttempts || IsPermanent(err)
+	retryAt := time.Now().Add(q.backoff(job.Attempts))
+
+	if dead {
+		logger.Error().Err(err).Msg("Job failed, moved to the dead letters")
+	} else {
+		logger.Warn().Err(err).Time("retry_at", retryAt).Msg("Job failed, will be retried")
+	}
+
+	if _, err = q.store.FailJob(releaseCtx, job.ID, q.cfg.Owner, err.Error(), retryAt, dead); err != nil {
+		span.RecordError(err)
+		logger.Error().Err(err).Msg("Unable to record job failure")
+	}
+}
+
+// backoff returns the delay before the next attempt: MinBackoff doubled on each attempt, up to MaxBackoff.
+func (q *Queue) backoff(attempts int) time.Duration {
+	delay := q.cfg.MinBackoff
+
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+
+		if delay >= q.cfg.MaxBackoff {
+			return q.cfg.MaxBackoff
+		}
+	}
+
+	return min(delay, q.cfg.MaxBackoff)
+}
+
+func newOwner() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("get hostname: %w", err)
+	}
+
+	raw := make([]byte, 4)
+	if _, err = rand.Read(raw); err != nil {
+		return "", fmt.Errorf("generate job lease owner: %w", err)
+	}
+
+	return hostname + "-" + hex.EncodeToString(raw), nil
+}
@@ -0,0 +1,371 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+// memoryStore is a Store keeping the jobs in memory.
+type memoryStore struct {
+	mu   sync.Mutex
+	jobs []db.Job
+}
+
+func (s *memoryStore) CreateJob(_ context.Context, job db.Job) (db.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	job.ID = fmt.Sprint(len(s.jobs) + 1)
+	job.Status = db.JobPending
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	if job.RunAt.IsZero() {
+		job.RunAt = now
+	}
+
+	s.jobs = append(s.jobs, job)
+
+	return job, nil
+}
+
+func (s *memoryStore) LeaseJob(_ context.Context, owner string, kinds []string, lease time.Duration) (db.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	for i, job := range s.jobs {
+		if !slices.Contains(kinds, job.Kind) {
+			continue
+		}
+
+		ready := job.Status == db.JobPending && !job.RunAt.After(now)
+		abandoned := job.Status == db.JobRunning && !job.LeaseUntil.After(now)
+
+		if !ready && !abandoned {
+			continue
+		}
+
+		s.jobs[i].Status = db.JobRunning
+		s.jobs[i].LeaseOwner = owner
+		s.jobs[i].LeaseUntil = now.Add(lease)
+		s.jobs[i].Attempts++
+
+		return s.jobs[i], nil
+	}
+
+	return db.Job{}, db.NotFoundError{}
+}
+
+func (s *memoryStore) CompleteJob(_ context.Context, id, owner string) (db.Job, error) {
+	return s.update(id, owner, func(job *db.Job) {
+		job.Status = db.JobSucceeded
+		job.Error = ""
+	})
+}
+
+func (s *memoryStore) FailJob(_ context.Context, id, owner, reason string, retryAt time.Time, dead bool) (db.Job, error) {
+	return s.update(id, owner, func(job *db.Job) {
+		job.Error = reason
+		job.Status = db.JobPending
+		job.RunAt = retryAt
+
+		if dead {
+			job.Status = db.JobDead
+		}
+	})
+}
+
+func (s *memoryStore) update(id, owner string, fn func(job *db.Job)) (db.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, job := range s.jobs {
+		if job.ID != id {
+			continue
+		}
+
+		if job.Status != db.JobRunning || job.LeaseOwner != owner {
+			return db.Job{}, db.ConflictError{}
+		}
+
+		fn(&s.jobs[i])
+		s.jobs[i].LeaseOwner = ""
+		s.jobs[i].LeaseUntil = time.Time{}
+
+		return s.jobs[i], nil
+	}
+
+	return db.Job{}, db.NotFoundError{}
+}
+
+func (s *memoryStore) RetryJob(_ context.Context, id string) (db.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, job := range s.jobs {
+		if job.ID == id && job.Status == db.JobDead {
+			s.jobs[i].Status = db.JobPending
+			s.jobs[i].Attempts = 0
+			s.jobs[i].RunAt = time.Now()
+
+			return s.jobs[i], nil
+		}
+	}
+
+	return db.Job{}, db.NotFoundError{}
+}
+
+func (s *memoryStore) GetJob(_ context.Context, id string) (db.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, job := range s.jobs {
+		if job.ID == id {
+			return job, nil
+		}
+	}
+
+	return db.Job{}, db.NotFoundError{}
+}
+
+func (s *memoryStore) ListJobs(_ context.Context, filter db.JobFilter) ([]db.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var jobs []db.Job
+
+	for _, job := range slices.Backward(s.jobs) {
+		if (filter.Module == "" || job.Module == filter.Module) && (filter.Status == "" || job.Status == filter.Status) {
+			jobs = append(jobs, job)
+		}
+	}
+
+	return jobs, nil
+}
+
+func TestQueue_RunNext(t *testing.T) {
+	ctx := context.Background()
+
+	store := &memoryStore{}
+
+	queue, err := NewQueue(store, Config{Owner: "test", Lease: time.Minute, MaxAttempts: 2, MinBackoff: 0, MaxBackoff: time.Hour})
+	require.NoError(t, err)
+
+	// No handler registered, no job is leased.
+	succeeded, err := queue.Enqueue(ctx, db.Job{Kind: db.JobPrewarm, Module: "github.com/traefik/plugindemo", Version: "v0.2.1"})
+	require.NoError(t, err)
+
+	assert.Equal(t, db.JobPending, succeeded.Status)
+	assert.Equal(t, 2, succeeded.MaxAttempts)
+
+	ran, err := queue.RunNext(ctx)
+	require.NoError(t, err)
+	assert.False(t, ran)
+
+	failures := 0
+
+	queue.Handle(db.JobPrewarm, func(_ context.Context, job db.Job) error {
+		if job.Version == "v0.1.0" {
+			failures++
+
+			return errors.New("boom")
+		}
+
+		return nil
+	})
+
+	failed, err := queue.Enqueue(ctx, db.Job{Kind: db.JobPrewarm, Module: "github.com/traefik/plugindemo", Version: "v0.1.0"})
+	require.NoError(t, err)
+
+	for range 3 {
+		ran, err = queue.RunNext(ctx)
+		require.NoError(t, err)
+		assert.True(t, ran)
+	}
+
+	ran, err = queue.RunNext(ctx)
+	require.NoError(t, err)
+	assert.False(t, ran)
+
+	job, err := queue.GetJob(ctx, succeeded.ID)
+	require.NoError(t, err)
+	assert.Equal(t, db.JobSucceeded, job.Status)
+	assert.Equal(t, 1, job.Attempts)
+
+	job, err = queue.GetJob(ctx, failed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, db.JobDead, job.Status)
+	assert.Equal(t, 2, job.Attempts)
+	assert.Equal(t, "boom", job.Error)
+	assert.Equal(t, 2, failures)
+
+	dead, err := queue.ListJobs(ctx, db.JobFilter{Status: db.JobDead})
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, failed.ID, dead[0].ID)
+
+	// A retried dead job gets new attempts.
+	job, err = queue.RetryJob(ctx, failed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, db.JobPending, job.Status)
+	assert.Equal(t, 0, job.Attempts)
+
+	ran, err = queue.RunNext(ctx)
+	require.NoError(t, err)
+	assert.True(t, ran)
+	assert.Equal(t, 3, failures)
+}
+
+func TestQueue_RunNext_backoff(t *testing.T) {
+	ctx := context.Background()
+
+	store := &memoryStore{}
+
+	queue, err := NewQueue(store, Config{Owner: "test", Lease: time.Minute, MaxAttempts: 5, MinBackoff: time.Hour, MaxBackoff: 2 * time.Hour})
+	require.NoError(t, err)
+
+	queue.Handle(db.JobPrewarm, func(_ context.Context, _ db.Job) error {
+		return errors.New("boom")
+	})
+
+	job, err := queue.Enqueue(ctx, db.Job{Kind: db.JobPrewarm, Module: "github.com/traefik/plugindemo", Version: "v0.1.0"})
+	require.NoError(t, err)
+
+	ran, err := queue.RunNext(ctx)
+	require.NoError(t, err)
+	assert.True(t, ran)
+
+	// The failed job is delayed.
+	ran, err = queue.RunNext(ctx)
+	require.NoError(t, err)
+	assert.False(t, ran)
+
+	job, err = queue.GetJob(ctx, job.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, db.JobPending, job.Status)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), job.RunAt, time.Minute)
+}
+
+func TestQueue_RunNext_permanent(t *testing.T) {
+	ctx := context.Background()
+
+	store := &memoryStore{}
+
+	queue, err := NewQueue(store, Config{Owner: "test", Lease: time.Minute, MaxAttempts: 5, MinBackoff: 0, MaxBackoff: time.Hour})
+	require.NoError(t, err)
+
+	queue.Handle(db.JobPrewarm, func(_ context.Context, _ db.Job) error {
+		return fmt.Errorf("prewarm: %w", Permanent(errors.New("boom")))
+	})
+
+	job, err := queue.Enqueue(ctx, db.Job{Kind: db.JobPrewarm, Module: "github.com/traefik/plugindemo", Version: "v0.1.0"})
+	require.NoError(t, err)
+
+	ran, err := queue.RunNext(ctx)
+	require.NoError(t, err)
+	assert.True(t, ran)
+
+	// The job isn't retried.
+	ran, err = queue.RunNext(ctx)
+	require.NoError(t, err)
+	assert.False(t, ran)
+
+	job, err = queue.GetJob(ctx, job.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, db.JobDead, job.Status)
+	assert.Equal(t, 1, job.Attempts)
+	assert.Equal(t, "prewarm: boom", job.Error)
+}
+
+func TestQueue_RunNext_abandoned(t *testing.T) {
+	ctx := context.Background()
+
+	store := &memoryStore{}
+
+	job, err := store.CreateJob(ctx, db.Job{Kind: db.JobPrewarm, Module: "github.com/traefik/plugindemo", Version: "v0.1.0", MaxAttempts: 5})
+	require.NoError(t, err)
+
+	// Leased by a replica which has crashed.
+	_, err = store.LeaseJob(ctx, "crashed", []string{db.JobPrewarm}, 0)
+	require.NoError(t, err)
+
+	queue, err := NewQueue(store, Config{Owner: "test", Lease: time.Minute, MaxAttempts: 5})
+	require.NoError(t, err)
+
+	queue.Handle(db.JobPrewarm, func(_ context.Context, _ db.Job) error { return nil })
+
+	ran, err := queue.RunNext(ctx)
+	require.NoError(t, err)
+	assert.True(t, ran)
+
+	job, err = queue.GetJob(ctx, job.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, db.JobSucceeded, job.Status)
+	assert.Equal(t, 2, job.Attempts)
+}
+
+func TestQueue_backoff(t *testing.T) {
+	queue := &Queue{cfg: Config{MinBackoff: 30 * time.Second, MaxBackoff: 5 * time.Minute}}
+
+	testCases := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{attempts: 1, expected: 30 * time.Second},
+		{attempts: 2, expected: time.Minute},
+		{attempts: 3, expected: 2 * time.Minute},
+		{attempts: 4, expected: 4 * time.Minute},
+		{attempts: 5, expected: 5 * time.Minute},
+		{attempts: 50, expected: 5 * time.Minute},
+	}
+
+	for _, test := range testCases {
+		t.Run(fmt.Sprint(test.attempts), func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, queue.backoff(test.attempts))
+		})
+	}
+}
+
+func TestQueue_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	store := &memoryStore{}
+
+	cfg := DefaultConfig()
+	cfg.PollInterval = 10 * time.Millisecond
+
+	queue, err := NewQueue(store, cfg)
+	require.NoError(t, err)
+
+	queue.Handle(db.JobPrewarm, func(_ context.Context, _ db.Job) error { return nil })
+
+	go queue.Run(ctx, 2)
+
+	job, err := queue.Enqueue(ctx, db.Job{Kind: db.JobPrewarm, Module: "github.com/traefik/plugindemo", Version: "v0.2.1"})
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		got, _ := queue.GetJob(ctx, job.ID)
+
+		return got.Status == db.JobSucceeded
+	}, time.Second, 10*time.Millisecond)
+}
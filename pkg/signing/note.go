@@ -0,0 +1,45 @@
+package signing
+
+import (
+	"crypto/ed25519"
+
+	"golang.org/x/mod/sumdb/note"
+)
+
+// noteSigner signs notes (as used by the Go checksum database) with the service key.
+type noteSigner struct {
+	name string
+	hash uint32
+	key  ed25519.PrivateKey
+}
+
+// NoteSigner returns a signer of notes named name, using the Ed25519 key of the signer.
+func (s *Signer) NoteSigner(name string) (note.Signer, error) {
+	verifier, err := s.NoteVerifier(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &noteSigner{name: name, hash: verifier.KeyHash(), key: s.key}, nil
+}
+
+// NoteVerifierKey returns the verifier key of the notes signed by NoteSigner, in the format expected by note.NewVerifier.
+func (s *Signer) NoteVerifierKey(name string) (string, error) {
+	return note.NewEd25519VerifierKey(name, s.PublicKey())
+}
+
+// NoteVerifier returns the verifier of the notes signed by NoteSigner.
+func (s *Signer) NoteVerifier(name string) (note.Verifier, error) {
+	vkey, err := s.NoteVerifierKey(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return note.NewVerifier(vkey)
+}
+
+func (s *noteSigner) Name() string { return s.name }
+
+func (s *noteSigner) KeyHash() uint32 { return s.hash }
+
+func (s *noteSigner) Sign(msg []byte) ([]byte, error) { return ed25519.Sign(s.key, msg), nil }
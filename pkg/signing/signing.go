@@ -0,0 +1,115 @@
+// Package signing signs statements about the plugin archives served by the service.
+//
+// A statement is the JSON object {"module":"...","version":"...","sha256":"..."} (fields in that order, no spaces),
+// and its signature is the Ed25519 signature of these bytes.
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Statement A statement about a plugin archive.
+type Statement struct {
+	Module  string `json:"module"`
+	Version string `json:"version"`
+	SHA256  string `json:"sha256"`
+}
+
+// Bytes returns the canonical encoding of the statement.
+func (s Statement) Bytes() ([]byte, error) {
+	return json.Marshal(s)
+}
+
+// Signer signs statements with an Ed25519 key.
+type Signer struct {
+	key   ed25519.PrivateKey
+	keyID string
+}
+
+// NewSigner creates a Signer.
+func NewSigner(key ed25519.PrivateKey) *Signer {
+	return &Signer{
+		key:   key,
+		keyID: KeyID(key.Public().(ed25519.PublicKey)),
+	}
+}
+
+// LoadSigner loads a PEM (PKCS #8) Ed25519 private key, as generated by `openssl genpkey -algorithm ed25519`.
+func LoadSigner(path string) (*Signer, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T, expected Ed25519", key)
+	}
+
+	return NewSigner(edKey), nil
+}
+
+// KeyID returns the ID of the signing key.
+func (s *Signer) KeyID() string {
+	return s.keyID
+}
+
+// PublicKey returns the public key used to verify the signatures.
+func (s *Signer) PublicKey() ed25519.PublicKey {
+	return s.key.Public().(ed25519.PublicKey)
+}
+
+// Sign signs a statement, and returns the base64 encoded signature.
+func (s *Signer) Sign(statement Statement) (string, error) {
+	msg, err := statement.Bytes()
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, msg)), nil
+}
+
+// Verify verifies the base64 encoded signature of a statement.
+func Verify(key ed25519.PublicKey, statement Statement, signature string) error {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+
+	msg, err := statement.Bytes()
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(key, msg, sig) {
+		return errors.New("invalid signature")
+	}
+
+	return nil
+}
+
+// KeyID returns the ID of a public key: the first 8 bytes of the SHA-256 of the key, hex encoded.
+func KeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+
+	return hex.EncodeToString(sum[:8])
+}
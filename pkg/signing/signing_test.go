@@ -0,0 +1,88 @@
+package signing
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/sumdb/note"
+)
+
+func TestStatement_Bytes(t *testing.T) {
+	statement := Statement{Module: "github.com/traefik/plugindemo", Version: "v0.2.1", SHA256: "123"}
+
+	got, err := statement.Bytes()
+	require.NoError(t, err)
+
+	assert.Equal(t, `{"module":"github.com/traefik/plugindemo","version":"v0.2.1","sha256":"123"}`, string(got))
+}
+
+func TestSigner_Sign(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, ed25519.SeedSize))
+	signer := NewSigner(key)
+
+	statement := Statement{Module: "github.com/traefik/plugindemo", Version: "v0.2.1", SHA256: "123"}
+
+	signature, err := signer.Sign(statement)
+	require.NoError(t, err)
+
+	require.NoError(t, Verify(signer.PublicKey(), statement, signature))
+
+	statement.SHA256 = "456"
+	require.EqualError(t, Verify(signer.PublicKey(), statement, signature), "invalid signature")
+}
+
+func TestLoadSigner(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, ed25519.SeedSize))
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "signing.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+
+	signer, err := LoadSigner(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, key.Public(), signer.PublicKey())
+	assert.Equal(t, KeyID(key.Public().(ed25519.PublicKey)), signer.KeyID())
+	assert.Len(t, signer.KeyID(), 16)
+
+	invalid := filepath.Join(t.TempDir(), "invalid.pem")
+	require.NoError(t, os.WriteFile(invalid, []byte("not a key"), 0o600))
+
+	_, err = LoadSigner(invalid)
+	require.EqualError(t, err, "no PEM block found")
+}
+
+func TestSigner_NoteSigner(t *testing.T) {
+	signer := NewSigner(ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, ed25519.SeedSize)))
+
+	noteSigner, err := signer.NoteSigner("plugins.traefik.io")
+	require.NoError(t, err)
+
+	msg, err := note.Sign(&note.Note{Text: "hello\n"}, noteSigner)
+	require.NoError(t, err)
+
+	verifier, err := signer.NoteVerifier("plugins.traefik.io")
+	require.NoError(t, err)
+
+	n, err := note.Open(msg, note.VerifierList(verifier))
+	require.NoError(t, err)
+
+	assert.Equal(t, "hello\n", n.Text)
+
+	other := NewSigner(ed25519.NewKeyFromSeed(bytes.Repeat([]byte{2}, ed25519.SeedSize)))
+
+	otherVerifier, err := other.NoteVerifier("plugins.traefik.io")
+	require.NoError(t, err)
+
+	_, err = note.Open(msg, note.VerifierList(otherVerifier))
+	require.Error(t, err)
+}
@@ -0,0 +1,235 @@
+// Package transparency implements the append-only transparency log of the plugin hashes.
+//
+// The log is a Merkle tree, as used by the Go checksum database (see golang.org/x/mod/sumdb/tlog):
+// every first seen plugin version is appended as a record "<module> <version> sha256:<hash>\n",
+// and the tree heads are signed notes, allowing clients to verify that a recorded hash has never been changed.
+// A hash reset appends a new record of the plugin version, the previous records stay in the log.
+package transparency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/traefik/plugin-service/pkg/db"
+	"golang.org/x/mod/sumdb/note"
+	"golang.org/x/mod/sumdb/tlog"
+)
+
+// maxAppendAttempts is the number of attempts to append a record while other records are appended concurrently.
+const maxAppendAttempts = 10
+
+// ErrUnsigned is returned when signing a tree head without a signer.
+var ErrUnsigned = errors.New("transparency log signing is not configured")
+
+// ErrOutOfRange is returned when a proof is requested for records or trees outside the log.
+var ErrOutOfRange = errors.New("out of range")
+
+// Store stores the records of the log.
+type Store interface {
+	AppendLogRecord(ctx context.Context, record db.LogRecord) (db.LogRecord, error)
+	GetLogSize(ctx context.Context) (int64, error)
+	// GetLogRecord returns the newest record of a plugin version.
+	GetLogRecord(ctx context.Context, module, version string) (db.LogRecord, error)
+	GetLogHashes(ctx context.Context, indexes []int64) ([]db.LogHash, error)
+}
+
+// Log is the transparency log.
+type Log struct {
+	store  Store
+	signer note.Signer
+}
+
+// New creates a Log. Without signer, records are still appended, but tree heads can't be served.
+func New(store Store, signer note.Signer) *Log {
+	return &Log{store: store, signer: signer}
+}
+
+// RecordText returns the text of the record of a plugin version.
+func RecordText(module, version, hash string) []byte {
+	return []byte(fmt.Sprintf("%s %s sha256:%s\n", module, version, hash))
+}
+
+// Append appends the hash of a plugin version to the log.
+// Appending an already recorded plugin version returns the existing record, or an error if the hashes differ.
+func (l *Log) Append(ctx context.Context, module, version, hash string) (db.LogRecord, error) {
+	return l.append(ctx, module, version, hash, false)
+}
+
+// AppendReset appends the hash set by a reset of a plugin version to the log.
+// The previous records of the plugin version are kept, the lookups return the newest one.
+func (l *Log) AppendReset(ctx context.Context, module, version, hash string) (db.LogRecord, error) {
+	return l.append(ctx, module, version, hash, true)
+}
+
+func (l *Log) append(ctx context.Context, module, version, hash string, reset bool) (db.LogRecord, error) {
+	text := RecordText(module, version, hash)
+
+	for range maxAppendAttempts {
+		var previous *int64
+
+		record, err := l.store.GetLogRecord(ctx, module, version)
+		switch {
+		case err == nil && record.Hash == hash:
+			return record, nil
+
+		case err == nil && !reset:
+			return record, fmt.Errorf("%s@%s is already recorded with hash %s", module, version, record.Hash)
+
+		case err == nil:
+			index := record.Index
+			previous = &index
+
+		case !errors.As(err, &db.NotFoundError{}):
+			return db.LogRecord{}, err
+		}
+
+		size, err := l.store.GetLogSize(ctx)
+		if err != nil {
+			return db.LogRecord{}, err
+		}
+
+		hashes, err := tlog.StoredHashes(size, text, l.hashReader(ctx))
+		if err != nil {
+			return db.LogRecord{}, fmt.Errorf("unable to compute tree hashes: %w", err)
+		}
+
+		record = db.LogRecord{
+			Index:    size,
+			Module:   module,
+			Version:  version,
+			Hash:     hash,
+			Previous: previous,
+		}
+
+		first := tlog.StoredHashIndex(0, size)
+		for i, h := range hashes {
+			record.TreeHashes = append(record.TreeHashes, db.LogHash{Index: first + int64(i), Hash: h[:]})
+		}
+
+		record, err = l.store.AppendLogRecord(ctx, record)
+		if err == nil {
+			return record, nil
+		}
+
+		if !errors.As(err, &db.ConflictError{}) {
+			return db.LogRecord{}, err
+		}
+
+		// Another record has been appended at the same index, or the same plugin version has been recorded concurrently.
+	}
+
+	return db.LogRecord{}, fmt.Errorf("unable to append %s@%s: too many concurrent appends", module, version)
+}
+
+// SignedTreeHead returns the current tree head, as a signed note.
+func (l *Log) SignedTreeHead(ctx context.Context) ([]byte, error) {
+	if l.signer == nil {
+		return nil, ErrUnsigned
+	}
+
+	size, err := l.store.GetLogSize(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := tlog.TreeHash(size, l.hashReader(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("unable to compute tree hash: %w", err)
+	}
+
+	text := tlog.FormatTree(tlog.Tree{N: size, Hash: hash})
+
+	return note.Sign(&note.Note{Text: string(text)}, l.signer)
+}
+
+// Lookup returns the record of a plugin version followed by a signed tree head including it,
+// in the format of the lookup endpoint of the Go checksum database.
+func (l *Log) Lookup(ctx context.Context, module, version string) ([]byte, error) {
+	if l.signer == nil {
+		return nil, ErrUnsigned
+	}
+
+	record, err := l.store.GetLogRecord(ctx, module, version)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := tlog.FormatRecord(record.Index, RecordText(record.Module, record.Version, record.Hash))
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := l.SignedTreeHead(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(msg, head...), nil
+}
+
+// ProveRecord returns the proof that the record at index is contained in the tree of size treeSize.
+func (l *Log) ProveRecord(ctx context.Context, index, treeSize int64) (tlog.RecordProof, error) {
+	if err := l.checkTreeSize(ctx, treeSize); err != nil {
+		return nil, err
+	}
+
+	if index < 0 || index >= treeSize {
+		return nil, fmt.Errorf("record %d of tree %d: %w", index, treeSize, ErrOutOfRange)
+	}
+
+	return tlog.ProveRecord(treeSize, index, l.hashReader(ctx))
+}
+
+// ProveTree returns the proof that the tree of size oldSize is a prefix of the tree of size treeSize.
+func (l *Log) ProveTree(ctx context.Context, treeSize, oldSize int64) (tlog.TreeProof, error) {
+	if err := l.checkTreeSize(ctx, treeSize); err != nil {
+		return nil, err
+	}
+
+	if oldSize < 1 || oldSize > treeSize {
+		return nil, fmt.Errorf("tree %d of tree %d: %w", oldSize, treeSize, ErrOutOfRange)
+	}
+
+	return tlog.ProveTree(treeSize, oldSize, l.hashReader(ctx))
+}
+
+func (l *Log) checkTreeSize(ctx context.Context, treeSize int64) error {
+	size, err := l.store.GetLogSize(ctx)
+	if err != nil {
+		return err
+	}
+
+	if treeSize < 1 || treeSize > size {
+		return fmt.Errorf("tree %d of log %d: %w", treeSize, size, ErrOutOfRange)
+	}
+
+	return nil
+}
+
+func (l *Log) hashReader(ctx context.Context) tlog.HashReader {
+	return tlog.HashReaderFunc(func(indexes []int64) ([]tlog.Hash, error) {
+		stored, err := l.store.GetLogHashes(ctx, indexes)
+		if err != nil {
+			return nil, err
+		}
+
+		byIndex := make(map[int64][]byte, len(stored))
+		for _, h := range stored {
+			byIndex[h.Index] = h.Hash
+		}
+
+		hashes := make([]tlog.Hash, len(indexes))
+
+		for i, index := range indexes {
+			h, ok := byIndex[index]
+			if !ok || len(h) != tlog.HashSize {
+				return nil, fmt.Errorf("missing tree hash %d", index)
+			}
+
+			copy(hashes[i][:], h)
+		}
+
+		return hashes, nil
+	})
+}
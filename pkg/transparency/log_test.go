@@ -0,0 +1,301 @@
+package transparency
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/plugin-service/pkg/db"
+	"github.com/traefik/plugin-service/pkg/signing"
+	"golang.org/x/mod/sumdb/note"
+	"golang.org/x/mod/sumdb/tlog"
+)
+
+const logName = "plugins.traefik.io"
+
+// memoryStore is an in-memory Store.
+type memoryStore struct {
+	mu      sync.Mutex
+	records []db.LogRecord
+	hashes  map[int64][]byte
+
+	// beforeAppend is called before appending a record, to simulate concurrent appends.
+	beforeAppend func(s *memoryStore)
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{hashes: make(map[int64][]byte)}
+}
+
+func (s *memoryStore) AppendLogRecord(_ context.Context, record db.LogRecord) (db.LogRecord, error) {
+	if s.beforeAppend != nil {
+		beforeAppend := s.beforeAppend
+		s.beforeAppend = nil
+		beforeAppend(s)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if record.Index != int64(len(s.records)) {
+		return db.LogRecord{}, db.ConflictError{}
+	}
+
+	for _, r := range s.records {
+		if r.Module == record.Module && r.Version == record.Version && equalPrevious(r.Previous, record.Previous) {
+			return db.LogRecord{}, db.ConflictError{}
+		}
+	}
+
+	for _, h := range record.TreeHashes {
+		s.hashes[h.Index] = h.Hash
+	}
+
+	record.TreeHashes = nil
+	s.records = append(s.records, record)
+
+	return record, nil
+}
+
+func (s *memoryStore) GetLogSize(_ context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return int64(len(s.records)), nil
+}
+
+func (s *memoryStore) GetLogRecord(_ context.Context, module, version string) (db.LogRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range slices.Backward(s.records) {
+		if r.Module == module && r.Version == version {
+			return r, nil
+		}
+	}
+
+	return db.LogRecord{}, db.NotFoundError{}
+}
+
+func equalPrevious(a, b *int64) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
+
+func (s *memoryStore) GetLogHashes(_ context.Context, indexes []int64) ([]db.LogHash, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var hashes []db.LogHash
+	for _, index := range indexes {
+		if h, ok := s.hashes[index]; ok {
+			hashes = append(hashes, db.LogHash{Index: index, Hash: h})
+		}
+	}
+
+	return hashes, nil
+}
+
+func TestLog_Append(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	log := New(store, nil)
+
+	for i := range 5 {
+		record, err := log.Append(ctx, "github.com/traefik/plugindemo", fmt.Sprintf("v0.%d.0", i), fmt.Sprintf("%064d", i))
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(i), record.Index)
+	}
+
+	record, err := log.Append(ctx, "github.com/traefik/plugindemo", "v0.1.0", fmt.Sprintf("%064d", 1))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), record.Index)
+
+	_, err = log.Append(ctx, "github.com/traefik/plugindemo", "v0.1.0", fmt.Sprintf("%064d", 42))
+	require.ErrorContains(t, err, "is already recorded")
+
+	size, err := store.GetLogSize(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), size)
+}
+
+func TestLog_AppendReset(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	log := New(store, nil)
+
+	first, err := log.Append(ctx, "github.com/traefik/plugindemo", "v0.1.0", "123")
+	require.NoError(t, err)
+
+	_, err = log.Append(ctx, "github.com/traefik/plugindemo", "v0.2.0", "456")
+	require.NoError(t, err)
+
+	reset, err := log.AppendReset(ctx, "github.com/traefik/plugindemo", "v0.1.0", "789")
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(2), reset.Index)
+	require.NotNil(t, reset.Previous)
+	assert.Equal(t, first.Index, *reset.Previous)
+
+	// Appending the reset hash again returns the newest record.
+	record, err := log.Append(ctx, "github.com/traefik/plugindemo", "v0.1.0", "789")
+	require.NoError(t, err)
+	assert.Equal(t, reset, record)
+
+	// A reset back to the first hash is a new record.
+	record, err = log.AppendReset(ctx, "github.com/traefik/plugindemo", "v0.1.0", "123")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), record.Index)
+
+	size, err := store.GetLogSize(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), size)
+}
+
+func TestLog_Append_concurrent(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	log := New(store, nil)
+
+	store.beforeAppend = func(s *memoryStore) {
+		_, err := New(s, nil).Append(ctx, "github.com/traefik/other", "v1.0.0", "123")
+		require.NoError(t, err)
+	}
+
+	record, err := log.Append(ctx, "github.com/traefik/plugindemo", "v0.1.0", "456")
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(1), record.Index)
+
+	hash, err := tlog.TreeHash(2, log.hashReader(ctx))
+	require.NoError(t, err)
+
+	expected := tlog.NodeHash(
+		tlog.RecordHash(RecordText("github.com/traefik/other", "v1.0.0", "123")),
+		tlog.RecordHash(RecordText("github.com/traefik/plugindemo", "v0.1.0", "456")),
+	)
+	assert.Equal(t, expected, hash)
+}
+
+func TestLog_proofs(t *testing.T) {
+	ctx := context.Background()
+	log, verifier := newSignedLog(t, newMemoryStore())
+
+	var heads []tlog.Tree
+
+	for i := range 7 {
+		_, err := log.Append(ctx, "github.com/traefik/plugindemo", fmt.Sprintf("v0.%d.0", i), fmt.Sprintf("%064d", i))
+		require.NoError(t, err)
+
+		heads = append(heads, openTreeHead(t, log, verifier))
+	}
+
+	latest := heads[len(heads)-1]
+	assert.Equal(t, int64(7), latest.N)
+
+	for i := range latest.N {
+		proof, err := log.ProveRecord(ctx, i, latest.N)
+		require.NoError(t, err)
+
+		leaf := tlog.RecordHash(RecordText("github.com/traefik/plugindemo", fmt.Sprintf("v0.%d.0", i), fmt.Sprintf("%064d", i)))
+		assert.NoError(t, tlog.CheckRecord(proof, latest.N, latest.Hash, i, leaf))
+	}
+
+	for _, old := range heads {
+		proof, err := log.ProveTree(ctx, latest.N, old.N)
+		require.NoError(t, err)
+
+		assert.NoError(t, tlog.CheckTree(proof, latest.N, latest.Hash, old.N, old.Hash))
+	}
+
+	_, err := log.ProveRecord(ctx, 7, 7)
+	require.ErrorIs(t, err, ErrOutOfRange)
+
+	_, err = log.ProveRecord(ctx, 0, 8)
+	require.ErrorIs(t, err, ErrOutOfRange)
+
+	_, err = log.ProveTree(ctx, 7, 0)
+	require.ErrorIs(t, err, ErrOutOfRange)
+
+	_, err = log.ProveTree(ctx, 5, 6)
+	require.ErrorIs(t, err, ErrOutOfRange)
+}
+
+func TestLog_Lookup(t *testing.T) {
+	ctx := context.Background()
+	log, verifier := newSignedLog(t, newMemoryStore())
+
+	_, err := log.Append(ctx, "github.com/traefik/plugindemo", "v0.1.0", "123")
+	require.NoError(t, err)
+
+	_, err = log.Append(ctx, "github.com/traefik/plugindemo", "v0.2.0", "456")
+	require.NoError(t, err)
+
+	msg, err := log.Lookup(ctx, "github.com/traefik/plugindemo", "v0.2.0")
+	require.NoError(t, err)
+
+	index, text, rest, err := tlog.ParseRecord(msg)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(1), index)
+	assert.Equal(t, "github.com/traefik/plugindemo v0.2.0 sha256:456\n", string(text))
+
+	n, err := note.Open(rest, note.VerifierList(verifier))
+	require.NoError(t, err)
+
+	tree, err := tlog.ParseTree([]byte(n.Text))
+	require.NoError(t, err)
+
+	proof, err := log.ProveRecord(ctx, index, tree.N)
+	require.NoError(t, err)
+
+	assert.NoError(t, tlog.CheckRecord(proof, tree.N, tree.Hash, index, tlog.RecordHash(text)))
+
+	_, err = log.Lookup(ctx, "github.com/traefik/plugindemo", "v0.3.0")
+	require.ErrorAs(t, err, &db.NotFoundError{})
+}
+
+func TestLog_unsigned(t *testing.T) {
+	log := New(newMemoryStore(), nil)
+
+	_, err := log.SignedTreeHead(context.Background())
+	require.ErrorIs(t, err, ErrUnsigned)
+
+	_, err = log.Lookup(context.Background(), "github.com/traefik/plugindemo", "v0.1.0")
+	require.ErrorIs(t, err, ErrUnsigned)
+}
+
+func newSignedLog(t *testing.T, store Store) (*Log, note.Verifier) {
+	t.Helper()
+
+	signer := signing.NewSigner(ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, ed25519.SeedSize)))
+
+	noteSigner, err := signer.NoteSigner(logName)
+	require.NoError(t, err)
+
+	verifier, err := signer.NoteVerifier(logName)
+	require.NoError(t, err)
+
+	return New(store, noteSigner), verifier
+}
+
+func openTreeHead(t *testing.T, log *Log, verifier note.Verifier) tlog.Tree {
+	t.Helper()
+
+	msg, err := log.SignedTreeHead(context.Background())
+	require.NoError(t, err)
+
+	n, err := note.Open(msg, note.VerifierList(verifier))
+	require.NoError(t, err)
+
+	tree, err := tlog.ParseTree([]byte(n.Text))
+	require.NoError(t, err)
+
+	return tree
+}
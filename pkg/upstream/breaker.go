@@ -0,0 +1,209 @@
+// Package upstream protects the service from slow or failing upstreams (Go proxy, GitHub):
+// the requests are bounded by a timeout, the idempotent requests are retried with a jittered backoff,
+// and a circuit breaker rejects the requests without sending them while an upstream keeps failing.
+package upstream
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// State the state of a circuit breaker.
+type State int
+
+// Circuit breaker states.
+const (
+	// StateClosed the requests are sent.
+	StateClosed State = iota
+	// StateHalfOpen a single request is sent to probe the upstream, the others are rejected.
+	StateHalfOpen
+	// StateOpen the requests are rejected until the end of the cooldown.
+	StateOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half-open"
+	case StateOpen:
+		return "open"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// OpenError is returned, without sending the request, when the circuit breaker of the upstream is open.
+type OpenError struct {
+	Upstream string
+	// RetryAt the end of the cooldown, after which a request probes the upstream.
+	RetryAt time.Time
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("%s circuit breaker open until %s", e.Upstream, e.RetryAt.Format(time.RFC3339))
+}
+
+// Status the state of a circuit breaker, as exposed by the readiness endpoint.
+type Status struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+	// Failures the number of consecutive failures.
+	Failures int        `json:"failures"`
+	RetryAt  *time.Time `json:"retryAt,omitempty"`
+}
+
+type outcome int
+
+const (
+	outcomeSuccess outcome = iota
+	outcomeFailure
+	// outcomeIgnored the request has been canceled by the caller, it tells nothing about the upstream.
+	outcomeIgnored
+)
+
+// Breaker is a circuit breaker, opened after threshold consecutive failures of an upstream.
+// After the cooldown, a single request probes the upstream: the breaker closes if it succeeds, and opens again otherwise.
+type Breaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+
+	now func() time.Time
+}
+
+// NewBreaker creates a Breaker, a threshold of 0 disables the breaker.
+func NewBreaker(name string, threshold int, cooldown time.Duration) *Breaker {
+	b := &Breaker{
+		name:      name,
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+
+	if err := b.registerMetrics(); err != nil {
+		log.Warn().Err(err).Str("upstream", name).Msg("Unable to register the circuit breaker metrics")
+	}
+
+	return b
+}
+
+// Name returns the name of the upstream.
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// Status returns the state of the breaker.
+func (b *Breaker) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := Status{Name: b.name, State: b.currentState().String(), Failures: b.failures}
+
+	if b.state == StateOpen {
+		retryAt := b.openedAt.Add(b.cooldown)
+		status.RetryAt = &retryAt
+	}
+
+	return status
+}
+
+// allow returns an OpenError if the request must not be sent.
+func (b *Breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.currentState() {
+	case StateOpen:
+		return &OpenError{Upstream: b.name, RetryAt: b.openedAt.Add(b.cooldown)}
+
+	case StateHalfOpen:
+		if b.probing {
+			return &OpenError{Upstream: b.name, RetryAt: b.now()}
+		}
+
+		b.state = StateHalfOpen
+		b.probing = true
+	}
+
+	return nil
+}
+
+// done records the outcome of a request allowed by the breaker.
+func (b *Breaker) done(result outcome) {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	probe := b.probing
+	b.probing = false
+
+	switch result {
+	case outcomeSuccess:
+		if b.state != StateClosed {
+			log.Info().Str("upstream", b.name).Msg("Circuit breaker closed")
+		}
+
+		b.state = StateClosed
+		b.failures = 0
+
+	case outcomeFailure:
+		b.failures++
+
+		if probe || b.state == StateClosed && b.failures >= b.threshold {
+			log.Warn().Str("upstream", b.name).Int("failures", b.failures).Dur("cooldown", b.cooldown).Msg("Circuit breaker opened")
+
+			b.state = StateOpen
+			b.openedAt = b.now()
+		}
+	}
+}
+
+// currentState returns the state of the breaker, half-open once the cooldown is over.
+func (b *Breaker) currentState() State {
+	if b.state == StateOpen && !b.now().Before(b.openedAt.Add(b.cooldown)) {
+		return StateHalfOpen
+	}
+
+	return b.state
+}
+
+func (b *Breaker) registerMetrics() error {
+	meter := otel.Meter("upstream")
+
+	state, err := meter.Int64ObservableGauge("upstream.breaker.state",
+		metric.WithDescription("State of the circuit breaker of an upstream: 0 closed, 1 half-open, 2 open."),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, observer metric.Observer) error {
+		b.mu.Lock()
+		current := b.currentState()
+		b.mu.Unlock()
+
+		observer.ObserveInt64(state, int64(current), metric.WithAttributes(attribute.String("upstream", b.name)))
+
+		return nil
+	}, state)
+
+	return err
+}
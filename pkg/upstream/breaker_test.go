@@ -0,0 +1,72 @@
+package upstream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	breaker := NewBreaker("goproxy", 2, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	require.NoError(t, breaker.allow())
+	breaker.done(outcomeFailure)
+
+	// A success resets the consecutive failures.
+	require.NoError(t, breaker.allow())
+	breaker.done(outcomeSuccess)
+
+	for range 2 {
+		require.NoError(t, breaker.allow())
+		breaker.done(outcomeFailure)
+	}
+
+	retryAt := now.Add(time.Minute)
+	assert.Equal(t, Status{Name: "goproxy", State: "open", Failures: 2, RetryAt: &retryAt}, breaker.Status())
+
+	var openErr *OpenError
+	require.ErrorAs(t, breaker.allow(), &openErr)
+	assert.Equal(t, &OpenError{Upstream: "goproxy", RetryAt: retryAt}, openErr)
+
+	// After the cooldown, a single request probes the upstream.
+	now = now.Add(time.Minute)
+
+	assert.Equal(t, "half-open", breaker.Status().State)
+
+	require.NoError(t, breaker.allow())
+	require.ErrorAs(t, breaker.allow(), &openErr)
+
+	// A failed probe opens the breaker again.
+	breaker.done(outcomeFailure)
+
+	assert.Equal(t, "open", breaker.Status().State)
+
+	now = now.Add(time.Minute)
+
+	// A canceled probe doesn't change the state.
+	require.NoError(t, breaker.allow())
+	breaker.done(outcomeIgnored)
+
+	assert.Equal(t, "half-open", breaker.Status().State)
+
+	require.NoError(t, breaker.allow())
+	breaker.done(outcomeSuccess)
+
+	assert.Equal(t, Status{Name: "goproxy", State: "closed"}, breaker.Status())
+}
+
+func TestBreaker_disabled(t *testing.T) {
+	breaker := NewBreaker("github", 0, time.Minute)
+
+	for range 10 {
+		require.NoError(t, breaker.allow())
+		breaker.done(outcomeFailure)
+	}
+
+	assert.Equal(t, Status{Name: "github", State: "closed"}, breaker.Status())
+}
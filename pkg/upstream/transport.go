@@ -0,0 +1,162 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/plugin-service/pkg/internal/httputil"
+)
+
+// Default configuration of an upstream.
+const (
+	DefaultTimeout          = 30 * time.Second
+	DefaultRetries          = 2
+	DefaultFailureThreshold = 5
+	DefaultCooldown         = 30 * time.Second
+)
+
+const (
+	retryBaseDelay = 200 * time.Millisecond
+	retryMaxDelay  = 5 * time.Second
+)
+
+// Config the configuration of an upstream.
+type Config struct {
+	// Timeout the timeout of a request, including the read of the response body (0 to disable).
+	Timeout time.Duration
+	// Retries the number of retries of the idempotent requests failing with a network error or a 5xx.
+	Retries int
+	// FailureThreshold the number of consecutive failures opening the circuit breaker (0 to disable).
+	FailureThreshold int
+	// Cooldown the duration during which the circuit breaker stays open.
+	Cooldown time.Duration
+}
+
+// Transport is an http.RoundTripper applying the timeout, the retries and the circuit breaker of an upstream.
+type Transport struct {
+	base    http.RoundTripper
+	breaker *Breaker
+	timeout time.Duration
+	retries int
+
+	baseDelay time.Duration
+}
+
+// NewTransport creates a Transport for the named upstream, sending the requests with base, or with http.DefaultTransport if base is nil.
+func NewTransport(name string, base http.RoundTripper, cfg Config) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &Transport{
+		base:      base,
+		breaker:   NewBreaker(name, cfg.FailureThreshold, cfg.Cooldown),
+		timeout:   cfg.Timeout,
+		retries:   cfg.Retries,
+		baseDelay: retryBaseDelay,
+	}
+}
+
+// Breaker returns the circuit breaker of the upstream.
+func (t *Transport) Breaker() *Breaker {
+	return t.breaker
+}
+
+// RoundTrip sends the request, unless the circuit breaker is open.
+// The idempotent requests failing with a network error or a 5xx are retried after a jittered backoff.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	retryable := httputil.IsReplayable(req)
+
+	for attempt := 0; ; attempt++ {
+		if err := t.breaker.allow(); err != nil {
+			httputil.CloseBody(req)
+			return nil, err
+		}
+
+		resp, err := t.send(req)
+
+		result := outcomeOf(req, resp, err)
+		t.breaker.done(result)
+
+		if result != outcomeFailure || !retryable || attempt >= t.retries {
+			return resp, err
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		log.Debug().Err(err).Str("upstream", t.breaker.Name()).Int("attempt", attempt+1).Msg("Upstream request failed, retrying")
+
+		timer := time.NewTimer(t.backoff(attempt))
+
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (t *Transport) send(req *http.Request) (*http.Response, error) {
+	if t.timeout <= 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// The timeout covers the read of the body, the context is released when the body is closed.
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+
+	return resp, nil
+}
+
+// backoff returns the delay before a retry: an exponential backoff, half of which is randomized.
+func (t *Transport) backoff(attempt int) time.Duration {
+	delay := min(t.baseDelay<<attempt, retryMaxDelay)
+
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// outcomeOf returns the outcome of a request for the circuit breaker.
+func outcomeOf(req *http.Request, resp *http.Response, err error) outcome {
+	if err != nil {
+		if errors.Is(req.Context().Err(), context.Canceled) {
+			return outcomeIgnored
+		}
+
+		return outcomeFailure
+	}
+
+	switch resp.StatusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return outcomeFailure
+	default:
+		return outcomeSuccess
+	}
+}
+
+type cancelBody struct {
+	io.ReadCloser
+
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+
+	return b.ReadCloser.Close()
+}
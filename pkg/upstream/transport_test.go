@@ -0,0 +1,151 @@
+package upstream
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupUpstream serves the given status codes in order, and then 200.
+func setupUpstream(t *testing.T, statuses ...int) (string, *atomic.Int64) {
+	t.Helper()
+
+	var requests atomic.Int64
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		i := int(requests.Add(1)) - 1
+		if i < len(statuses) {
+			rw.WriteHeader(statuses[i])
+			return
+		}
+
+		_, _ = rw.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+
+	return server.URL, &requests
+}
+
+func newTestTransport(cfg Config) *Transport {
+	transport := NewTransport("goproxy", nil, cfg)
+	transport.baseDelay = time.Millisecond
+
+	return transport
+}
+
+func TestTransport_RoundTrip_retries(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		method    string
+		statuses  []int
+		expStatus int
+		expSent   int64
+	}{
+		{
+			desc:      "retried server errors",
+			method:    http.MethodGet,
+			statuses:  []int{http.StatusBadGateway, http.StatusServiceUnavailable},
+			expStatus: http.StatusOK,
+			expSent:   3,
+		},
+		{
+			desc:      "retries exhausted",
+			method:    http.MethodGet,
+			statuses:  []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			expStatus: http.StatusBadGateway,
+			expSent:   3,
+		},
+		{
+			desc:      "client error",
+			method:    http.MethodGet,
+			statuses:  []int{http.StatusNotFound},
+			expStatus: http.StatusNotFound,
+			expSent:   1,
+		},
+		{
+			desc:      "not idempotent",
+			method:    http.MethodPost,
+			statuses:  []int{http.StatusBadGateway},
+			expStatus: http.StatusBadGateway,
+			expSent:   1,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			serverURL, requests := setupUpstream(t, test.statuses...)
+
+			transport := newTestTransport(Config{Timeout: time.Second, Retries: 2})
+
+			req, err := http.NewRequestWithContext(context.Background(), test.method, serverURL, http.NoBody)
+			require.NoError(t, err)
+
+			resp, err := transport.RoundTrip(req)
+			require.NoError(t, err)
+
+			_ = resp.Body.Close()
+
+			assert.Equal(t, test.expStatus, resp.StatusCode)
+			assert.Equal(t, test.expSent, requests.Load())
+		})
+	}
+}
+
+func TestTransport_RoundTrip_timeout(t *testing.T) {
+	var requests atomic.Int64
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+
+		select {
+		case <-req.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	transport := newTestTransport(Config{Timeout: 20 * time.Millisecond, Retries: 1, FailureThreshold: 2, Cooldown: time.Minute})
+
+	client := &http.Client{Transport: transport}
+
+	_, err := client.Get(server.URL)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	assert.EqualValues(t, 2, requests.Load())
+
+	// The breaker opened after the two timeouts, the next request is rejected without being sent.
+	_, err = client.Get(server.URL)
+
+	var openErr *OpenError
+	require.ErrorAs(t, err, &openErr)
+	assert.Equal(t, "goproxy", openErr.Upstream)
+
+	assert.EqualValues(t, 2, requests.Load())
+	assert.Equal(t, "open", transport.Breaker().Status().State)
+}
+
+func TestTransport_RoundTrip_bodyTimeout(t *testing.T) {
+	serverURL, _ := setupUpstream(t)
+
+	transport := newTestTransport(Config{Timeout: time.Second})
+
+	resp, err := (&http.Client{Transport: transport}).Get(serverURL)
+	require.NoError(t, err)
+
+	// The body can be read until the timeout.
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	assert.Equal(t, "ok", strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,280 @@
+// Package webhooks sends the catalog change events to the subscribed webhooks.
+//
+// An event is recorded as a delivery for every matching subscription, and sent by a job of the jobs.Queue:
+// a failed delivery is retried with the backoff of the queue, and every attempt is kept in the delivery history.
+// The payloads are signed with the secret of the subscription (X-Plugin-Signature-256: sha256=<hex HMAC-SHA256>).
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/plugin-service/pkg/db"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Headers of the deliveries.
+const (
+	EventHeader     = "X-Plugin-Event"
+	DeliveryHeader  = "X-Plugin-Delivery"
+	SignatureHeader = "X-Plugin-Signature-256"
+)
+
+const (
+	defaultTimeout = 10 * time.Second
+	maxErrorBody   = 1 << 10 // 1KB
+)
+
+// Store persists the subscriptions and the deliveries.
+type Store interface {
+	CreateSubscription(ctx context.Context, subscription db.Subscription) (db.Subscription, error)
+	GetSubscription(ctx context.Context, id string) (db.Subscription, error)
+	ListSubscriptions(ctx context.Context) ([]db.Subscription, error)
+	DeleteSubscription(ctx context.Context, id string) error
+
+	CreateDelivery(ctx context.Context, delivery db.Delivery) (db.Delivery, error)
+	GetDelivery(ctx context.Context, id string) (db.Delivery, error)
+	RecordDeliveryAttempt(ctx context.Context, id string, attempt db.DeliveryAttempt, status string) (db.Delivery, error)
+	ListDeliveries(ctx context.Context, subscription string) ([]db.Delivery, error)
+}
+
+// Queue runs the deliveries asynchronously.
+type Queue interface {
+	Enqueue(ctx context.Context, job db.Job) (db.Job, error)
+}
+
+// Dispatcher sends the events to the subscribed webhooks.
+type Dispatcher struct {
+	store  Store
+	queue  Queue
+	client *http.Client
+	tracer trace.Tracer
+}
+
+// NewDispatcher creates a Dispatcher.
+// The deliveries are sent with the given client, or with a client timing out after 10s.
+func NewDispatcher(store Store, queue Queue, client *http.Client) *Dispatcher {
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+
+	return &Dispatcher{
+		store:  store,
+		queue:  queue,
+		client: client,
+		tracer: otel.Tracer("webhooks"),
+	}
+}
+
+// Subscribe creates a subscription.
+// A secret is generated when the subscription doesn't define one.
+func (d *Dispatcher) Subscribe(ctx context.Context, subscription db.Subscription) (db.Subscription, error) {
+	if subscription.Secret == "" {
+		secret, err := newSecret()
+		if err != nil {
+			return db.Subscription{}, err
+		}
+
+		subscription.Secret = secret
+	}
+
+	return d.store.CreateSubscription(ctx, subscription)
+}
+
+// Unsubscribe deletes a subscription.
+func (d *Dispatcher) Unsubscribe(ctx context.Context, id string) error {
+	return d.store.DeleteSubscription(ctx, id)
+}
+
+// GetSubscription gets a subscription, without its secret.
+func (d *Dispatcher) GetSubscription(ctx context.Context, id string) (db.Subscription, error) {
+	subscription, err := d.store.GetSubscription(ctx, id)
+	if err != nil {
+		return db.Subscription{}, err
+	}
+
+	subscription.Secret = ""
+
+	return subscription, nil
+}
+
+// ListSubscriptions lists the subscriptions, without their secret.
+func (d *Dispatcher) ListSubscriptions(ctx context.Context) ([]db.Subscription, error) {
+	subscriptions, err := d.store.ListSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+
+	return subscriptions, nil
+}
+
+// ListDeliveries lists the deliveries of a subscription, the most recent first.
+func (d *Dispatcher) ListDeliveries(ctx context.Context, subscription string) ([]db.Delivery, error) {
+	if _, err := d.store.GetSubscription(ctx, subscription); err != nil {
+		return nil, err
+	}
+
+	return d.store.ListDeliveries(ctx, subscription)
+}
+
+// Notify records a delivery of the event for every subscription matching its type, and enqueues them.
+func (d *Dispatcher) Notify(ctx context.Context, event db.Event) error {
+	ctx, span := d.tracer.Start(ctx, "webhooks_notify")
+	defer span.End()
+
+	subscriptions, err := d.store.ListSubscriptions(ctx)
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("list subscriptions: %w", err)
+	}
+
+	var errs []error
+
+	for _, subscription := range subscriptions {
+		if len(subscription.Events) > 0 && !slices.Contains(subscription.Events, event.Type) {
+			continue
+		}
+
+		delivery, err := d.store.CreateDelivery(ctx, db.Delivery{Subscription: subscription.ID, Event: event})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("create delivery for subscription %s: %w", subscription.ID, err))
+			continue
+		}
+
+		_, err = d.queue.Enqueue(ctx, db.Job{
+			Kind:     db.JobDeliver,
+			Module:   event.Module,
+			Version:  event.Version,
+			Delivery: delivery.ID,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("enqueue delivery %s: %w", delivery.ID, err))
+		}
+	}
+
+	if err = errors.Join(errs...); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	return nil
+}
+
+// Deliver sends a delivery, it is the handler of the db.JobDeliver jobs.
+// A failed attempt returns an error, so that the delivery is retried by the queue.
+func (d *Dispatcher) Deliver(ctx context.Context, job db.Job) error {
+	ctx, span := d.tracer.Start(ctx, "webhooks_deliver")
+	defer span.End()
+
+	delivery, err := d.store.GetDelivery(ctx, job.Delivery)
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("get delivery: %w", err)
+	}
+
+	subscription, err := d.store.GetSubscription(ctx, delivery.Subscription)
+	if err != nil {
+		if errors.As(err, &db.NotFoundError{}) {
+			log.Info().Str("delivery_id", delivery.ID).Str("subscription_id", delivery.Subscription).Msg("Subscription deleted, delivery dropped")
+			return nil
+		}
+
+		span.RecordError(err)
+
+		return fmt.Errorf("get subscription: %w", err)
+	}
+
+	attempt := d.send(ctx, subscription, delivery)
+
+	status := db.DeliverySucceeded
+	if attempt.Error != "" {
+		status = db.DeliveryFailed
+	}
+
+	if _, err = d.store.RecordDeliveryAttempt(ctx, delivery.ID, attempt, status); err != nil {
+		span.RecordError(err)
+		log.Error().Err(err).Str("delivery_id", delivery.ID).Msg("Unable to record delivery attempt")
+	}
+
+	if attempt.Error != "" {
+		return errors.New(attempt.Error)
+	}
+
+	return nil
+}
+
+func (d *Dispatcher) send(ctx context.Context, subscription db.Subscription, delivery db.Delivery) db.DeliveryAttempt {
+	attempt := db.DeliveryAttempt{At: time.Now()}
+
+	payload, err := json.Marshal(delivery.Event)
+	if err != nil {
+		attempt.Error = fmt.Sprintf("marshal event: %v", err)
+		return attempt
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(payload))
+	if err != nil {
+		attempt.Error = fmt.Sprintf("create request: %v", err)
+		return attempt
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "plugin-service")
+	req.Header.Set(EventHeader, delivery.Event.Type)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(SignatureHeader, Sign(payload, subscription.Secret))
+
+	resp, err := d.client.Do(req)
+
+	attempt.Duration = time.Since(attempt.At).Milliseconds()
+
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	attempt.StatusCode = resp.StatusCode
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		attempt.Error = fmt.Sprintf("unexpected status code %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+
+	return attempt
+}
+
+// Sign returns the signature of a payload: sha256=<hex HMAC-SHA256 of the payload>.
+func Sign(payload []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newSecret() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("generate webhook secret: %w", err)
+	}
+
+	return hex.EncodeToString(raw), nil
+}
@@ -0,0 +1,285 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/plugin-service/pkg/db"
+)
+
+// memoryStore is a Store keeping the subscriptions and the deliveries in memory.
+type memoryStore struct {
+	mu            sync.Mutex
+	subscriptions []db.Subscription
+	deliveries    []db.Delivery
+}
+
+func (s *memoryStore) CreateSubscription(_ context.Context, subscription db.Subscription) (db.Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscription.ID = fmt.Sprint("s", len(s.subscriptions)+1)
+	subscription.CreatedAt = time.Now()
+	s.subscriptions = append(s.subscriptions, subscription)
+
+	return subscription, nil
+}
+
+func (s *memoryStore) GetSubscription(_ context.Context, id string) (db.Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, subscription := range s.subscriptions {
+		if subscription.ID == id {
+			return subscription, nil
+		}
+	}
+
+	return db.Subscription{}, db.NotFoundError{}
+}
+
+func (s *memoryStore) ListSubscriptions(_ context.Context) ([]db.Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.subscriptions), nil
+}
+
+func (s *memoryStore) DeleteSubscription(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, subscription := range s.subscriptions {
+		if subscription.ID == id {
+			s.subscriptions = slices.Delete(s.subscriptions, i, i+1)
+			return nil
+		}
+	}
+
+	return db.NotFoundError{}
+}
+
+func (s *memoryStore) CreateDelivery(_ context.Context, delivery db.Delivery) (db.Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivery.ID = fmt.Sprint("d", len(s.deliveries)+1)
+	delivery.Status = db.DeliveryPending
+	delivery.CreatedAt = time.Now()
+	s.deliveries = append(s.deliveries, delivery)
+
+	return delivery, nil
+}
+
+func (s *memoryStore) GetDelivery(_ context.Context, id string) (db.Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, delivery := range s.deliveries {
+		if delivery.ID == id {
+			return delivery, nil
+		}
+	}
+
+	return db.Delivery{}, db.NotFoundError{}
+}
+
+func (s *memoryStore) RecordDeliveryAttempt(_ context.Context, id string, attempt db.DeliveryAttempt, status string) (db.Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, delivery := range s.deliveries {
+		if delivery.ID == id {
+			s.deliveries[i].Attempts = append(s.deliveries[i].Attempts, attempt)
+			s.deliveries[i].Status = status
+
+			return s.deliveries[i], nil
+		}
+	}
+
+	return db.Delivery{}, db.NotFoundError{}
+}
+
+func (s *memoryStore) ListDeliveries(_ context.Context, subscription string) ([]db.Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deliveries []db.Delivery
+
+	for _, delivery := range s.deliveries {
+		if delivery.Subscription == subscription {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+
+	return deliveries, nil
+}
+
+// fakeQueue is a Queue recording the enqueued jobs.
+type fakeQueue struct {
+	jobs []db.Job
+}
+
+func (f *fakeQueue) Enqueue(_ context.Context, job db.Job) (db.Job, error) {
+	f.jobs = append(f.jobs, job)
+
+	return job, nil
+}
+
+func TestDispatcher_Notify(t *testing.T) {
+	store := &memoryStore{}
+	queue := &fakeQueue{}
+
+	dispatcher := NewDispatcher(store, queue, nil)
+
+	all, err := dispatcher.Subscribe(context.Background(), db.Subscription{URL: "https://example.com/all"})
+	require.NoError(t, err)
+	assert.Len(t, all.Secret, 64)
+
+	_, err = dispatcher.Subscribe(context.Background(), db.Subscription{URL: "https://example.com/mismatches", Events: []string{db.EventHashMismatch}})
+	require.NoError(t, err)
+
+	event := db.Event{ID: "e1", Type: db.EventVersionPublished, Module: "github.com/traefik/plugindemo", Version: "v0.2.1"}
+
+	err = dispatcher.Notify(context.Background(), event)
+	require.NoError(t, err)
+
+	assert.Equal(t, []db.Job{
+		{Kind: db.JobDeliver, Module: "github.com/traefik/plugindemo", Version: "v0.2.1", Delivery: "d1"},
+	}, queue.jobs)
+
+	deliveries, err := dispatcher.ListDeliveries(context.Background(), all.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+
+	assert.Equal(t, event, deliveries[0].Event)
+	assert.Equal(t, db.DeliveryPending, deliveries[0].Status)
+
+	subscriptions, err := dispatcher.ListSubscriptions(context.Background())
+	require.NoError(t, err)
+	require.Len(t, subscriptions, 2)
+
+	for _, subscription := range subscriptions {
+		assert.Empty(t, subscription.Secret)
+	}
+}
+
+func TestDispatcher_Deliver(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		status         int
+		expectedError  string
+		expectedStatus string
+	}{
+		{
+			desc:           "delivered",
+			status:         http.StatusNoContent,
+			expectedStatus: db.DeliverySucceeded,
+		},
+		{
+			desc:           "rejected",
+			status:         http.StatusInternalServerError,
+			expectedError:  "unexpected status code 500: boom",
+			expectedStatus: db.DeliveryFailed,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var received []byte
+
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				var err error
+
+				received, err = io.ReadAll(req.Body)
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				assert.Equal(t, db.EventPluginCreated, req.Header.Get(EventHeader))
+				assert.Equal(t, "d1", req.Header.Get(DeliveryHeader))
+				assert.Equal(t, Sign(received, "secret"), req.Header.Get(SignatureHeader))
+
+				rw.WriteHeader(test.status)
+				_, _ = rw.Write([]byte("boom\n"))
+			}))
+			t.Cleanup(server.Close)
+
+			store := &memoryStore{}
+			queue := &fakeQueue{}
+
+			dispatcher := NewDispatcher(store, queue, server.Client())
+
+			_, err := dispatcher.Subscribe(context.Background(), db.Subscription{URL: server.URL, Secret: "secret"})
+			require.NoError(t, err)
+
+			event := db.Event{ID: "e1", Type: db.EventPluginCreated, Module: "github.com/traefik/plugindemo"}
+
+			err = dispatcher.Notify(context.Background(), event)
+			require.NoError(t, err)
+			require.Len(t, queue.jobs, 1)
+
+			err = dispatcher.Deliver(context.Background(), queue.jobs[0])
+			if test.expectedError != "" {
+				require.EqualError(t, err, test.expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+
+			var payload db.Event
+			require.NoError(t, json.Unmarshal(received, &payload))
+			assert.Equal(t, event, payload)
+
+			delivery, err := store.GetDelivery(context.Background(), "d1")
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expectedStatus, delivery.Status)
+			require.Len(t, delivery.Attempts, 1)
+			assert.Equal(t, test.status, delivery.Attempts[0].StatusCode)
+			assert.Equal(t, test.expectedError, delivery.Attempts[0].Error)
+		})
+	}
+}
+
+func TestDispatcher_Deliver_unsubscribed(t *testing.T) {
+	store := &memoryStore{}
+	queue := &fakeQueue{}
+
+	dispatcher := NewDispatcher(store, queue, nil)
+
+	subscription, err := dispatcher.Subscribe(context.Background(), db.Subscription{URL: "http://127.0.0.1:0"})
+	require.NoError(t, err)
+
+	err = dispatcher.Notify(context.Background(), db.Event{ID: "e1", Type: db.EventPluginDeleted})
+	require.NoError(t, err)
+
+	err = dispatcher.Unsubscribe(context.Background(), subscription.ID)
+	require.NoError(t, err)
+
+	err = dispatcher.Deliver(context.Background(), queue.jobs[0])
+	require.NoError(t, err)
+
+	delivery, err := store.GetDelivery(context.Background(), "d1")
+	require.NoError(t, err)
+
+	assert.Empty(t, delivery.Attempts)
+}
+
+func TestSign(t *testing.T) {
+	// Reference value from the GitHub webhook documentation.
+	signature := Sign([]byte("Hello, World!"), "It's a Secret to Everybody")
+
+	assert.Equal(t, "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17", signature)
+}